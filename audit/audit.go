@@ -0,0 +1,87 @@
+// Package audit describes who performed a mutation, and as part of which request, for the audit log.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"ggv2/auth"
+	"ggv2/entities"
+)
+
+// Operations recorded in the audit log
+const (
+	OpCreateTable          = "create_table"
+	OpCreateTables         = "create_tables"
+	OpUpdateTable          = "update_table"
+	OpDeleteTable          = "delete_table"
+	OpEmptyTables          = "empty_tables"
+	OpRestoreArchive       = "restore_archive"
+	OpRestoreSnapshot      = "restore_snapshot"
+	OpAddToGuestList       = "add_to_guest_list"
+	OpGuestArrival         = "guest_arrival"
+	OpGuestDepart          = "guest_depart"
+	OpMemberArrival        = "member_arrival"
+	OpSaveLayoutTemplate   = "save_layout_template"
+	OpDeleteLayoutTemplate = "delete_layout_template"
+	OpApplyLayoutTemplate  = "apply_layout_template"
+	OpCreateAPIKey         = "create_api_key"
+	OpRevokeAPIKey         = "revoke_api_key"
+)
+
+// Anonymous is the actor of mutations performed without an authenticated principal
+const Anonymous = "anonymous"
+
+type requestIDKey struct{}
+
+// NewContext returns a copy of ctx carrying the request ID.
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Actor identifies the principal of ctx by authentication method and subject, e.g. "api_key:kiosk-1".
+func Actor(ctx context.Context) string {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p == nil {
+		return Anonymous
+	}
+	return p.Method + ":" + p.Subject
+}
+
+// NewEntry builds the audit entry of an operation on target performed within ctx.
+// Before and after are stored as JSON, nil values are stored as NULL.
+func NewEntry(ctx context.Context, operation, target string, before, after interface{}) (*entities.AuditEntry, error) {
+	b, err := marshal(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := marshal(after)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.AuditEntry{
+		Actor:     Actor(ctx),
+		RequestID: RequestID(ctx),
+		Operation: operation,
+		Target:    target,
+		Before:    b,
+		After:     a,
+	}, nil
+}
+
+func marshal(v interface{}) (entities.JSON, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if string(b) == "null" {
+		return nil, nil
+	}
+	return b, nil
+}
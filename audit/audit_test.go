@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"ggv2/auth"
+	"ggv2/entities"
+)
+
+func TestNewEntry(t *testing.T) {
+	type TestCase struct {
+		name      string
+		desc      string
+		ctx       context.Context
+		before    interface{}
+		after     interface{}
+		expActor  string
+		expReqID  string
+		expBefore entities.JSON
+		expAfter  entities.JSON
+	}
+	var noTable *entities.Table
+	testcases := []TestCase{
+		{
+			name:      "Happy case",
+			desc:      "authenticated request",
+			ctx:       NewContext(auth.NewContext(context.Background(), &auth.Principal{Subject: "kiosk-1", Method: auth.MethodAPIKey}), "req-1"),
+			before:    map[string]int{"capacity": 8},
+			after:     map[string]int{"capacity": 10},
+			expActor:  "api_key:kiosk-1",
+			expReqID:  "req-1",
+			expBefore: entities.JSON(`{"capacity":8}`),
+			expAfter:  entities.JSON(`{"capacity":10}`),
+		},
+		{
+			name:     "Happy case",
+			desc:     "anonymous, nil values stored as NULL",
+			ctx:      context.Background(),
+			before:   nil,
+			after:    noTable,
+			expActor: Anonymous,
+		},
+	}
+	for _, v := range testcases {
+		entry, err := NewEntry(v.ctx, OpUpdateTable, "table:1", v.before, v.after)
+		assert.Nil(t, err, v.desc)
+		assert.Equal(t, &entities.AuditEntry{
+			Actor:     v.expActor,
+			RequestID: v.expReqID,
+			Operation: OpUpdateTable,
+			Target:    "table:1",
+			Before:    v.expBefore,
+			After:     v.expAfter,
+		}, entry, v.desc)
+	}
+	_, err := NewEntry(context.Background(), OpUpdateTable, "table:1", nil, make(chan int))
+	assert.NotNil(t, err)
+}
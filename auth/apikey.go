@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+)
+
+const (
+	// apiKeyPrefix makes keys recognisable in configuration and secret scanners
+	apiKeyPrefix = "ggv2_"
+	// PrefixLength is the number of leading characters of a key kept in clear to identify it
+	PrefixLength = 12
+)
+
+// ErrInvalidAPIKey reports a key that is unknown or revoked, the two are not told apart
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// GenerateAPIKey returns a new random API key. Only its hash should ever be stored.
+func GenerateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash of key.
+// Keys are long random strings, so a fast hash is enough to make stored hashes useless to an attacker.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyPrefix returns the clear text prefix used to identify key in listings.
+func KeyPrefix(key string) string {
+	if len(key) < PrefixLength {
+		return key
+	}
+	return key[:PrefixLength]
+}
+
+// equalKeys compares two keys in constant time.
+func equalKeys(a, b string) bool {
+	ha, hb := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.RegisteredClaims) string {
+	return signWithRole(t, method, key, "", claims)
+}
+
+func signWithRole(t *testing.T, method jwt.SigningMethod, key interface{}, role string, claims jwt.RegisteredClaims) string {
+	token, err := jwt.NewWithClaims(method, &Claims{Role: role, RegisteredClaims: claims}).SignedString(key)
+	assert.Nil(t, err)
+	return token
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	secret := []byte("secret")
+	valid := jwt.RegisteredClaims{Subject: "planner", Issuer: "ggv2", Audience: jwt.ClaimStrings{"ggv2-api"}, ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+	type TestCase struct {
+		name    string
+		desc    string
+		cfg     *Config
+		token   string
+		expSub  string
+		expRole string
+	}
+	testcases := []TestCase{
+		{
+			name:    "Happy case",
+			desc:    "HS256 token",
+			cfg:     &Config{JWTSecret: secret},
+			token:   sign(t, jwt.SigningMethodHS256, secret, valid),
+			expSub:  "planner",
+			expRole: RoleViewer,
+		},
+		{
+			name:    "Happy case",
+			desc:    "RS256 token",
+			cfg:     &Config{JWTPublicKey: &rsaKey.PublicKey},
+			token:   sign(t, jwt.SigningMethodRS256, rsaKey, valid),
+			expSub:  "planner",
+			expRole: RoleViewer,
+		},
+		{
+			name:    "Happy case",
+			desc:    "issuer and audience match",
+			cfg:     &Config{JWTSecret: secret, JWTIssuer: "ggv2", JWTAudience: "ggv2-api"},
+			token:   sign(t, jwt.SigningMethodHS256, secret, valid),
+			expSub:  "planner",
+			expRole: RoleViewer,
+		},
+		{
+			name:    "Happy case",
+			desc:    "token with role",
+			cfg:     &Config{JWTSecret: secret},
+			token:   signWithRole(t, jwt.SigningMethodHS256, secret, RolePlanner, valid),
+			expSub:  "planner",
+			expRole: RolePlanner,
+		},
+		{
+			name:  "Sad case",
+			desc:  "unknown role",
+			cfg:   &Config{JWTSecret: secret},
+			token: signWithRole(t, jwt.SigningMethodHS256, secret, "owner", valid),
+		},
+		{
+			name:  "Sad case",
+			desc:  "wrong secret",
+			cfg:   &Config{JWTSecret: secret},
+			token: sign(t, jwt.SigningMethodHS256, []byte("other"), valid),
+		},
+		{
+			name:  "Sad case",
+			desc:  "RS256 token signed by another key",
+			cfg:   &Config{JWTPublicKey: &rsaKey.PublicKey},
+			token: sign(t, jwt.SigningMethodRS256, otherKey, valid),
+		},
+		{
+			name:  "Sad case",
+			desc:  "HS256 token when only RS256 is configured",
+			cfg:   &Config{JWTPublicKey: &rsaKey.PublicKey},
+			token: sign(t, jwt.SigningMethodHS256, secret, valid),
+		},
+		{
+			name:  "Sad case",
+			desc:  "unsigned token",
+			cfg:   &Config{JWTSecret: secret},
+			token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid),
+		},
+		{
+			name:  "Sad case",
+			desc:  "HS512 token",
+			cfg:   &Config{JWTSecret: secret},
+			token: sign(t, jwt.SigningMethodHS512, secret, valid),
+		},
+		{
+			name:  "Sad case",
+			desc:  "expired token",
+			cfg:   &Config{JWTSecret: secret},
+			token: sign(t, jwt.SigningMethodHS256, secret, jwt.RegisteredClaims{Subject: "planner", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}),
+		},
+		{
+			name:  "Sad case",
+			desc:  "token without subject",
+			cfg:   &Config{JWTSecret: secret},
+			token: sign(t, jwt.SigningMethodHS256, secret, jwt.RegisteredClaims{}),
+		},
+		{
+			name:  "Sad case",
+			desc:  "token without expiry",
+			cfg:   &Config{JWTSecret: secret},
+			token: sign(t, jwt.SigningMethodHS256, secret, jwt.RegisteredClaims{Subject: "planner"}),
+		},
+		{
+			name:  "Sad case",
+			desc:  "token expiring beyond the maximum lifetime",
+			cfg:   &Config{JWTSecret: secret, JWTMaxLifetime: 30 * time.Minute},
+			token: sign(t, jwt.SigningMethodHS256, secret, valid),
+		},
+		{
+			name:  "Sad case",
+			desc:  "wrong issuer",
+			cfg:   &Config{JWTSecret: secret, JWTIssuer: "other"},
+			token: sign(t, jwt.SigningMethodHS256, secret, valid),
+		},
+		{
+			name:  "Sad case",
+			desc:  "wrong audience",
+			cfg:   &Config{JWTSecret: secret, JWTAudience: "other"},
+			token: sign(t, jwt.SigningMethodHS256, secret, valid),
+		},
+		{
+			name:  "Sad case",
+			desc:  "malformed token",
+			cfg:   &Config{JWTSecret: secret},
+			token: "not.a.token",
+		},
+	}
+	for _, v := range testcases {
+		p, err := NewJWTVerifier(v.cfg).Verify(v.token)
+		if v.expSub == "" {
+			assert.NotNil(t, err, v.desc)
+			assert.Nil(t, p, v.desc)
+			continue
+		}
+		assert.Nil(t, err, v.desc)
+		assert.Equal(t, &Principal{Subject: v.expSub, Method: MethodJWT, Role: v.expRole}, p, v.desc)
+	}
+}
+
+func TestNewJWTVerifierWithoutKeys(t *testing.T) {
+	assert.Nil(t, NewJWTVerifier(&Config{}))
+}
+
+func TestAPIKey(t *testing.T) {
+	key, err := GenerateAPIKey()
+	assert.Nil(t, err)
+	other, err := GenerateAPIKey()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(key, apiKeyPrefix))
+	assert.NotEqual(t, key, other)
+	assert.Len(t, HashAPIKey(key), 64)
+	assert.Equal(t, HashAPIKey(key), HashAPIKey(key))
+	assert.NotEqual(t, HashAPIKey(key), HashAPIKey(other))
+	assert.Equal(t, key[:PrefixLength], KeyPrefix(key))
+}
+
+func TestIsBootstrapKey(t *testing.T) {
+	assert.True(t, (&Config{BootstrapAPIKey: "bootstrap"}).IsBootstrapKey("bootstrap"))
+	assert.False(t, (&Config{BootstrapAPIKey: "bootstrap"}).IsBootstrapKey("other"))
+	assert.False(t, (&Config{}).IsBootstrapKey(""))
+}
+
+func TestCan(t *testing.T) {
+	type TestCase struct {
+		name    string
+		desc    string
+		role    string
+		allowed []Permission
+	}
+	testcases := []TestCase{
+		{
+			name:    "Happy case",
+			desc:    "admin can do everything",
+			role:    RoleAdmin,
+			allowed: []Permission{PermRead, PermCheckIn, PermPlan, PermAdmin},
+		},
+		{
+			name:    "Happy case",
+			desc:    "planner cannot check in or administer",
+			role:    RolePlanner,
+			allowed: []Permission{PermRead, PermPlan},
+		},
+		{
+			name:    "Happy case",
+			desc:    "door staff can only read and check in",
+			role:    RoleDoorStaff,
+			allowed: []Permission{PermRead, PermCheckIn},
+		},
+		{
+			name:    "Happy case",
+			desc:    "viewer can only read",
+			role:    RoleViewer,
+			allowed: []Permission{PermRead},
+		},
+		{
+			name: "Sad case",
+			desc: "unknown role cannot do anything",
+			role: "owner",
+		},
+	}
+	for _, v := range testcases {
+		p := &Principal{Subject: "dummy", Role: v.role}
+		for _, perm := range []Permission{PermRead, PermCheckIn, PermPlan, PermAdmin} {
+			allowed := false
+			for _, a := range v.allowed {
+				allowed = allowed || a == perm
+			}
+			assert.Equal(t, allowed, p.Can(perm), "%s: %s", v.desc, perm)
+		}
+		assert.Equal(t, v.name == "Happy case", IsValidRole(v.role), v.desc)
+	}
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+	p := &Principal{Subject: "planner", Method: MethodAPIKey}
+	actP, ok := FromContext(NewContext(context.Background(), p))
+	assert.True(t, ok)
+	assert.Equal(t, p, actP)
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"io/ioutil"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"ggv2/config"
+)
+
+// Config holds the credentials accepted by the service
+type Config struct {
+	// JWTSecret verifies HS256 tokens
+	JWTSecret []byte
+	// JWTPublicKey verifies RS256 tokens
+	JWTPublicKey *rsa.PublicKey
+	// JWTIssuer and JWTAudience, when set, must match the iss and aud claims
+	JWTIssuer   string
+	JWTAudience string
+	// JWTMaxLifetime rejects tokens expiring further in the future, tokens must expire
+	JWTMaxLifetime time.Duration
+	// BootstrapAPIKey is accepted without a database lookup, so that the first keys can be created
+	BootstrapAPIKey string
+}
+
+// NewConfig builds the authentication settings, reading the RS256 public key file when set.
+func NewConfig(c config.AuthConfig) (*Config, error) {
+	cfg := &Config{
+		JWTSecret:       []byte(c.JWTSecret),
+		JWTIssuer:       c.JWTIssuer,
+		JWTAudience:     c.JWTAudience,
+		JWTMaxLifetime:  c.JWTMaxLifetime,
+		BootstrapAPIKey: c.BootstrapAPIKey,
+	}
+	if path := c.JWTPublicKeyFile; path != "" {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.JWTPublicKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// IsBootstrapKey reports whether key is the configured bootstrap key.
+func (c *Config) IsBootstrapKey(key string) bool {
+	return c.BootstrapAPIKey != "" && equalKeys(c.BootstrapAPIKey, key)
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	errUnexpectedAlg   = errors.New("unexpected signing algorithm")
+	errMissingClaim    = errors.New("token has no subject")
+	errInvalidIssuer   = errors.New("invalid token issuer")
+	errInvalidAud      = errors.New("invalid token audience")
+	errInvalidRole     = errors.New("invalid token role")
+	errMissingExpiry   = errors.New("token has no expiry")
+	errLifetimeTooLong = errors.New("token expires too far in the future")
+)
+
+// Claims are the JWT claims accepted by the service
+type Claims struct {
+	Role string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// JWTVerifier validates HS256 and RS256 signed tokens against locally configured keys
+type JWTVerifier struct {
+	secret      []byte
+	publicKey   *rsa.PublicKey
+	issuer      string
+	audience    string
+	maxLifetime time.Duration
+}
+
+// NewJWTVerifier returns a verifier for the keys in cfg, or nil when no key is configured.
+func NewJWTVerifier(cfg *Config) *JWTVerifier {
+	if len(cfg.JWTSecret) == 0 && cfg.JWTPublicKey == nil {
+		return nil
+	}
+	return &JWTVerifier{
+		secret:      cfg.JWTSecret,
+		publicKey:   cfg.JWTPublicKey,
+		issuer:      cfg.JWTIssuer,
+		audience:    cfg.JWTAudience,
+		maxLifetime: cfg.JWTMaxLifetime,
+	}
+}
+
+// Verify checks the signature and standard claims of token and returns its principal. Tokens
+// must expire, within the maximum lifetime when one is configured.
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, v.key)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errMissingClaim
+	}
+	if claims.ExpiresAt == nil {
+		return nil, errMissingExpiry
+	}
+	if v.maxLifetime > 0 && time.Until(claims.ExpiresAt.Time) > v.maxLifetime {
+		return nil, errLifetimeTooLong
+	}
+	if v.issuer != "" && !claims.VerifyIssuer(v.issuer, true) {
+		return nil, errInvalidIssuer
+	}
+	if v.audience != "" && !claims.VerifyAudience(v.audience, true) {
+		return nil, errInvalidAud
+	}
+	role := claims.Role
+	if role == "" {
+		// Tokens without a role are read-only
+		role = RoleViewer
+	}
+	if !IsValidRole(role) {
+		return nil, errInvalidRole
+	}
+	return &Principal{Subject: claims.Subject, Method: MethodJWT, Role: role}, nil
+}
+
+// key selects the verification key by algorithm, so that a token can never pick
+// an algorithm the service was not configured for.
+func (v *JWTVerifier) key(t *jwt.Token) (interface{}, error) {
+	switch t.Method {
+	case jwt.SigningMethodHS256:
+		if len(v.secret) > 0 {
+			return v.secret, nil
+		}
+	case jwt.SigningMethodRS256:
+		if v.publicKey != nil {
+			return v.publicKey, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", errUnexpectedAlg, t.Header["alg"])
+}
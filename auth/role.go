@@ -0,0 +1,46 @@
+package auth
+
+// Roles that can be granted to API keys and JWT subjects
+const (
+	RoleAdmin     = "admin"
+	RolePlanner   = "planner"
+	RoleDoorStaff = "door_staff"
+	RoleViewer    = "viewer"
+)
+
+// Permission is an action a role may be allowed to perform
+type Permission string
+
+const (
+	// PermRead allows reading tables, guest lists, floor plans and reports
+	PermRead Permission = "read"
+	// PermCheckIn allows marking guests as arrived or departed
+	PermCheckIn Permission = "check_in"
+	// PermPlan allows creating and modifying tables, templates and guest lists
+	PermPlan Permission = "plan"
+	// PermAdmin allows emptying tables and managing API keys
+	PermAdmin Permission = "admin"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin:     {PermRead, PermCheckIn, PermPlan, PermAdmin},
+	RolePlanner:   {PermRead, PermPlan},
+	RoleDoorStaff: {PermRead, PermCheckIn},
+	RoleViewer:    {PermRead},
+}
+
+// IsValidRole reports whether role is one of the known roles.
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Can reports whether the principal's role grants the permission.
+func (p *Principal) Can(perm Permission) bool {
+	for _, v := range rolePermissions[p.Role] {
+		if v == perm {
+			return true
+		}
+	}
+	return false
+}
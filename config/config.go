@@ -0,0 +1,385 @@
+// Package config loads the settings of the service from a YAML file, environment variables and flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v2"
+)
+
+// redacted replaces secrets when the configuration is exposed
+const redacted = "[REDACTED]"
+
+// Config holds every setting of the service. Each setting is read from the YAML file
+// given by -config or CONFIG_FILE, then from its environment variable, then from its
+// flag, later sources taking precedence. The env tag of a section prefixes the
+// environment variables of its settings, flags are named after the YAML path unless
+// a flag tag is set, e.g. -server.read_timeout.
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	DB          DBConfig          `yaml:"db"`
+	Log         LogConfig         `yaml:"log" env:"LOG_"`
+	CORS        CORSConfig        `yaml:"cors" env:"CORS_"`
+	Auth        AuthConfig        `yaml:"auth" env:"AUTH_"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit" env:"RATE_LIMIT_"`
+	Idempotency IdempotencyConfig `yaml:"idempotency" env:"IDEMPOTENCY_"`
+	Tracing     TracingConfig     `yaml:"tracing" env:"TRACING_"`
+	Features    FeatureConfig     `yaml:"features" env:"FEATURE_"`
+}
+
+// ServerConfig holds the address and timeouts of the HTTP server
+type ServerConfig struct {
+	Port         int           `yaml:"port" env:"PORT" flag:"p"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// DrainDelay is how long readiness fails before the server stops accepting connections,
+	// so that load balancers stop routing to it first
+	DrainDelay time.Duration `yaml:"drain_delay" env:"SERVER_DRAIN_DELAY"`
+	// ShutdownTimeout is how long in-flight requests are given to complete on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	// TrustedProxies lists the CIDR ranges of the proxies in front of the server. The client IP
+	// is taken from X-Forwarded-For past these proxies, from the connection when there are none
+	TrustedProxies []string `yaml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES"`
+}
+
+// DBConfig holds the MySQL datasource and its connection pool settings
+type DBConfig struct {
+	DSN             string        `yaml:"dsn" env:"DSN" flag:"dsn"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	// ConnectAttempts is how many times the database is pinged on startup before giving up,
+	// waiting ConnectBackoff after the first failure and twice as long after each next one
+	ConnectAttempts int           `yaml:"connect_attempts" env:"DB_CONNECT_ATTEMPTS"`
+	ConnectBackoff  time.Duration `yaml:"connect_backoff" env:"DB_CONNECT_BACKOFF"`
+	// Locking is optimistic, writes fail when a row changed since it was read, or pessimistic,
+	// rows are read with SELECT ... FOR UPDATE and concurrent check-ins wait for each other
+	Locking string `yaml:"locking" env:"DB_LOCKING"`
+}
+
+// LogConfig holds the level, encoding and outputs of the logger, and the policy of the request log.
+// Outputs are stdout, stderr, file paths or tcp://host:port and udp://host:port of a Logstash input,
+// the encoding is json or console and only applies to stdout and stderr.
+type LogConfig struct {
+	Level    string      `yaml:"level" env:"LEVEL"`
+	Outputs  []string    `yaml:"outputs" env:"OUTPUTS"`
+	Encoding string      `yaml:"encoding" env:"ENCODING"`
+	Rotation LogRotation `yaml:"rotation" env:"ROTATION_"`
+	// Debug logs every request in full, bodies included, nothing is redacted or sampled
+	Debug bool `yaml:"debug" env:"DEBUG"`
+	// BodyRoutes lists the routes whose response bodies are logged, as "/path" or "METHOD /path"
+	BodyRoutes   []string `yaml:"body_routes" env:"BODY_ROUTES"`
+	MaxBodySize  int      `yaml:"max_body_size" env:"MAX_BODY_SIZE"`
+	RedactFields []string `yaml:"redact_fields" env:"REDACT_FIELDS"`
+	// SampleReads is the share of successful GET requests that are logged
+	SampleReads float64 `yaml:"sample_reads" env:"SAMPLE_READS"`
+}
+
+// LogRotation holds when log files are rotated and how long rotated files are kept
+type LogRotation struct {
+	MaxSizeMB int `yaml:"max_size_mb" env:"MAX_SIZE_MB"`
+	// Interval also rotates files on a schedule, e.g. 24h rotates daily at midnight UTC, 0 disables it
+	Interval   time.Duration `yaml:"interval" env:"INTERVAL"`
+	MaxAge     time.Duration `yaml:"max_age" env:"MAX_AGE"`
+	MaxBackups int           `yaml:"max_backups" env:"MAX_BACKUPS"`
+	Compress   bool          `yaml:"compress" env:"COMPRESS"`
+}
+
+// CORSConfig lists the origins allowed to call the API from a browser
+type CORSConfig struct {
+	AllowOrigins []string `yaml:"allow_origins" env:"ALLOW_ORIGINS"`
+}
+
+// AuthConfig holds the credentials accepted by the service
+type AuthConfig struct {
+	JWTSecret        string `yaml:"jwt_secret" env:"JWT_SECRET"`
+	JWTPublicKeyFile string `yaml:"jwt_public_key_file" env:"JWT_PUBLIC_KEY_FILE"`
+	JWTIssuer        string `yaml:"jwt_issuer" env:"JWT_ISSUER"`
+	JWTAudience      string `yaml:"jwt_audience" env:"JWT_AUDIENCE"`
+	// JWTMaxLifetime rejects tokens expiring further in the future
+	JWTMaxLifetime  time.Duration `yaml:"jwt_max_lifetime" env:"JWT_MAX_LIFETIME"`
+	BootstrapAPIKey string        `yaml:"bootstrap_api_key" env:"BOOTSTRAP_API_KEY"`
+}
+
+// RateLimitConfig holds the limit of each route group, and the limit per IP of every request
+// before it is authenticated
+type RateLimitConfig struct {
+	Authenticate RateLimit `yaml:"authenticate" env:"AUTHENTICATE_"`
+	Read         RateLimit `yaml:"read" env:"READ_"`
+	CheckIn      RateLimit `yaml:"check_in" env:"CHECK_IN_"`
+	Plan         RateLimit `yaml:"plan" env:"PLAN_"`
+	Admin        RateLimit `yaml:"admin" env:"ADMIN_"`
+}
+
+// RateLimit is the token bucket of a route group, a zero RPS disables limiting
+type RateLimit struct {
+	RPS   float64 `yaml:"rps" env:"RPS"`
+	Burst int     `yaml:"burst" env:"BURST"`
+}
+
+// IdempotencyConfig holds how long responses to requests with an Idempotency-Key are replayed for
+// and the largest body in bytes of such a request
+type IdempotencyConfig struct {
+	TTL         time.Duration `yaml:"ttl" env:"TTL"`
+	MaxBodySize int64         `yaml:"max_body_size" env:"MAX_BODY_SIZE"`
+}
+
+// TracingConfig selects where spans are exported to, either stdout or an OTLP collector over HTTP.
+// No spans are exported when Exporter is empty, the trace context of requests is still propagated.
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" env:"EXPORTER"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"OTLP_ENDPOINT"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" env:"OTLP_INSECURE"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"SAMPLE_RATIO"`
+	ServiceName  string  `yaml:"service_name" env:"SERVICE_NAME"`
+}
+
+// FeatureConfig toggles optional behaviour
+type FeatureConfig struct {
+	RateLimit   bool `yaml:"rate_limit" env:"RATE_LIMIT"`
+	Idempotency bool `yaml:"idempotency" env:"IDEMPOTENCY"`
+}
+
+// Default returns the configuration used when no setting is given.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port: 1323,
+			// Leave room to upload and download snapshots
+			ReadTimeout:     30 * time.Second,
+			WriteTimeout:    60 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			DrainDelay:      5 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+			TrustedProxies:  []string{},
+		},
+		DB: DBConfig{
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+			ConnMaxIdleTime: time.Minute,
+			ConnectAttempts: 5,
+			ConnectBackoff:  time.Second,
+			Locking:         "optimistic",
+		},
+		Log: LogConfig{
+			Level:    "info",
+			Outputs:  []string{"stdout", "./logs/ggv2.log"},
+			Encoding: "json",
+			Rotation: LogRotation{
+				MaxSizeMB:  100,
+				Interval:   24 * time.Hour,
+				MaxAge:     7 * 24 * time.Hour,
+				MaxBackups: 14,
+				Compress:   true,
+			},
+			BodyRoutes:  []string{},
+			MaxBodySize: 1024,
+			// Guest names, dietary needs, audit actors and created API keys
+			RedactFields: []string{"name", "member", "guests", "actor", "target", "dietary_notes", "allergens", "key"},
+			SampleReads:  1,
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"*"},
+		},
+		Auth: AuthConfig{
+			JWTMaxLifetime: 24 * time.Hour,
+		},
+		// Door staff check guests in quickly while bulk and admin operations stay slow
+		RateLimit: RateLimitConfig{
+			// Every group of a client together, failed logins included
+			Authenticate: RateLimit{RPS: 50, Burst: 100},
+			Read:         RateLimit{RPS: 20, Burst: 40},
+			CheckIn:      RateLimit{RPS: 10, Burst: 20},
+			Plan:         RateLimit{RPS: 5, Burst: 10},
+			Admin:        RateLimit{RPS: 1, Burst: 5},
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:         24 * time.Hour,
+			MaxBodySize: 64 << 20,
+		},
+		Tracing: TracingConfig{
+			OTLPEndpoint: "localhost:4318",
+			SampleRatio:  1,
+			ServiceName:  "ggv2",
+		},
+		Features: FeatureConfig{
+			RateLimit:   true,
+			Idempotency: true,
+		},
+	}
+}
+
+// Load reads the configuration from the YAML file, environment variables and the
+// command line args, then validates it.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet("ggv2", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "path of a YAML configuration file, overrides CONFIG_FILE")
+	for _, s := range settings {
+		fs.String(s.flag, "", fmt.Sprintf("%s, overrides %s", s.key, s.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *file != "" {
+		data, err := ioutil.ReadFile(*file)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", *file, err)
+		}
+	}
+	for _, s := range settings {
+		if v := os.Getenv(s.env); v != "" {
+			if err := s.set(v); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", s.env, v, err)
+			}
+		}
+	}
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && err == nil {
+				if setErr := s.set(f.Value.String()); setErr != nil {
+					err = fmt.Errorf("invalid -%s %q: %w", f.Name, f.Value.String(), setErr)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// Validate reports every invalid setting.
+func (c *Config) Validate() error {
+	problems := []string{}
+	check := func(ok bool, format string, a ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, a...))
+		}
+	}
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port must be between 1 and 65535")
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout cannot be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout cannot be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout cannot be negative")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay cannot be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	for _, p := range c.Server.TrustedProxies {
+		_, _, err := net.ParseCIDR(p)
+		check(err == nil, "server.trusted_proxies %q must be a CIDR range", p)
+	}
+	check(c.DB.DSN != "", "db.dsn is required")
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns cannot be negative")
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns cannot be negative")
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db.max_idle_conns cannot exceed db.max_open_conns")
+	check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime cannot be negative")
+	check(c.DB.ConnMaxIdleTime >= 0, "db.conn_max_idle_time cannot be negative")
+	check(c.DB.ConnectAttempts >= 1, "db.connect_attempts must be at least 1")
+	check(c.DB.ConnectBackoff >= 0, "db.connect_backoff cannot be negative")
+	check(c.DB.Locking == "optimistic" || c.DB.Locking == "pessimistic", "db.locking %q must be optimistic or pessimistic", c.DB.Locking)
+	var level zapcore.Level
+	check(level.Set(c.Log.Level) == nil, "log.level %q is not a valid level", c.Log.Level)
+	check(len(c.Log.Outputs) > 0, "log.outputs requires at least one output")
+	for _, o := range c.Log.Outputs {
+		if strings.HasPrefix(o, "tcp://") || strings.HasPrefix(o, "udp://") {
+			_, _, err := net.SplitHostPort(o[len("tcp://"):])
+			check(err == nil, "log.outputs %q must be tcp://host:port or udp://host:port", o)
+		}
+	}
+	check(c.Log.Encoding == "json" || c.Log.Encoding == "console", "log.encoding %q must be json or console", c.Log.Encoding)
+	check(c.Log.Rotation.MaxSizeMB >= 0, "log.rotation.max_size_mb cannot be negative")
+	check(c.Log.Rotation.Interval >= 0, "log.rotation.interval cannot be negative")
+	check(c.Log.Rotation.MaxAge >= 0, "log.rotation.max_age cannot be negative")
+	check(c.Log.Rotation.MaxBackups >= 0, "log.rotation.max_backups cannot be negative")
+	for _, r := range c.Log.BodyRoutes {
+		path := r
+		if i := strings.Index(r, " "); i >= 0 {
+			path = r[i+1:]
+		}
+		check(strings.HasPrefix(path, "/"), "log.body_routes %q must be /path or METHOD /path", r)
+	}
+	check(c.Log.MaxBodySize >= 0, "log.max_body_size cannot be negative")
+	check(c.Log.SampleReads >= 0 && c.Log.SampleReads <= 1, "log.sample_reads must be between 0 and 1")
+	check(len(c.CORS.AllowOrigins) > 0, "cors.allow_origins requires at least one origin")
+	check(c.Auth.JWTMaxLifetime > 0, "auth.jwt_max_lifetime must be positive")
+	for _, l := range []struct {
+		name  string
+		limit RateLimit
+	}{{"authenticate", c.RateLimit.Authenticate}, {"read", c.RateLimit.Read}, {"check_in", c.RateLimit.CheckIn}, {"plan", c.RateLimit.Plan}, {"admin", c.RateLimit.Admin}} {
+		check(l.limit.RPS >= 0, "rate_limit.%s.rps cannot be negative", l.name)
+		check(l.limit.RPS == 0 || l.limit.Burst >= 1, "rate_limit.%s.burst must be at least 1", l.name)
+	}
+	check(c.Idempotency.TTL > 0, "idempotency.ttl must be positive")
+	check(c.Idempotency.MaxBodySize > 0, "idempotency.max_body_size must be positive")
+	switch c.Tracing.Exporter {
+	case "", "stdout":
+	case "otlp":
+		check(c.Tracing.OTLPEndpoint != "", "tracing.otlp_endpoint is required by the otlp exporter")
+	default:
+		check(false, "tracing.exporter %q must be stdout, otlp or empty", c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+	check(c.Tracing.ServiceName != "", "tracing.service_name is required")
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Redacted returns the settings as nested maps, keyed like the YAML file, with secrets redacted.
+func (c *Config) Redacted() map[string]interface{} {
+	cp := *c
+	cp.DB.DSN = redactDSN(cp.DB.DSN)
+	if cp.Auth.JWTSecret != "" {
+		cp.Auth.JWTSecret = redacted
+	}
+	if cp.Auth.BootstrapAPIKey != "" {
+		cp.Auth.BootstrapAPIKey = redacted
+	}
+	out := map[string]interface{}{}
+	for _, s := range cp.settings() {
+		path := strings.Split(s.key, ".")
+		m := out
+		for _, p := range path[:len(path)-1] {
+			if _, ok := m[p]; !ok {
+				m[p] = map[string]interface{}{}
+			}
+			m = m[p].(map[string]interface{})
+		}
+		m[path[len(path)-1]] = s.value()
+	}
+	return out
+}
+
+// redactDSN hides the password of a MySQL datasource.
+func redactDSN(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return redacted
+	}
+	if cfg.Passwd != "" {
+		cfg.Passwd = redacted
+	}
+	return cfg.FormatDSN()
+}
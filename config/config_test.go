@@ -0,0 +1,304 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ggv2.yml")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`
+server:
+  port: 8080
+  read_timeout: 10s
+db:
+  dsn: file:password@tcp(localhost:3306)/getground
+  max_open_conns: 50
+log:
+  level: debug
+rate_limit:
+  check_in:
+    rps: 15
+`), 0644))
+	invalidFile := filepath.Join(dir, "invalid.yml")
+	assert.Nil(t, ioutil.WriteFile(invalidFile, []byte("server:\n  prot: 8080\n"), 0644))
+
+	type TestCase struct {
+		name   string
+		desc   string
+		env    map[string]string
+		args   []string
+		modify func(*Config)
+		expErr bool
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "defaults with a datasource",
+			env:  map[string]string{"DSN": "env:password@tcp(localhost:3306)/getground"},
+			modify: func(c *Config) {
+				c.DB.DSN = "env:password@tcp(localhost:3306)/getground"
+			},
+		},
+		{
+			name: "Happy case",
+			desc: "file",
+			args: []string{"-config", file},
+			modify: func(c *Config) {
+				c.Server.Port = 8080
+				c.Server.ReadTimeout = 10 * time.Second
+				c.DB.DSN = "file:password@tcp(localhost:3306)/getground"
+				c.DB.MaxOpenConns = 50
+				c.Log.Level = "debug"
+				c.RateLimit.CheckIn.RPS = 15
+			},
+		},
+		{
+			name: "Happy case",
+			desc: "environment overrides file",
+			env: map[string]string{
+				"CONFIG_FILE":             file,
+				"PORT":                    "9090",
+				"SERVER_READ_TIMEOUT":     "5s",
+				"DSN":                     "env:password@tcp(localhost:3306)/getground",
+				"DB_MAX_IDLE_CONNS":       "10",
+				"LOG_OUTPUTS":             "stdout",
+				"CORS_ALLOW_ORIGINS":      "https://a.example, https://b.example",
+				"AUTH_JWT_SECRET":         "secret",
+				"RATE_LIMIT_CHECK_IN_RPS": "2.5",
+				"IDEMPOTENCY_TTL":         "1h",
+				"FEATURE_IDEMPOTENCY":     "false",
+			},
+			modify: func(c *Config) {
+				c.Server.Port = 9090
+				c.Server.ReadTimeout = 5 * time.Second
+				c.DB.DSN = "env:password@tcp(localhost:3306)/getground"
+				c.DB.MaxOpenConns = 50
+				c.DB.MaxIdleConns = 10
+				c.Log.Level = "debug"
+				c.Log.Outputs = []string{"stdout"}
+				c.CORS.AllowOrigins = []string{"https://a.example", "https://b.example"}
+				c.Auth.JWTSecret = "secret"
+				c.RateLimit.CheckIn.RPS = 2.5
+				c.Idempotency.TTL = time.Hour
+				c.Features.Idempotency = false
+			},
+		},
+		{
+			name: "Happy case",
+			desc: "flags override environment",
+			env:  map[string]string{"PORT": "9090", "DSN": "env:password@tcp(localhost:3306)/getground"},
+			args: []string{"-config", file, "-p", "7070", "-dsn", "flag:password@tcp(localhost:3306)/getground", "-rate_limit.check_in.burst", "30"},
+			modify: func(c *Config) {
+				c.Server.Port = 7070
+				c.Server.ReadTimeout = 10 * time.Second
+				c.DB.DSN = "flag:password@tcp(localhost:3306)/getground"
+				c.DB.MaxOpenConns = 50
+				c.Log.Level = "debug"
+				c.RateLimit.CheckIn = RateLimit{RPS: 15, Burst: 30}
+			},
+		},
+		{
+			name:   "Sad case",
+			desc:   "missing datasource",
+			expErr: true,
+		},
+		{
+			name:   "Sad case",
+			desc:   "config file not found",
+			args:   []string{"-config", filepath.Join(dir, "missing.yml")},
+			expErr: true,
+		},
+		{
+			name:   "Sad case",
+			desc:   "unknown setting in config file",
+			args:   []string{"-config", invalidFile},
+			expErr: true,
+		},
+		{
+			name:   "Sad case",
+			desc:   "invalid environment variable",
+			env:    map[string]string{"DSN": "dsn", "SERVER_IDLE_TIMEOUT": "forever"},
+			expErr: true,
+		},
+		{
+			name:   "Sad case",
+			desc:   "invalid flag",
+			env:    map[string]string{"DSN": "dsn"},
+			args:   []string{"-p", "port"},
+			expErr: true,
+		},
+		{
+			name:   "Sad case",
+			desc:   "unknown flag",
+			args:   []string{"-port", "1323"},
+			expErr: true,
+		},
+	}
+	for _, v := range testcases {
+		for k, e := range v.env {
+			os.Setenv(k, e)
+		}
+		cfg, err := Load(v.args)
+		for k := range v.env {
+			os.Unsetenv(k)
+		}
+		assert.Equal(t, v.expErr, err != nil, v.desc)
+		if !v.expErr {
+			exp := Default()
+			v.modify(exp)
+			assert.Equal(t, exp, cfg, v.desc)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type TestCase struct {
+		name   string
+		desc   string
+		modify func(*Config)
+		expErr string
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "valid",
+			modify: func(c *Config) {},
+		},
+		{
+			name: "Sad case",
+			desc: "every problem reported",
+			modify: func(c *Config) {
+				c.Server.Port = 0
+				c.DB.MaxIdleConns = 30
+				c.Log.Level = "loud"
+				c.RateLimit.Plan.Burst = 0
+			},
+			expErr: `invalid configuration: server.port must be between 1 and 65535; db.max_idle_conns cannot exceed db.max_open_conns; log.level "loud" is not a valid level; rate_limit.plan.burst must be at least 1`,
+		},
+		{
+			name: "Sad case",
+			desc: "negative timeout",
+			modify: func(c *Config) {
+				c.Server.WriteTimeout = -time.Second
+			},
+			expErr: "invalid configuration: server.write_timeout cannot be negative",
+		},
+		{
+			name: "Sad case",
+			desc: "unbounded token lifetime",
+			modify: func(c *Config) {
+				c.Auth.JWTMaxLifetime = 0
+			},
+			expErr: "invalid configuration: auth.jwt_max_lifetime must be positive",
+		},
+		{
+			name: "Sad case",
+			desc: "unknown tracing exporter",
+			modify: func(c *Config) {
+				c.Tracing.Exporter = "jaeger"
+				c.Tracing.SampleRatio = 2
+			},
+			expErr: `invalid configuration: tracing.exporter "jaeger" must be stdout, otlp or empty; tracing.sample_ratio must be between 0 and 1`,
+		},
+		{
+			name: "Sad case",
+			desc: "invalid log policy",
+			modify: func(c *Config) {
+				c.Log.BodyRoutes = []string{"GET /tables", "tables"}
+				c.Log.SampleReads = -0.5
+			},
+			expErr: `invalid configuration: log.body_routes "tables" must be /path or METHOD /path; log.sample_reads must be between 0 and 1`,
+		},
+		{
+			name: "Sad case",
+			desc: "invalid log outputs",
+			modify: func(c *Config) {
+				c.Log.Outputs = []string{"stdout", "tcp://logstash"}
+				c.Log.Encoding = "text"
+			},
+			expErr: `invalid configuration: log.outputs "tcp://logstash" must be tcp://host:port or udp://host:port; log.encoding "text" must be json or console`,
+		},
+		{
+			name: "Sad case",
+			desc: "unknown locking",
+			modify: func(c *Config) {
+				c.DB.Locking = "none"
+			},
+			expErr: `invalid configuration: db.locking "none" must be optimistic or pessimistic`,
+		},
+		{
+			name: "Sad case",
+			desc: "trusted proxy not a range",
+			modify: func(c *Config) {
+				c.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy"}
+			},
+			expErr: `invalid configuration: server.trusted_proxies "proxy" must be a CIDR range`,
+		},
+		{
+			name: "Sad case",
+			desc: "idempotency body bound not positive",
+			modify: func(c *Config) {
+				c.Idempotency.MaxBodySize = 0
+			},
+			expErr: "invalid configuration: idempotency.max_body_size must be positive",
+		},
+	}
+	for _, v := range testcases {
+		cfg := Default()
+		cfg.DB.DSN = "dsn"
+		v.modify(cfg)
+		err := cfg.Validate()
+		if v.expErr == "" {
+			assert.Nil(t, err, v.desc)
+		} else {
+			assert.EqualError(t, err, v.expErr, v.desc)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.DB.DSN = "getground:password@tcp(localhost:3306)/getground"
+	cfg.Auth.JWTSecret = "secret"
+	cfg.Auth.BootstrapAPIKey = "bootstrap"
+	values := cfg.Redacted()
+
+	assert.Equal(t, map[string]interface{}{
+		"dsn":                "getground:[REDACTED]@tcp(localhost:3306)/getground",
+		"max_open_conns":     25,
+		"max_idle_conns":     25,
+		"conn_max_lifetime":  "5m0s",
+		"conn_max_idle_time": "1m0s",
+		"connect_attempts":   5,
+		"connect_backoff":    "1s",
+		"locking":            "optimistic",
+	}, values["db"])
+	assert.Equal(t, map[string]interface{}{
+		"jwt_secret":          "[REDACTED]",
+		"jwt_public_key_file": "",
+		"jwt_issuer":          "",
+		"jwt_audience":        "",
+		"jwt_max_lifetime":    "24h0m0s",
+		"bootstrap_api_key":   "[REDACTED]",
+	}, values["auth"])
+	assert.Equal(t, map[string]interface{}{"rps": 10.0, "burst": 20}, values["rate_limit"].(map[string]interface{})["check_in"])
+	// The configuration itself is untouched
+	assert.Equal(t, "secret", cfg.Auth.JWTSecret)
+	assert.Equal(t, "getground:password@tcp(localhost:3306)/getground", cfg.DB.DSN)
+}
+
+func TestSampleConfig(t *testing.T) {
+	// The sample file documents the defaults
+	cfg, err := Load([]string{"-config", "../etc/config/ggv2.yml", "-dsn", "dsn"})
+	assert.Nil(t, err)
+	exp := Default()
+	exp.DB.DSN = "dsn"
+	assert.Equal(t, exp, cfg)
+}
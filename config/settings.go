@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting is a single configurable value of Config
+type setting struct {
+	// key is the YAML path of the setting, e.g. server.read_timeout
+	key  string
+	env  string
+	flag string
+	v    reflect.Value
+}
+
+// settings lists every setting of c, in order of declaration.
+func (c *Config) settings() []*setting {
+	return collect(reflect.ValueOf(c).Elem(), "", "")
+}
+
+func collect(v reflect.Value, keyPrefix, envPrefix string) []*setting {
+	settings := []*setting{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := keyPrefix + f.Tag.Get("yaml")
+		env := envPrefix + f.Tag.Get("env")
+		if f.Type.Kind() == reflect.Struct {
+			settings = append(settings, collect(v.Field(i), key+".", env)...)
+			continue
+		}
+		s := &setting{key: key, env: env, flag: f.Tag.Get("flag"), v: v.Field(i)}
+		if s.flag == "" {
+			s.flag = key
+		}
+		settings = append(settings, s)
+	}
+	return settings
+}
+
+// set parses raw into the setting, lists are comma separated.
+func (s *setting) set(raw string) error {
+	if s.v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		s.v.SetInt(int64(d))
+		return nil
+	}
+	switch s.v.Kind() {
+	case reflect.String:
+		s.v.SetString(raw)
+	case reflect.Int:
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		s.v.SetInt(int64(i))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		s.v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		s.v.SetBool(b)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		s.v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.v.Type())
+	}
+	return nil
+}
+
+// value returns the setting for display, durations are formatted like "30s".
+func (s *setting) value() interface{} {
+	if s.v.Type() == durationType {
+		return time.Duration(s.v.Int()).String()
+	}
+	return s.v.Interface()
+}
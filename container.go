@@ -0,0 +1,72 @@
+package main
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"ggv2/auth"
+	"ggv2/config"
+	"ggv2/handler"
+	"ggv2/health"
+	"ggv2/repo"
+	"ggv2/services"
+)
+
+// container builds the service graph of the application once. Consumers receive the
+// interfaces they depend on, so another repo, a cache or a decorator is swapped in here
+// without touching the handlers.
+type container struct {
+	Config  *config.Config
+	Auth    *auth.Config
+	Conn    *sqlx.DB
+	Repo    repo.DbRepo
+	Service services.DbService
+	Health  *health.Checker
+}
+
+func NewContainer(conn *sqlx.DB, cfg *config.Config, authCfg *auth.Config) *container {
+	dbRepo := repo.NewDbRepo(conn).WithLocking(repo.Locking(cfg.DB.Locking))
+	return &container{
+		Config:  cfg,
+		Auth:    authCfg,
+		Conn:    conn,
+		Repo:    dbRepo,
+		Service: services.NewDbService(dbRepo),
+		Health:  health.NewChecker(),
+	}
+}
+
+// Collectors returns the metrics collectors of the pool and of the service graph.
+func (c *container) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		repo.NewDBStatsCollector(c.Conn),
+		services.NewOccupancyCollector(c.Repo),
+	}
+}
+
+// handlers holds the handlers of the routes, all sharing the services of the container
+type handlers struct {
+	Table  *handler.TableHandler
+	Guest  *handler.GuestHandler
+	Report *handler.ReportHandler
+	Layout *handler.LayoutHandler
+	Auth   *handler.AuthHandler
+	Admin  *handler.AdminHandler
+	Audit  *handler.AuditHandler
+	Health *handler.HealthHandler
+	Config *handler.ConfigHandler
+}
+
+func (c *container) Handlers() *handlers {
+	return &handlers{
+		Table:  handler.NewTableHandler(c.Service),
+		Guest:  handler.NewGuestHandler(c.Service),
+		Report: handler.NewReportHandler(c.Service),
+		Layout: handler.NewLayoutHandler(c.Service),
+		Auth:   handler.NewAuthHandler(c.Service),
+		Admin:  handler.NewAdminHandler(c.Service),
+		Audit:  handler.NewAuditHandler(c.Service),
+		Health: handler.NewHealthHandler(c.Service, c.Health),
+		Config: handler.NewConfigHandler(c.Config),
+	}
+}
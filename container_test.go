@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	sqlxmock "github.com/zhashkevych/go-sqlxmock"
+
+	"ggv2/auth"
+	"ggv2/config"
+)
+
+func TestContainer(t *testing.T) {
+	db, mock, err := sqlxmock.Newx(sqlxmock.MonitorPingsOption(true))
+	assert.Nil(t, err)
+	app := NewContainer(db, config.Default(), &auth.Config{})
+
+	// Collectors of a single graph register without conflicts
+	reg := prometheus.NewRegistry()
+	for _, c := range app.Collectors() {
+		assert.Nil(t, reg.Register(c))
+	}
+
+	// Readiness checks the database through the shared service
+	app.Handlers()
+	mock.ExpectPing()
+	_, components := app.Health.Ready(context.Background())
+	assert.Len(t, components, 3)
+	assert.Equal(t, "database", components[0].Name)
+	assert.Nil(t, components[0].Err)
+}
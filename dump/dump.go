@@ -0,0 +1,124 @@
+// Package dump encodes snapshots of event data as gzipped tarballs, with one JSON file per kind of row.
+package dump
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"ggv2/entities"
+)
+
+const (
+	manifestFile     = "manifest.json"
+	tablesFile       = "tables.json"
+	guestsFile       = "guests.json"
+	partyMembersFile = "party_members.json"
+
+	// maxFileSize bounds every file read from a tarball, in bytes
+	maxFileSize = 32 << 20
+)
+
+var (
+	errMissingManifest = errors.New("tarball has no " + manifestFile)
+	errFileTooLarge    = errors.New("tarball file too large")
+)
+
+// manifest describes the snapshot stored in a tarball
+type manifest struct {
+	Version int       `json:"version"`
+	Event   string    `json:"event,omitempty"`
+	TakenAt time.Time `json:"taken_at"`
+}
+
+// WriteTarball writes the snapshot to w as a gzipped tarball.
+func WriteTarball(w io.Writer, s *entities.Snapshot) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{manifestFile, &manifest{Version: s.Version, Event: s.Event, TakenAt: s.TakenAt}},
+		{tablesFile, s.Tables},
+		{guestsFile, s.Guests},
+		{partyMembersFile, s.PartyMembers},
+	}
+	for _, f := range files {
+		b, err := json.Marshal(f.data)
+		if err != nil {
+			return err
+		}
+		err = tw.WriteHeader(&tar.Header{
+			Name:    f.name,
+			Mode:    0644,
+			Size:    int64(len(b)),
+			ModTime: s.TakenAt,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err = tw.Write(b); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadTarball reads a snapshot written by WriteTarball. Unknown files are ignored,
+// missing row files are read as empty.
+func ReadTarball(r io.Reader) (*entities.Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	s := &entities.Snapshot{
+		Tables:       []*entities.Table{},
+		Guests:       []*entities.Guest{},
+		PartyMembers: []*entities.PartyMember{},
+	}
+	var m *manifest
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var dest interface{}
+		switch hdr.Name {
+		case manifestFile:
+			m = &manifest{}
+			dest = m
+		case tablesFile:
+			dest = &s.Tables
+		case guestsFile:
+			dest = &s.Guests
+		case partyMembersFile:
+			dest = &s.PartyMembers
+		default:
+			continue
+		}
+		if hdr.Size > maxFileSize {
+			return nil, errFileTooLarge
+		}
+		if err = json.NewDecoder(io.LimitReader(tr, maxFileSize)).Decode(dest); err != nil {
+			return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+	}
+	if m == nil {
+		return nil, errMissingManifest
+	}
+	s.Version, s.Event, s.TakenAt = m.Version, m.Event, m.TakenAt
+	return s, nil
+}
@@ -0,0 +1,102 @@
+package dump
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"ggv2/entities"
+)
+
+func TestTarballRoundTrip(t *testing.T) {
+	snapshot := &entities.Snapshot{
+		Version: entities.SnapshotVersion,
+		Event:   "gala",
+		TakenAt: time.Date(2021, 6, 1, 20, 0, 0, 0, time.UTC),
+		Tables: []*entities.Table{
+			{TableID: 1, Capacity: 8, AvailableCapacity: 6, PlannedCapacity: 4, Event: "gala", Zone: "Garden", Tags: entities.Tags{"vip"}},
+		},
+		Guests: []*entities.Guest{
+			{ID: 2, Name: "bob", TableID: 1, TotalGuests: 4, TotalArrivedGuests: 2, Allergens: entities.Tags{"peanut"}},
+		},
+		PartyMembers: []*entities.PartyMember{
+			{ID: 3, GuestID: 2, Name: "alice", AgeGroup: entities.AgeGroupChild, Arrived: true},
+		},
+	}
+	buf := &bytes.Buffer{}
+	err := WriteTarball(buf, snapshot)
+	assert.Nil(t, err)
+	actRes, actErr := ReadTarball(buf)
+	assert.Nil(t, actErr)
+	assert.Equal(t, snapshot, actRes)
+}
+
+func TestReadTarball(t *testing.T) {
+	type TestCase struct {
+		name   string
+		desc   string
+		files  map[string]string
+		raw    []byte
+		expErr bool
+	}
+	testcases := []TestCase{
+		{
+			name:  "Happy case",
+			desc:  "missing row files read as empty, unknown files ignored",
+			files: map[string]string{manifestFile: `{"version":1}`, "README": "hello"},
+		},
+		{
+			name:   "Sad case",
+			desc:   "not gzipped",
+			raw:    []byte("not a tarball"),
+			expErr: true,
+		},
+		{
+			name:   "Sad case",
+			desc:   "missing manifest",
+			files:  map[string]string{tablesFile: `[]`},
+			expErr: true,
+		},
+		{
+			name:   "Sad case",
+			desc:   "invalid json",
+			files:  map[string]string{manifestFile: `{"version":1}`, guestsFile: `{`},
+			expErr: true,
+		},
+	}
+	for _, v := range testcases {
+		raw := v.raw
+		if raw == nil {
+			raw = tarball(t, v.files)
+		}
+		actRes, actErr := ReadTarball(bytes.NewReader(raw))
+		if v.expErr {
+			assert.NotNil(t, actErr, v.desc)
+			assert.Nil(t, actRes, v.desc)
+			continue
+		}
+		assert.Nil(t, actErr, v.desc)
+		assert.Equal(t, 1, actRes.Version, v.desc)
+		assert.Equal(t, []*entities.Table{}, actRes.Tables, v.desc)
+		assert.Equal(t, []*entities.Guest{}, actRes.Guests, v.desc)
+		assert.Equal(t, []*entities.PartyMember{}, actRes.PartyMembers, v.desc)
+	}
+}
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, tw.Close())
+	assert.Nil(t, gz.Close())
+	return buf.Bytes()
+}
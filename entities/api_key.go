@@ -0,0 +1,12 @@
+package entities
+
+// APIKey represents a static API key, only the hash of the key is stored
+type APIKey struct {
+	ID        int64  `db:"id" json:"id"`
+	Name      string `db:"name" json:"name"`
+	Prefix    string `db:"prefix" json:"prefix"`
+	Role      string `db:"role" json:"role"`
+	KeyHash   string `db:"key_hash" json:"-"`
+	CreatedAt string `db:"created_at" json:"created_at,omitempty"`
+	Revoked   bool   `db:"revoked" json:"revoked"`
+}
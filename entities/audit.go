@@ -0,0 +1,55 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// AuditEntry represents a mutation recorded in the audit log
+type AuditEntry struct {
+	ID        int64  `db:"id"`
+	Actor     string `db:"actor"`
+	RequestID string `db:"request_id"`
+	Operation string `db:"operation"`
+	Target    string `db:"target"`
+	Before    JSON   `db:"before_value"`
+	After     JSON   `db:"after_value"`
+	CreatedAt string `db:"created_at"`
+}
+
+// AuditFilter represents criteria to filter the audit log by, empty fields match every entry
+type AuditFilter struct {
+	Actor     string
+	RequestID string
+	Operation string
+	Target    string
+	Since     time.Time
+	Until     time.Time
+}
+
+// JSON represents an encoded JSON value stored in a nullable text column, nil is stored as NULL
+type JSON []byte
+
+// Scan implements sql.Scanner.
+func (j *JSON) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append(JSON{}, v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON", src)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (j JSON) Value() (driver.Value, error) {
+	if j == nil {
+		return nil, nil
+	}
+	return string(j), nil
+}
@@ -0,0 +1,82 @@
+package entities
+
+const (
+	DietVegetarian  = "vegetarian"
+	DietVegan       = "vegan"
+	DietHalal       = "halal"
+	DietKosher      = "kosher"
+	DietGlutenFree  = "gluten_free"
+	DietLactoseFree = "lactose_free"
+
+	// MealUnspecified is reported for diners without a meal choice
+	MealUnspecified = "unspecified"
+)
+
+// Diner represents the catering requirements of a single person
+type Diner struct {
+	DietaryTags  Tags
+	Allergens    Tags
+	DietaryNotes string
+	MealChoice   string
+}
+
+// CateringSummary represents aggregated catering requirements of a group of diners
+type CateringSummary struct {
+	Headcount int64
+	Diets     map[string]int64
+	Allergens map[string]int64
+	Meals     map[string]int64
+	Notes     []string
+}
+
+// TableCatering represents catering requirements of a single table
+type TableCatering struct {
+	TableID int64
+	RSVP    *CateringSummary
+	Arrived *CateringSummary
+}
+
+// CateringReport represents catering requirements per table and for the whole event
+type CateringReport struct {
+	Tables  []*TableCatering
+	RSVP    *CateringSummary
+	Arrived *CateringSummary
+}
+
+// IsValidDiet reports whether diet is one of the known dietary tags.
+func IsValidDiet(diet string) bool {
+	switch diet {
+	case DietVegetarian, DietVegan, DietHalal, DietKosher, DietGlutenFree, DietLactoseFree:
+		return true
+	}
+	return false
+}
+
+// NewCateringSummary returns an empty CateringSummary.
+func NewCateringSummary() *CateringSummary {
+	return &CateringSummary{
+		Diets:     map[string]int64{},
+		Allergens: map[string]int64{},
+		Meals:     map[string]int64{},
+		Notes:     []string{},
+	}
+}
+
+// Add counts a single diner into the summary.
+func (s *CateringSummary) Add(d *Diner) {
+	s.Headcount++
+	for _, t := range d.DietaryTags {
+		s.Diets[t]++
+	}
+	for _, a := range d.Allergens {
+		s.Allergens[a]++
+	}
+	meal := d.MealChoice
+	if meal == "" {
+		meal = MealUnspecified
+	}
+	s.Meals[meal]++
+	if d.DietaryNotes != "" {
+		s.Notes = append(s.Notes, d.DietaryNotes)
+	}
+}
@@ -0,0 +1,7 @@
+package entities
+
+// FloorPlanTable represents a Table placed in the room together with the names of its seated guests
+type FloorPlanTable struct {
+	*Table
+	Guests []string
+}
@@ -2,11 +2,27 @@ package entities
 
 // Guest represents a Guest object
 type Guest struct {
-	ID                 int64  `db:"id"`
-	Name               string `db:"name"`
-	TableID            int64  `db:"tableid"`
-	TotalGuests        int64  `db:"total_rsvp_guests"`
-	TotalArrivedGuests int64  `db:"total_arrived_guests"`
-	ArrivalTime        string `db:"arrivaltime"`
-	Version            int64  `db:"version"`
+	ID                 int64  `db:"id" json:"id"`
+	Name               string `db:"name" json:"name"`
+	TableID            int64  `db:"tableid" json:"tableid"`
+	TotalGuests        int64  `db:"total_rsvp_guests" json:"total_rsvp_guests"`
+	TotalArrivedGuests int64  `db:"total_arrived_guests" json:"total_arrived_guests"`
+	ArrivalTime        string `db:"arrivaltime" json:"arrivaltime"`
+	Version            int64  `db:"version" json:"version"`
+	DietaryTags        Tags   `db:"dietary_tags" json:"dietary_tags"`
+	Allergens          Tags   `db:"allergens" json:"allergens"`
+	DietaryNotes       string `db:"dietary_notes" json:"dietary_notes"`
+	MealChoice         string `db:"meal_choice" json:"meal_choice"`
+
+	Members []*PartyMember `db:"-" json:"-"`
+}
+
+// Diner returns the catering requirements of the guest.
+func (g *Guest) Diner() *Diner {
+	return &Diner{
+		DietaryTags:  g.DietaryTags,
+		Allergens:    g.Allergens,
+		DietaryNotes: g.DietaryNotes,
+		MealChoice:   g.MealChoice,
+	}
 }
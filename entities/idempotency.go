@@ -0,0 +1,20 @@
+package entities
+
+// IdempotencyRecord is the response stored for a request carrying an Idempotency-Key,
+// keys are scoped to the actor that sent them
+type IdempotencyRecord struct {
+	Actor       string `db:"actor"`
+	Key         string `db:"idempotency_key"`
+	RequestHash string `db:"request_hash"`
+	Status      int    `db:"status"`
+	ContentType string `db:"content_type"`
+	Body        []byte `db:"body"`
+	CreatedAt   string `db:"created_at"`
+	ExpiresAt   string `db:"expires_at"`
+}
+
+// Completed reports whether the response of the request has been stored,
+// a record without a status belongs to a request still in progress.
+func (r *IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
@@ -0,0 +1,78 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// TableGroup describes a number of identical tables, e.g. 10 tables of 8 seats
+type TableGroup struct {
+	Count    int64  `json:"count"`
+	Capacity int64  `json:"capacity"`
+	Zone     string `json:"zone,omitempty"`
+	Shape    string `json:"shape,omitempty"`
+	Tags     Tags   `json:"tags,omitempty"`
+}
+
+// TableGroups represents a table layout stored as a JSON column
+type TableGroups []*TableGroup
+
+// Scan implements sql.Scanner.
+func (g *TableGroups) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*g = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, g)
+	case string:
+		return json.Unmarshal([]byte(v), g)
+	default:
+		return fmt.Errorf("cannot scan %T into TableGroups", src)
+	}
+}
+
+// Value implements driver.Valuer.
+func (g TableGroups) Value() (driver.Value, error) {
+	b, err := json.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Tables expands the groups into new, empty tables.
+func (g TableGroups) Tables() []*Table {
+	tables := []*Table{}
+	for _, group := range g {
+		for i := int64(0); i < group.Count; i++ {
+			tables = append(tables, &Table{
+				Capacity:          group.Capacity,
+				AvailableCapacity: group.Capacity,
+				PlannedCapacity:   group.Capacity,
+				Zone:              group.Zone,
+				Shape:             group.Shape,
+				Tags:              group.Tags,
+			})
+		}
+	}
+	return tables
+}
+
+// Size returns the total number of tables and seats of the groups.
+func (g TableGroups) Size() (tables, seats int64) {
+	for _, group := range g {
+		tables += group.Count
+		seats += group.Count * group.Capacity
+	}
+	return tables, seats
+}
+
+// LayoutTemplate represents a named, reusable table layout
+type LayoutTemplate struct {
+	ID      int64       `db:"id" json:"id"`
+	Name    string      `db:"name" json:"name"`
+	Groups  TableGroups `db:"layout" json:"groups"`
+	Version int64       `db:"version" json:"version"`
+}
@@ -0,0 +1,41 @@
+package entities
+
+const (
+	AgeGroupAdult  = "adult"
+	AgeGroupTeen   = "teen"
+	AgeGroupChild  = "child"
+	AgeGroupInfant = "infant"
+)
+
+// PartyMember represents a named member of a Guest's party
+type PartyMember struct {
+	ID       int64  `db:"id" json:"id"`
+	GuestID  int64  `db:"guestid" json:"guestid"`
+	Name     string `db:"name" json:"name"`
+	AgeGroup string `db:"age_group" json:"age_group"`
+	Arrived  bool   `db:"arrived" json:"arrived"`
+
+	DietaryTags  Tags   `db:"dietary_tags" json:"dietary_tags"`
+	Allergens    Tags   `db:"allergens" json:"allergens"`
+	DietaryNotes string `db:"dietary_notes" json:"dietary_notes"`
+	MealChoice   string `db:"meal_choice" json:"meal_choice"`
+}
+
+// Diner returns the catering requirements of the party member.
+func (m *PartyMember) Diner() *Diner {
+	return &Diner{
+		DietaryTags:  m.DietaryTags,
+		Allergens:    m.Allergens,
+		DietaryNotes: m.DietaryNotes,
+		MealChoice:   m.MealChoice,
+	}
+}
+
+// IsValidAgeGroup reports whether ageGroup is empty or one of the known age groups.
+func IsValidAgeGroup(ageGroup string) bool {
+	switch ageGroup {
+	case "", AgeGroupAdult, AgeGroupTeen, AgeGroupChild, AgeGroupInfant:
+		return true
+	}
+	return false
+}
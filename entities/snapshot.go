@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SnapshotVersion is the format version of snapshots, bumped whenever the format changes incompatibly
+const SnapshotVersion = 1
+
+// Snapshot represents a point in time copy of the tables, guests and party members of an event
+type Snapshot struct {
+	Version      int            `json:"version"`
+	Event        string         `json:"event,omitempty"`
+	TakenAt      time.Time      `json:"taken_at"`
+	Tables       []*Table       `json:"tables"`
+	Guests       []*Guest       `json:"guests"`
+	PartyMembers []*PartyMember `json:"party_members"`
+}
+
+// Scan implements sql.Scanner.
+func (s *Snapshot) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("cannot scan %T into Snapshot", src)
+	}
+}
+
+// Value implements driver.Valuer.
+func (s Snapshot) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Archive represents a snapshot taken before tables were emptied, so that emptying can be undone
+type Archive struct {
+	ID        int64     `db:"id" json:"id"`
+	Event     string    `db:"event" json:"event"`
+	Tables    int64     `db:"tables" json:"tables"`
+	Guests    int64     `db:"guests" json:"guests"`
+	CreatedAt string    `db:"created_at" json:"created_at,omitempty"`
+	Restored  bool      `db:"restored" json:"restored"`
+	Snapshot  *Snapshot `db:"data" json:"-"`
+}
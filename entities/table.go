@@ -1,10 +1,121 @@
 package entities
 
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	ShapeRound     = "round"
+	ShapeRectangle = "rectangle"
+	ShapeSquare    = "square"
+	ShapeOval      = "oval"
+)
+
 // Table represents a Table object
 type Table struct {
-	TableID           int64 `db:"id"`
-	Capacity          int64 `db:"capacity"`
-	AvailableCapacity int64 `db:"acapacity"`
-	PlannedCapacity   int64 `db:"pcapacity"`
-	Version           int64 `db:"version"`
+	TableID           int64  `db:"id" json:"id"`
+	Capacity          int64  `db:"capacity" json:"capacity"`
+	AvailableCapacity int64  `db:"acapacity" json:"acapacity"`
+	PlannedCapacity   int64  `db:"pcapacity" json:"pcapacity"`
+	Version           int64  `db:"version" json:"version"`
+	Event             string `db:"event" json:"event"`
+	Name              string `db:"name" json:"name"`
+	Zone              string `db:"zone" json:"zone"`
+	Shape             string `db:"shape" json:"shape"`
+	Tags              Tags   `db:"tags" json:"tags"`
+	X                 int64  `db:"pos_x" json:"pos_x"`
+	Y                 int64  `db:"pos_y" json:"pos_y"`
+	Rotation          int64  `db:"rotation" json:"rotation"`
+}
+
+// TablePatch represents a partial update of a Table, nil fields are left unchanged
+type TablePatch struct {
+	Capacity *int64
+
+	Event *string
+	Name  *string
+	Zone  *string
+	Shape *string
+	Tags  *Tags
+
+	X        *int64
+	Y        *int64
+	Rotation *int64
+}
+
+// TableFilter represents criteria to filter tables by, empty fields match every table
+type TableFilter struct {
+	Event string
+	Name  string
+	Zone  string
+	Shape string
+	Tag   string
+}
+
+// IsValidShape reports whether shape is empty or one of the known table shapes.
+func IsValidShape(shape string) bool {
+	switch shape {
+	case "", ShapeRound, ShapeRectangle, ShapeSquare, ShapeOval:
+		return true
+	}
+	return false
+}
+
+// Label returns a human readable reference to the table, e.g. "Table 12 – Garden".
+func (t *Table) Label() string {
+	label := t.Name
+	if label == "" {
+		label = fmt.Sprintf("Table %d", t.TableID)
+	} else if _, err := strconv.ParseInt(label, 10, 64); err == nil {
+		// Tables named by number read as "Table 12"
+		label = "Table " + label
+	}
+	if t.Zone != "" {
+		label = fmt.Sprintf("%s – %s", label, t.Zone)
+	}
+	return label
+}
+
+// Apply updates the table with every non nil field of the patch.
+// Resizing a table shifts its planned and available capacity by the same difference.
+func (p *TablePatch) Apply(t *Table) {
+	if p.Capacity != nil {
+		delta := *p.Capacity - t.Capacity
+		t.Capacity = *p.Capacity
+		t.PlannedCapacity += delta
+		t.AvailableCapacity += delta
+	}
+	if p.Event != nil {
+		t.Event = *p.Event
+	}
+	if p.Name != nil {
+		t.Name = *p.Name
+	}
+	if p.Zone != nil {
+		t.Zone = *p.Zone
+	}
+	if p.Shape != nil {
+		t.Shape = *p.Shape
+	}
+	if p.Tags != nil {
+		t.Tags = *p.Tags
+	}
+	if p.X != nil {
+		t.X = *p.X
+	}
+	if p.Y != nil {
+		t.Y = *p.Y
+	}
+	if p.Rotation != nil {
+		t.Rotation = *p.Rotation
+	}
+}
+
+// Occupancy returns the ratio of arrived guests to the table capacity.
+func (t *Table) Occupancy() float64 {
+	if t.Capacity <= 0 {
+		return 0
+	}
+	return float64(t.Capacity-t.AvailableCapacity) / float64(t.Capacity)
 }
@@ -0,0 +1,48 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// Tags represents a list of labels stored as a comma separated column
+type Tags []string
+
+// Scan implements sql.Scanner.
+func (t *Tags) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("cannot scan %T into Tags", src)
+	}
+	*t = nil
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (t Tags) Value() (driver.Value, error) {
+	return strings.Join(t, ","), nil
+}
+
+// Contains reports whether tag is part of the list.
+func (t Tags) Contains(tag string) bool {
+	for _, v := range t {
+		if v == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package floorplan
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+
+	"ggv2/entities"
+)
+
+const (
+	// margin is the free space kept around the outermost tables, in centimetres
+	margin = 100
+	// minimum room size, in centimetres
+	minWidth  = 600
+	minHeight = 400
+
+	labelFontSize = 24
+	guestFontSize = 18
+)
+
+// colour stops used to shade tables from empty to full
+var (
+	colourEmpty = [3]float64{0x4c, 0xaf, 0x50}
+	colourHalf  = [3]float64{0xff, 0xc1, 0x07}
+	colourFull  = [3]float64{0xf4, 0x43, 0x36}
+)
+
+// Render writes the floor plan of tables as an SVG document to w.
+// Coordinates, sizes and font sizes are expressed in centimetres of the room.
+func Render(w io.Writer, tables []*entities.FloorPlanTable) error {
+	x, y, width, height := roomBounds(tables)
+	b := &strings.Builder{}
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="%d %d %d %d" font-family="sans-serif">`+"\n", width, height, x, y, width, height)
+	fmt.Fprintf(b, `<rect class="room" x="%d" y="%d" width="%d" height="%d" fill="#fafafa" stroke="#333333" stroke-width="4"/>`+"\n", x, y, width, height)
+	for _, t := range tables {
+		renderTable(b, t)
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func renderTable(b *strings.Builder, t *entities.FloorPlanTable) {
+	seated := t.Capacity - t.AvailableCapacity
+	fmt.Fprintf(b, `<g class="table" id="table-%d" transform="translate(%d %d)">`+"\n", t.TableID, t.X, t.Y)
+	fmt.Fprintf(b, "<title>%s: %d/%d seated</title>\n", html.EscapeString(t.Label()), seated, t.Capacity)
+	fmt.Fprintf(b, `<g transform="rotate(%d)" fill="%s" stroke="#333333" stroke-width="2">`+"\n", t.Rotation, Colour(t.Occupancy()))
+	w, h := footprint(t.Table)
+	switch t.Shape {
+	case entities.ShapeRectangle, entities.ShapeSquare:
+		fmt.Fprintf(b, `<rect x="%d" y="%d" width="%d" height="%d" rx="4"/>`+"\n", -w/2, -h/2, w, h)
+	case entities.ShapeOval:
+		fmt.Fprintf(b, `<ellipse rx="%d" ry="%d"/>`+"\n", w/2, h/2)
+	default:
+		fmt.Fprintf(b, `<circle r="%d"/>`+"\n", w/2)
+	}
+	b.WriteString("</g>\n")
+	// Labels are not rotated so they stay readable
+	lines := int64(len(t.Guests))
+	y := -(lines * guestFontSize) / 2
+	fmt.Fprintf(b, `<text class="label" y="%d" text-anchor="middle" font-size="%d" font-weight="bold">%s</text>`+"\n", y, labelFontSize, html.EscapeString(t.Label()))
+	for _, g := range t.Guests {
+		y += guestFontSize + 4
+		fmt.Fprintf(b, `<text class="guest" y="%d" text-anchor="middle" font-size="%d">%s</text>`+"\n", y, guestFontSize, html.EscapeString(g))
+	}
+	b.WriteString("</g>\n")
+}
+
+// footprint returns the unrotated width and height of a table, sized by its capacity.
+func footprint(t *entities.Table) (int64, int64) {
+	switch t.Shape {
+	case entities.ShapeRectangle:
+		return 70 * ((t.Capacity + 1) / 2), 90
+	case entities.ShapeSquare:
+		side := 50 + 12*t.Capacity
+		return side, side
+	case entities.ShapeOval:
+		d := 60 + 12*t.Capacity
+		return d * 13 / 10, d * 8 / 10
+	}
+	d := 60 + 12*t.Capacity
+	return d, d
+}
+
+// roomBounds returns the origin and size of a room large enough to fit every table whatever its
+// rotation. The room starts at 0, 0 unless tables placed near or past it need more space.
+func roomBounds(tables []*entities.FloorPlanTable) (x, y, width, height int64) {
+	maxX, maxY := int64(minWidth), int64(minHeight)
+	for _, t := range tables {
+		w, h := footprint(t.Table)
+		// Half diagonal covers every rotation
+		r := int64(math.Ceil(math.Hypot(float64(w), float64(h)) / 2))
+		if left := t.X - r - margin; left < x {
+			x = left
+		}
+		if top := t.Y - r - margin; top < y {
+			y = top
+		}
+		if right := t.X + r + margin; right > maxX {
+			maxX = right
+		}
+		if bottom := t.Y + r + margin; bottom > maxY {
+			maxY = bottom
+		}
+	}
+	return x, y, maxX - x, maxY - y
+}
+
+// Colour returns the fill colour of a table for the given occupancy ratio,
+// shading from green when empty through amber to red when full.
+func Colour(occupancy float64) string {
+	occupancy = math.Max(0, math.Min(1, occupancy))
+	from, to, ratio := colourEmpty, colourHalf, occupancy*2
+	if occupancy > 0.5 {
+		from, to, ratio = colourHalf, colourFull, (occupancy-0.5)*2
+	}
+	c := [3]int{}
+	for i := range c {
+		c[i] = int(math.Round(from[i] + (to[i]-from[i])*ratio))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c[0], c[1], c[2])
+}
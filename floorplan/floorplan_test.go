@@ -0,0 +1,123 @@
+package floorplan
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"ggv2/entities"
+)
+
+func TestRender(t *testing.T) {
+	tables := []*entities.FloorPlanTable{
+		{
+			Table: &entities.Table{
+				TableID:           12,
+				Name:              "12",
+				Zone:              "Garden",
+				Shape:             entities.ShapeRound,
+				Capacity:          8,
+				AvailableCapacity: 8,
+				X:                 200,
+				Y:                 300,
+			},
+			Guests: []string{},
+		},
+		{
+			Table: &entities.Table{
+				TableID:           13,
+				Shape:             entities.ShapeRectangle,
+				Capacity:          10,
+				AvailableCapacity: 0,
+				X:                 1200,
+				Y:                 900,
+				Rotation:          90,
+			},
+			Guests: []string{"Tom & Jerry", "<script>"},
+		},
+	}
+	buf := new(bytes.Buffer)
+	err := Render(buf, tables)
+	assert.Nil(t, err)
+	svg := buf.String()
+
+	// Output is well formed XML
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, err := dec.Token()
+		if err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			break
+		}
+	}
+	// Table 12 reaches past the left wall once its margin is kept
+	assert.Contains(t, svg, `viewBox="-11 0 1492 1181"`)
+	assert.Contains(t, svg, `id="table-12" transform="translate(200 300)"`)
+	assert.Contains(t, svg, `<circle r="78"/>`)
+	assert.Contains(t, svg, "Table 12 – Garden")
+	assert.Contains(t, svg, `fill="`+Colour(0)+`"`)
+	assert.Contains(t, svg, `<g transform="rotate(90)" fill="`+Colour(1)+`"`)
+	assert.Contains(t, svg, "Tom &amp; Jerry")
+	assert.Contains(t, svg, "&lt;script&gt;")
+	assert.NotContains(t, svg, "<script>")
+}
+
+func TestRoomBounds(t *testing.T) {
+	type TestCase struct {
+		name      string
+		desc      string
+		tables    []*entities.FloorPlanTable
+		expX      int64
+		expY      int64
+		expWidth  int64
+		expHeight int64
+	}
+	testcases := []TestCase{
+		{
+			name:      "Happy case",
+			desc:      "empty room uses minimum size",
+			tables:    []*entities.FloorPlanTable{},
+			expWidth:  minWidth,
+			expHeight: minHeight,
+		},
+		{
+			name: "Happy case",
+			desc: "room grows to fit tables",
+			tables: []*entities.FloorPlanTable{
+				{Table: &entities.Table{Capacity: 5, X: 1000, Y: 2000}},
+			},
+			expWidth:  1000 + 85 + margin,
+			expHeight: 2000 + 85 + margin,
+		},
+		{
+			name: "Happy case",
+			desc: "room extends past the origin to fit tables near it",
+			tables: []*entities.FloorPlanTable{
+				{Table: &entities.Table{Capacity: 5, X: 0, Y: 50}},
+				{Table: &entities.Table{Capacity: 5, X: 1000, Y: -300}},
+			},
+			expX:      -85 - margin,
+			expY:      -300 - 85 - margin,
+			expWidth:  1000 + 85 + margin + 85 + margin,
+			expHeight: minHeight + 300 + 85 + margin,
+		},
+	}
+	for _, v := range testcases {
+		x, y, w, h := roomBounds(v.tables)
+		assert.Equal(t, v.expX, x, v.desc)
+		assert.Equal(t, v.expY, y, v.desc)
+		assert.Equal(t, v.expWidth, w, v.desc)
+		assert.Equal(t, v.expHeight, h, v.desc)
+	}
+}
+
+func TestColour(t *testing.T) {
+	assert.Equal(t, "#4caf50", Colour(0))
+	assert.Equal(t, "#ffc107", Colour(0.5))
+	assert.Equal(t, "#f44336", Colour(1))
+	assert.Equal(t, Colour(1), Colour(1.5))
+	assert.Equal(t, Colour(0), Colour(-1))
+}
@@ -0,0 +1,358 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"go.uber.org/zap"
+
+	"ggv2/dump"
+	"ggv2/entities"
+	"ggv2/handler/presenter"
+	"ggv2/logger"
+	"ggv2/repo"
+	"ggv2/services"
+)
+
+// HeaderConfirm names the destructive action a request confirms, e.g. "X-Confirm: empty_tables".
+const HeaderConfirm = "X-Confirm"
+
+const (
+	confirmEmptyTables = "empty_tables"
+	confirmRestore     = "restore"
+)
+
+const (
+	snapshotFormatJSON = "json"
+	snapshotFormatTar  = "tar"
+
+	mimeApplicationGzip = "application/gzip"
+
+	// maxSnapshotSize bounds a JSON snapshot in bytes, tarballs bound each of their files
+	maxSnapshotSize = 64 << 20
+	// errBodyTooLarge is the error of a body read past the bound of http.MaxBytesReader
+	errBodyTooLarge = "http: request body too large"
+)
+
+var (
+	errConfirmationRequired        = errors.New("confirm by setting the X-Confirm header to " + confirmEmptyTables)
+	errRestoreConfirmationRequired = errors.New("confirm replacing data by setting the X-Confirm header to " + confirmRestore)
+	errInvalidSnapshotFormat       = errors.New("format must be json or tar")
+	errUnsupportedSnapshotMedia    = errors.New("snapshot must be sent as application/json or application/gzip")
+	errInvalidSnapshot             = errors.New("snapshot is not valid json or tarball")
+	errSnapshotTooLarge            = fmt.Errorf("snapshot cannot be larger than %d bytes", maxSnapshotSize)
+	errSnapshotVersion             = fmt.Errorf("snapshot version must be %d", entities.SnapshotVersion)
+	errSnapshotInvalidID           = errors.New("ids must be greater than 0")
+	errSnapshotDuplicateID         = errors.New("duplicate id")
+	errSnapshotEventMismatch       = errors.New("table does not belong to the event of the snapshot")
+	errSnapshotSeatsMismatch       = errors.New("table seats do not add up with its guests")
+	errSnapshotUnknownTable        = errors.New("guest refers to a table missing from the snapshot")
+	errSnapshotUnknownGuest        = errors.New("party member refers to a guest missing from the snapshot")
+	errSnapshotArrivalMismatch     = errors.New("arrived party members do not add up with the arrived guests of their guest")
+	errGuestNameTooLong            = errors.New("guest name cannot be longer than 45 characters")
+	errPartyMemberNameTooLong      = errors.New("party member name cannot be longer than 45 characters")
+)
+
+type getArchivesResponse struct {
+	Archives []*presenter.Archive `json:"archives"`
+}
+
+type postRestoreResponse struct {
+	Tables       int                `json:"tables"`
+	Guests       int                `json:"guests"`
+	PartyMembers int                `json:"party_members"`
+	Replaced     *presenter.Archive `json:"replaced,omitempty"`
+}
+
+type AdminHandler struct {
+	dbSvc services.DbService
+}
+
+func NewAdminHandler(dbSvc services.DbService) *AdminHandler {
+	return &AdminHandler{
+		dbSvc: dbSvc,
+	}
+}
+
+// EmptyTables handles POST /admin/empty_tables
+func (ah *AdminHandler) EmptyTables(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	if c.Request().Header.Get(HeaderConfirm) != confirmEmptyTables {
+		// Destructive action not confirmed
+		return c.JSON(http.StatusPreconditionRequired, presenter.ErrResp(reqID, errConfirmationRequired))
+	}
+	event, err := toEvent(c.QueryParam("event"))
+	if err != nil {
+		// Invalid event
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	// Query database
+	data, err := ah.dbSvc.EmptyTables(c.Request().Context(), event)
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrLockConflict), errors.Is(err, repo.ErrFailedOptimisticLock):
+			// Tables updated concurrently
+			return conflict(c, reqID, err)
+		default:
+			// Error while querying database
+			return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+		}
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, toArchivePresenter(data))
+}
+
+// ListArchives handles GET /admin/archives
+func (ah *AdminHandler) ListArchives(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	// Query database
+	data, err := ah.dbSvc.ListArchives(c.Request().Context())
+	if err != nil {
+		// Error while querying database
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Map response fields
+	res := &getArchivesResponse{Archives: []*presenter.Archive{}}
+	for _, a := range data {
+		res.Archives = append(res.Archives, toArchivePresenter(a))
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, res)
+}
+
+// RestoreArchive handles POST /admin/archives/:id/restore
+func (ah *AdminHandler) RestoreArchive(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
+	}
+	// Query database
+	data, err := ah.dbSvc.RestoreArchive(c.Request().Context(), id)
+	if err != nil {
+		// Error while querying database
+		switch {
+		case errors.Is(err, repo.ErrArchiveNotFound):
+			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		case errors.Is(err, repo.ErrArchiveAlreadyRestored), errors.Is(err, repo.ErrRestoreConflict):
+			return c.JSON(http.StatusConflict, presenter.ErrResp(reqID, err))
+		}
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, toArchivePresenter(data))
+}
+
+// Snapshot handles GET /admin/snapshot
+func (ah *AdminHandler) Snapshot(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	event, err := toEvent(c.QueryParam("event"))
+	if err != nil {
+		// Invalid event
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	format := c.QueryParam("format")
+	if format == "" {
+		format = snapshotFormatJSON
+	}
+	if format != snapshotFormatJSON && format != snapshotFormatTar {
+		// Invalid format
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(errInvalidSnapshotFormat))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidSnapshotFormat))
+	}
+	// Query database
+	data, err := ah.dbSvc.Snapshot(c.Request().Context(), event)
+	if err != nil {
+		// Error while querying database
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	name := "snapshot-" + data.TakenAt.Format("20060102T150405Z")
+	// Return ok
+	if format == snapshotFormatTar {
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+".tar.gz"))
+		c.Response().Header().Set(echo.HeaderContentType, mimeApplicationGzip)
+		c.Response().WriteHeader(http.StatusOK)
+		return dump.WriteTarball(c.Response(), data)
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+".json"))
+	return c.JSON(http.StatusOK, data)
+}
+
+// RestoreSnapshot handles POST /admin/restore
+func (ah *AdminHandler) RestoreSnapshot(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	replace := c.QueryParam("replace") == "true"
+	if replace && c.Request().Header.Get(HeaderConfirm) != confirmRestore {
+		// Replacing data not confirmed
+		return c.JSON(http.StatusPreconditionRequired, presenter.ErrResp(reqID, errRestoreConfirmationRequired))
+	}
+	snapshot := &entities.Snapshot{}
+	ctype := c.Request().Header.Get(echo.HeaderContentType)
+	switch {
+	case strings.HasPrefix(ctype, echo.MIMEApplicationJSON):
+		body := http.MaxBytesReader(c.Response(), c.Request().Body, maxSnapshotSize)
+		err = json.NewDecoder(body).Decode(snapshot)
+	case strings.HasPrefix(ctype, mimeApplicationGzip):
+		snapshot, err = dump.ReadTarball(c.Request().Body)
+	default:
+		return c.JSON(http.StatusUnsupportedMediaType, presenter.ErrResp(reqID, errUnsupportedSnapshotMedia))
+	}
+	if err != nil {
+		// Invalid request body
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		if err.Error() == errBodyTooLarge {
+			return c.JSON(http.StatusRequestEntityTooLarge, presenter.ErrResp(reqID, errSnapshotTooLarge))
+		}
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidSnapshot))
+	}
+	if err = validateSnapshot(snapshot); err != nil {
+		// Inconsistent snapshot
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	// Query database
+	data, err := ah.dbSvc.RestoreSnapshot(c.Request().Context(), snapshot, replace)
+	if err != nil {
+		// Error while querying database
+		if errors.Is(err, repo.ErrRestoreConflict) {
+			return c.JSON(http.StatusConflict, presenter.ErrResp(reqID, err))
+		}
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Map response fields
+	res := &postRestoreResponse{
+		Tables:       len(snapshot.Tables),
+		Guests:       len(snapshot.Guests),
+		PartyMembers: len(snapshot.PartyMembers),
+	}
+	if data != nil {
+		res.Replaced = toArchivePresenter(data)
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, res)
+}
+
+// validateSnapshot checks that a snapshot is of a known version and that its rows are consistent:
+// ids are unique, values fit their columns, tables belong to the snapshot's event, guests and
+// party members refer to rows of the snapshot, the planned and available seats of every table add
+// up with its guests, and no more party members arrived than their guest checked-in.
+func validateSnapshot(s *entities.Snapshot) error {
+	if s.Version != entities.SnapshotVersion {
+		return errSnapshotVersion
+	}
+	if _, err := toEvent(s.Event); err != nil {
+		return err
+	}
+	tables := map[int64]*entities.Table{}
+	planned := map[int64]int64{}
+	arrived := map[int64]int64{}
+	for _, t := range s.Tables {
+		if t.TableID < 1 {
+			return fmt.Errorf("table %d: %w", t.TableID, errSnapshotInvalidID)
+		}
+		if _, ok := tables[t.TableID]; ok {
+			return fmt.Errorf("table %d: %w", t.TableID, errSnapshotDuplicateID)
+		}
+		if s.Event != "" && t.Event != s.Event {
+			return fmt.Errorf("table %d: %w", t.TableID, errSnapshotEventMismatch)
+		}
+		if t.Capacity < 1 {
+			return fmt.Errorf("table %d: %w", t.TableID, errCapacityLessThanOne)
+		}
+		if !entities.IsValidShape(t.Shape) {
+			return fmt.Errorf("table %d: %w", t.TableID, errInvalidShape)
+		}
+		if len(t.Event) > 45 {
+			return fmt.Errorf("table %d: %w", t.TableID, errEventTooLong)
+		}
+		if len(t.Name) > 45 {
+			return fmt.Errorf("table %d: %w", t.TableID, errTableNameTooLong)
+		}
+		if len(t.Zone) > 45 {
+			return fmt.Errorf("table %d: %w", t.TableID, errZoneTooLong)
+		}
+		if len(strings.Join(t.Tags, ",")) > 255 {
+			return fmt.Errorf("table %d: %w", t.TableID, errTagsTooLong)
+		}
+		tables[t.TableID] = t
+	}
+	guests := map[int64]*entities.Guest{}
+	for _, g := range s.Guests {
+		if g.ID < 1 {
+			return fmt.Errorf("guest %d: %w", g.ID, errSnapshotInvalidID)
+		}
+		if _, ok := guests[g.ID]; ok {
+			return fmt.Errorf("guest %d: %w", g.ID, errSnapshotDuplicateID)
+		}
+		if _, ok := tables[g.TableID]; !ok {
+			return fmt.Errorf("guest %d: %w", g.ID, errSnapshotUnknownTable)
+		}
+		if len(g.Name) > 45 {
+			return fmt.Errorf("guest %d: %w", g.ID, errGuestNameTooLong)
+		}
+		if err := checkDiner(g.Diner()); err != nil {
+			return fmt.Errorf("guest %d: %w", g.ID, err)
+		}
+		guests[g.ID] = g
+		planned[g.TableID] += g.TotalGuests
+		arrived[g.TableID] += g.TotalArrivedGuests
+	}
+	for _, t := range s.Tables {
+		if t.PlannedCapacity != t.Capacity-planned[t.TableID] || t.AvailableCapacity != t.Capacity-arrived[t.TableID] {
+			return fmt.Errorf("table %d: %w", t.TableID, errSnapshotSeatsMismatch)
+		}
+	}
+	members := map[int64]bool{}
+	arrivedMembers := map[int64]int64{}
+	for _, m := range s.PartyMembers {
+		if m.ID < 1 {
+			return fmt.Errorf("party member %d: %w", m.ID, errSnapshotInvalidID)
+		}
+		if members[m.ID] {
+			return fmt.Errorf("party member %d: %w", m.ID, errSnapshotDuplicateID)
+		}
+		g, ok := guests[m.GuestID]
+		if !ok {
+			return fmt.Errorf("party member %d: %w", m.ID, errSnapshotUnknownGuest)
+		}
+		if !entities.IsValidAgeGroup(m.AgeGroup) {
+			return fmt.Errorf("party member %d: %w", m.ID, errInvalidAgeGroup)
+		}
+		if len(m.Name) > 45 {
+			return fmt.Errorf("party member %d: %w", m.ID, errPartyMemberNameTooLong)
+		}
+		if err := checkDiner(m.Diner()); err != nil {
+			return fmt.Errorf("party member %d: %w", m.ID, err)
+		}
+		if m.Arrived {
+			// Arrived guests count the guest itself, members only arrive with their guest
+			arrivedMembers[g.ID]++
+			if g.TotalArrivedGuests == 0 || arrivedMembers[g.ID] > g.TotalArrivedGuests-1 {
+				return fmt.Errorf("party member %d: %w", m.ID, errSnapshotArrivalMismatch)
+			}
+		}
+		members[m.ID] = true
+	}
+	return nil
+}
+
+func toArchivePresenter(a *entities.Archive) *presenter.Archive {
+	return &presenter.Archive{
+		ID:        a.ID,
+		Event:     a.Event,
+		Tables:    a.Tables,
+		Guests:    a.Guests,
+		CreatedAt: a.CreatedAt,
+		Restored:  a.Restored,
+	}
+}
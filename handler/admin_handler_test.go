@@ -0,0 +1,467 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"ggv2/dump"
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/services/mocks"
+)
+
+func TestEmptyTables(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		confirm  string
+		event    string
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "every event emptied",
+			confirm:  "empty_tables",
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Happy case",
+			desc:     "single event emptied",
+			confirm:  "empty_tables",
+			event:    "gala",
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "confirmation missing",
+			httpCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:     "Sad case",
+			desc:     "confirmation for another action",
+			confirm:  "delete_everything",
+			httpCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:     "Sad case",
+			desc:     "event too long",
+			confirm:  "empty_tables",
+			event:    strings.Repeat("a", 46),
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			confirm:  "empty_tables",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+		{
+			name:     "Sad case",
+			desc:     "lock held by a concurrent update",
+			confirm:  "empty_tables",
+			err:      repo.ErrLockConflict,
+			httpCode: http.StatusConflict,
+		},
+		{
+			name:     "Sad case",
+			desc:     "tables updated concurrently",
+			confirm:  "empty_tables",
+			err:      repo.ErrFailedOptimisticLock,
+			httpCode: http.StatusConflict,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("EmptyTables", context.Background(), v.event).Return(&entities.Archive{ID: 1, Event: v.event}, v.err)
+		ah := AdminHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:1323/admin/empty_tables?event="+v.event, nil)
+		if v.confirm != "" {
+			req.Header.Set(HeaderConfirm, v.confirm)
+		}
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.POST("/admin/empty_tables", ah.EmptyTables)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		if v.httpCode == http.StatusPreconditionRequired {
+			dbSvc.AssertNotCalled(t, "EmptyTables", context.Background(), v.event)
+		}
+	}
+}
+
+func TestListArchives(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("ListArchives", context.Background()).Return([]*entities.Archive{{ID: 1, Event: "gala", Tables: 10, Guests: 40}}, v.err)
+		ah := AdminHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/admin/archives", nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.GET("/admin/archives", ah.ListArchives)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
+
+func TestRestoreArchive(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		id       string
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			id:       "1",
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid id",
+			id:       "abc",
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "archive not found",
+			id:       "1",
+			err:      repo.ErrArchiveNotFound,
+			httpCode: http.StatusNotFound,
+		},
+		{
+			name:     "Sad case",
+			desc:     "archive already restored",
+			id:       "1",
+			err:      repo.ErrArchiveAlreadyRestored,
+			httpCode: http.StatusConflict,
+		},
+		{
+			name:     "Sad case",
+			desc:     "archived data conflicts",
+			id:       "1",
+			err:      repo.ErrRestoreConflict,
+			httpCode: http.StatusConflict,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			id:       "1",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("RestoreArchive", context.Background(), int64(1)).Return(&entities.Archive{ID: 1, Restored: true}, v.err)
+		ah := AdminHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:1323/admin/archives/"+v.id+"/restore", nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.POST("/admin/archives/:id/restore", ah.RestoreArchive)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		query    string
+		event    string
+		ctype    string
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "json snapshot of every event",
+			ctype:    echo.MIMEApplicationJSONCharsetUTF8,
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Happy case",
+			desc:     "tarball snapshot of an event",
+			query:    "?event=gala&format=tar",
+			event:    "gala",
+			ctype:    mimeApplicationGzip,
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid format",
+			query:    "?format=zip",
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "event too long",
+			query:    "?event=" + strings.Repeat("a", 46),
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("Snapshot", context.Background(), v.event).Return(&entities.Snapshot{
+			Version:      entities.SnapshotVersion,
+			Event:        v.event,
+			TakenAt:      time.Date(2021, 6, 1, 20, 0, 0, 0, time.UTC),
+			Tables:       []*entities.Table{{TableID: 1, Capacity: 8, AvailableCapacity: 8, PlannedCapacity: 8, Event: v.event}},
+			Guests:       []*entities.Guest{},
+			PartyMembers: []*entities.PartyMember{},
+		}, v.err)
+		ah := AdminHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/admin/snapshot"+v.query, nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.GET("/admin/snapshot", ah.Snapshot)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		if v.httpCode == http.StatusOK {
+			assert.Equal(t, v.ctype, w.Header().Get(echo.HeaderContentType), v.desc)
+			assert.Contains(t, w.Header().Get(echo.HeaderContentDisposition), "snapshot-20210601T200000Z", v.desc)
+		}
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	snapshot := &entities.Snapshot{
+		Version:      entities.SnapshotVersion,
+		Event:        "gala",
+		TakenAt:      time.Date(2021, 6, 1, 20, 0, 0, 0, time.UTC),
+		Tables:       []*entities.Table{{TableID: 1, Capacity: 8, AvailableCapacity: 6, PlannedCapacity: 4, Event: "gala"}},
+		Guests:       []*entities.Guest{{ID: 2, Name: "bob", TableID: 1, TotalGuests: 4, TotalArrivedGuests: 2}},
+		PartyMembers: []*entities.PartyMember{{ID: 3, GuestID: 2, Name: "alice", Arrived: true}},
+	}
+	valid, err := json.Marshal(snapshot)
+	assert.Nil(t, err)
+	tarball := &bytes.Buffer{}
+	assert.Nil(t, dump.WriteTarball(tarball, snapshot))
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		query    string
+		confirm  string
+		ctype    string
+		body     string
+		replace  bool
+		replaced *entities.Archive
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "json snapshot restored",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     string(valid),
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Happy case",
+			desc:     "tarball snapshot replaces event",
+			query:    "?replace=true",
+			confirm:  "restore",
+			ctype:    mimeApplicationGzip,
+			body:     tarball.String(),
+			replace:  true,
+			replaced: &entities.Archive{ID: 4, Event: "gala", Tables: 2},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "replace not confirmed",
+			query:    "?replace=true",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     string(valid),
+			httpCode: http.StatusPreconditionRequired,
+		},
+		{
+			name:     "Sad case",
+			desc:     "unsupported media type",
+			ctype:    echo.MIMETextPlain,
+			body:     string(valid),
+			httpCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid json",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid tarball",
+			ctype:    mimeApplicationGzip,
+			body:     "not a tarball",
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "unsupported version",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":2}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "duplicate table id",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":1,"tables":[{"id":1,"capacity":8,"acapacity":8,"pcapacity":8},{"id":1,"capacity":8,"acapacity":8,"pcapacity":8}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "table of another event",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":1,"event":"gala","tables":[{"id":1,"capacity":8,"acapacity":8,"pcapacity":8,"event":"rehearsal"}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "seats do not add up",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":1,"tables":[{"id":1,"capacity":8,"acapacity":8,"pcapacity":8}],"guests":[{"id":2,"name":"bob","tableid":1,"total_rsvp_guests":4}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "guest of unknown table",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":1,"guests":[{"id":2,"name":"bob","tableid":1}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "party member of unknown guest",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":1,"party_members":[{"id":3,"guestid":2,"name":"alice"}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "snapshot too large",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     strings.Repeat(" ", maxSnapshotSize) + string(valid),
+			httpCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "Sad case",
+			desc:     "table tags too long",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":1,"tables":[{"id":1,"capacity":8,"acapacity":8,"pcapacity":8,"tags":["` + strings.Repeat("a", 128) + `","` + strings.Repeat("b", 127) + `"]}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "guest name too long",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":1,"tables":[{"id":1,"capacity":8,"acapacity":8,"pcapacity":8}],"guests":[{"id":2,"name":"` + strings.Repeat("b", 46) + `","tableid":1}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "party member allergens too long",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":1,"tables":[{"id":1,"capacity":8,"acapacity":8,"pcapacity":8}],"guests":[{"id":2,"name":"bob","tableid":1}],"party_members":[{"id":3,"guestid":2,"name":"alice","allergens":["` + strings.Repeat("a", 256) + `"]}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "party member arrived without the guest",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":1,"tables":[{"id":1,"capacity":8,"acapacity":8,"pcapacity":6}],"guests":[{"id":2,"name":"bob","tableid":1,"total_rsvp_guests":2}],"party_members":[{"id":3,"guestid":2,"name":"alice","arrived":true}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "more party members arrived than checked-in",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     `{"version":1,"tables":[{"id":1,"capacity":8,"acapacity":6,"pcapacity":5}],"guests":[{"id":2,"name":"bob","tableid":1,"total_rsvp_guests":3,"total_arrived_guests":2}],"party_members":[{"id":3,"guestid":2,"name":"alice","arrived":true},{"id":4,"guestid":2,"name":"carol","arrived":true}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "restored rows conflict",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     string(valid),
+			err:      repo.ErrRestoreConflict,
+			httpCode: http.StatusConflict,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			ctype:    echo.MIMEApplicationJSON,
+			body:     string(valid),
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("RestoreSnapshot", context.Background(), snapshot, v.replace).Return(v.replaced, v.err)
+		ah := AdminHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:1323/admin/restore"+v.query, strings.NewReader(v.body))
+		req.Header.Set(echo.HeaderContentType, v.ctype)
+		if v.confirm != "" {
+			req.Header.Set(HeaderConfirm, v.confirm)
+		}
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.POST("/admin/restore", ah.RestoreSnapshot)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		if v.httpCode == http.StatusOK {
+			expRes := &postRestoreResponse{Tables: 1, Guests: 1, PartyMembers: 1}
+			if v.replaced != nil {
+				expRes.Replaced = toArchivePresenter(v.replaced)
+			}
+			res := &postRestoreResponse{}
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), res), v.desc)
+			assert.Equal(t, expRes, res, v.desc)
+		}
+	}
+}
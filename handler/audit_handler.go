@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"go.uber.org/zap"
+
+	"ggv2/entities"
+	"ggv2/handler/presenter"
+	"ggv2/logger"
+	"ggv2/services"
+)
+
+var (
+	errInvalidAuditTime = errors.New("since and until must be RFC3339 timestamps")
+)
+
+type getAuditResponse struct {
+	Entries []*presenter.AuditEntry `json:"entries"`
+}
+
+type AuditHandler struct {
+	dbSvc services.DbService
+}
+
+func NewAuditHandler(dbSvc services.DbService) *AuditHandler {
+	return &AuditHandler{
+		dbSvc: dbSvc,
+	}
+}
+
+// ListAuditEntries handles GET /audit
+func (ah *AuditHandler) ListAuditEntries(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	limit, offset, err := getLimitAndOffest(c)
+	if err != nil {
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	filter := &entities.AuditFilter{
+		Actor:     c.QueryParam("actor"),
+		RequestID: c.QueryParam("request_id"),
+		Operation: c.QueryParam("operation"),
+		Target:    c.QueryParam("target"),
+	}
+	filter.Since, err = parseAuditTime(c.QueryParam("since"))
+	if err == nil {
+		filter.Until, err = parseAuditTime(c.QueryParam("until"))
+	}
+	if err != nil {
+		// Invalid time range
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidAuditTime))
+	}
+	// Query database
+	data, err := ah.dbSvc.ListAuditEntries(c.Request().Context(), filter, limit, offset)
+	if err != nil {
+		// Error while querying database
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Map response fields
+	res := &getAuditResponse{Entries: []*presenter.AuditEntry{}}
+	for _, e := range data {
+		res.Entries = append(res.Entries, &presenter.AuditEntry{
+			ID:        e.ID,
+			Actor:     e.Actor,
+			RequestID: e.RequestID,
+			Operation: e.Operation,
+			Target:    e.Target,
+			Before:    json.RawMessage(e.Before),
+			After:     json.RawMessage(e.After),
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, res)
+}
+
+// parseAuditTime parses an optional RFC3339 timestamp, the zero time is returned when s is empty.
+func parseAuditTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"ggv2/entities"
+	"ggv2/services/mocks"
+)
+
+func TestListAuditEntries(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		query    string
+		filter   *entities.AuditFilter
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "no filter",
+			filter:   &entities.AuditFilter{},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:  "Happy case",
+			desc:  "every filter",
+			query: "?actor=jwt:alice&request_id=req-1&operation=update_table&target=table:1&since=2021-06-01T08:00:00%2B08:00&until=2021-06-02T00:00:00Z",
+			filter: &entities.AuditFilter{
+				Actor:     "jwt:alice",
+				RequestID: "req-1",
+				Operation: "update_table",
+				Target:    "table:1",
+				Since:     time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+				Until:     time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC),
+			},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid since",
+			query:    "?since=yesterday",
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid until",
+			query:    "?until=2021-06-02",
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid limit",
+			query:    "?limit=abc",
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			filter:   &entities.AuditFilter{},
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("ListAuditEntries", context.Background(), v.filter, int64(10), int64(0)).Return([]*entities.AuditEntry{
+			{ID: 1, Actor: "jwt:alice", Operation: "update_table", Target: "table:1", Before: entities.JSON(`{"zone":""}`), After: entities.JSON(`{"zone":"Garden"}`)},
+		}, v.err)
+		ah := AuditHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/audit"+v.query, nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.GET("/audit", ah.ListAuditEntries)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		if v.httpCode == http.StatusOK {
+			assert.Contains(t, w.Body.String(), `"before":{"zone":""},"after":{"zone":"Garden"}`, v.desc)
+		}
+	}
+}
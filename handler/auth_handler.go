@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"go.uber.org/zap"
+
+	"ggv2/auth"
+	"ggv2/entities"
+	"ggv2/handler/presenter"
+	"ggv2/logger"
+	"ggv2/repo"
+	"ggv2/services"
+)
+
+var (
+	errInvalidKeyName = errors.New("key name must be between 1 and 45 characters")
+	errInvalidRole    = errors.New("role must be one of admin, planner, door_staff or viewer")
+)
+
+type postAPIKeyRequest struct {
+	Name string `json:"name" form:"name"`
+	Role string `json:"role" form:"role"`
+}
+
+type getAPIKeysResponse struct {
+	Keys []*presenter.APIKey `json:"keys"`
+}
+
+type AuthHandler struct {
+	dbSvc services.DbService
+}
+
+func NewAuthHandler(dbSvc services.DbService) *AuthHandler {
+	return &AuthHandler{
+		dbSvc: dbSvc,
+	}
+}
+
+// ListAPIKeys handles GET /admin/api_keys
+func (ah *AuthHandler) ListAPIKeys(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	// Query database
+	data, err := ah.dbSvc.ListAPIKeys(c.Request().Context())
+	if err != nil {
+		// Error while querying database
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Map response fields
+	res := &getAPIKeysResponse{Keys: []*presenter.APIKey{}}
+	for _, k := range data {
+		res.Keys = append(res.Keys, toAPIKeyPresenter(k))
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, res)
+}
+
+// CreateAPIKey handles POST /admin/api_keys
+func (ah *AuthHandler) CreateAPIKey(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	r := new(postAPIKeyRequest)
+	if err = c.Bind(r); err != nil {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
+	}
+	name := strings.TrimSpace(r.Name)
+	if name == "" || len(name) > 45 {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(errInvalidKeyName))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidKeyName))
+	}
+	role := strings.ToLower(strings.TrimSpace(r.Role))
+	if !auth.IsValidRole(role) {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(errInvalidRole))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRole))
+	}
+	// Query database
+	data, key, err := ah.dbSvc.CreateAPIKey(c.Request().Context(), name, role)
+	if err != nil {
+		// Error while querying database
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Map response fields, the key cannot be retrieved again
+	res := toAPIKeyPresenter(data)
+	res.Key = key
+	// Return ok
+	return c.JSON(http.StatusCreated, res)
+}
+
+// RevokeAPIKey handles DELETE /admin/api_keys/:id
+func (ah *AuthHandler) RevokeAPIKey(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
+	}
+	// Query database
+	err = ah.dbSvc.RevokeAPIKey(c.Request().Context(), id)
+	if err != nil {
+		// Error while querying database
+		if errors.Is(err, repo.ErrAPIKeyNotFound) {
+			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		}
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Return ok
+	return c.NoContent(http.StatusNoContent)
+}
+
+func toAPIKeyPresenter(k *entities.APIKey) *presenter.APIKey {
+	return &presenter.APIKey{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Role:      k.Role,
+		CreatedAt: k.CreatedAt,
+		Revoked:   k.Revoked,
+	}
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/services/mocks"
+)
+
+func TestListAPIKeys(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("ListAPIKeys", context.Background()).Return([]*entities.APIKey{
+			{ID: 1, Name: "kiosk", Prefix: "ggv2_abcdefg", Role: "viewer", KeyHash: "hash", CreatedAt: "2021-06-04 04:06:44"},
+		}, v.err)
+		ah := AuthHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/admin/api_keys", nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.GET("/admin/api_keys", ah.ListAPIKeys)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		if v.err == nil {
+			// Hashes are never returned
+			assert.JSONEq(t, `{"keys":[{"id":1,"name":"kiosk","prefix":"ggv2_abcdefg","role":"viewer","created_at":"2021-06-04 04:06:44","revoked":false}]}`, w.Body.String())
+		}
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		body     string
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			body:     `{"name":" kiosk ","role":"door_staff"}`,
+			httpCode: http.StatusCreated,
+		},
+		{
+			name:     "Sad case",
+			desc:     "missing role",
+			body:     `{"name":"kiosk"}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "unknown role",
+			body:     `{"name":"kiosk","role":"owner"}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "empty name",
+			body:     `{"name":" "}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid body",
+			body:     `{"name":1}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			body:     `{"name":"kiosk","role":"door_staff"}`,
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("CreateAPIKey", context.Background(), "kiosk", "door_staff").Return(&entities.APIKey{ID: 1, Name: "kiosk", Prefix: "ggv2_abcdefg", Role: "door_staff"}, "ggv2_abcdefghijk", v.err)
+		ah := AuthHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:1323/admin/api_keys", strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.POST("/admin/api_keys", ah.CreateAPIKey)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		if v.httpCode == http.StatusCreated {
+			assert.JSONEq(t, `{"id":1,"name":"kiosk","prefix":"ggv2_abcdefg","role":"door_staff","revoked":false,"key":"ggv2_abcdefghijk"}`, w.Body.String())
+		}
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		url      string
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			url:      "http://localhost:1323/admin/api_keys/1",
+			httpCode: http.StatusNoContent,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid id",
+			url:      "http://localhost:1323/admin/api_keys/invalid",
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "key not found",
+			url:      "http://localhost:1323/admin/api_keys/1",
+			err:      repo.ErrAPIKeyNotFound,
+			httpCode: http.StatusNotFound,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			url:      "http://localhost:1323/admin/api_keys/1",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("RevokeAPIKey", context.Background(), int64(1)).Return(v.err)
+		ah := AuthHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodDelete, v.url, nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.DELETE("/admin/api_keys/:id", ah.RevokeAPIKey)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"ggv2/config"
+)
+
+type ConfigHandler struct {
+	cfg *config.Config
+}
+
+func NewConfigHandler(cfg *config.Config) *ConfigHandler {
+	return &ConfigHandler{
+		cfg: cfg,
+	}
+}
+
+// GetConfig handles GET /admin/config, secrets are redacted
+func (con *ConfigHandler) GetConfig(c echo.Context) (err error) {
+	return c.JSON(http.StatusOK, con.cfg.Redacted())
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"ggv2/config"
+)
+
+func TestGetConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.DB.DSN = "getground:password@tcp(localhost:3306)/getground"
+	cfg.Auth.JWTSecret = "s3cr3t"
+	ch := ConfigHandler{cfg}
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/admin/config", nil)
+	w := httptest.NewRecorder()
+	r := echo.New()
+	r.GET("/admin/config", ch.GetConfig)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"dsn":"getground:[REDACTED]@tcp(localhost:3306)/getground"`)
+	assert.Contains(t, w.Body.String(), `"jwt_secret":"[REDACTED]"`)
+	assert.Contains(t, w.Body.String(), `"read_timeout":"30s"`)
+	assert.NotContains(t, w.Body.String(), "password")
+	assert.NotContains(t, w.Body.String(), "s3cr3t")
+}
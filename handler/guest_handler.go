@@ -4,13 +4,16 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
-	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
 
 	"go.uber.org/zap"
 
+	"ggv2/entities"
+	"ggv2/handler/middleware"
 	"ggv2/handler/presenter"
+	"ggv2/logger"
 	"ggv2/repo"
 	"ggv2/services"
 )
@@ -19,8 +22,16 @@ var (
 	errInvalidRequest                = errors.New("invalid request parameter")
 	errCapacityLessThanOne           = errors.New("capacity cannot be less than 1")
 	errAccompanyingGuestLessThanZero = errors.New("accompanying guest cannot be less than 0")
-	errTableNotFound                 = errors.New("table not found")
-	errGuestNotFound                 = errors.New("guest not found")
+	errPartyMembersMismatch          = errors.New("accompanying guests must match number of party members")
+	errPartyMemberNameEmpty          = errors.New("party member name cannot be empty")
+	errDuplicatePartyMember          = errors.New("party member names must be unique")
+	errInvalidAgeGroup               = errors.New("invalid age group")
+	errInvalidDietaryTag             = errors.New("invalid dietary tag")
+	errInvalidAllergen               = errors.New("invalid allergen")
+	errDietaryTagsTooLong            = errors.New("dietary tags cannot be longer than 255 characters")
+	errAllergensTooLong              = errors.New("allergens cannot be longer than 255 characters")
+	errDietaryNotesTooLong           = errors.New("dietary notes cannot be longer than 255 characters")
+	errMealChoiceTooLong             = errors.New("meal choice cannot be longer than 45 characters")
 )
 
 type GuestHandler struct {
@@ -28,15 +39,30 @@ type GuestHandler struct {
 }
 
 type putGuestArrivesRequest struct {
-	AccompanyingGuests int64 `json:"accompanying_guests" form:"accompanying_guests"`
+	AccompanyingGuests int64    `json:"accompanying_guests" form:"accompanying_guests"`
+	Members            []string `json:"members" form:"members"`
 }
 type putGuestArrivesResponse struct {
 	Name string `json:"name"`
 }
 
+type partyMemberRequest struct {
+	Name         string   `json:"name"`
+	AgeGroup     string   `json:"age_group"`
+	DietaryTags  []string `json:"dietary_tags"`
+	Allergens    []string `json:"allergens"`
+	DietaryNotes string   `json:"dietary_notes"`
+	MealChoice   string   `json:"meal_choice"`
+}
+
 type postGuestListRequest struct {
-	Table              int64 `json:"table" form:"table"`
-	AccompanyingGuests int64 `json:"accompanying_guests" form:"accompanying_guests"`
+	Table              int64                 `json:"table" form:"table"`
+	AccompanyingGuests int64                 `json:"accompanying_guests" form:"accompanying_guests"`
+	Members            []*partyMemberRequest `json:"members"`
+	DietaryTags        []string              `json:"dietary_tags" form:"dietary_tags"`
+	Allergens          []string              `json:"allergens" form:"allergens"`
+	DietaryNotes       string                `json:"dietary_notes" form:"dietary_notes"`
+	MealChoice         string                `json:"meal_choice" form:"meal_choice"`
 }
 type postGuestListResponse struct {
 	Name string `json:"name"`
@@ -46,10 +72,16 @@ type getGuestListResponse struct {
 	Guests []*presenter.Guest `json:"guests"`
 }
 
-func NewGuestHandler(conn *sqlx.DB) *GuestHandler {
-	dbRepo := repo.NewDbRepo(conn)
-	dbSvc := services.NewDbService(dbRepo)
+type getPartyMembersResponse struct {
+	Members []*presenter.PartyMember `json:"members"`
+}
+
+type putMemberArrivesResponse struct {
+	Name   string `json:"name"`
+	Member string `json:"member"`
+}
 
+func NewGuestHandler(dbSvc services.DbService) *GuestHandler {
 	return &GuestHandler{
 		dbSvc: dbSvc,
 	}
@@ -63,28 +95,52 @@ func (con *GuestHandler) AddToGuestList(c echo.Context) (err error) {
 	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
 	if err = c.Bind(r); err != nil {
 		// Invalid request parameter
-		zap.L().Error(errInvalidRequest.Error(), zap.Error(err))
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
 		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
 	}
 	if r.Table < 1 {
 		// Invalid request parameter
-		zap.L().Error(errInvalidRequest.Error(), zap.Error(errCapacityLessThanOne))
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(errCapacityLessThanOne))
 		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errCapacityLessThanOne))
 	}
 	if r.AccompanyingGuests < 0 {
 		// Invalid request parameter
 		// c.Response().Header().Get(echo.HeaderXRequestID)
-		zap.L().Error(errInvalidRequest.Error(), zap.Error(errAccompanyingGuestLessThanZero), zap.String("rqId", reqID))
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(errAccompanyingGuestLessThanZero), zap.String("rqId", reqID))
 		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errAccompanyingGuestLessThanZero))
 	}
+	diner, err := toDiner(r.DietaryTags, r.Allergens, r.DietaryNotes, r.MealChoice)
+	if err != nil {
+		// Invalid dietary requirement
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	members, err := toPartyMembers(r.Members)
+	if err != nil {
+		// Invalid party member
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	if len(members) > 0 {
+		// Party size is derived from named members when not given
+		if r.AccompanyingGuests == 0 {
+			r.AccompanyingGuests = int64(len(members))
+		}
+		if r.AccompanyingGuests != int64(len(members)) {
+			logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(errPartyMembersMismatch))
+			return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errPartyMembersMismatch))
+		}
+	}
 
 	// Query database
-	err = con.dbSvc.AddToGuestList(c.Request().Context(), r.AccompanyingGuests, r.Table, name)
+	err = con.dbSvc.AddToGuestList(c.Request().Context(), r.AccompanyingGuests, r.Table, name, diner, members)
 	if err != nil {
 		// Error while querying database
-		if err == errTableNotFound {
-
-			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, errTableNotFound))
+		switch {
+		case errors.Is(err, repo.ErrTableNotFound):
+			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, repo.ErrTableNotFound))
+		case errors.Is(err, repo.ErrGuestAlreadyRSVP), errors.Is(err, services.ErrTableIsFull), errors.Is(err, repo.ErrLockConflict):
+			return conflict(c, reqID, err)
 		}
 		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
 	}
@@ -104,7 +160,7 @@ func (con *GuestHandler) GetGuestList(c echo.Context) (err error) {
 	res := getGuestListResponse{}
 	limit, offset, err := getLimitAndOffest(c)
 	if err != nil {
-		zap.L().Error(errInvalidRequest.Error(), zap.Error(err))
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
 		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
 	}
 	// Query database
@@ -121,6 +177,10 @@ func (con *GuestHandler) GetGuestList(c echo.Context) (err error) {
 			Name:               d.Name,
 			TableID:            d.TableID,
 			AccompanyingGuests: d.TotalGuests,
+			DietaryTags:        d.DietaryTags,
+			Allergens:          d.Allergens,
+			DietaryNotes:       d.DietaryNotes,
+			MealChoice:         d.MealChoice,
 		})
 	}
 	res.Guests = guests
@@ -135,23 +195,36 @@ func (con *GuestHandler) GuestArrived(c echo.Context) (err error) {
 	// Get and validate request parameter
 	r := putGuestArrivesRequest{}
 	name := c.Param("name")
-	if err = c.Bind(r); err != nil {
+	if err = c.Bind(&r); err != nil {
 		// Invalid request parameter
-		zap.L().Error(errInvalidRequest.Error(), zap.Error(err))
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
 		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
 	}
 	if r.AccompanyingGuests < 0 {
 		// Invalid request parameter
-		zap.L().Error(errAccompanyingGuestLessThanZero.Error(), zap.Error(errAccompanyingGuestLessThanZero))
+		logger.FromContext(c.Request().Context()).Error(errAccompanyingGuestLessThanZero.Error(), zap.Error(errAccompanyingGuestLessThanZero))
 		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errAccompanyingGuestLessThanZero))
 	}
+	if len(r.Members) > 0 {
+		// Number of arriving guests is derived from named members when not given
+		if r.AccompanyingGuests == 0 {
+			r.AccompanyingGuests = int64(len(r.Members))
+		}
+		if r.AccompanyingGuests != int64(len(r.Members)) {
+			logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(errPartyMembersMismatch))
+			return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errPartyMembersMismatch))
+		}
+	}
 	res := putGuestArrivesResponse{}
 	// Query database
-	err = con.dbSvc.GuestArrival(c.Request().Context(), r.AccompanyingGuests, name)
+	err = con.dbSvc.GuestArrival(c.Request().Context(), r.AccompanyingGuests, name, r.Members)
 	if err != nil {
 		// Error while querying database
-		if err.Error() == "guest did not register" || err.Error() == "guest already arrived" {
+		switch {
+		case errors.Is(err, services.ErrGuestNeverRSVP), errors.Is(err, services.ErrGuestAlreadyArrived), errors.Is(err, repo.ErrPartyMemberNotFound):
 			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		case errors.Is(err, services.ErrPartyMemberAlreadyArrived), errors.Is(err, services.ErrTableIsFull), errors.Is(err, repo.ErrFailedOptimisticLock), errors.Is(err, repo.ErrLockConflict):
+			return conflict(c, reqID, err)
 		}
 		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
 	}
@@ -167,7 +240,7 @@ func (con *GuestHandler) ListArrivedGuest(c echo.Context) (err error) {
 	res := getGuestListResponse{}
 	limit, offset, err := getLimitAndOffest(c)
 	if err != nil {
-		zap.L().Error(errInvalidRequest.Error(), zap.Error(err))
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
 		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
 	}
 	// Query database
@@ -201,8 +274,11 @@ func (con *GuestHandler) GuestDepart(c echo.Context) (err error) {
 	err = con.dbSvc.GuestDepart(c.Request().Context(), name)
 	if err != nil {
 		// Error while querying database
-		if err == errGuestNotFound || err == errTableNotFound {
+		switch {
+		case errors.Is(err, repo.ErrGuestNotFound), errors.Is(err, repo.ErrTableNotFound):
 			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		case errors.Is(err, services.ErrGuestNotArrived), errors.Is(err, repo.ErrFailedOptimisticLock), errors.Is(err, repo.ErrLockConflict):
+			return conflict(c, reqID, err)
 		}
 		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
 	}
@@ -210,6 +286,151 @@ func (con *GuestHandler) GuestDepart(c echo.Context) (err error) {
 	return c.JSON(http.StatusAccepted, "OK!")
 }
 
+// ListPartyMembers handles GET /guests/:name/members
+func (con *GuestHandler) ListPartyMembers(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	name := c.Param("name")
+	// Query database
+	data, err := con.dbSvc.ListPartyMembers(c.Request().Context(), name)
+	if err != nil {
+		// Error while querying database
+		if errors.Is(err, repo.ErrGuestNotFound) {
+			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		}
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Map response fields
+	res := getPartyMembersResponse{Members: []*presenter.PartyMember{}}
+	for _, d := range data {
+		res.Members = append(res.Members, &presenter.PartyMember{
+			Name:         d.Name,
+			AgeGroup:     d.AgeGroup,
+			Arrived:      d.Arrived,
+			DietaryTags:  d.DietaryTags,
+			Allergens:    d.Allergens,
+			DietaryNotes: d.DietaryNotes,
+			MealChoice:   d.MealChoice,
+		})
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, res)
+}
+
+// MemberArrived handles PUT /guests/:name/members/:member
+func (con *GuestHandler) MemberArrived(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	// Get request parameter
+	name := c.Param("name")
+	member := c.Param("member")
+	// Query database
+	err = con.dbSvc.MemberArrival(c.Request().Context(), name, member)
+	if err != nil {
+		// Error while querying database
+		switch {
+		case errors.Is(err, services.ErrGuestNeverRSVP), errors.Is(err, services.ErrGuestNotArrived), errors.Is(err, repo.ErrPartyMemberNotFound):
+			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		case errors.Is(err, services.ErrPartyMemberAlreadyArrived), errors.Is(err, services.ErrPartyAlreadyArrived), errors.Is(err, services.ErrTableIsFull), errors.Is(err, repo.ErrFailedOptimisticLock), errors.Is(err, repo.ErrLockConflict):
+			return conflict(c, reqID, err)
+		}
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Return ok
+	return c.JSON(http.StatusCreated, putMemberArrivesResponse{Name: name, Member: member})
+}
+
+// toPartyMembers validates named party members of a guest list request.
+func toPartyMembers(req []*partyMemberRequest) ([]*entities.PartyMember, error) {
+	var members []*entities.PartyMember
+	seen := map[string]bool{}
+	for _, m := range req {
+		if m == nil || m.Name == "" {
+			return nil, errPartyMemberNameEmpty
+		}
+		if len(m.Name) > 45 {
+			return nil, errPartyMemberNameTooLong
+		}
+		// Names are unique within a party regardless of case, like the unique key compares them
+		key := strings.ToLower(m.Name)
+		if seen[key] {
+			return nil, errDuplicatePartyMember
+		}
+		if !entities.IsValidAgeGroup(m.AgeGroup) {
+			return nil, errInvalidAgeGroup
+		}
+		diner, err := toDiner(m.DietaryTags, m.Allergens, m.DietaryNotes, m.MealChoice)
+		if err != nil {
+			return nil, err
+		}
+		seen[key] = true
+		members = append(members, &entities.PartyMember{
+			Name:         m.Name,
+			AgeGroup:     m.AgeGroup,
+			DietaryTags:  diner.DietaryTags,
+			Allergens:    diner.Allergens,
+			DietaryNotes: diner.DietaryNotes,
+			MealChoice:   diner.MealChoice,
+		})
+	}
+	return members, nil
+}
+
+// toDiner validates and normalises dietary requirements of a request.
+func toDiner(tags, allergens []string, notes, meal string) (*entities.Diner, error) {
+	diner := &entities.Diner{
+		DietaryNotes: strings.TrimSpace(notes),
+		MealChoice:   strings.ToLower(strings.TrimSpace(meal)),
+	}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !entities.IsValidDiet(t) {
+			return nil, errInvalidDietaryTag
+		}
+		if !diner.DietaryTags.Contains(t) {
+			diner.DietaryTags = append(diner.DietaryTags, t)
+		}
+	}
+	for _, a := range allergens {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" || strings.Contains(a, ",") {
+			return nil, errInvalidAllergen
+		}
+		if !diner.Allergens.Contains(a) {
+			diner.Allergens = append(diner.Allergens, a)
+		}
+	}
+	if err := checkDiner(diner); err != nil {
+		return nil, err
+	}
+	return diner, nil
+}
+
+// checkDiner checks that dietary requirements fit their columns, tags and allergens are stored
+// comma separated.
+func checkDiner(diner *entities.Diner) error {
+	if len(strings.Join(diner.DietaryTags, ",")) > 255 {
+		return errDietaryTagsTooLong
+	}
+	if len(strings.Join(diner.Allergens, ",")) > 255 {
+		return errAllergensTooLong
+	}
+	if len(diner.DietaryNotes) > 255 {
+		return errDietaryNotesTooLong
+	}
+	if len(diner.MealChoice) > 45 {
+		return errMealChoiceTooLong
+	}
+	return nil
+}
+
+// conflict responds to a request refused by the seating rules or by a concurrent update. Lock
+// conflicts are marked retryable, a retry with the same idempotency key runs the request again.
+func conflict(c echo.Context, reqID string, err error) error {
+	if errors.Is(err, repo.ErrLockConflict) || errors.Is(err, repo.ErrFailedOptimisticLock) {
+		middleware.Retryable(c)
+	}
+	return c.JSON(http.StatusConflict, presenter.ErrResp(reqID, err))
+}
+
 func getLimitAndOffest(c echo.Context) (int64, int64, error) {
 	strlimit := c.QueryParam("limit")
 	stroffset := c.QueryParam("offset")
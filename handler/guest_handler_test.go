@@ -11,8 +11,12 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"ggv2/entities"
+	"ggv2/handler/middleware"
+	"ggv2/repo"
+	"ggv2/services"
 	"ggv2/services/mocks"
 )
 
@@ -58,7 +62,23 @@ func TestAddToGuestList(t *testing.T) {
 			name:               "Sad case",
 			desc:               "Table not found",
 			httpCode:           http.StatusNotFound,
-			err:                fmt.Errorf("table not found"),
+			err:                repo.ErrTableNotFound,
+			table:              "1",
+			accompanyingGuests: "2",
+		},
+		{
+			name:               "Sad case",
+			desc:               "guest already RSVP",
+			httpCode:           http.StatusConflict,
+			err:                repo.ErrGuestAlreadyRSVP,
+			table:              "1",
+			accompanyingGuests: "2",
+		},
+		{
+			name:               "Sad case",
+			desc:               "table is full",
+			httpCode:           http.StatusConflict,
+			err:                services.ErrTableIsFull,
 			table:              "1",
 			accompanyingGuests: "2",
 		},
@@ -75,7 +95,7 @@ func TestAddToGuestList(t *testing.T) {
 		dbSvc := new(mocks.DbService)
 		
 		
-		dbSvc.On("AddToGuestList", context.Background(), int64(2), int64(1), "dummy").Return(v.err)
+		dbSvc.On("AddToGuestList", context.Background(), int64(2), int64(1), "dummy", &entities.Diner{}, []*entities.PartyMember(nil)).Return(v.err)
 		gh := GuestHandler{dbSvc}
 		form := url.Values{}
 		if v.table != "" {
@@ -95,6 +115,146 @@ func TestAddToGuestList(t *testing.T) {
 	}
 }
 
+func TestAddToGuestListWithMembers(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		body     string
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "accompanying guests derived from members",
+			body:     `{"table":1,"members":[{"name":"alice","age_group":"adult"},{"name":"bob","age_group":"child"}]}`,
+			httpCode: http.StatusCreated,
+		},
+		{
+			name:     "Happy case",
+			desc:     "accompanying guests matches members",
+			body:     `{"table":1,"accompanying_guests":2,"members":[{"name":"alice","age_group":"adult"},{"name":"bob","age_group":"child"}]}`,
+			httpCode: http.StatusCreated,
+		},
+		{
+			name:     "Sad case",
+			desc:     "accompanying guests does not match members",
+			body:     `{"table":1,"accompanying_guests":3,"members":[{"name":"alice","age_group":"adult"},{"name":"bob","age_group":"child"}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "empty member name",
+			body:     `{"table":1,"members":[{"name":""},{"name":"bob"}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "duplicate member name",
+			body:     `{"table":1,"members":[{"name":"bob"},{"name":"bob"}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "duplicate member name in another case",
+			body:     `{"table":1,"members":[{"name":"bob"},{"name":"Bob"}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "member name too long",
+			body:     `{"table":1,"members":[{"name":"alice"},{"name":"` + strings.Repeat("b", 46) + `"}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid age group",
+			body:     `{"table":1,"members":[{"name":"alice","age_group":"elder"},{"name":"bob"}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		members := []*entities.PartyMember{
+			{Name: "alice", AgeGroup: entities.AgeGroupAdult},
+			{Name: "bob", AgeGroup: entities.AgeGroupChild},
+		}
+		dbSvc.On("AddToGuestList", context.Background(), int64(2), int64(1), "dummy", &entities.Diner{}, members).Return(nil)
+		gh := GuestHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:1323/guest_list/dummy", strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.POST("/guest_list/:name", gh.AddToGuestList)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
+
+func TestAddToGuestListWithDietary(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		body     string
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "dietary requirements normalised",
+			body:     `{"table":1,"accompanying_guests":2,"dietary_tags":["Vegan","vegan"],"allergens":[" Peanut "],"meal_choice":"Tofu","dietary_notes":"no spice"}`,
+			httpCode: http.StatusCreated,
+		},
+		{
+			name:     "Sad case",
+			desc:     "unknown dietary tag",
+			body:     `{"table":1,"accompanying_guests":2,"dietary_tags":["carnivore"]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "empty allergen",
+			body:     `{"table":1,"accompanying_guests":2,"allergens":[""]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "allergens longer than their column",
+			body:     `{"table":1,"accompanying_guests":2,"allergens":["` + strings.Repeat("a", 128) + `","` + strings.Repeat("b", 127) + `"]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "member allergens longer than their column",
+			body:     `{"table":1,"members":[{"name":"alice","allergens":["` + strings.Repeat("a", 256) + `"]},{"name":"bob"}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "member with unknown dietary tag",
+			body:     `{"table":1,"members":[{"name":"alice","dietary_tags":["carnivore"]},{"name":"bob"}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		diner := &entities.Diner{
+			DietaryTags:  entities.Tags{entities.DietVegan},
+			Allergens:    entities.Tags{"peanut"},
+			DietaryNotes: "no spice",
+			MealChoice:   "tofu",
+		}
+		dbSvc.On("AddToGuestList", context.Background(), int64(2), int64(1), "dummy", diner, []*entities.PartyMember(nil)).Return(nil)
+		gh := GuestHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:1323/guest_list/dummy", strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.POST("/guest_list/:name", gh.AddToGuestList)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
+
 func TestPing(t *testing.T) {
 	dbSvc := new(mocks.DbService)
 	gh := GuestHandler{dbSvc}
@@ -177,6 +337,7 @@ func TestGuestArrived(t *testing.T) {
 		err                error
 		httpCode           int
 		accompanyingGuests string
+		retryable          bool
 	}
 	testcases := []TestCase{
 		{
@@ -201,9 +362,24 @@ func TestGuestArrived(t *testing.T) {
 			name:               "Sad case",
 			desc:               "no rsvp/alrady checked-in error",
 			httpCode:           http.StatusNotFound,
-			err:                fmt.Errorf("guest did not register"),
+			err:                services.ErrGuestNeverRSVP,
+			accompanyingGuests: "2",
+		},
+		{
+			name:               "Sad case",
+			desc:               "table is full",
+			httpCode:           http.StatusConflict,
+			err:                services.ErrTableIsFull,
 			accompanyingGuests: "2",
 		},
+		{
+			name:               "Sad case",
+			desc:               "lock held by a concurrent update",
+			httpCode:           http.StatusConflict,
+			err:                repo.ErrLockConflict,
+			accompanyingGuests: "2",
+			retryable:          true,
+		},
 		{
 			name:               "Sad case",
 			desc:               "db error",
@@ -216,7 +392,10 @@ func TestGuestArrived(t *testing.T) {
 		dbSvc := new(mocks.DbService)
 		
 		
-		dbSvc.On("GuestArrival", context.Background(), int64(2), "dummy").Return(v.err)
+		dbSvc.On("GuestArrival", context.Background(), int64(2), "dummy", []string(nil)).Return(v.err)
+		dbSvc.On("ReserveIdempotencyKey", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+		dbSvc.On("CompleteIdempotencyKey", mock.Anything, mock.Anything).Return(nil)
+		dbSvc.On("ReleaseIdempotencyKey", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 		gh := GuestHandler{dbSvc}
 		form := url.Values{}
 		if v.accompanyingGuests != "" {
@@ -224,11 +403,19 @@ func TestGuestArrived(t *testing.T) {
 		}
 		req := httptest.NewRequest(http.MethodPut, "http://localhost:1323/guests/dummy", strings.NewReader(form.Encode()))
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set(middleware.HeaderIdempotencyKey, "key-1")
 		w := httptest.NewRecorder()
 		r := echo.New()
+		r.Use(middleware.Idempotency(middleware.IdempotencyConfig{Store: dbSvc}))
 		r.PUT("/guests/:name", gh.GuestArrived)
 		r.ServeHTTP(w, req)
 		assert.Equal(t, v.httpCode, w.Code)
+		if v.retryable {
+			dbSvc.AssertCalled(t, "ReleaseIdempotencyKey", mock.Anything, mock.Anything, mock.Anything)
+			dbSvc.AssertNotCalled(t, "CompleteIdempotencyKey", mock.Anything, mock.Anything)
+		} else if v.httpCode == http.StatusConflict {
+			dbSvc.AssertCalled(t, "CompleteIdempotencyKey", mock.Anything, mock.Anything)
+		}
 	}
 }
 
@@ -318,9 +505,21 @@ func TestGuestDepart(t *testing.T) {
 		{
 			name:     "Sad case",
 			desc:     "guest not found error",
-			err:      errGuestNotFound,
+			err:      repo.ErrGuestNotFound,
 			httpCode: http.StatusNotFound,
 		},
+		{
+			name:     "Sad case",
+			desc:     "guest not arrived error",
+			err:      services.ErrGuestNotArrived,
+			httpCode: http.StatusConflict,
+		},
+		{
+			name:     "Sad case",
+			desc:     "lock held by a concurrent update error",
+			err:      repo.ErrLockConflict,
+			httpCode: http.StatusConflict,
+		},
 	}
 	for _, v := range testcases {
 		dbSvc := new(mocks.DbService)
@@ -333,6 +532,149 @@ func TestGuestDepart(t *testing.T) {
 		r := echo.New()
 		r.DELETE("/guests/:name", gh.GuestDepart)
 		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
+
+func TestGuestArrivedWithMembers(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		form     url.Values
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "accompanying guests derived from members",
+			form:     url.Values{"members": {"alice", "bob"}},
+			httpCode: http.StatusCreated,
+		},
+		{
+			name:     "Sad case",
+			desc:     "accompanying guests does not match members",
+			form:     url.Values{"members": {"alice", "bob"}, "accompanying_guests": {"1"}},
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "member not in party",
+			form:     url.Values{"members": {"alice", "bob"}},
+			err:      repo.ErrPartyMemberNotFound,
+			httpCode: http.StatusNotFound,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("GuestArrival", context.Background(), int64(2), "dummy", []string{"alice", "bob"}).Return(v.err)
+		gh := GuestHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodPut, "http://localhost:1323/guests/dummy", strings.NewReader(v.form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.PUT("/guests/:name", gh.GuestArrived)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
+
+func TestListPartyMembers(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		expRes   []*entities.PartyMember
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "All ok",
+			expRes: []*entities.PartyMember{
+				{
+					ID:       1,
+					GuestID:  2,
+					Name:     "alice",
+					AgeGroup: entities.AgeGroupAdult,
+					Arrived:  true,
+				},
+			},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "guest not found",
+			err:      repo.ErrGuestNotFound,
+			httpCode: http.StatusNotFound,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("ListPartyMembers", context.Background(), "dummy").Return(v.expRes, v.err)
+		gh := GuestHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/guests/dummy/members", nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.GET("/guests/:name/members", gh.ListPartyMembers)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code)
+	}
+}
+
+func TestMemberArrived(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			httpCode: http.StatusCreated,
+		},
+		{
+			name:     "Sad case",
+			desc:     "main guest not arrived",
+			err:      services.ErrGuestNotArrived,
+			httpCode: http.StatusNotFound,
+		},
+		{
+			name:     "Sad case",
+			desc:     "member not in party",
+			err:      repo.ErrPartyMemberNotFound,
+			httpCode: http.StatusNotFound,
+		},
+		{
+			name:     "Sad case",
+			desc:     "member already arrived",
+			err:      services.ErrPartyMemberAlreadyArrived,
+			httpCode: http.StatusConflict,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("MemberArrival", context.Background(), "dummy", "alice").Return(v.err)
+		gh := GuestHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodPut, "http://localhost:1323/guests/dummy/members/alice", nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.PUT("/guests/:name/members/:member", gh.MemberArrived)
+		r.ServeHTTP(w, req)
 		assert.Equal(t, v.httpCode, w.Code)
 	}
 }
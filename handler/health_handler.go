@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"go.uber.org/zap"
+
+	"ggv2/handler/presenter"
+	"ggv2/health"
+	"ggv2/logger"
+	"ggv2/services"
+)
+
+const (
+	statusUnavailable = "unavailable"
+)
+
+type HealthHandler struct {
+	health *health.Checker
+}
+
+// NewHealthHandler registers the database, its migrations and the log rotation worker as
+// components of readiness.
+func NewHealthHandler(dbSvc services.DbService, checker *health.Checker) *HealthHandler {
+	checker.Register("database", dbSvc.CheckDatabase)
+	checker.Register("migrations", dbSvc.CheckMigrations)
+	checker.Register("log_rotation", logger.CheckRotation)
+
+	return &HealthHandler{
+		health: checker,
+	}
+}
+
+// Healthz handles GET /healthz, the process is alive as long as it responds
+func (con *HealthHandler) Healthz(c echo.Context) (err error) {
+	return c.JSON(http.StatusOK, presenter.Health{Status: health.StatusOK})
+}
+
+// Readyz handles GET /readyz
+func (con *HealthHandler) Readyz(c echo.Context) (err error) {
+	ready, components := con.health.Ready(c.Request().Context())
+
+	// Map response fields
+	res := presenter.Health{Status: health.StatusOK, Components: map[string]*presenter.HealthComponent{}}
+	for _, v := range components {
+		hc := &presenter.HealthComponent{Status: v.Status}
+		if v.Err != nil {
+			logger.FromContext(c.Request().Context()).Warn("readiness check failed", zap.String("component", v.Name), zap.Error(v.Err))
+			hc.Error = v.Err.Error()
+		}
+		res.Components[v.Name] = hc
+	}
+	if con.health.Draining() {
+		res.Components["shutdown"] = &presenter.HealthComponent{Status: health.StatusDraining}
+	}
+	if !ready {
+		res.Status = statusUnavailable
+		return c.JSON(http.StatusServiceUnavailable, res)
+	}
+
+	// Return ok
+	return c.JSON(http.StatusOK, res)
+}
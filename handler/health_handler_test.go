@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/health"
+	"ggv2/services/mocks"
+)
+
+func TestHealthz(t *testing.T) {
+	hh := HealthHandler{health.NewChecker()}
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/healthz", nil)
+	w := httptest.NewRecorder()
+	r := echo.New()
+	r.GET("/healthz", hh.Healthz)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestNewHealthHandler(t *testing.T) {
+	dbSvc := new(mocks.DbService)
+	dbSvc.On("CheckDatabase", mock.Anything).Return(nil)
+	dbSvc.On("CheckMigrations", mock.Anything).Return(nil)
+	checker := health.NewChecker()
+	NewHealthHandler(dbSvc, checker)
+	ready, components := checker.Ready(context.Background())
+	assert.True(t, ready)
+	names := []string{}
+	for _, c := range components {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"database", "migrations", "log_rotation"}, names)
+}
+
+func TestReadyz(t *testing.T) {
+	type TestCase struct {
+		name          string
+		desc          string
+		databaseErr   error
+		migrationsErr error
+		draining      bool
+		expBody       string
+		httpCode      int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			expBody:  `{"status":"ok","components":{"database":{"status":"ok"},"migrations":{"status":"ok"}}}`,
+			httpCode: http.StatusOK,
+		},
+		{
+			name:        "Sad case",
+			desc:        "database unreachable",
+			databaseErr: fmt.Errorf("database returns error"),
+			expBody:     `{"status":"unavailable","components":{"database":{"status":"fail","error":"database returns error"},"migrations":{"status":"ok"}}}`,
+			httpCode:    http.StatusServiceUnavailable,
+		},
+		{
+			name:          "Sad case",
+			desc:          "migrations not applied",
+			migrationsErr: fmt.Errorf("missing schema: guests unique key name"),
+			expBody:       `{"status":"unavailable","components":{"database":{"status":"ok"},"migrations":{"status":"fail","error":"missing schema: guests unique key name"}}}`,
+			httpCode:      http.StatusServiceUnavailable,
+		},
+		{
+			name:     "Sad case",
+			desc:     "draining",
+			draining: true,
+			expBody:  `{"status":"unavailable","components":{"database":{"status":"ok"},"migrations":{"status":"ok"},"shutdown":{"status":"draining"}}}`,
+			httpCode: http.StatusServiceUnavailable,
+		},
+	}
+	for _, v := range testcases {
+		v := v
+		checker := health.NewChecker()
+		checker.Register("database", func(context.Context) error { return v.databaseErr })
+		checker.Register("migrations", func(context.Context) error { return v.migrationsErr })
+		if v.draining {
+			checker.SetDraining()
+		}
+		hh := HealthHandler{checker}
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/readyz", nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.GET("/readyz", hh.Readyz)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		assert.JSONEq(t, v.expBody, w.Body.String(), v.desc)
+	}
+}
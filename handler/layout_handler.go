@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"go.uber.org/zap"
+
+	"ggv2/entities"
+	"ggv2/handler/presenter"
+	"ggv2/logger"
+	"ggv2/repo"
+	"ggv2/services"
+)
+
+var (
+	errInvalidTemplateName = errors.New("template name must be between 1 and 45 characters")
+)
+
+type postApplyLayoutTemplateRequest struct {
+	Event string `json:"event" form:"event"`
+}
+
+type putLayoutTemplateRequest struct {
+	Groups []*tableGroupRequest `json:"groups"`
+}
+
+type getLayoutTemplatesResponse struct {
+	Templates []*presenter.LayoutTemplate `json:"templates"`
+}
+
+type LayoutHandler struct {
+	dbSvc services.DbService
+}
+
+func NewLayoutHandler(dbSvc services.DbService) *LayoutHandler {
+	return &LayoutHandler{
+		dbSvc: dbSvc,
+	}
+}
+
+// ListLayoutTemplates handles GET /layout_templates
+func (lh *LayoutHandler) ListLayoutTemplates(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	// Query database
+	data, err := lh.dbSvc.ListLayoutTemplates(c.Request().Context())
+	if err != nil {
+		// Error while querying database
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Map response fields
+	res := &getLayoutTemplatesResponse{Templates: []*presenter.LayoutTemplate{}}
+	for _, t := range data {
+		res.Templates = append(res.Templates, toLayoutTemplatePresenter(t))
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetLayoutTemplate handles GET /layout_templates/:name
+func (lh *LayoutHandler) GetLayoutTemplate(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	// Query database
+	data, err := lh.dbSvc.GetLayoutTemplate(c.Request().Context(), c.Param("name"))
+	if err != nil {
+		// Error while querying database
+		if errors.Is(err, repo.ErrLayoutTemplateNotFound) {
+			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		}
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, toLayoutTemplatePresenter(data))
+}
+
+// SaveLayoutTemplate handles PUT /layout_templates/:name
+func (lh *LayoutHandler) SaveLayoutTemplate(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	name := strings.TrimSpace(c.Param("name"))
+	if name == "" || len(name) > 45 {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(errInvalidTemplateName))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidTemplateName))
+	}
+	r := new(putLayoutTemplateRequest)
+	if err = c.Bind(r); err != nil {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
+	}
+	groups, err := toTableGroups(r.Groups)
+	if err != nil {
+		// Invalid table groups
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	// Query database
+	data, err := lh.dbSvc.SaveLayoutTemplate(c.Request().Context(), name, groups)
+	if err != nil {
+		// Error while querying database
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, toLayoutTemplatePresenter(data))
+}
+
+// DeleteLayoutTemplate handles DELETE /layout_templates/:name
+func (lh *LayoutHandler) DeleteLayoutTemplate(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	// Query database
+	err = lh.dbSvc.DeleteLayoutTemplate(c.Request().Context(), c.Param("name"))
+	if err != nil {
+		// Error while querying database
+		if errors.Is(err, repo.ErrLayoutTemplateNotFound) {
+			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		}
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Return ok
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ApplyLayoutTemplate handles POST /layout_templates/:name/apply
+func (lh *LayoutHandler) ApplyLayoutTemplate(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	r := new(postApplyLayoutTemplateRequest)
+	if err = c.Bind(r); err != nil {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
+	}
+	event, err := toEvent(r.Event)
+	if err != nil {
+		// Invalid event
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	// Query database
+	data, err := lh.dbSvc.ApplyLayoutTemplate(c.Request().Context(), c.Param("name"), event)
+	if err != nil {
+		// Error while querying database
+		switch {
+		case errors.Is(err, repo.ErrLayoutTemplateNotFound):
+			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		case errors.Is(err, services.ErrTablesAlreadyExist):
+			return c.JSON(http.StatusConflict, presenter.ErrResp(reqID, err))
+		}
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Map response fields
+	res := &putCreateTablesResponse{Tables: []*presenter.Table{}}
+	for _, t := range data {
+		res.Tables = append(res.Tables, toTablePresenter(t))
+	}
+	// Return ok
+	return c.JSON(http.StatusCreated, res)
+}
+
+func toLayoutTemplatePresenter(t *entities.LayoutTemplate) *presenter.LayoutTemplate {
+	tables, seats := t.Groups.Size()
+	res := &presenter.LayoutTemplate{
+		Name:   t.Name,
+		Groups: []*presenter.TableGroup{},
+		Tables: tables,
+		Seats:  seats,
+	}
+	for _, g := range t.Groups {
+		res.Groups = append(res.Groups, &presenter.TableGroup{
+			Count:    g.Count,
+			Capacity: g.Capacity,
+			Zone:     g.Zone,
+			Shape:    g.Shape,
+			Tags:     g.Tags,
+		})
+	}
+	return res
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/services"
+	"ggv2/services/mocks"
+)
+
+func TestListLayoutTemplates(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("ListLayoutTemplates", context.Background()).Return([]*entities.LayoutTemplate{
+			{Name: "wedding", Groups: entities.TableGroups{{Count: 10, Capacity: 8}, {Count: 4, Capacity: 12}}},
+		}, v.err)
+		lh := LayoutHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/layout_templates", nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.GET("/layout_templates", lh.ListLayoutTemplates)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		if v.err == nil {
+			assert.JSONEq(t, `{"templates":[{"name":"wedding","groups":[{"count":10,"capacity":8},{"count":4,"capacity":12}],"tables":14,"seats":128}]}`, w.Body.String())
+		}
+	}
+}
+
+func TestGetLayoutTemplate(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "template not found",
+			err:      repo.ErrLayoutTemplateNotFound,
+			httpCode: http.StatusNotFound,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("GetLayoutTemplate", context.Background(), "wedding").Return(&entities.LayoutTemplate{Name: "wedding"}, v.err)
+		lh := LayoutHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/layout_templates/wedding", nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.GET("/layout_templates/:name", lh.GetLayoutTemplate)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
+
+func TestSaveLayoutTemplate(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		url      string
+		body     string
+		groups   entities.TableGroups
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			url:      "http://localhost:1323/layout_templates/wedding",
+			body:     `{"groups":[{"count":10,"capacity":8},{"count":4,"capacity":12,"shape":"rectangle"}]}`,
+			groups:   entities.TableGroups{{Count: 10, Capacity: 8}, {Count: 4, Capacity: 12, Shape: "rectangle"}},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "template name too long",
+			url:      "http://localhost:1323/layout_templates/" + strings.Repeat("a", 46),
+			body:     `{"groups":[{"count":10,"capacity":8}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid body",
+			url:      "http://localhost:1323/layout_templates/wedding",
+			body:     `{"groups":1}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid groups",
+			url:      "http://localhost:1323/layout_templates/wedding",
+			body:     `{"groups":[{"count":-1,"capacity":8}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			url:      "http://localhost:1323/layout_templates/wedding",
+			body:     `{"groups":[{"count":10,"capacity":8}]}`,
+			groups:   entities.TableGroups{{Count: 10, Capacity: 8}},
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("SaveLayoutTemplate", context.Background(), "wedding", v.groups).Return(&entities.LayoutTemplate{Name: "wedding", Groups: v.groups}, v.err)
+		lh := LayoutHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodPut, v.url, strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.PUT("/layout_templates/:name", lh.SaveLayoutTemplate)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
+
+func TestDeleteLayoutTemplate(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			httpCode: http.StatusNoContent,
+		},
+		{
+			name:     "Sad case",
+			desc:     "template not found",
+			err:      repo.ErrLayoutTemplateNotFound,
+			httpCode: http.StatusNotFound,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("DeleteLayoutTemplate", context.Background(), "wedding").Return(v.err)
+		lh := LayoutHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodDelete, "http://localhost:1323/layout_templates/wedding", nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.DELETE("/layout_templates/:name", lh.DeleteLayoutTemplate)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
+
+func TestApplyLayoutTemplate(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		body     string
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			body:     `{"event":" gala "}`,
+			httpCode: http.StatusCreated,
+		},
+		{
+			name:     "Sad case",
+			desc:     "event too long",
+			body:     `{"event":"` + strings.Repeat("a", 46) + `"}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "template not found",
+			body:     `{"event":"gala"}`,
+			err:      repo.ErrLayoutTemplateNotFound,
+			httpCode: http.StatusNotFound,
+		},
+		{
+			name:     "Sad case",
+			desc:     "event already has tables",
+			body:     `{"event":"gala"}`,
+			err:      services.ErrTablesAlreadyExist,
+			httpCode: http.StatusConflict,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			body:     `{"event":"gala"}`,
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("ApplyLayoutTemplate", context.Background(), "wedding", "gala").Return([]*entities.Table{{TableID: 1, Capacity: 8}}, v.err)
+		lh := LayoutHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:1323/layout_templates/wedding/apply", strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.POST("/layout_templates/:name/apply", lh.ApplyLayoutTemplate)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"go.uber.org/zap"
+
+	"ggv2/auth"
+	"ggv2/handler/presenter"
+	"ggv2/logger"
+)
+
+const HeaderAPIKey = "X-API-Key"
+
+var (
+	errUnauthorized = errors.New("missing or invalid credentials")
+	errForbidden    = errors.New("insufficient permissions")
+)
+
+// APIKeyAuthenticator resolves a raw API key into a principal
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(context.Context, string) (*auth.Principal, error)
+}
+
+// AuthConfig configures the authentication middleware
+type AuthConfig struct {
+	// Skipper lists requests served without credentials, e.g. health checks
+	Skipper func(echo.Context) bool
+	Config  *auth.Config
+	Keys    APIKeyAuthenticator
+}
+
+// Auth authenticates every request with either an X-API-Key header or an
+// Authorization: Bearer JWT. The principal is stored in the request context.
+func Auth(cfg AuthConfig) echo.MiddlewareFunc {
+	verifier := auth.NewJWTVerifier(cfg.Config)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			if cfg.Skipper != nil && cfg.Skipper(c) {
+				return next(c)
+			}
+			reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+			req := c.Request()
+			var p *auth.Principal
+			if key := req.Header.Get(HeaderAPIKey); key != "" {
+				if cfg.Config.IsBootstrapKey(key) {
+					p = &auth.Principal{Subject: "bootstrap", Method: auth.MethodAPIKey, Role: auth.RoleAdmin}
+				} else {
+					p, err = cfg.Keys.AuthenticateAPIKey(req.Context(), key)
+					if err != nil && !errors.Is(err, auth.ErrInvalidAPIKey) {
+						// Error while querying database
+						return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+					}
+				}
+			} else if token := bearerToken(req); token != "" && verifier != nil {
+				p, err = verifier.Verify(token)
+			}
+			if p == nil {
+				// Missing, unknown or invalid credentials
+				logger.FromContext(c.Request().Context()).Warn(errUnauthorized.Error(), zap.Error(err))
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return c.JSON(http.StatusUnauthorized, presenter.ErrResp(reqID, errUnauthorized))
+			}
+			c.SetRequest(req.WithContext(auth.NewContext(req.Context(), p)))
+			return next(c)
+		}
+	}
+}
+
+// RequirePermission rejects requests whose principal's role does not grant perm.
+// It must run after Auth.
+func RequirePermission(perm auth.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p, ok := auth.FromContext(c.Request().Context())
+			if !ok || !p.Can(perm) {
+				reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+				logger.FromContext(c.Request().Context()).Warn(errForbidden.Error(), zap.String("permission", string(perm)))
+				return c.JSON(http.StatusForbidden, presenter.ErrResp(reqID, errForbidden))
+			}
+			return next(c)
+		}
+	}
+}
+
+// bearerToken returns the token of an Authorization: Bearer header.
+func bearerToken(req *http.Request) string {
+	h := req.Header.Get(echo.HeaderAuthorization)
+	if len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/auth"
+	"ggv2/services/mocks"
+)
+
+func TestAuth(t *testing.T) {
+	secret := []byte("secret")
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "planner",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}).SignedString(secret)
+	type TestCase struct {
+		name         string
+		desc         string
+		path         string
+		headers      map[string]string
+		keyPrincipal *auth.Principal
+		keyErr       error
+		expSubject   string
+		httpCode     int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "skipped route",
+			path:     "/ping",
+			httpCode: http.StatusOK,
+		},
+		{
+			name:       "Happy case",
+			desc:       "bootstrap api key",
+			path:       "/tables",
+			headers:    map[string]string{HeaderAPIKey: "bootstrap"},
+			expSubject: "bootstrap",
+			httpCode:   http.StatusOK,
+		},
+		{
+			name:         "Happy case",
+			desc:         "stored api key",
+			path:         "/tables",
+			headers:      map[string]string{HeaderAPIKey: "ggv2_key"},
+			keyPrincipal: &auth.Principal{Subject: "kiosk", Method: auth.MethodAPIKey},
+			expSubject:   "kiosk",
+			httpCode:     http.StatusOK,
+		},
+		{
+			name:       "Happy case",
+			desc:       "bearer jwt",
+			path:       "/tables",
+			headers:    map[string]string{echo.HeaderAuthorization: "Bearer " + token},
+			expSubject: "planner",
+			httpCode:   http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "no credentials",
+			path:     "/tables",
+			httpCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid api key",
+			path:     "/tables",
+			headers:  map[string]string{HeaderAPIKey: "ggv2_key"},
+			keyErr:   auth.ErrInvalidAPIKey,
+			httpCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Sad case",
+			desc:     "api key lookup returns error",
+			path:     "/tables",
+			headers:  map[string]string{HeaderAPIKey: "ggv2_key"},
+			keyErr:   fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid jwt",
+			path:     "/tables",
+			headers:  map[string]string{echo.HeaderAuthorization: "Bearer " + token + "x"},
+			httpCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Sad case",
+			desc:     "basic authorization",
+			path:     "/tables",
+			headers:  map[string]string{echo.HeaderAuthorization: "Basic dXNlcjpwYXNz"},
+			httpCode: http.StatusUnauthorized,
+		},
+	}
+	for _, v := range testcases {
+		keys := new(mocks.DbService)
+		keys.On("AuthenticateAPIKey", mock.Anything, "ggv2_key").Return(v.keyPrincipal, v.keyErr)
+		r := echo.New()
+		r.Use(Auth(AuthConfig{
+			Skipper: func(c echo.Context) bool { return c.Path() == "/ping" },
+			Config:  &auth.Config{JWTSecret: secret, BootstrapAPIKey: "bootstrap"},
+			Keys:    keys,
+		}))
+		subject := ""
+		handler := func(c echo.Context) error {
+			if p, ok := auth.FromContext(c.Request().Context()); ok {
+				subject = p.Subject
+			}
+			return c.NoContent(http.StatusOK)
+		}
+		r.GET("/ping", handler)
+		r.GET("/tables", handler)
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323"+v.path, nil)
+		for k, h := range v.headers {
+			req.Header.Set(k, h)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		assert.Equal(t, v.expSubject, subject, v.desc)
+		if v.httpCode == http.StatusUnauthorized {
+			assert.Equal(t, "Bearer", w.Header().Get(echo.HeaderWWWAuthenticate), v.desc)
+			assert.Contains(t, w.Body.String(), errUnauthorized.Error(), v.desc)
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	type TestCase struct {
+		name      string
+		desc      string
+		principal *auth.Principal
+		perm      auth.Permission
+		httpCode  int
+	}
+	testcases := []TestCase{
+		{
+			name:      "Happy case",
+			desc:      "role grants permission",
+			principal: &auth.Principal{Subject: "door", Role: auth.RoleDoorStaff},
+			perm:      auth.PermCheckIn,
+			httpCode:  http.StatusOK,
+		},
+		{
+			name:      "Sad case",
+			desc:      "role does not grant permission",
+			principal: &auth.Principal{Subject: "door", Role: auth.RoleDoorStaff},
+			perm:      auth.PermPlan,
+			httpCode:  http.StatusForbidden,
+		},
+		{
+			name:     "Sad case",
+			desc:     "no principal",
+			perm:     auth.PermRead,
+			httpCode: http.StatusForbidden,
+		},
+	}
+	for _, v := range testcases {
+		r := echo.New()
+		r.GET("/tables", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		}, RequirePermission(v.perm))
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/tables", nil)
+		if v.principal != nil {
+			req = req.WithContext(auth.NewContext(req.Context(), v.principal))
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		if v.httpCode == http.StatusForbidden {
+			assert.Contains(t, w.Body.String(), errForbidden.Error(), v.desc)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"go.uber.org/zap"
+
+	"ggv2/audit"
+	"ggv2/entities"
+	"ggv2/handler/presenter"
+	"ggv2/logger"
+	"ggv2/repo"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	// DefaultIdempotencyTTL is how long a stored response is replayed for
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultIdempotencyMaxBodySize bounds the request bodies read to fingerprint a request, in bytes
+	DefaultIdempotencyMaxBodySize = 64 << 20
+
+	maxIdempotencyKeyLength = 255
+
+	// retryableKey marks the response of a request in the echo context as not to be stored
+	retryableKey = "idempotency_retryable"
+)
+
+var (
+	errIdempotencyKeyTooLong    = errors.New("idempotency key must be at most 255 characters")
+	errIdempotencyKeyMismatch   = errors.New("idempotency key was already used with a different request")
+	errIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+	errInvalidRequestBody       = errors.New("unable to read request body")
+	errRequestBodyTooLarge      = errors.New("request body is too large")
+)
+
+// transientStatuses are the client errors that depend on the credentials, the rate limit or
+// the timing of a request rather than on the request itself, a retry may succeed
+var transientStatuses = map[int]bool{
+	http.StatusUnauthorized:    true,
+	http.StatusForbidden:       true,
+	http.StatusRequestTimeout:  true,
+	http.StatusTooManyRequests: true,
+}
+
+// IdempotencyStore claims idempotency keys and stores the responses of their requests
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(context.Context, *entities.IdempotencyRecord, time.Duration) (*entities.IdempotencyRecord, error)
+	CompleteIdempotencyKey(context.Context, *entities.IdempotencyRecord) error
+	ReleaseIdempotencyKey(context.Context, string, string) error
+}
+
+// IdempotencyConfig configures the idempotency middleware
+type IdempotencyConfig struct {
+	Store IdempotencyStore
+	// TTL is how long a response is replayed for, DefaultIdempotencyTTL when zero
+	TTL time.Duration
+	// MaxBodySize is the largest request body in bytes, DefaultIdempotencyMaxBodySize when zero
+	MaxBodySize int64
+}
+
+// Retryable marks the response of a request as caused by a concurrent update, e.g. a lock
+// conflict, rather than by the request itself. The response is not stored, a retry with the
+// same idempotency key runs the request again.
+func Retryable(c echo.Context) {
+	c.Set(retryableKey, true)
+}
+
+// Idempotency honours the Idempotency-Key header of POST and PUT requests. The first
+// response is stored and replayed unchanged for identical retries of the request, a
+// key reused with a different request is rejected. Server errors, transient client errors
+// and responses marked Retryable are not stored so that the request can be retried. It must run after Auth, keys
+// are scoped to the actor, and after authorisation and rate limiting so that replays are
+// limited too.
+func Idempotency(cfg IdempotencyConfig) echo.MiddlewareFunc {
+	ttl := cfg.TTL
+	if ttl == 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	maxBodySize := cfg.MaxBodySize
+	if maxBodySize == 0 {
+		maxBodySize = DefaultIdempotencyMaxBodySize
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			req := c.Request()
+			key := req.Header.Get(HeaderIdempotencyKey)
+			if key == "" || (req.Method != http.MethodPost && req.Method != http.MethodPut) {
+				return next(c)
+			}
+			reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+			if len(key) > maxIdempotencyKeyLength {
+				return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errIdempotencyKeyTooLong))
+			}
+			// Read one byte past the bound to tell a body of exactly maxBodySize from a larger one
+			body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxBodySize+1))
+			if err != nil {
+				logger.FromContext(c.Request().Context()).Error(errInvalidRequestBody.Error(), zap.Error(err))
+				return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequestBody))
+			}
+			if int64(len(body)) > maxBodySize {
+				return c.JSON(http.StatusRequestEntityTooLarge, presenter.ErrResp(reqID, errRequestBodyTooLarge))
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			ctx := req.Context()
+			record := &entities.IdempotencyRecord{
+				Actor:       audit.Actor(ctx),
+				Key:         key,
+				RequestHash: requestHash(req, body),
+			}
+			existing, err := cfg.Store.ReserveIdempotencyKey(ctx, record, ttl)
+			if err != nil {
+				if errors.Is(err, repo.ErrIdempotencyKeyExists) {
+					return c.JSON(http.StatusConflict, presenter.ErrResp(reqID, errIdempotencyKeyInProgress))
+				}
+				// Error while querying database
+				return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+			}
+			if existing != nil {
+				if existing.RequestHash != record.RequestHash {
+					return c.JSON(http.StatusUnprocessableEntity, presenter.ErrResp(reqID, errIdempotencyKeyMismatch))
+				}
+				if !existing.Completed() {
+					return c.JSON(http.StatusConflict, presenter.ErrResp(reqID, errIdempotencyKeyInProgress))
+				}
+				// Replay stored response
+				res := c.Response()
+				if existing.ContentType != "" {
+					res.Header().Set(echo.HeaderContentType, existing.ContentType)
+				}
+				res.Header().Set(HeaderIdempotentReplayed, "true")
+				res.WriteHeader(existing.Status)
+				_, err = res.Write(existing.Body)
+				return err
+			}
+
+			// Release the key when the request does not complete, including when next panics
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := cfg.Store.ReleaseIdempotencyKey(ctx, record.Actor, record.Key); err != nil {
+					logger.FromContext(c.Request().Context()).Error(err.Error(), zap.Error(err))
+				}
+			}()
+
+			res := c.Response()
+			resBody := new(bytes.Buffer)
+			res.Writer = &bodyDumpResponseWriter{Writer: io.MultiWriter(res.Writer, resBody), ResponseWriter: res.Writer}
+			if err = next(c); err != nil {
+				c.Error(err)
+			}
+			if res.Status >= http.StatusInternalServerError || transientStatuses[res.Status] || c.Get(retryableKey) != nil {
+				return nil
+			}
+			record.Status = res.Status
+			record.ContentType = res.Header().Get(echo.HeaderContentType)
+			record.Body = resBody.Bytes()
+			// The request already took effect, when storing its response fails the claim is
+			// kept so that retries are reported as in progress rather than run again
+			completed = true
+			if err := cfg.Store.CompleteIdempotencyKey(ctx, record); err != nil {
+				logger.FromContext(c.Request().Context()).Error(err.Error(), zap.Error(err))
+			}
+			return nil
+		}
+	}
+}
+
+// requestHash fingerprints a request by its method, URI and body.
+func requestHash(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
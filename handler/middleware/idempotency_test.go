@@ -0,0 +1,350 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/services/mocks"
+)
+
+func TestIdempotency(t *testing.T) {
+	body := `{"capacity":8}`
+	hashReq := httptest.NewRequest(http.MethodPut, "http://localhost:1323/table", nil)
+	hash := requestHash(hashReq, []byte(body))
+	type TestCase struct {
+		name        string
+		desc        string
+		method      string
+		key         string
+		maxBodySize int64
+		existing    *entities.IdempotencyRecord
+		reserveErr  error
+		handlerCode int
+		retryable   bool
+		expCalled   bool
+		expComplete bool
+		expRelease  bool
+		expReplayed bool
+		expBody     string
+		httpCode    int
+	}
+	testcases := []TestCase{
+		{
+			name:        "Happy case",
+			desc:        "no idempotency key",
+			method:      http.MethodPut,
+			handlerCode: http.StatusCreated,
+			expCalled:   true,
+			expBody:     `{"id":1}`,
+			httpCode:    http.StatusCreated,
+		},
+		{
+			name:        "Happy case",
+			desc:        "method not covered",
+			method:      http.MethodPatch,
+			key:         "key-1",
+			handlerCode: http.StatusOK,
+			expCalled:   true,
+			expBody:     `{"id":1}`,
+			httpCode:    http.StatusOK,
+		},
+		{
+			name:        "Happy case",
+			desc:        "first request stores response",
+			method:      http.MethodPut,
+			key:         "key-1",
+			handlerCode: http.StatusCreated,
+			expCalled:   true,
+			expComplete: true,
+			expBody:     `{"id":1}`,
+			httpCode:    http.StatusCreated,
+		},
+		{
+			name:        "Happy case",
+			desc:        "client errors are stored",
+			method:      http.MethodPut,
+			key:         "key-1",
+			handlerCode: http.StatusBadRequest,
+			expCalled:   true,
+			expComplete: true,
+			expBody:     `{"id":1}`,
+			httpCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "Happy case",
+			desc:        "retry replays stored response",
+			method:      http.MethodPut,
+			key:         "key-1",
+			existing:    &entities.IdempotencyRecord{RequestHash: hash, Status: http.StatusCreated, ContentType: echo.MIMEApplicationJSONCharsetUTF8, Body: []byte(`{"id":7}`)},
+			expReplayed: true,
+			expBody:     `{"id":7}`,
+			httpCode:    http.StatusCreated,
+		},
+		{
+			name:        "Sad case",
+			desc:        "unauthorized releases key",
+			method:      http.MethodPut,
+			key:         "key-1",
+			handlerCode: http.StatusUnauthorized,
+			expCalled:   true,
+			expRelease:  true,
+			expBody:     `{"id":1}`,
+			httpCode:    http.StatusUnauthorized,
+		},
+		{
+			name:        "Sad case",
+			desc:        "forbidden releases key",
+			method:      http.MethodPut,
+			key:         "key-1",
+			handlerCode: http.StatusForbidden,
+			expCalled:   true,
+			expRelease:  true,
+			expBody:     `{"id":1}`,
+			httpCode:    http.StatusForbidden,
+		},
+		{
+			name:        "Sad case",
+			desc:        "request timeout releases key",
+			method:      http.MethodPut,
+			key:         "key-1",
+			handlerCode: http.StatusRequestTimeout,
+			expCalled:   true,
+			expRelease:  true,
+			expBody:     `{"id":1}`,
+			httpCode:    http.StatusRequestTimeout,
+		},
+		{
+			name:        "Sad case",
+			desc:        "rate limited releases key",
+			method:      http.MethodPut,
+			key:         "key-1",
+			handlerCode: http.StatusTooManyRequests,
+			expCalled:   true,
+			expRelease:  true,
+			expBody:     `{"id":1}`,
+			httpCode:    http.StatusTooManyRequests,
+		},
+		{
+			name:        "Sad case",
+			desc:        "server error releases key",
+			method:      http.MethodPut,
+			key:         "key-1",
+			handlerCode: http.StatusInternalServerError,
+			expCalled:   true,
+			expRelease:  true,
+			expBody:     `{"id":1}`,
+			httpCode:    http.StatusInternalServerError,
+		},
+		{
+			name:        "Sad case",
+			desc:        "lock conflict releases key",
+			method:      http.MethodPut,
+			key:         "key-1",
+			handlerCode: http.StatusConflict,
+			retryable:   true,
+			expCalled:   true,
+			expRelease:  true,
+			expBody:     `{"id":1}`,
+			httpCode:    http.StatusConflict,
+		},
+		{
+			name:     "Sad case",
+			desc:     "key reused with a different request",
+			method:   http.MethodPut,
+			key:      "key-1",
+			existing: &entities.IdempotencyRecord{RequestHash: "other", Status: http.StatusCreated},
+			expBody:  errIdempotencyKeyMismatch.Error(),
+			httpCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Sad case",
+			desc:     "request still in progress",
+			method:   http.MethodPut,
+			key:      "key-1",
+			existing: &entities.IdempotencyRecord{RequestHash: hash},
+			expBody:  errIdempotencyKeyInProgress.Error(),
+			httpCode: http.StatusConflict,
+		},
+		{
+			name:       "Sad case",
+			desc:       "claim expired while reserving",
+			method:     http.MethodPut,
+			key:        "key-1",
+			reserveErr: repo.ErrIdempotencyKeyExists,
+			expBody:    errIdempotencyKeyInProgress.Error(),
+			httpCode:   http.StatusConflict,
+		},
+		{
+			name:       "Sad case",
+			desc:       "store returns error",
+			method:     http.MethodPut,
+			key:        "key-1",
+			reserveErr: fmt.Errorf("mock error"),
+			expBody:    "mock error",
+			httpCode:   http.StatusInternalServerError,
+		},
+		{
+			name:        "Sad case",
+			desc:        "body larger than bound",
+			method:      http.MethodPut,
+			key:         "key-1",
+			maxBodySize: int64(len(body)) - 1,
+			expBody:     errRequestBodyTooLarge.Error(),
+			httpCode:    http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "Happy case",
+			desc:        "body as large as bound",
+			method:      http.MethodPut,
+			key:         "key-1",
+			maxBodySize: int64(len(body)),
+			handlerCode: http.StatusCreated,
+			expCalled:   true,
+			expComplete: true,
+			expBody:     `{"id":1}`,
+			httpCode:    http.StatusCreated,
+		},
+		{
+			name:     "Sad case",
+			desc:     "key too long",
+			method:   http.MethodPut,
+			key:      strings.Repeat("k", 256),
+			expBody:  errIdempotencyKeyTooLong.Error(),
+			httpCode: http.StatusBadRequest,
+		},
+	}
+	for _, v := range testcases {
+		store := new(mocks.DbService)
+		store.On("ReserveIdempotencyKey", mock.Anything, mock.MatchedBy(func(r *entities.IdempotencyRecord) bool {
+			return r.Actor == "anonymous" && r.Key == "key-1" && r.RequestHash == hash
+		}), DefaultIdempotencyTTL).Return(v.existing, v.reserveErr)
+		store.On("CompleteIdempotencyKey", mock.Anything, mock.MatchedBy(func(r *entities.IdempotencyRecord) bool {
+			return r.Status == v.handlerCode && string(r.Body) == "{\"id\":1}\n" && r.ContentType == echo.MIMEApplicationJSONCharsetUTF8
+		})).Return(nil)
+		store.On("ReleaseIdempotencyKey", mock.Anything, "anonymous", "key-1").Return(nil)
+		r := echo.New()
+		r.Use(Idempotency(IdempotencyConfig{Store: store, MaxBodySize: v.maxBodySize}))
+		called := false
+		handler := func(c echo.Context) error {
+			called = true
+			b, _ := ioutil.ReadAll(c.Request().Body)
+			assert.Equal(t, body, string(b), v.desc)
+			if v.retryable {
+				Retryable(c)
+			}
+			return c.JSON(v.handlerCode, map[string]int{"id": 1})
+		}
+		r.PUT("/table", handler)
+		r.PATCH("/table", handler)
+		req := httptest.NewRequest(v.method, "http://localhost:1323/table", strings.NewReader(body))
+		if v.key != "" {
+			req.Header.Set(HeaderIdempotencyKey, v.key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		assert.Equal(t, v.expCalled, called, v.desc)
+		assert.Contains(t, w.Body.String(), v.expBody, v.desc)
+		if v.expReplayed {
+			assert.Equal(t, "true", w.Header().Get(HeaderIdempotentReplayed), v.desc)
+			assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, w.Header().Get(echo.HeaderContentType), v.desc)
+		}
+		if v.expComplete {
+			store.AssertCalled(t, "CompleteIdempotencyKey", mock.Anything, mock.Anything)
+		} else {
+			store.AssertNotCalled(t, "CompleteIdempotencyKey", mock.Anything, mock.Anything)
+		}
+		if v.expRelease {
+			store.AssertCalled(t, "ReleaseIdempotencyKey", mock.Anything, "anonymous", "key-1")
+		} else {
+			store.AssertNotCalled(t, "ReleaseIdempotencyKey", mock.Anything, "anonymous", "key-1")
+		}
+	}
+}
+
+// memIdempotencyStore keeps idempotency records in memory, keyed by actor and key
+type memIdempotencyStore struct {
+	records map[string]*entities.IdempotencyRecord
+}
+
+func (s *memIdempotencyStore) ReserveIdempotencyKey(ctx context.Context, record *entities.IdempotencyRecord, ttl time.Duration) (*entities.IdempotencyRecord, error) {
+	if existing, ok := s.records[record.Actor+"/"+record.Key]; ok {
+		return existing, nil
+	}
+	s.records[record.Actor+"/"+record.Key] = &entities.IdempotencyRecord{Actor: record.Actor, Key: record.Key, RequestHash: record.RequestHash}
+	return nil, nil
+}
+
+func (s *memIdempotencyStore) CompleteIdempotencyKey(ctx context.Context, record *entities.IdempotencyRecord) error {
+	s.records[record.Actor+"/"+record.Key] = record
+	return nil
+}
+
+func (s *memIdempotencyStore) ReleaseIdempotencyKey(ctx context.Context, actor, key string) error {
+	delete(s.records, actor+"/"+key)
+	return nil
+}
+
+func TestIdempotencyRetry(t *testing.T) {
+	type Response struct {
+		httpCode  int
+		retryable bool
+	}
+	type TestCase struct {
+		name      string
+		desc      string
+		responses []Response
+		expCalls  int
+		expCodes  []int
+	}
+	testcases := []TestCase{
+		{
+			name:      "Happy case",
+			desc:      "retry after a lock conflict runs again",
+			responses: []Response{{http.StatusConflict, true}, {http.StatusCreated, false}},
+			expCalls:  2,
+			expCodes:  []int{http.StatusConflict, http.StatusCreated},
+		},
+		{
+			name:      "Sad case",
+			desc:      "retry after a conflict of the request replays it",
+			responses: []Response{{http.StatusConflict, false}, {http.StatusCreated, false}},
+			expCalls:  1,
+			expCodes:  []int{http.StatusConflict, http.StatusConflict},
+		},
+	}
+	for _, v := range testcases {
+		store := &memIdempotencyStore{records: map[string]*entities.IdempotencyRecord{}}
+		r := echo.New()
+		r.Use(Idempotency(IdempotencyConfig{Store: store}))
+		calls := 0
+		r.PUT("/table", func(c echo.Context) error {
+			res := v.responses[calls]
+			calls++
+			if res.retryable {
+				Retryable(c)
+			}
+			return c.JSON(res.httpCode, map[string]int{"id": calls})
+		})
+		for i, code := range v.expCodes {
+			req := httptest.NewRequest(http.MethodPut, "http://localhost:1323/table", strings.NewReader(`{"capacity":8}`))
+			req.Header.Set(HeaderIdempotencyKey, "key-1")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, code, w.Code, "%s: request %d", v.desc, i+1)
+		}
+		assert.Equal(t, v.expCalls, calls, v.desc)
+	}
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces redacted values in the request log
+const redactedValue = "[REDACTED]"
+
+// LogConfig is the policy of the request log
+type LogConfig struct {
+	// Debug logs every request in full, bodies included, nothing is redacted or sampled
+	Debug bool
+	// BodyRoutes lists the routes whose response bodies are logged, as "/path" or "METHOD /path"
+	// with the path as registered, e.g. "GET /table/:id"
+	BodyRoutes []string
+	// MaxBodySize truncates logged bodies to that many bytes, zero does not truncate
+	MaxBodySize int
+	// RedactFields are the JSON keys and the path, query and form params whose values are redacted
+	RedactFields []string
+	// SampleReads is the share of successful GET requests that are logged
+	SampleReads float64
+}
+
+type logPolicy struct {
+	LogConfig
+	bodyRoutes map[string]bool
+	redact     map[string]bool
+}
+
+func newLogPolicy(cfg LogConfig) *logPolicy {
+	p := &logPolicy{
+		LogConfig:  cfg,
+		bodyRoutes: map[string]bool{},
+		redact:     map[string]bool{},
+	}
+	for _, r := range cfg.BodyRoutes {
+		p.bodyRoutes[r] = true
+	}
+	for _, f := range cfg.RedactFields {
+		p.redact[strings.ToLower(f)] = true
+	}
+	return p
+}
+
+// logBody reports whether the response body of a route is logged.
+func (p *logPolicy) logBody(method, path string) bool {
+	return p.Debug || p.bodyRoutes[path] || p.bodyRoutes[method+" "+path]
+}
+
+// sampled reports whether a request is logged, only successful reads are sampled.
+func (p *logPolicy) sampled(method string, status int) bool {
+	if p.Debug || method != http.MethodGet || status >= http.StatusBadRequest {
+		return true
+	}
+	return rand.Float64() < p.SampleReads
+}
+
+// param returns the value of a path, query or form param as it may be logged.
+func (p *logPolicy) param(name, value string) string {
+	if !p.Debug && p.redact[strings.ToLower(name)] {
+		return redactedValue
+	}
+	return value
+}
+
+// params returns query or form params as they may be logged.
+func (p *logPolicy) params(values url.Values) url.Values {
+	out := url.Values{}
+	for k, vs := range values {
+		for _, v := range vs {
+			out.Add(k, p.param(k, v))
+		}
+	}
+	return out
+}
+
+// body returns a response body as it may be logged. JSON bodies are logged with the
+// values of redacted keys replaced, other bodies are only described by their size.
+func (p *logPolicy) body(b []byte) string {
+	if p.Debug || len(b) == 0 {
+		return string(b)
+	}
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return fmt.Sprintf("[%d bytes, not JSON]", len(b))
+	}
+	redactedBody, err := json.Marshal(p.redactJSON(v))
+	if err != nil {
+		return fmt.Sprintf("[%d bytes, not JSON]", len(b))
+	}
+	if p.MaxBodySize > 0 && len(redactedBody) > p.MaxBodySize {
+		return fmt.Sprintf("%s...[truncated %d bytes]", redactedBody[:p.MaxBodySize], len(redactedBody)-p.MaxBodySize)
+	}
+	return string(redactedBody)
+}
+
+// redactJSON replaces the values of redacted keys at any depth of a decoded JSON value.
+func (p *logPolicy) redactJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if p.redact[strings.ToLower(k)] {
+				t[k] = redactedValue
+			} else {
+				t[k] = p.redactJSON(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = p.redactJSON(child)
+		}
+	}
+	return v
+}
@@ -16,47 +16,54 @@ import (
 
 	"go.uber.org/zap"
 
+	"ggv2/audit"
 	"ggv2/logger"
 )
 
+// maxRequestIDLength bounds request IDs sent by callers, IDs are stored with audit entries in
+// the request_id column of audit_log which holds 64 characters
+const maxRequestIDLength = 64
+
 type bodyDumpResponseWriter struct {
 	io.Writer
 	http.ResponseWriter
 }
 
-func Middleware() echo.MiddlewareFunc {
+// Middleware assigns every request its ID and logger, then logs the request and its
+// response as allowed by the log policy.
+func Middleware(cfg LogConfig) echo.MiddlewareFunc {
+	policy := newLogPolicy(cfg)
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) (err error) {
-
-			uuid := uuid.New().String()
-			zap.ReplaceGlobals(logger.NewLogger())
-			logger := zap.L().With(zap.String("rqId", uuid))
-			zap.ReplaceGlobals(logger)
-
-			resBody := new(bytes.Buffer)
-			mw := io.MultiWriter(c.Response().Writer, resBody)
-			writer := &bodyDumpResponseWriter{Writer: mw, ResponseWriter: c.Response().Writer}
-			c.Response().Writer = writer
-
 			req := c.Request()
 			res := c.Response()
 			start := time.Now()
 
-			// Request ID
+			// Request ID, keeping the one set by the caller or a proxy
 			rid := req.Header.Get(echo.HeaderXRequestID)
-			if rid == "" {
-				res.Header().Set(echo.HeaderXRequestID, uuid)
+			if !validRequestID(rid) {
+				rid = uuid.New().String()
+			}
+			res.Header().Set(echo.HeaderXRequestID, rid)
+			// Carry request ID down to the audit log and the logger of the request
+			log := zap.L().With(zap.String("rqId", rid))
+			ctx := logger.NewContext(audit.NewContext(req.Context(), rid), log)
+			c.SetRequest(req.WithContext(ctx))
+
+			// Metrics scrapes and probes are not logged
+			logged := req.RequestURI != "/metrics" && req.RequestURI != "/healthz" && req.RequestURI != "/readyz"
+			logBody := logged && policy.logBody(req.Method, c.Path())
+			resBody := new(bytes.Buffer)
+			if logBody {
+				mw := io.MultiWriter(res.Writer, resBody)
+				res.Writer = &bodyDumpResponseWriter{Writer: mw, ResponseWriter: res.Writer}
 			}
-
-			// CORS
-			res.Header().Set(echo.HeaderAccessControlAllowOrigin, "*")
-			res.Header().Set(echo.HeaderAccessControlAllowMethods, strings.Join([]string{echo.GET, echo.HEAD, echo.PUT, echo.PATCH, echo.POST, echo.DELETE, echo.OPTIONS}, ","))
 
 			if err = next(c); err != nil {
 				c.Error(err)
 			}
 			stop := time.Now()
-			if req.RequestURI != "/metrics" {
+			if logged && policy.sampled(req.Method, res.Status) {
 				// Log Request
 				zf := []zap.Field{}
 				qp := c.QueryParams()
@@ -64,28 +71,38 @@ func Middleware() echo.MiddlewareFunc {
 				pn := c.ParamNames()
 				pathParams := []string{}
 				zf = append(zf, zap.String("method", req.Method))
-				zf = append(zf, zap.String("uri", req.RequestURI))
-				if c.Request().URL.RawQuery != "" {
-					zf = append(zf, zap.String("QueryString", c.Request().URL.RawQuery))
+				if policy.Debug {
+					zf = append(zf, zap.String("uri", req.RequestURI))
+				} else {
+					// Path params may hold names, log the route instead
+					zf = append(zf, zap.String("route", c.Path()))
+				}
+				if query := c.Request().URL.RawQuery; query != "" {
+					if !policy.Debug {
+						query = policy.params(qp).Encode()
+					}
+					zf = append(zf, zap.String("QueryString", query))
 				}
 				if fmt.Sprintf("%v", fp) != fmt.Sprintf("%v", qp) {
-					zf = append(zf, zap.String("FormData", fmt.Sprintf("%s", fp)))
+					zf = append(zf, zap.String("FormData", fmt.Sprintf("%s", policy.params(fp))))
 				}
 				for _, v := range pn {
-					pathParams = append(pathParams, fmt.Sprintf("%s=%s", v, c.Param(v)))
+					pathParams = append(pathParams, fmt.Sprintf("%s=%s", v, policy.param(v, c.Param(v))))
 				}
 				if len(pathParams) > 0 {
 					zf = append(zf, zap.String("PathParam", strings.Join(pathParams, "&")))
 				}
-				logger.Info("RqLog:", zf...)
+				log.Info("RqLog:", zf...)
 
 				// Log Response
 				zf = []zap.Field{}
 				zf = append(zf, zap.String("status", fmt.Sprintf("%d", res.Status)))
 				zf = append(zf, zap.String("latency", stop.Sub(start).String()))
-				zf = append(zf, zap.String("rsBody", resBody.String()))
+				if logBody {
+					zf = append(zf, zap.String("rsBody", policy.body(resBody.Bytes())))
+				}
 
-				logger.Info("RsLog:", zf...)
+				log.Info("RsLog:", zf...)
 
 			}
 
@@ -109,6 +126,20 @@ func Middleware() echo.MiddlewareFunc {
 	}
 }
 
+// validRequestID reports whether a request ID sent by the caller can be kept, it is
+// logged and echoed back so it must be short and printable.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
 func (w *bodyDumpResponseWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
@@ -124,3 +155,13 @@ func (w *bodyDumpResponseWriter) Flush() {
 func (w *bodyDumpResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return w.ResponseWriter.(http.Hijacker).Hijack()
 }
+
+// Chain combines middleware into one, the first runs outermost.
+func Chain(m ...echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		for i := len(m) - 1; i >= 0; i-- {
+			next = m[i](next)
+		}
+		return next
+	}
+}
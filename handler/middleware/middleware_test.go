@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"ggv2/audit"
+	"ggv2/logger"
+)
+
+func TestMiddlewareRequestID(t *testing.T) {
+	type TestCase struct {
+		name      string
+		desc      string
+		requestID string
+		expKept   bool
+	}
+	testcases := []TestCase{
+		{
+			name:      "Happy case",
+			desc:      "request ID of the caller kept",
+			requestID: "req-1",
+			expKept:   true,
+		},
+		{
+			name:      "Happy case",
+			desc:      "request ID as long as the audit log allows kept",
+			requestID: strings.Repeat("a", 64),
+			expKept:   true,
+		},
+		{
+			name: "Happy case",
+			desc: "request ID generated",
+		},
+		{
+			name:      "Sad case",
+			desc:      "unprintable request ID replaced",
+			requestID: "req 1\n",
+		},
+		{
+			name:      "Sad case",
+			desc:      "long request ID replaced",
+			requestID: strings.Repeat("a", 65),
+		},
+	}
+	for _, v := range testcases {
+		core, logs := observer.New(zap.InfoLevel)
+		restore := zap.ReplaceGlobals(zap.New(core))
+		var auditID string
+		e := echo.New()
+		e.Use(Middleware(LogConfig{SampleReads: 1}))
+		e.GET("/tables", func(c echo.Context) error {
+			auditID = audit.RequestID(c.Request().Context())
+			logger.FromContext(c.Request().Context()).Info("handled")
+			return c.NoContent(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/tables", nil)
+		if v.requestID != "" {
+			req.Header.Set(echo.HeaderXRequestID, v.requestID)
+		}
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		restore()
+
+		rid := w.Header().Get(echo.HeaderXRequestID)
+		assert.NotEmpty(t, rid, v.desc)
+		assert.Equal(t, v.expKept, rid == v.requestID, v.desc)
+		assert.Equal(t, rid, auditID, v.desc)
+		handled := logs.FilterMessage("handled").All()
+		if assert.Len(t, handled, 1, v.desc) {
+			assert.Equal(t, rid, handled[0].ContextMap()["rqId"], v.desc)
+		}
+	}
+}
+
+func TestMiddlewareConcurrentRequests(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+	e := echo.New()
+	e.Use(Middleware(LogConfig{SampleReads: 1}))
+	e.GET("/tables", func(c echo.Context) error {
+		logger.FromContext(c.Request().Context()).Info("handled", zap.String("sent", c.Request().Header.Get(echo.HeaderXRequestID)))
+		return c.NoContent(http.StatusOK)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/tables", nil)
+			req.Header.Set(echo.HeaderXRequestID, fmt.Sprintf("req-%d", i))
+			e.ServeHTTP(httptest.NewRecorder(), req)
+		}(i)
+	}
+	wg.Wait()
+
+	// Every request logs under its own request ID
+	handled := logs.FilterMessage("handled").All()
+	assert.Len(t, handled, 50)
+	for _, entry := range handled {
+		fields := entry.ContextMap()
+		assert.Equal(t, fields["sent"], fields["rqId"])
+	}
+}
+
+func TestMiddlewareLogPolicy(t *testing.T) {
+	redact := []string{"name", "actor"}
+	type TestCase struct {
+		name      string
+		desc      string
+		cfg       LogConfig
+		method    string
+		target    string
+		status    int
+		expLogged bool
+		expReq    map[string]interface{}
+		expBody   interface{}
+	}
+	testcases := []TestCase{
+		{
+			name:      "Happy case",
+			desc:      "names redacted, body not logged",
+			cfg:       LogConfig{RedactFields: redact, SampleReads: 1},
+			method:    http.MethodGet,
+			target:    "/guests/alice?actor=bob&limit=5",
+			status:    http.StatusOK,
+			expLogged: true,
+			expReq:    map[string]interface{}{"method": "GET", "route": "/guests/:name", "PathParam": "name=[REDACTED]", "QueryString": "actor=%5BREDACTED%5D&limit=5"},
+		},
+		{
+			name:      "Happy case",
+			desc:      "body of route logged redacted and truncated",
+			cfg:       LogConfig{BodyRoutes: []string{"GET /guests/:name"}, RedactFields: redact, MaxBodySize: 30, SampleReads: 1},
+			method:    http.MethodGet,
+			target:    "/guests/alice",
+			status:    http.StatusOK,
+			expLogged: true,
+			expReq:    map[string]interface{}{"method": "GET", "route": "/guests/:name", "PathParam": "name=[REDACTED]"},
+			expBody:   `{"guests":[{"accompanying_gues...[truncated 62 bytes]`,
+		},
+		{
+			name:      "Happy case",
+			desc:      "debug logs everything",
+			cfg:       LogConfig{Debug: true, RedactFields: redact},
+			method:    http.MethodGet,
+			target:    "/guests/alice",
+			status:    http.StatusOK,
+			expLogged: true,
+			expReq:    map[string]interface{}{"method": "GET", "uri": "/guests/alice", "PathParam": "name=alice"},
+			expBody:   `{"guests":[{"accompanying_guests":2,"members":[{"name":"bob"}],"name":"alice"}]}` + "\n",
+		},
+		{
+			name:   "Happy case",
+			desc:   "successful read sampled out",
+			cfg:    LogConfig{RedactFields: redact},
+			method: http.MethodGet,
+			target: "/guests/alice",
+			status: http.StatusOK,
+		},
+		{
+			name:      "Sad case",
+			desc:      "failed read always logged",
+			cfg:       LogConfig{RedactFields: redact},
+			method:    http.MethodGet,
+			target:    "/guests/alice",
+			status:    http.StatusNotFound,
+			expLogged: true,
+			expReq:    map[string]interface{}{"method": "GET", "route": "/guests/:name", "PathParam": "name=[REDACTED]"},
+		},
+		{
+			name:      "Happy case",
+			desc:      "writes always logged",
+			cfg:       LogConfig{RedactFields: redact},
+			method:    http.MethodPut,
+			target:    "/guests/alice",
+			status:    http.StatusOK,
+			expLogged: true,
+			expReq:    map[string]interface{}{"method": "PUT", "route": "/guests/:name", "PathParam": "name=[REDACTED]"},
+		},
+	}
+	for _, v := range testcases {
+		core, logs := observer.New(zap.InfoLevel)
+		restore := zap.ReplaceGlobals(zap.New(core))
+		e := echo.New()
+		e.Use(Middleware(v.cfg))
+		h := func(c echo.Context) error {
+			return c.JSON(v.status, map[string]interface{}{
+				"guests": []map[string]interface{}{{"name": "alice", "accompanying_guests": 2, "members": []map[string]string{{"name": "bob"}}}},
+			})
+		}
+		e.GET("/guests/:name", h)
+		e.PUT("/guests/:name", h)
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(v.method, v.target, nil))
+		restore()
+
+		rq := logs.FilterMessage("RqLog:").All()
+		rs := logs.FilterMessage("RsLog:").All()
+		if !v.expLogged {
+			assert.Empty(t, rq, v.desc)
+			assert.Empty(t, rs, v.desc)
+			continue
+		}
+		if assert.Len(t, rq, 1, v.desc) && assert.Len(t, rs, 1, v.desc) {
+			fields := rq[0].ContextMap()
+			delete(fields, "rqId")
+			assert.Equal(t, v.expReq, fields, v.desc)
+			assert.Equal(t, v.expBody, rs[0].ContextMap()["rsBody"], v.desc)
+		}
+	}
+}
+
+func TestLogPolicyBody(t *testing.T) {
+	p := newLogPolicy(LogConfig{RedactFields: []string{"Name"}})
+	type TestCase struct {
+		name   string
+		desc   string
+		body   string
+		expRes string
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "nested keys redacted case insensitively, numbers kept",
+			body:   `{"table":{"id":12345678901,"NAME":"top"},"guests":[{"name":"alice"}]}`,
+			expRes: `{"guests":[{"name":"[REDACTED]"}],"table":{"NAME":"[REDACTED]","id":12345678901}}`,
+		},
+		{
+			name: "Happy case",
+			desc: "empty body",
+		},
+		{
+			name:   "Sad case",
+			desc:   "not JSON",
+			body:   "name,table\nalice,1\n",
+			expRes: "[19 bytes, not JSON]",
+		},
+	}
+	for _, v := range testcases {
+		assert.Equal(t, v.expRes, p.body([]byte(v.body)), v.desc)
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+
+	"go.uber.org/zap"
+
+	"ggv2/auth"
+	"ggv2/handler/presenter"
+	"ggv2/logger"
+)
+
+const (
+	HeaderRetryAfter = "Retry-After"
+
+	// rateLimitExpiry is how long the bucket of an idle client is kept
+	rateLimitExpiry = 3 * time.Minute
+
+	// GroupAuthenticate is the route group of every request before it is authenticated, it is
+	// limited per IP so that clients failing authentication are limited too
+	GroupAuthenticate auth.Permission = "authenticate"
+)
+
+var (
+	errRateLimited = errors.New("rate limit exceeded, please retry later")
+)
+
+// RateLimit is the token bucket of a route group, a zero Rate disables limiting
+type RateLimit struct {
+	// Rate is the number of requests per second refilled into the bucket
+	Rate float64
+	// Burst is the size of the bucket, at least 1 when limiting
+	Burst int
+}
+
+// RateLimitConfig holds the limit of each route group, route groups are the
+// permissions required by their routes
+type RateLimitConfig map[auth.Permission]RateLimit
+
+// RateLimiter limits the requests of each client per route group. Clients
+// authenticated with an API key are limited per key, other clients per IP.
+type RateLimiter struct {
+	cfg RateLimitConfig
+	// requests counts the requests allowed and limited per route group
+	requests *prometheus.CounterVec
+}
+
+// NewRateLimiter creates a rate limiter, requests is optional and must be
+// labelled by group and result.
+func NewRateLimiter(cfg RateLimitConfig, requests *prometheus.CounterVec) *RateLimiter {
+	return &RateLimiter{
+		cfg:      cfg,
+		requests: requests,
+	}
+}
+
+// Limit rejects requests beyond the limit of a route group with 429 Too Many Requests.
+// It must run after Auth.
+func (l *RateLimiter) Limit(group auth.Permission) echo.MiddlewareFunc {
+	return l.limit(group, echomw.DefaultSkipper, clientIdentifier)
+}
+
+// LimitIP rejects requests beyond the limit of GroupAuthenticate per IP. It runs ahead of Auth,
+// requests skipped are neither limited nor counted.
+func (l *RateLimiter) LimitIP(skipper echomw.Skipper) echo.MiddlewareFunc {
+	return l.limit(GroupAuthenticate, skipper, func(c echo.Context) string {
+		return "ip:" + c.RealIP()
+	})
+}
+
+func (l *RateLimiter) limit(group auth.Permission, skipper echomw.Skipper, identify func(echo.Context) string) echo.MiddlewareFunc {
+	limit := l.cfg[group]
+	if limit.Rate <= 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return next
+		}
+	}
+	store := echomw.NewRateLimiterMemoryStoreWithConfig(echomw.RateLimiterMemoryStoreConfig{
+		Rate:      rate.Limit(limit.Rate),
+		Burst:     limit.Burst,
+		ExpiresIn: rateLimitExpiry,
+	})
+	// A token is refilled every 1/rate seconds
+	retryAfter := strconv.Itoa(int(math.Ceil(1 / limit.Rate)))
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if skipper(c) {
+				return next(c)
+			}
+			client := identify(c)
+			if allow, _ := store.Allow(client); !allow {
+				l.count(group, "limited")
+				logger.FromContext(c.Request().Context()).Warn(errRateLimited.Error(), zap.String("group", string(group)), zap.String("client", client))
+				reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+				c.Response().Header().Set(HeaderRetryAfter, retryAfter)
+				return c.JSON(http.StatusTooManyRequests, presenter.ErrResp(reqID, errRateLimited))
+			}
+			l.count(group, "allowed")
+			return next(c)
+		}
+	}
+}
+
+func (l *RateLimiter) count(group auth.Permission, result string) {
+	if l.requests == nil {
+		return
+	}
+	l.requests.WithLabelValues(string(group), result).Inc()
+}
+
+// clientIdentifier keys requests by API key, or by IP when no API key was used. The IP is
+// taken by the IP extractor of the echo instance, which must only trust headers set by proxies.
+func clientIdentifier(c echo.Context) string {
+	if p, ok := auth.FromContext(c.Request().Context()); ok && p.Method == auth.MethodAPIKey {
+		return "api_key:" + p.Subject
+	}
+	return "ip:" + c.RealIP()
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"ggv2/auth"
+)
+
+func TestRateLimiter(t *testing.T) {
+	type Request struct {
+		principal *auth.Principal
+		ip        string
+		httpCode  int
+	}
+	type TestCase struct {
+		name       string
+		desc       string
+		limit      RateLimit
+		requests   []Request
+		expAllowed float64
+		expLimited float64
+	}
+	kiosk := &auth.Principal{Subject: "kiosk", Method: auth.MethodAPIKey}
+	testcases := []TestCase{
+		{
+			name:  "Happy case",
+			desc:  "within burst",
+			limit: RateLimit{Rate: 0.001, Burst: 2},
+			requests: []Request{
+				{ip: "10.0.0.1", httpCode: http.StatusOK},
+				{ip: "10.0.0.1", httpCode: http.StatusOK},
+			},
+			expAllowed: 2,
+		},
+		{
+			name:  "Happy case",
+			desc:  "limiting disabled",
+			limit: RateLimit{},
+			requests: []Request{
+				{ip: "10.0.0.1", httpCode: http.StatusOK},
+				{ip: "10.0.0.1", httpCode: http.StatusOK},
+			},
+		},
+		{
+			name:  "Happy case",
+			desc:  "clients limited separately",
+			limit: RateLimit{Rate: 0.001, Burst: 1},
+			requests: []Request{
+				{ip: "10.0.0.1", httpCode: http.StatusOK},
+				{ip: "10.0.0.2", httpCode: http.StatusOK},
+				{ip: "10.0.0.1", principal: kiosk, httpCode: http.StatusOK},
+			},
+			expAllowed: 3,
+		},
+		{
+			name:  "Sad case",
+			desc:  "ip over limit",
+			limit: RateLimit{Rate: 0.001, Burst: 1},
+			requests: []Request{
+				{ip: "10.0.0.1", httpCode: http.StatusOK},
+				{ip: "10.0.0.1", httpCode: http.StatusTooManyRequests},
+			},
+			expAllowed: 1,
+			expLimited: 1,
+		},
+		{
+			name:  "Sad case",
+			desc:  "api key over limit from another ip",
+			limit: RateLimit{Rate: 0.001, Burst: 1},
+			requests: []Request{
+				{ip: "10.0.0.1", principal: kiosk, httpCode: http.StatusOK},
+				{ip: "10.0.0.2", principal: kiosk, httpCode: http.StatusTooManyRequests},
+			},
+			expAllowed: 1,
+			expLimited: 1,
+		},
+	}
+	for _, v := range testcases {
+		requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests"}, []string{"group", "result"})
+		limiter := NewRateLimiter(RateLimitConfig{auth.PermCheckIn: v.limit}, requests)
+		r := echo.New()
+		r.PUT("/guests/:name", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		}, limiter.Limit(auth.PermCheckIn))
+		for _, rq := range v.requests {
+			req := httptest.NewRequest(http.MethodPut, "http://localhost:1323/guests/dummy", nil)
+			req.Header.Set(echo.HeaderXRealIP, rq.ip)
+			if rq.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), rq.principal))
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, rq.httpCode, w.Code, v.desc)
+			if rq.httpCode == http.StatusTooManyRequests {
+				assert.Equal(t, "1000", w.Header().Get(HeaderRetryAfter), v.desc)
+				assert.Contains(t, w.Body.String(), errRateLimited.Error(), v.desc)
+			}
+		}
+		assert.Equal(t, v.expAllowed, testutil.ToFloat64(requests.WithLabelValues("check_in", "allowed")), v.desc)
+		assert.Equal(t, v.expLimited, testutil.ToFloat64(requests.WithLabelValues("check_in", "limited")), v.desc)
+	}
+}
+
+func TestRateLimiterIP(t *testing.T) {
+	type Request struct {
+		path     string
+		ip       string
+		httpCode int
+	}
+	type TestCase struct {
+		name       string
+		desc       string
+		requests   []Request
+		expAllowed float64
+		expLimited float64
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "clients limited separately",
+			requests: []Request{
+				{path: "/tables", ip: "10.0.0.1", httpCode: http.StatusUnauthorized},
+				{path: "/tables", ip: "10.0.0.2", httpCode: http.StatusUnauthorized},
+			},
+			expAllowed: 2,
+		},
+		{
+			name: "Happy case",
+			desc: "skipped requests neither limited nor counted",
+			requests: []Request{
+				{path: "/healthz", ip: "10.0.0.1", httpCode: http.StatusOK},
+				{path: "/healthz", ip: "10.0.0.1", httpCode: http.StatusOK},
+			},
+		},
+		{
+			name: "Sad case",
+			desc: "requests failing authentication limited",
+			requests: []Request{
+				{path: "/tables", ip: "10.0.0.1", httpCode: http.StatusUnauthorized},
+				{path: "/tables", ip: "10.0.0.1", httpCode: http.StatusTooManyRequests},
+			},
+			expAllowed: 1,
+			expLimited: 1,
+		},
+	}
+	for _, v := range testcases {
+		requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests"}, []string{"group", "result"})
+		limiter := NewRateLimiter(RateLimitConfig{GroupAuthenticate: {Rate: 0.001, Burst: 1}}, requests)
+		r := echo.New()
+		r.Use(limiter.LimitIP(func(c echo.Context) bool {
+			return c.Path() == "/healthz"
+		}))
+		r.GET("/tables", func(c echo.Context) error {
+			return c.NoContent(http.StatusUnauthorized)
+		})
+		r.GET("/healthz", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+		for _, rq := range v.requests {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:1323"+rq.path, nil)
+			req.Header.Set(echo.HeaderXRealIP, rq.ip)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, rq.httpCode, w.Code, v.desc)
+		}
+		assert.Equal(t, v.expAllowed, testutil.ToFloat64(requests.WithLabelValues("authenticate", "allowed")), v.desc)
+		assert.Equal(t, v.expLimited, testutil.ToFloat64(requests.WithLabelValues("authenticate", "limited")), v.desc)
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	type TestCase struct {
+		name        string
+		desc        string
+		path        string
+		traceparent string
+		handlerErr  error
+		expSpan     bool
+		expName     string
+		expStatus   codes.Code
+		expCode     int64
+	}
+	testcases := []TestCase{
+		{
+			name:      "Happy case",
+			desc:      "new trace",
+			path:      "/guests/alice?accompanying_guests=2",
+			expSpan:   true,
+			expName:   "GET /guests/:name",
+			expStatus: codes.Unset,
+			expCode:   http.StatusOK,
+		},
+		{
+			name:        "Happy case",
+			desc:        "trace of the caller continued",
+			path:        "/guests/alice?accompanying_guests=2",
+			traceparent: parent,
+			expSpan:     true,
+			expName:     "GET /guests/:name",
+			expStatus:   codes.Unset,
+			expCode:     http.StatusOK,
+		},
+		{
+			name: "Happy case",
+			desc: "health checks not traced",
+			path: "/ping",
+		},
+		{
+			name:       "Sad case",
+			desc:       "handler error recorded",
+			path:       "/guests/alice?accompanying_guests=2",
+			handlerErr: echo.NewHTTPError(http.StatusInternalServerError),
+			expSpan:    true,
+			expName:    "GET /guests/:name",
+			expStatus:  codes.Error,
+			expCode:    http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		before := len(recorder.Ended())
+		var handlerSpan trace.SpanContext
+		e := echo.New()
+		e.Use(Tracing(func(c echo.Context) bool { return c.Path() == "/ping" }))
+		h := func(c echo.Context) error {
+			handlerSpan = trace.SpanContextFromContext(c.Request().Context())
+			if v.handlerErr != nil {
+				return v.handlerErr
+			}
+			return c.NoContent(http.StatusOK)
+		}
+		e.GET("/guests/:name", h)
+		e.GET("/ping", h)
+		req := httptest.NewRequest(http.MethodGet, v.path, nil)
+		if v.traceparent != "" {
+			req.Header.Set("traceparent", v.traceparent)
+		}
+		e.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()[before:]
+		if !v.expSpan {
+			assert.Empty(t, spans, v.desc)
+			assert.False(t, handlerSpan.IsValid(), v.desc)
+			continue
+		}
+		if assert.Len(t, spans, 1, v.desc) {
+			span := spans[0]
+			assert.Equal(t, v.expName, span.Name(), v.desc)
+			assert.Equal(t, trace.SpanKindServer, span.SpanKind(), v.desc)
+			assert.Equal(t, v.expStatus, span.Status().Code, v.desc)
+			// Only the route, method and status are recorded, never the URI with the guest name
+			attrs := map[attribute.Key]attribute.Value{}
+			for _, a := range span.Attributes() {
+				attrs[a.Key] = a.Value
+				assert.NotContains(t, a.Value.Emit(), "alice", v.desc)
+			}
+			assert.Equal(t, "/guests/:name", attrs[semconv.HTTPRouteKey].AsString(), v.desc)
+			assert.Equal(t, http.MethodGet, attrs[semconv.HTTPMethodKey].AsString(), v.desc)
+			assert.Equal(t, v.expCode, attrs[semconv.HTTPStatusCodeKey].AsInt64(), v.desc)
+			assert.NotContains(t, attrs, semconv.HTTPTargetKey, v.desc)
+			assert.NotContains(t, attrs, semconv.HTTPURLKey, v.desc)
+			// Handlers run within the span
+			assert.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID(), v.desc)
+			if v.traceparent != "" {
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String(), v.desc)
+				assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String(), v.desc)
+			} else {
+				assert.False(t, span.Parent().IsValid(), v.desc)
+			}
+		}
+	}
+}
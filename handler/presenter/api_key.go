@@ -0,0 +1,12 @@
+package presenter
+
+// APIKey represents an API key, the key itself is only returned when created
+type APIKey struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Prefix    string `json:"prefix"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at,omitempty"`
+	Revoked   bool   `json:"revoked"`
+	Key       string `json:"key,omitempty"`
+}
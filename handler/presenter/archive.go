@@ -0,0 +1,11 @@
+package presenter
+
+// Archive represents the data removed when tables were emptied
+type Archive struct {
+	ID        int64  `json:"id"`
+	Event     string `json:"event,omitempty"`
+	Tables    int64  `json:"tables"`
+	Guests    int64  `json:"guests"`
+	CreatedAt string `json:"created_at,omitempty"`
+	Restored  bool   `json:"restored"`
+}
@@ -0,0 +1,15 @@
+package presenter
+
+import "encoding/json"
+
+// AuditEntry represents a mutation recorded in the audit log
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Actor     string          `json:"actor"`
+	RequestID string          `json:"request_id,omitempty"`
+	Operation string          `json:"operation"`
+	Target    string          `json:"target,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt string          `json:"created_at"`
+}
@@ -0,0 +1,23 @@
+package presenter
+
+// CateringSummary represents aggregated catering requirements of a group of diners
+type CateringSummary struct {
+	Headcount int64            `json:"headcount"`
+	Diets     map[string]int64 `json:"diets"`
+	Allergens map[string]int64 `json:"allergens"`
+	Meals     map[string]int64 `json:"meals"`
+	Notes     []string         `json:"notes"`
+}
+
+// TableCatering represents catering requirements of a single table
+type TableCatering struct {
+	TableID int64            `json:"tableid"`
+	RSVP    *CateringSummary `json:"rsvp"`
+	Arrived *CateringSummary `json:"arrived"`
+}
+
+// EventCatering represents catering requirements of the whole event
+type EventCatering struct {
+	RSVP    *CateringSummary `json:"rsvp"`
+	Arrived *CateringSummary `json:"arrived"`
+}
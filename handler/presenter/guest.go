@@ -7,4 +7,9 @@ type Guest struct {
 	TableID            int64  `json:"tableid,omitempty"`
 	AccompanyingGuests int64  `json:"accompanying_guests"`
 	ArrivalTime        string `json:"arrived_time,omitempty"`
+
+	DietaryTags  []string `json:"dietary_tags,omitempty"`
+	Allergens    []string `json:"allergens,omitempty"`
+	DietaryNotes string   `json:"dietary_notes,omitempty"`
+	MealChoice   string   `json:"meal_choice,omitempty"`
 }
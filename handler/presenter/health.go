@@ -0,0 +1,13 @@
+package presenter
+
+// Health represents the readiness of the service and of each of its components
+type Health struct {
+	Status     string                      `json:"status"`
+	Components map[string]*HealthComponent `json:"components,omitempty"`
+}
+
+// HealthComponent represents the readiness of a single component
+type HealthComponent struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,18 @@
+package presenter
+
+// TableGroup represents a number of identical tables
+type TableGroup struct {
+	Count    int64    `json:"count"`
+	Capacity int64    `json:"capacity"`
+	Zone     string   `json:"zone,omitempty"`
+	Shape    string   `json:"shape,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// LayoutTemplate represents a named, reusable table layout
+type LayoutTemplate struct {
+	Name   string        `json:"name"`
+	Groups []*TableGroup `json:"groups"`
+	Tables int64         `json:"tables"`
+	Seats  int64         `json:"seats"`
+}
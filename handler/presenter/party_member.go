@@ -0,0 +1,13 @@
+package presenter
+
+// PartyMember represents a named member of a Guest's party
+type PartyMember struct {
+	Name     string `json:"name"`
+	AgeGroup string `json:"age_group,omitempty"`
+	Arrived  bool   `json:"arrived"`
+
+	DietaryTags  []string `json:"dietary_tags,omitempty"`
+	Allergens    []string `json:"allergens,omitempty"`
+	DietaryNotes string   `json:"dietary_notes,omitempty"`
+	MealChoice   string   `json:"meal_choice,omitempty"`
+}
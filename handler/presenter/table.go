@@ -2,6 +2,15 @@ package presenter
 
 // Table represents a Table object
 type Table struct {
-	TableID  int64 `json:"id,omitempty"`
-	Capacity int64 `json:"capacity,omitempty"`
+	TableID  int64    `json:"id,omitempty"`
+	Capacity int64    `json:"capacity,omitempty"`
+	Event    string   `json:"event,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Zone     string   `json:"zone,omitempty"`
+	Shape    string   `json:"shape,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Label    string   `json:"label,omitempty"`
+	X        int64    `json:"x"`
+	Y        int64    `json:"y"`
+	Rotation int64    `json:"rotation"`
 }
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"ggv2/entities"
+	"ggv2/handler/presenter"
+	"ggv2/logger"
+	"ggv2/services"
+)
+
+type getCateringReportResponse struct {
+	Tables []*presenter.TableCatering `json:"tables"`
+	Event  *presenter.EventCatering   `json:"event"`
+}
+
+type ReportHandler struct {
+	dbSvc services.DbService
+}
+
+func NewReportHandler(dbSvc services.DbService) *ReportHandler {
+	return &ReportHandler{
+		dbSvc: dbSvc,
+	}
+}
+
+// CateringReport handles GET /reports/catering
+func (rh *ReportHandler) CateringReport(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	event, err := toEvent(c.QueryParam("event"))
+	if err != nil {
+		// Invalid event
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	// Query database
+	data, err := rh.dbSvc.CateringReport(c.Request().Context(), event)
+	if err != nil {
+		// Error while querying database
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Map response fields
+	res := getCateringReportResponse{
+		Tables: []*presenter.TableCatering{},
+		Event: &presenter.EventCatering{
+			RSVP:    toCateringSummary(data.RSVP),
+			Arrived: toCateringSummary(data.Arrived),
+		},
+	}
+	for _, t := range data.Tables {
+		res.Tables = append(res.Tables, &presenter.TableCatering{
+			TableID: t.TableID,
+			RSVP:    toCateringSummary(t.RSVP),
+			Arrived: toCateringSummary(t.Arrived),
+		})
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, res)
+}
+
+func toCateringSummary(s *entities.CateringSummary) *presenter.CateringSummary {
+	return &presenter.CateringSummary{
+		Headcount: s.Headcount,
+		Diets:     s.Diets,
+		Allergens: s.Allergens,
+		Meals:     s.Meals,
+		Notes:     s.Notes,
+	}
+}
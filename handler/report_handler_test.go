@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"ggv2/entities"
+	"ggv2/services/mocks"
+)
+
+func TestCateringReport(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		event    string
+		err      error
+		expRes   *entities.CateringReport
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "All ok",
+			expRes: &entities.CateringReport{
+				Tables: []*entities.TableCatering{
+					{
+						TableID: 1,
+						RSVP:    entities.NewCateringSummary(),
+						Arrived: entities.NewCateringSummary(),
+					},
+				},
+				RSVP:    entities.NewCateringSummary(),
+				Arrived: entities.NewCateringSummary(),
+			},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:  "Happy case",
+			desc:  "single event",
+			event: "gala",
+			expRes: &entities.CateringReport{
+				Tables:  []*entities.TableCatering{},
+				RSVP:    entities.NewCateringSummary(),
+				Arrived: entities.NewCateringSummary(),
+			},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "event too long",
+			event:    strings.Repeat("a", 46),
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("CateringReport", context.Background(), v.event).Return(v.expRes, v.err)
+		rh := ReportHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/reports/catering?event="+v.event, nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.GET("/reports/catering", rh.CateringReport)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		if v.httpCode == http.StatusBadRequest {
+			dbSvc.AssertNotCalled(t, "CateringReport", context.Background(), v.event)
+		}
+	}
+}
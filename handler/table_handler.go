@@ -1,22 +1,88 @@
 package handler
 
 import (
+	"bytes"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
-	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
 
 	"go.uber.org/zap"
 
+	"ggv2/entities"
+	"ggv2/floorplan"
 	"ggv2/handler/presenter"
+	"ggv2/logger"
 	"ggv2/repo"
 	"ggv2/services"
 )
 
+var (
+	errTableNameTooLong = errors.New("table name cannot be longer than 45 characters")
+	errZoneTooLong      = errors.New("zone cannot be longer than 45 characters")
+	errEventTooLong     = errors.New("event cannot be longer than 45 characters")
+	errInvalidShape     = errors.New("invalid table shape")
+	errInvalidTag       = errors.New("tags cannot be empty or contain commas")
+	errTagsTooLong      = errors.New("tags cannot be longer than 255 characters")
+	errInvalidPosition  = errors.New("table position cannot be negative")
+
+	errReassignToSameTable = errors.New("cannot reassign guests to the table being deleted")
+
+	errNoTableGroups         = errors.New("at least one group of tables is required")
+	errTableCountLessThanOne = errors.New("count cannot be less than 1")
+	errTooManyTables         = errors.New("cannot create more than 500 tables at once")
+)
+
+// maxBulkTables limits the number of tables created by a single request
+const maxBulkTables = 500
+
 type createTableRequest struct {
-	Capacity int64 `json:"capacity" form:"capacity"`
+	Capacity int64    `json:"capacity" form:"capacity"`
+	Event    string   `json:"event" form:"event"`
+	Name     string   `json:"name" form:"name"`
+	Zone     string   `json:"zone" form:"zone"`
+	Shape    string   `json:"shape" form:"shape"`
+	Tags     []string `json:"tags" form:"tags"`
+	X        int64    `json:"x" form:"x"`
+	Y        int64    `json:"y" form:"y"`
+	Rotation int64    `json:"rotation" form:"rotation"`
+}
+
+type patchTableRequest struct {
+	Capacity *int64    `json:"capacity"`
+	Event    *string   `json:"event"`
+	Name     *string   `json:"name"`
+	Zone     *string   `json:"zone"`
+	Shape    *string   `json:"shape"`
+	Tags     *[]string `json:"tags"`
+	X        *int64    `json:"x"`
+	Y        *int64    `json:"y"`
+	Rotation *int64    `json:"rotation"`
 }
+
+type tableGroupRequest struct {
+	Count    int64    `json:"count"`
+	Capacity int64    `json:"capacity"`
+	Zone     string   `json:"zone"`
+	Shape    string   `json:"shape"`
+	Tags     []string `json:"tags"`
+}
+
+type putCreateTablesRequest struct {
+	Event  string               `json:"event"`
+	Groups []*tableGroupRequest `json:"groups"`
+}
+
+type putCreateTablesResponse struct {
+	Tables []*presenter.Table `json:"tables"`
+}
+
+type deleteTableRequest struct {
+	ReassignTo int64 `query:"reassign_to" form:"reassign_to"`
+}
+
 type putCreateTableResponse struct {
 	Table *presenter.Table `json:"table"`
 }
@@ -29,10 +95,7 @@ type TableHandler struct {
 	dbSvc services.DbService
 }
 
-func NewTableHandler(conn *sqlx.DB) *TableHandler {
-	dbRepo := repo.NewDbRepo(conn)
-	dbSvc := services.NewDbService(dbRepo)
-
+func NewTableHandler(dbSvc services.DbService) *TableHandler {
 	return &TableHandler{
 		dbSvc: dbSvc,
 	}
@@ -45,20 +108,20 @@ func (th *TableHandler) GetTable(c echo.Context) (err error) {
 	tableId, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
 		// Invalid request parameter
-		zap.L().Error(errInvalidRequest.Error(), zap.Error(err))
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
 		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
 	}
 	// Query database
 	res, err := th.dbSvc.GetTable(c.Request().Context(), tableId)
 	if err != nil {
 		// Error while querying database
+		if errors.Is(err, repo.ErrTableNotFound) {
+			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		}
 		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
 	}
 	// Return ok
-	return c.JSON(http.StatusOK, &presenter.Table{
-		// TableID:  res.TableID,
-		Capacity: res.Capacity,
-	})
+	return c.JSON(http.StatusOK, toTablePresenter(res))
 }
 
 // GetTables handles GET /tables
@@ -66,11 +129,18 @@ func (th *TableHandler) GetTables(c echo.Context) (err error) {
 	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
 	limit, offset, err := getLimitAndOffest(c)
 	if err != nil {
-		zap.L().Error(errInvalidRequest.Error(), zap.Error(err))
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
 		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
 	}
+	filter := &entities.TableFilter{
+		Event: c.QueryParam("event"),
+		Name:  c.QueryParam("name"),
+		Zone:  c.QueryParam("zone"),
+		Shape: c.QueryParam("shape"),
+		Tag:   c.QueryParam("tag"),
+	}
 	// Query database
-	data, err := th.dbSvc.ListTables(c.Request().Context(), limit, offset)
+	data, err := th.dbSvc.ListTables(c.Request().Context(), filter, limit, offset)
 
 	if err != nil {
 		// Error while querying database
@@ -79,10 +149,7 @@ func (th *TableHandler) GetTables(c echo.Context) (err error) {
 	// Map response fields
 	var tables []*presenter.Table
 	for _, d := range data {
-		tables = append(tables, &presenter.Table{
-			TableID:  d.TableID,
-			Capacity: d.Capacity,
-		})
+		tables = append(tables, toTablePresenter(d))
 	}
 
 	// Return ok
@@ -95,41 +162,174 @@ func (th *TableHandler) CreateTable(c echo.Context) (err error) {
 	r := new(createTableRequest)
 	if err = c.Bind(r); err != nil {
 		// Invalid request parameter
-		zap.L().Error(errInvalidRequest.Error(), zap.Error(err))
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
 		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
 	}
 	if r.Capacity < 1 {
 		// Invalid request parameter
-		zap.L().Error(errInvalidRequest.Error(), zap.Error(errCapacityLessThanOne))
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(errCapacityLessThanOne))
 		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errCapacityLessThanOne))
 	}
+	patch, err := toTablePatch(&patchTableRequest{
+		Event:    &r.Event,
+		Name:     &r.Name,
+		Zone:     &r.Zone,
+		Shape:    &r.Shape,
+		Tags:     &r.Tags,
+		X:        &r.X,
+		Y:        &r.Y,
+		Rotation: &r.Rotation,
+	})
+	if err != nil {
+		// Invalid table metadata
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
 	res := &putCreateTableResponse{}
 	// Query database
-	data, err := th.dbSvc.CreateTable(c.Request().Context(), r.Capacity)
+	data, err := th.dbSvc.CreateTable(c.Request().Context(), r.Capacity, patch)
+	if err != nil {
+		// Error while querying database
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Map response fields
+	res.Table = toTablePresenter(data)
+	// Return ok
+	return c.JSON(http.StatusCreated, res)
+}
+
+// CreateTables handles PUT /tables
+func (th *TableHandler) CreateTables(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	r := new(putCreateTablesRequest)
+	if err = c.Bind(r); err != nil {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
+	}
+	groups, err := toTableGroups(r.Groups)
+	if err != nil {
+		// Invalid table groups
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	event, err := toEvent(r.Event)
+	if err != nil {
+		// Invalid event
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	// Query database
+	data, err := th.dbSvc.CreateTables(c.Request().Context(), event, groups)
 	if err != nil {
 		// Error while querying database
 		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
 	}
 	// Map response fields
-	res.Table = &presenter.Table{
-		TableID:  data.TableID,
-		Capacity: data.Capacity,
+	res := &putCreateTablesResponse{Tables: []*presenter.Table{}}
+	for _, t := range data {
+		res.Tables = append(res.Tables, toTablePresenter(t))
 	}
 	// Return ok
 	return c.JSON(http.StatusCreated, res)
 }
 
-// Init handles GET /init
-func (th *TableHandler) EmptyTables(c echo.Context) (err error) {
+// UpdateTable handles PATCH /table/:id
+func (th *TableHandler) UpdateTable(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	tableId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
+	}
+	r := new(patchTableRequest)
+	if err = c.Bind(r); err != nil {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
+	}
+	patch, err := toTablePatch(r)
+	if err != nil {
+		// Invalid table metadata
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
+	// Query database
+	data, err := th.dbSvc.UpdateTable(c.Request().Context(), tableId, patch)
+	if err != nil {
+		// Error while querying database
+		switch {
+		case errors.Is(err, repo.ErrTableNotFound):
+			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		case errors.Is(err, repo.ErrFailedOptimisticLock), errors.Is(err, repo.ErrLockConflict), errors.Is(err, services.ErrCapacityBelowGuests):
+			return conflict(c, reqID, err)
+		}
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Return ok
+	return c.JSON(http.StatusOK, toTablePresenter(data))
+}
+
+// DeleteTable handles DELETE /table/:id
+func (th *TableHandler) DeleteTable(c echo.Context) (err error) {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	tableId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
+	}
+	r := new(deleteTableRequest)
+	if err = c.Bind(r); err != nil || r.ReassignTo < 0 {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errInvalidRequest))
+	}
+	if r.ReassignTo == tableId {
+		// Invalid request parameter
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(errReassignToSameTable))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, errReassignToSameTable))
+	}
+	// Query database
+	err = th.dbSvc.DeleteTable(c.Request().Context(), tableId, r.ReassignTo)
+	if err != nil {
+		// Error while querying database
+		switch {
+		case errors.Is(err, repo.ErrTableNotFound), errors.Is(err, services.ErrReassignTableNotFound):
+			return c.JSON(http.StatusNotFound, presenter.ErrResp(reqID, err))
+		case errors.Is(err, services.ErrTableNotEmpty), errors.Is(err, services.ErrTableIsFull), errors.Is(err, repo.ErrFailedOptimisticLock), errors.Is(err, repo.ErrLockConflict):
+			return conflict(c, reqID, err)
+		}
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
+	// Return ok
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetFloorPlan handles GET /floor_plan
+func (th *TableHandler) GetFloorPlan(c echo.Context) (err error) {
 	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	event, err := toEvent(c.QueryParam("event"))
+	if err != nil {
+		// Invalid event
+		logger.FromContext(c.Request().Context()).Error(errInvalidRequest.Error(), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, presenter.ErrResp(reqID, err))
+	}
 	// Query database
-	err = th.dbSvc.EmptyTables(c.Request().Context())
+	tables, err := th.dbSvc.FloorPlan(c.Request().Context(), event)
 	if err != nil {
 		// Error while querying database
 		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
 	}
+	// Render plan
+	buf := new(bytes.Buffer)
+	if err = floorplan.Render(buf, tables); err != nil {
+		logger.FromContext(c.Request().Context()).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, presenter.ErrResp(reqID, err))
+	}
 	// Return ok
-	return c.JSON(http.StatusOK, "Tables emptied!")
+	return c.Blob(http.StatusOK, "image/svg+xml", buf.Bytes())
 }
 
 // GetEmptySeatsCount handles GET /seats_empty
@@ -147,3 +347,136 @@ func (th *TableHandler) GetEmptySeatsCount(c echo.Context) (err error) {
 	// Return ok
 	return c.JSON(http.StatusOK, res)
 }
+
+// toTablePatch validates and normalises table metadata of a request, nil fields are left unchanged.
+func toTablePatch(r *patchTableRequest) (*entities.TablePatch, error) {
+	if r.Capacity != nil && *r.Capacity < 1 {
+		return nil, errCapacityLessThanOne
+	}
+	patch := &entities.TablePatch{
+		Capacity: r.Capacity,
+		X:        r.X,
+		Y:        r.Y,
+	}
+	if (r.X != nil && *r.X < 0) || (r.Y != nil && *r.Y < 0) {
+		return nil, errInvalidPosition
+	}
+	if r.Rotation != nil {
+		// Normalise rotation to [0, 360)
+		rot := (*r.Rotation%360 + 360) % 360
+		patch.Rotation = &rot
+	}
+	if r.Event != nil {
+		e, err := toEvent(*r.Event)
+		if err != nil {
+			return nil, err
+		}
+		patch.Event = &e
+	}
+	if r.Name != nil {
+		n := strings.TrimSpace(*r.Name)
+		if len(n) > 45 {
+			return nil, errTableNameTooLong
+		}
+		patch.Name = &n
+	}
+	if r.Zone != nil {
+		z := strings.TrimSpace(*r.Zone)
+		if len(z) > 45 {
+			return nil, errZoneTooLong
+		}
+		patch.Zone = &z
+	}
+	if r.Shape != nil {
+		sh := strings.ToLower(strings.TrimSpace(*r.Shape))
+		if !entities.IsValidShape(sh) {
+			return nil, errInvalidShape
+		}
+		patch.Shape = &sh
+	}
+	if r.Tags != nil {
+		t := entities.Tags{}
+		for _, tag := range *r.Tags {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || strings.Contains(tag, ",") {
+				return nil, errInvalidTag
+			}
+			if !t.Contains(tag) {
+				t = append(t, tag)
+			}
+		}
+		if len(strings.Join(t, ",")) > 255 {
+			return nil, errTagsTooLong
+		}
+		patch.Tags = &t
+	}
+	return patch, nil
+}
+
+// toEvent validates and normalises the event a table belongs to, an empty event is the default event.
+func toEvent(event string) (string, error) {
+	event = strings.TrimSpace(event)
+	if len(event) > 45 {
+		return "", errEventTooLong
+	}
+	return event, nil
+}
+
+// toTableGroups validates and normalises groups of tables to be created together.
+func toTableGroups(r []*tableGroupRequest) (entities.TableGroups, error) {
+	if len(r) == 0 {
+		return nil, errNoTableGroups
+	}
+	groups := entities.TableGroups{}
+	total := int64(0)
+	for _, g := range r {
+		if g == nil {
+			return nil, errNoTableGroups
+		}
+		if g.Count < 1 {
+			return nil, errTableCountLessThanOne
+		}
+		if g.Capacity < 1 {
+			return nil, errCapacityLessThanOne
+		}
+		total += g.Count
+		if total > maxBulkTables {
+			return nil, errTooManyTables
+		}
+		tags := g.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		patch, err := toTablePatch(&patchTableRequest{Zone: &g.Zone, Shape: &g.Shape, Tags: &tags})
+		if err != nil {
+			return nil, err
+		}
+		group := &entities.TableGroup{
+			Count:    g.Count,
+			Capacity: g.Capacity,
+			Zone:     *patch.Zone,
+			Shape:    *patch.Shape,
+		}
+		if len(*patch.Tags) > 0 {
+			group.Tags = *patch.Tags
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func toTablePresenter(t *entities.Table) *presenter.Table {
+	return &presenter.Table{
+		TableID:  t.TableID,
+		Capacity: t.Capacity,
+		Event:    t.Event,
+		Name:     t.Name,
+		Zone:     t.Zone,
+		Shape:    t.Shape,
+		Tags:     t.Tags,
+		Label:    t.Label(),
+		X:        t.X,
+		Y:        t.Y,
+		Rotation: t.Rotation,
+	}
+}
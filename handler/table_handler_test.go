@@ -11,8 +11,11 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/services"
 	"ggv2/services/mocks"
 )
 
@@ -66,7 +69,7 @@ func TestGetTables(t *testing.T) {
 		
 		
 
-		dbSvc.On("ListTables", context.Background(), int64(10), int64(0)).Return(v.expRes, v.err)
+		dbSvc.On("ListTables", context.Background(), &entities.TableFilter{}, int64(10), int64(0)).Return(v.expRes, v.err)
 		th := TableHandler{dbSvc}
 		req := httptest.NewRequest("GET", v.url, nil)
 		w := httptest.NewRecorder()
@@ -116,6 +119,13 @@ func TestGetTable(t *testing.T) {
 			httpCode: http.StatusBadRequest,
 			url:      "http://localhost:1323/table/invalid",
 		},
+		{
+			name:     "Sad case",
+			desc:     "table not found",
+			err:      repo.ErrTableNotFound,
+			httpCode: http.StatusNotFound,
+			url:      "http://localhost:1323/table/1",
+		},
 	}
 	for _, v := range testcases {
 		dbSvc := new(mocks.DbService)
@@ -184,7 +194,7 @@ func TestCreateTables(t *testing.T) {
 		dbSvc := new(mocks.DbService)
 		
 		
-		dbSvc.On("CreateTable", context.Background(), int64(5)).Return(v.expRes, v.err)
+		dbSvc.On("CreateTable", context.Background(), int64(5), mock.AnythingOfType("*entities.TablePatch")).Return(v.expRes, v.err)
 		th := TableHandler{dbSvc}
 		form := url.Values{}
 		if v.capacity != "" {
@@ -200,22 +210,25 @@ func TestCreateTables(t *testing.T) {
 	}
 }
 
-func TestEmptyTables(t *testing.T) {
+func TestGetEmptySeatsCount(t *testing.T) {
 	type TestCase struct {
 		name     string
 		desc     string
 		err      error
+		expRes   int
 		httpCode int
 	}
 	testcases := []TestCase{
 		{
 			name:     "Happy case",
 			desc:     "All ok",
+			expRes:   99,
 			httpCode: http.StatusOK,
 		},
 		{
 			name:     "Sad case",
 			desc:     "service returns error",
+			expRes:   0,
 			err:      fmt.Errorf("mock error"),
 			httpCode: http.StatusInternalServerError,
 		},
@@ -224,51 +237,407 @@ func TestEmptyTables(t *testing.T) {
 		dbSvc := new(mocks.DbService)
 		
 		
-		dbSvc.On("EmptyTables", context.Background()).Return(v.err)
+		dbSvc.On("GetEmptySeatsCount", context.Background()).Return(v.expRes, v.err)
 		th := TableHandler{dbSvc}
-		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/empty_tables", nil)
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/seats_empty", nil)
 		w := httptest.NewRecorder()
 		r := echo.New()
-		r.GET("/empty_tables", th.EmptyTables)
+		r.GET("/seats_empty", th.GetEmptySeatsCount)
 		r.ServeHTTP(w, req)
 		assert.Equal(t, v.httpCode, w.Code)
 	}
 }
 
-func TestGetEmptySeatsCount(t *testing.T) {
+func TestGetTablesWithFilter(t *testing.T) {
+	dbSvc := new(mocks.DbService)
+	dbSvc.On("ListTables", context.Background(), &entities.TableFilter{Zone: "Garden", Tag: "vip"}, int64(10), int64(0)).Return([]*entities.Table{{TableID: 12, Capacity: 8, Zone: "Garden"}}, nil)
+	th := TableHandler{dbSvc}
+	req := httptest.NewRequest("GET", "http://localhost:1323/tables?zone=Garden&tag=vip", nil)
+	w := httptest.NewRecorder()
+	r := echo.New()
+	r.GET("/tables", th.GetTables)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"id":12,"capacity":8,"zone":"Garden","label":"Table 12 – Garden","x":0,"y":0,"rotation":0}]`, w.Body.String())
+}
+
+func TestUpdateTable(t *testing.T) {
 	type TestCase struct {
 		name     string
 		desc     string
 		err      error
-		expRes   int
+		url      string
+		body     string
+		patch    *entities.TablePatch
 		httpCode int
 	}
+	name := "12"
+	zone := "Garden"
+	shape := entities.ShapeRound
+	tags := entities.Tags{"vip", "window"}
+	x, y, rotation := int64(150), int64(300), int64(270)
+	capacity := int64(10)
 	testcases := []TestCase{
 		{
 			name:     "Happy case",
 			desc:     "All ok",
-			expRes:   99,
+			url:      "http://localhost:1323/table/1",
+			body:     `{"name":"12","zone":" Garden ","shape":"Round","tags":["vip","window","vip"]}`,
+			patch:    &entities.TablePatch{Name: &name, Zone: &zone, Shape: &shape, Tags: &tags},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Happy case",
+			desc:     "partial update",
+			url:      "http://localhost:1323/table/1",
+			body:     `{"zone":"Garden"}`,
+			patch:    &entities.TablePatch{Zone: &zone},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid table id",
+			url:      "http://localhost:1323/table/invalid",
+			body:     `{"zone":"Garden"}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Happy case",
+			desc:     "position and rotation normalised",
+			url:      "http://localhost:1323/table/1",
+			body:     `{"x":150,"y":300,"rotation":-90}`,
+			patch:    &entities.TablePatch{X: &x, Y: &y, Rotation: &rotation},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "negative position",
+			url:      "http://localhost:1323/table/1",
+			body:     `{"x":-1}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid shape",
+			url:      "http://localhost:1323/table/1",
+			body:     `{"shape":"hexagon"}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid tag",
+			url:      "http://localhost:1323/table/1",
+			body:     `{"tags":["a,b"]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "table not found",
+			url:      "http://localhost:1323/table/1",
+			body:     `{"zone":"Garden"}`,
+			patch:    &entities.TablePatch{Zone: &zone},
+			err:      repo.ErrTableNotFound,
+			httpCode: http.StatusNotFound,
+		},
+		{
+			name:     "Sad case",
+			desc:     "optimistic lock error",
+			url:      "http://localhost:1323/table/1",
+			body:     `{"zone":"Garden"}`,
+			patch:    &entities.TablePatch{Zone: &zone},
+			err:      repo.ErrFailedOptimisticLock,
+			httpCode: http.StatusConflict,
+		},
+		{
+			name:     "Happy case",
+			desc:     "table resized",
+			url:      "http://localhost:1323/table/1",
+			body:     `{"capacity":10}`,
+			patch:    &entities.TablePatch{Capacity: &capacity},
 			httpCode: http.StatusOK,
 		},
+		{
+			name:     "Sad case",
+			desc:     "capacity less than one",
+			url:      "http://localhost:1323/table/1",
+			body:     `{"capacity":0}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "capacity below guests",
+			url:      "http://localhost:1323/table/1",
+			body:     `{"capacity":10}`,
+			patch:    &entities.TablePatch{Capacity: &capacity},
+			err:      services.ErrCapacityBelowGuests,
+			httpCode: http.StatusConflict,
+		},
 		{
 			name:     "Sad case",
 			desc:     "service returns error",
-			expRes:   0,
+			url:      "http://localhost:1323/table/1",
+			body:     `{"zone":"Garden"}`,
+			patch:    &entities.TablePatch{Zone: &zone},
 			err:      fmt.Errorf("mock error"),
 			httpCode: http.StatusInternalServerError,
 		},
 	}
 	for _, v := range testcases {
 		dbSvc := new(mocks.DbService)
-		
-		
-		dbSvc.On("GetEmptySeatsCount", context.Background()).Return(v.expRes, v.err)
+		dbSvc.On("UpdateTable", context.Background(), int64(1), v.patch).Return(&entities.Table{TableID: 1, Capacity: 8}, v.err)
 		th := TableHandler{dbSvc}
-		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/seats_empty", nil)
+		req := httptest.NewRequest(http.MethodPatch, v.url, strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 		r := echo.New()
-		r.GET("/seats_empty", th.GetEmptySeatsCount)
+		r.PATCH("/table/:id", th.UpdateTable)
 		r.ServeHTTP(w, req)
-		assert.Equal(t, v.httpCode, w.Code)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
+
+func TestDeleteTable(t *testing.T) {
+	type TestCase struct {
+		name       string
+		desc       string
+		err        error
+		url        string
+		reassignTo int64
+		httpCode   int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "empty table deleted",
+			url:      "http://localhost:1323/table/1",
+			httpCode: http.StatusNoContent,
+		},
+		{
+			name:       "Happy case",
+			desc:       "guests reassigned",
+			url:        "http://localhost:1323/table/1?reassign_to=2",
+			reassignTo: 2,
+			httpCode:   http.StatusNoContent,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid table id",
+			url:      "http://localhost:1323/table/invalid",
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid reassign target",
+			url:      "http://localhost:1323/table/1?reassign_to=abc",
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "reassign to same table",
+			url:      "http://localhost:1323/table/1?reassign_to=1",
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "table not found",
+			url:      "http://localhost:1323/table/1",
+			err:      repo.ErrTableNotFound,
+			httpCode: http.StatusNotFound,
+		},
+		{
+			name:       "Sad case",
+			desc:       "reassign target not found",
+			url:        "http://localhost:1323/table/1?reassign_to=2",
+			reassignTo: 2,
+			err:        services.ErrReassignTableNotFound,
+			httpCode:   http.StatusNotFound,
+		},
+		{
+			name:     "Sad case",
+			desc:     "table not empty",
+			url:      "http://localhost:1323/table/1",
+			err:      services.ErrTableNotEmpty,
+			httpCode: http.StatusConflict,
+		},
+		{
+			name:     "Sad case",
+			desc:     "lock held by a concurrent update",
+			url:      "http://localhost:1323/table/1",
+			err:      repo.ErrLockConflict,
+			httpCode: http.StatusConflict,
+		},
+		{
+			name:       "Sad case",
+			desc:       "reassign target full",
+			url:        "http://localhost:1323/table/1?reassign_to=2",
+			reassignTo: 2,
+			err:        services.ErrTableIsFull,
+			httpCode:   http.StatusConflict,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			url:      "http://localhost:1323/table/1",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("DeleteTable", context.Background(), int64(1), v.reassignTo).Return(v.err)
+		th := TableHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodDelete, v.url, nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.DELETE("/table/:id", th.DeleteTable)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+	}
+}
+
+func TestGetFloorPlan(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		event    string
+		err      error
+		expRes   []*entities.FloorPlanTable
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "All ok",
+			expRes: []*entities.FloorPlanTable{
+				{
+					Table:  &entities.Table{TableID: 1, Capacity: 8, AvailableCapacity: 6, X: 200, Y: 200},
+					Guests: []string{"dummy +1"},
+				},
+			},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:  "Happy case",
+			desc:  "single event",
+			event: "gala",
+			expRes: []*entities.FloorPlanTable{
+				{
+					Table:  &entities.Table{TableID: 1, Event: "gala", Capacity: 8, AvailableCapacity: 6, X: 200, Y: 200},
+					Guests: []string{"dummy +1"},
+				},
+			},
+			httpCode: http.StatusOK,
+		},
+		{
+			name:     "Sad case",
+			desc:     "event too long",
+			event:    strings.Repeat("a", 46),
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("FloorPlan", context.Background(), v.event).Return(v.expRes, v.err)
+		th := TableHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:1323/floor_plan?event="+v.event, nil)
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.GET("/floor_plan", th.GetFloorPlan)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		if v.httpCode == http.StatusOK {
+			assert.Equal(t, "image/svg+xml", w.Header().Get(echo.HeaderContentType))
+			assert.Contains(t, w.Body.String(), "dummy +1")
+		}
+	}
+}
+
+func TestBulkCreateTables(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		body     string
+		event    string
+		groups   entities.TableGroups
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "All ok",
+			body:     `{"event":" gala ","groups":[{"count":10,"capacity":8,"zone":" Garden ","shape":"Round","tags":["vip"]},{"count":4,"capacity":12}]}`,
+			event:    "gala",
+			groups:   entities.TableGroups{{Count: 10, Capacity: 8, Zone: "Garden", Shape: "round", Tags: entities.Tags{"vip"}}, {Count: 4, Capacity: 12}},
+			httpCode: http.StatusCreated,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid body",
+			body:     `{"groups":"error"}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "no groups",
+			body:     `{"groups":[]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "count < 1",
+			body:     `{"groups":[{"count":0,"capacity":8}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "capacity < 1",
+			body:     `{"groups":[{"count":10,"capacity":0}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "too many tables",
+			body:     `{"groups":[{"count":400,"capacity":8},{"count":101,"capacity":8}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "event too long",
+			body:     `{"event":"` + strings.Repeat("a", 46) + `","groups":[{"count":4,"capacity":12}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "invalid shape",
+			body:     `{"groups":[{"count":1,"capacity":8,"shape":"hexagon"}]}`,
+			httpCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Sad case",
+			desc:     "service returns error",
+			body:     `{"groups":[{"count":4,"capacity":12}]}`,
+			groups:   entities.TableGroups{{Count: 4, Capacity: 12}},
+			err:      fmt.Errorf("mock error"),
+			httpCode: http.StatusInternalServerError,
+		},
+	}
+	for _, v := range testcases {
+		dbSvc := new(mocks.DbService)
+		dbSvc.On("CreateTables", context.Background(), v.event, v.groups).Return([]*entities.Table{{TableID: 1, Capacity: 8}}, v.err)
+		th := TableHandler{dbSvc}
+		req := httptest.NewRequest(http.MethodPut, "http://localhost:1323/tables", strings.NewReader(v.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r := echo.New()
+		r.PUT("/tables", th.CreateTables)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
 	}
 }
@@ -0,0 +1,97 @@
+// Package health tracks whether the service is ready to serve requests.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout bounds each component check so that a hung dependency fails readiness quickly
+const checkTimeout = 2 * time.Second
+
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// Check reports the readiness of a component, e.g. a database or a background worker
+type Check func(context.Context) error
+
+// Component is the status of a single component
+type Component struct {
+	Name   string
+	Status string
+	Err    error
+}
+
+// Checker runs the readiness checks of registered components
+type Checker struct {
+	mu       sync.RWMutex
+	names    []string
+	checks   map[string]Check
+	draining int32
+}
+
+func NewChecker() *Checker {
+	return &Checker{
+		checks: map[string]Check{},
+	}
+}
+
+// Register adds a component to readiness, registering a name again replaces its check.
+func (h *Checker) Register(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.checks[name]; !ok {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+}
+
+// SetDraining marks the service as shutting down, readiness fails from then on.
+func (h *Checker) SetDraining() {
+	atomic.StoreInt32(&h.draining, 1)
+}
+
+// Draining reports whether the service is shutting down.
+func (h *Checker) Draining() bool {
+	return atomic.LoadInt32(&h.draining) == 1
+}
+
+// Ready runs the check of every component concurrently, in order of registration.
+// The service is ready when every component is ok and it is not draining.
+func (h *Checker) Ready(ctx context.Context) (bool, []*Component) {
+	h.mu.RLock()
+	names := append([]string{}, h.names...)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = h.checks[name]
+	}
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	components := make([]*Component, len(names))
+	wg := sync.WaitGroup{}
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := &Component{Name: names[i], Status: StatusOK}
+			if c.Err = checks[i](ctx); c.Err != nil {
+				c.Status = StatusFail
+			}
+			components[i] = c
+		}(i)
+	}
+	wg.Wait()
+
+	ready := !h.Draining()
+	for _, c := range components {
+		ready = ready && c.Status == StatusOK
+	}
+	return ready, components
+}
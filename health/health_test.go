@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady(t *testing.T) {
+	type TestCase struct {
+		name          string
+		desc          string
+		checks        map[string]error
+		draining      bool
+		expReady      bool
+		expComponents []*Component
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "no component",
+			expReady: true,
+		},
+		{
+			name:     "Happy case",
+			desc:     "every component ok",
+			checks:   map[string]error{"database": nil, "migrations": nil},
+			expReady: true,
+			expComponents: []*Component{
+				{Name: "database", Status: StatusOK},
+				{Name: "migrations", Status: StatusOK},
+			},
+		},
+		{
+			name:     "Sad case",
+			desc:     "a component fails",
+			checks:   map[string]error{"database": fmt.Errorf("mock error"), "migrations": nil},
+			expReady: false,
+			expComponents: []*Component{
+				{Name: "database", Status: StatusFail, Err: fmt.Errorf("mock error")},
+				{Name: "migrations", Status: StatusOK},
+			},
+		},
+		{
+			name:     "Sad case",
+			desc:     "draining",
+			checks:   map[string]error{"database": nil},
+			draining: true,
+			expReady: false,
+			expComponents: []*Component{
+				{Name: "database", Status: StatusOK},
+			},
+		},
+	}
+	for _, v := range testcases {
+		h := NewChecker()
+		for _, name := range []string{"database", "migrations"} {
+			if err, ok := v.checks[name]; ok {
+				err := err
+				h.Register(name, func(context.Context) error { return err })
+			}
+		}
+		if v.draining {
+			h.SetDraining()
+		}
+		ready, components := h.Ready(context.Background())
+		assert.Equal(t, v.expReady, ready, v.desc)
+		assert.Equal(t, len(v.expComponents), len(components), v.desc)
+		for i := range v.expComponents {
+			assert.Equal(t, v.expComponents[i], components[i], v.desc)
+		}
+		assert.Equal(t, v.draining, h.Draining(), v.desc)
+	}
+}
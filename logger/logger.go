@@ -1,21 +1,243 @@
+// Package logger builds the zap loggers of the service and carries request loggers in contexts.
 package logger
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-func NewLogger() *zap.Logger {
-	const (
-		logPath = "./logs/ggv2.log"
-	)
-	os.OpenFile(logPath, os.O_RDONLY|os.O_CREATE, 0666)
-	c := zap.NewProductionConfig()
-	c.OutputPaths = []string{"stdout", logPath}
-	l, err := c.Build()
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+)
+
+// Config holds the level and outputs of the logger. Outputs are stdout, stderr, file paths or
+// tcp://host:port and udp://host:port addresses of a Logstash JSON input. Encoding applies to
+// stdout and stderr, files and network outputs are always written as JSON lines.
+type Config struct {
+	Level    string
+	Outputs  []string
+	Encoding string
+	Rotation Rotation
+}
+
+// Rotation holds when log files are rotated and how many rotated files are kept
+type Rotation struct {
+	// MaxSizeMB rotates a file once it reaches that many megabytes
+	MaxSizeMB int
+	// Interval also rotates files on a schedule aligned to UTC, e.g. daily at midnight, zero disables it
+	Interval time.Duration
+	// MaxAge and MaxBackups remove rotated files older or beyond that count, zero keeps them
+	MaxAge     time.Duration
+	MaxBackups int
+	Compress   bool
+}
+
+// sinks are the outputs of the current configuration
+type sinks struct {
+	core    zapcore.Core
+	closers []io.Closer
+	stop    chan struct{}
+	rotator *rotator
+}
+
+// rotator is the background worker rotating files on a schedule, it keeps the error of the
+// last scheduled rotation
+type rotator struct {
+	interval time.Duration
+	files    []*lumberjack.Logger
+	done     chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+var errRotationStopped = errors.New("log rotation stopped")
+
+var (
+	mu sync.RWMutex
+	// config bootstraps loggers built before SetConfig, it only writes to stdout so that
+	// startup errors are reported even where no log file can be opened
+	config = Config{
+		Level:   "info",
+		Outputs: []string{"stdout"},
+	}
+	current *sinks
+)
+
+// SetConfig changes the level and outputs of loggers built from then on. The outputs of the
+// previous configuration are closed.
+func SetConfig(c Config) error {
+	s, err := build(c)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	mu.Lock()
+	prev := current
+	config = c
+	current = s
+	mu.Unlock()
+	if prev != nil {
+		prev.close()
+	}
+	return nil
+}
+
+// NewLogger returns a logger writing to the outputs of the current configuration, the
+// outputs are opened once and shared by every logger.
+func NewLogger() *zap.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		s, err := build(config)
+		if err != nil {
+			panic(err)
+		}
+		current = s
+	}
+	return zap.New(current.core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
+}
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying l, e.g. a logger annotated with the request ID.
+func NewContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, the global logger when there is none.
+func FromContext(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return zap.L()
+}
+
+func build(c Config) (*sinks, error) {
+	var level zapcore.Level
+	if err := level.Set(c.Level); err != nil {
+		return nil, err
+	}
+	encoderCfg := zap.NewProductionEncoderConfig()
+	jsonEncoder := zapcore.NewJSONEncoder(encoderCfg)
+	consoleEncoder := jsonEncoder
+	switch c.Encoding {
+	case "", EncodingJSON:
+	case EncodingConsole:
+		devCfg := zap.NewDevelopmentEncoderConfig()
+		devCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		consoleEncoder = zapcore.NewConsoleEncoder(devCfg)
+	default:
+		return nil, fmt.Errorf("unknown log encoding %q", c.Encoding)
+	}
+
+	s := &sinks{stop: make(chan struct{})}
+	cores := []zapcore.Core{}
+	rotated := []*lumberjack.Logger{}
+	for _, out := range c.Outputs {
+		switch {
+		case out == "stdout":
+			cores = append(cores, zapcore.NewCore(consoleEncoder, zapcore.Lock(os.Stdout), level))
+		case out == "stderr":
+			cores = append(cores, zapcore.NewCore(consoleEncoder, zapcore.Lock(os.Stderr), level))
+		case strings.HasPrefix(out, "tcp://"), strings.HasPrefix(out, "udp://"):
+			network := newNetworkSink(out[:3], out[len("tcp://"):])
+			s.closers = append(s.closers, network)
+			cores = append(cores, zapcore.NewCore(jsonEncoder, network, level))
+		default:
+			file := &lumberjack.Logger{
+				Filename:   out,
+				MaxSize:    c.Rotation.MaxSizeMB,
+				MaxAge:     days(c.Rotation.MaxAge),
+				MaxBackups: c.Rotation.MaxBackups,
+				Compress:   c.Rotation.Compress,
+			}
+			// Fail now rather than on the first log line when the file cannot be opened
+			if _, err := file.Write(nil); err != nil {
+				s.close()
+				return nil, err
+			}
+			rotated = append(rotated, file)
+			s.closers = append(s.closers, file)
+			cores = append(cores, zapcore.NewCore(jsonEncoder, zapcore.AddSync(file), level))
+		}
+	}
+	if len(cores) == 0 {
+		return nil, fmt.Errorf("no log outputs")
+	}
+	s.core = zapcore.NewTee(cores...)
+	if c.Rotation.Interval > 0 && len(rotated) > 0 {
+		s.rotator = &rotator{interval: c.Rotation.Interval, files: rotated, done: make(chan struct{})}
+		go s.rotator.run(s.stop)
+	}
+	return s, nil
+}
+
+// CheckRotation reports whether the files of the current configuration are rotated on schedule,
+// it fails while the last scheduled rotation failed or when the rotation worker stopped.
+func CheckRotation(ctx context.Context) error {
+	mu.RLock()
+	s := current
+	mu.RUnlock()
+	if s == nil || s.rotator == nil {
+		return nil
+	}
+	select {
+	case <-s.rotator.done:
+		return errRotationStopped
+	default:
+	}
+	s.rotator.mu.Lock()
+	defer s.rotator.mu.Unlock()
+	return s.rotator.err
+}
+
+// run rotates files at every multiple of the interval until stop is closed.
+func (r *rotator) run(stop chan struct{}) {
+	defer close(r.done)
+	for {
+		now := time.Now()
+		t := time.NewTimer(now.Truncate(r.interval).Add(r.interval).Sub(now))
+		select {
+		case <-stop:
+			t.Stop()
+			return
+		case <-t.C:
+			var failed error
+			for _, f := range r.files {
+				if err := f.Rotate(); err != nil {
+					fmt.Fprintf(os.Stderr, "unable to rotate log file %s: %v\n", f.Filename, err)
+					failed = fmt.Errorf("unable to rotate log file %s: %w", f.Filename, err)
+				}
+			}
+			r.mu.Lock()
+			r.err = failed
+			r.mu.Unlock()
+		}
+	}
+}
+
+func (s *sinks) close() {
+	close(s.stop)
+	for _, c := range s.closers {
+		c.Close()
+	}
+}
+
+// days rounds a retention up to whole days, the unit of lumberjack.
+func days(d time.Duration) int {
+	if d <= 0 {
+		return 0
 	}
-	return l
+	return int((d + 24*time.Hour - 1) / (24 * time.Hour))
 }
@@ -0,0 +1,237 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestFromContext(t *testing.T) {
+	l := zap.NewNop()
+	type TestCase struct {
+		name   string
+		desc   string
+		ctx    context.Context
+		expRes *zap.Logger
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "logger of the request",
+			ctx:    NewContext(context.Background(), l),
+			expRes: l,
+		},
+		{
+			name:   "Happy case",
+			desc:   "global logger outside requests",
+			ctx:    context.Background(),
+			expRes: zap.L(),
+		},
+	}
+	for _, v := range testcases {
+		assert.Same(t, v.expRes, FromContext(v.ctx), v.desc)
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	defer SetConfig(Config{Level: "info", Outputs: []string{"stdout"}})
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer tcp.Close()
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer udp.Close()
+
+	type TestCase struct {
+		name    string
+		desc    string
+		cfg     func(dir string) Config
+		expErr  bool
+		expRead func(t *testing.T, dir string) string
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "file written as JSON with a console stdout",
+			cfg: func(dir string) Config {
+				return Config{Level: "info", Encoding: EncodingConsole, Outputs: []string{"stdout", filepath.Join(dir, "ggv2.log")}}
+			},
+			expRead: func(t *testing.T, dir string) string {
+				data, err := ioutil.ReadFile(filepath.Join(dir, "ggv2.log"))
+				assert.Nil(t, err)
+				return string(data)
+			},
+		},
+		{
+			name: "Happy case",
+			desc: "logstash over tcp",
+			cfg: func(dir string) Config {
+				return Config{Level: "info", Outputs: []string{"tcp://" + tcp.Addr().String()}}
+			},
+			expRead: func(t *testing.T, dir string) string {
+				conn, err := tcp.Accept()
+				assert.Nil(t, err)
+				defer conn.Close()
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				assert.Nil(t, err)
+				return line
+			},
+		},
+		{
+			name: "Happy case",
+			desc: "logstash over udp",
+			cfg: func(dir string) Config {
+				return Config{Level: "info", Outputs: []string{"udp://" + udp.LocalAddr().String()}}
+			},
+			expRead: func(t *testing.T, dir string) string {
+				buf := make([]byte, 4096)
+				udp.SetReadDeadline(time.Now().Add(5 * time.Second))
+				n, _, err := udp.ReadFrom(buf)
+				assert.Nil(t, err)
+				return string(buf[:n])
+			},
+		},
+		{
+			name: "Sad case",
+			desc: "invalid level",
+			cfg: func(dir string) Config {
+				return Config{Level: "loud", Outputs: []string{"stdout"}}
+			},
+			expErr: true,
+		},
+		{
+			name: "Sad case",
+			desc: "invalid encoding",
+			cfg: func(dir string) Config {
+				return Config{Level: "info", Encoding: "text", Outputs: []string{"stdout"}}
+			},
+			expErr: true,
+		},
+		{
+			name: "Sad case",
+			desc: "file cannot be created",
+			cfg: func(dir string) Config {
+				assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0644))
+				return Config{Level: "info", Outputs: []string{filepath.Join(dir, "file", "ggv2.log")}}
+			},
+			expErr: true,
+		},
+	}
+	for _, v := range testcases {
+		dir := t.TempDir()
+		err := SetConfig(v.cfg(dir))
+		assert.Equal(t, v.expErr, err != nil, v.desc)
+		if err != nil {
+			continue
+		}
+		NewLogger().Info("hello", zap.String("rqId", "req-1"))
+		entry := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal([]byte(v.expRead(t, dir)), &entry), v.desc)
+		assert.Equal(t, "hello", entry["msg"], v.desc)
+		assert.Equal(t, "req-1", entry["rqId"], v.desc)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	defer SetConfig(Config{Level: "info", Outputs: []string{"stdout"}})
+	dir := t.TempDir()
+	err := SetConfig(Config{
+		Level:    "info",
+		Outputs:  []string{filepath.Join(dir, "ggv2.log")},
+		Rotation: Rotation{Interval: 50 * time.Millisecond, MaxBackups: 1},
+	})
+	assert.Nil(t, err)
+	l := NewLogger()
+	// Rotated on schedule, keeping a single backup
+	assert.Eventually(t, func() bool {
+		l.Info("hello")
+		files, _ := ioutil.ReadDir(dir)
+		return len(files) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	l.Info("hello")
+	assert.Eventually(t, func() bool {
+		files, _ := ioutil.ReadDir(dir)
+		return len(files) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestCheckRotation(t *testing.T) {
+	defer SetConfig(Config{Level: "info", Outputs: []string{"stdout"}})
+	dir := filepath.Join(t.TempDir(), "logs")
+	assert.Nil(t, SetConfig(Config{
+		Level:    "info",
+		Outputs:  []string{filepath.Join(dir, "ggv2.log")},
+		Rotation: Rotation{Interval: 50 * time.Millisecond},
+	}))
+	assert.Nil(t, CheckRotation(context.Background()))
+	// Rotation fails once the log directory is replaced by a file
+	assert.Nil(t, os.RemoveAll(dir))
+	assert.Nil(t, ioutil.WriteFile(dir, nil, 0644))
+	assert.Eventually(t, func() bool {
+		return CheckRotation(context.Background()) != nil
+	}, 5*time.Second, 20*time.Millisecond)
+	// No rotation worker without a schedule
+	assert.Nil(t, SetConfig(Config{Level: "info", Outputs: []string{"stdout"}}))
+	assert.Nil(t, CheckRotation(context.Background()))
+}
+
+func TestDays(t *testing.T) {
+	assert.Equal(t, 0, days(0))
+	assert.Equal(t, 1, days(time.Hour))
+	assert.Equal(t, 7, days(7*24*time.Hour))
+	assert.Equal(t, 8, days(7*24*time.Hour+time.Minute))
+}
+
+func TestNetworkSinkOverflow(t *testing.T) {
+	// No worker drains the queue, as when the output is too slow to keep up
+	s := &networkSink{lines: make(chan []byte, 1), done: make(chan struct{})}
+	type TestCase struct {
+		name   string
+		desc   string
+		close  bool
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "line queued",
+		},
+		{
+			name:   "Sad case",
+			desc:   "line dropped when the queue is full",
+			expErr: errNetworkSinkFull,
+		},
+		{
+			name:   "Sad case",
+			desc:   "line dropped once closed",
+			close:  true,
+			expErr: errNetworkSinkClosed,
+		},
+	}
+	for _, v := range testcases {
+		if v.close {
+			close(s.done)
+			s.Close()
+		}
+		p := []byte("{\"msg\":\"hello\"}\n")
+		n, err := s.Write(p)
+		assert.Equal(t, v.expErr, err, v.desc)
+		if v.expErr == nil {
+			assert.Equal(t, len(p), n, v.desc)
+			// The queued line is a copy, zap reuses the buffer
+			p[2] = 'x'
+			assert.Equal(t, "{\"msg\":\"hello\"}\n", string(<-s.lines), v.desc)
+			s.lines <- []byte{}
+		}
+	}
+}
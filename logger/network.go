@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// networkTimeout bounds connecting and writing to a network output, so that
+	// an unreachable Logstash drops log lines rather than holding up the ones behind
+	networkTimeout = time.Second
+	// networkRetry is how long a failed network output is left alone before reconnecting
+	networkRetry = 5 * time.Second
+	// networkBuffer is how many log lines wait for a network output before new ones are dropped
+	networkBuffer = 1024
+)
+
+var (
+	errNetworkSinkClosed = errors.New("log output closed, dropping log line")
+	errNetworkSinkFull   = errors.New("log output falling behind, dropping log line")
+)
+
+// networkSink writes each log entry to a TCP or UDP address, one JSON line per entry.
+// Lines are queued and sent in the background so that a slow or unreachable output never
+// blocks the request logging them, lines beyond the queue are dropped. The connection is
+// dialled lazily and redialled after a write fails.
+type networkSink struct {
+	network string
+	addr    string
+	lines   chan []byte
+	done    chan struct{}
+
+	mu       sync.Mutex
+	closed   bool
+	conn     net.Conn
+	failedAt time.Time
+}
+
+func newNetworkSink(network, addr string) *networkSink {
+	s := &networkSink{
+		network: network,
+		addr:    addr,
+		lines:   make(chan []byte, networkBuffer),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Write queues a copy of p, zap reuses the buffer once Write returns.
+func (s *networkSink) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, errNetworkSinkClosed
+	}
+	select {
+	case s.lines <- line:
+		return len(p), nil
+	default:
+		return 0, errNetworkSinkFull
+	}
+}
+
+// run sends queued lines until the sink is closed and the queue drained.
+func (s *networkSink) run() {
+	defer close(s.done)
+	for line := range s.lines {
+		s.send(line)
+	}
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// send writes a line to the connection, lines are dropped while the output is unreachable.
+func (s *networkSink) send(line []byte) {
+	if s.conn == nil {
+		if !s.failedAt.IsZero() && time.Since(s.failedAt) < networkRetry {
+			return
+		}
+		conn, err := net.DialTimeout(s.network, s.addr, networkTimeout)
+		if err != nil {
+			s.failedAt = time.Now()
+			return
+		}
+		s.conn = conn
+		s.failedAt = time.Time{}
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(networkTimeout))
+	if _, err := s.conn.Write(line); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		s.failedAt = time.Now()
+	}
+}
+
+func (s *networkSink) Sync() error {
+	return nil
+}
+
+// Close stops accepting lines and waits for the queued ones to be sent or dropped.
+func (s *networkSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.lines)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
@@ -4,384 +4,678 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 
 	"go.uber.org/zap"
 
 	"ggv2/entities"
+	"ggv2/logger"
 )
 
 type DBRepo struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	locking Locking
 }
 
+// Errors returned by the repo and the units of work, match them with errors.Is
 var (
-	errTableNotFound = errors.New("table not found")
-
-	errGuestNotFound = errors.New("guest not found")
+	ErrTableNotFound          = errors.New("table not found")
+	ErrGuestNotFound          = errors.New("guest not found")
+	ErrGuestAlreadyRSVP       = errors.New("guest already RSVP")
+	ErrFailedOptimisticLock   = errors.New("unable to secure optimistic lock, please retry")
+	ErrLockConflict           = errors.New("unable to secure lock held by a concurrent update, please retry")
+	ErrDBErr                  = errors.New("database returns error")
+	ErrPartyMemberNotFound    = errors.New("party member not found")
+	ErrLayoutTemplateNotFound = errors.New("layout template not found")
+	ErrAPIKeyNotFound         = errors.New("api key not found")
+	ErrArchiveNotFound        = errors.New("archive not found")
+	ErrArchiveAlreadyRestored = errors.New("archive already restored")
+	ErrRestoreConflict        = errors.New("restored data conflicts with existing tables, guests or party members")
+	ErrIdempotencyKeyExists   = errors.New("idempotency key already used")
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+)
 
-	errGuestAlreadyRSVP = errors.New("guest already RSVP")
+// schemaTable is a table created by the scripts in sql/ with the columns the repo reads and
+// writes, and the unique keys it relies on to reject duplicates
+type schemaTable struct {
+	name       string
+	columns    []string
+	uniqueKeys []string
+}
 
-	errGuestAlreadyArrived = errors.New("guest already arrived")
+// schema lists the tables of the scripts in sql/, in the order they are checked.
+var schema = []schemaTable{
+	{name: "table", columns: []string{"id", "capacity", "pcapacity", "acapacity", "version", "event", "name", "zone", "shape", "tags", "pos_x", "pos_y", "rotation"}},
+	{name: "guests", columns: []string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid", "dietary_tags", "allergens", "dietary_notes", "meal_choice"}, uniqueKeys: []string{"name"}},
+	{name: "party_members", columns: []string{"id", "guestid", "name", "age_group", "arrived", "dietary_tags", "allergens", "dietary_notes", "meal_choice"}, uniqueKeys: []string{"guestid_name"}},
+	{name: "layout_templates", columns: []string{"id", "name", "layout", "version"}, uniqueKeys: []string{"name"}},
+	{name: "api_keys", columns: []string{"id", "name", "prefix", "role", "key_hash", "created_at", "revoked"}, uniqueKeys: []string{"key_hash"}},
+	{name: "archives", columns: []string{"id", "event", "tables", "guests", "created_at", "restored", "data"}},
+	{name: "audit_log", columns: []string{"id", "actor", "request_id", "operation", "target", "before_value", "after_value", "created_at"}},
+	{name: "idempotency_keys", columns: []string{"actor", "idempotency_key", "request_hash", "status", "content_type", "body", "created_at", "expires_at"}},
+}
 
-	errTableIsFull = errors.New("table is full")
+// mysqlErrDupEntry is the MySQL error number of a duplicate key violation.
+const mysqlErrDupEntry = 1062
 
-	errFailedOptimisticLock = errors.New("unable to secure optimistic lock, please retry")
+// snapshotQueries select the tables, guests and party members of every event.
+var snapshotQueries = [3]string{
+	"SELECT * FROM `table` ORDER BY id",
+	"SELECT * FROM `guests` ORDER BY id",
+	"SELECT * FROM `party_members` ORDER BY id",
+}
 
-	errDBErr = errors.New("database returns error")
+// eventSnapshotQueries select the tables, guests and party members of a single event.
+var eventSnapshotQueries = [3]string{
+	"SELECT * FROM `table` WHERE event = ? ORDER BY id",
+	"SELECT g.* FROM `guests` g JOIN `table` t ON g.tableid = t.id WHERE t.event = ? ORDER BY g.id",
+	"SELECT m.* FROM `party_members` m JOIN `guests` g ON m.guestid = g.id JOIN `table` t ON g.tableid = t.id WHERE t.event = ? ORDER BY m.id",
+}
 
-	errGuestNeverRSVP = errors.New("guest never rsvp")
+// emptyQueries remove the party members, guests and tables of every event. DELETE is used
+// rather than TRUNCATE as TRUNCATE implicitly commits the transaction it runs in.
+var emptyQueries = [3]string{
+	"DELETE FROM `party_members`",
+	"DELETE FROM `guests`",
+	"DELETE FROM `table`",
+}
 
-	errGuestNotArrived = errors.New("guest not arrived")
-)
+// eventEmptyQueries remove the party members, guests and tables of a single event.
+var eventEmptyQueries = [3]string{
+	"DELETE m FROM `party_members` m JOIN `guests` g ON m.guestid = g.id JOIN `table` t ON g.tableid = t.id WHERE t.event = ?",
+	"DELETE g FROM `guests` g JOIN `table` t ON g.tableid = t.id WHERE t.event = ?",
+	"DELETE FROM `table` WHERE event = ?",
+}
 
 func NewDbRepo(db *sqlx.DB) *DBRepo {
 	return &DBRepo{
-		db: db,
+		db:      db,
+		locking: OptimisticLocking,
 	}
 }
 
+// WithLocking selects how units of work guard the rows they read.
+func (r *DBRepo) WithLocking(locking Locking) *DBRepo {
+	r.locking = locking
+	return r
+}
+
 // GetTable returns detail of a single table.
 func (r *DBRepo) GetTable(ctx context.Context, id int64) (*entities.Table, error) {
+	defer observeQuery("GetTable")()
+	return getTable(ctx, r.db, id, "")
+}
+
+// getTable reads a table, lock is appended to the statement to lock its row.
+func getTable(ctx context.Context, q sqlx.QueryerContext, id int64, lock string) (*entities.Table, error) {
 	table := entities.Table{}
 	// Execute Statement
-	err := r.db.Get(&table, "SELECT * FROM `table` WHERE id=?", id)
+	err := sqlx.GetContext(ctx, q, &table, "SELECT * FROM `table` WHERE id=?"+lock, id)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errTableNotFound
+			return nil, ErrTableNotFound
 		}
 		// Error paring statement result into struct
-		return nil, errDBErr
+		return nil, dbErr(ctx, err)
 	}
 	return &table, nil
 }
 
-func (r *DBRepo) CreateTable(ctx context.Context, table *entities.Table) (*entities.Table, error) {
-	// Execute Statement
-	res, err := r.db.Exec("INSERT INTO `table` (capacity, pcapacity, acapacity) VALUES(?, ?, ?)", table.Capacity, table.Capacity, table.Capacity)
+func (r *DBRepo) ListTables(ctx context.Context, filter *entities.TableFilter, limit, offset int64) ([]*entities.Table, error) {
+	defer observeQuery("ListTables")()
+	tables := []*entities.Table{}
+	where, args := tableFilterClause(filter)
+	args = append(args, limit, offset)
+	err := r.db.SelectContext(ctx, &tables, "SELECT * FROM `table`"+where+" LIMIT ? OFFSET ?", args...)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error paring statement result into struct
-		return nil, errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTableNotFound
+		}
+		return nil, ErrDBErr
+	}
+	return tables, nil
+}
+
+// ListAllTables returns every table of an event ordered by id, the tables of every event when event is empty.
+func (r *DBRepo) ListAllTables(ctx context.Context, event string) ([]*entities.Table, error) {
+	defer observeQuery("ListAllTables")()
+	tables := []*entities.Table{}
+	queries, args := snapshotQueries, []interface{}{}
+	if event != "" {
+		queries, args = eventSnapshotQueries, []interface{}{event}
 	}
-	id, err := res.LastInsertId()
+	err := r.db.SelectContext(ctx, &tables, queries[0], args...)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting ID of newly created record
-		return nil, errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
 	}
-	table.TableID = id
+	return tables, nil
+}
 
-	return table, nil
+// tableFilterClause builds the WHERE clause matching a TableFilter.
+func tableFilterClause(filter *entities.TableFilter) (string, []interface{}) {
+	if filter == nil {
+		return "", nil
+	}
+	conds := []string{}
+	args := []interface{}{}
+	if filter.Event != "" {
+		conds = append(conds, "event = ?")
+		args = append(args, filter.Event)
+	}
+	if filter.Name != "" {
+		conds = append(conds, "name = ?")
+		args = append(args, filter.Name)
+	}
+	if filter.Zone != "" {
+		conds = append(conds, "zone = ?")
+		args = append(args, filter.Zone)
+	}
+	if filter.Shape != "" {
+		conds = append(conds, "shape = ?")
+		args = append(args, filter.Shape)
+	}
+	if filter.Tag != "" {
+		conds = append(conds, "FIND_IN_SET(?, tags) > 0")
+		args = append(args, filter.Tag)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
 }
 
-func (r *DBRepo) ListTables(ctx context.Context, limit, offset int64) ([]*entities.Table, error) {
-	tables := []*entities.Table{}
-	err := r.db.Select(&tables, "SELECT * FROM `table` LIMIT ? OFFSET ?", limit, offset)
+// archiveAndEmpty archives and then removes the tables, guests and party members of an event within tx.
+// The rows are read with a locking read, which reads the latest rows rather than the snapshot of
+// the transaction and holds them until it ends, so that rows committed concurrently are either
+// archived or wait to be added until the tables are empty, never removed without being archived.
+func archiveAndEmpty(ctx context.Context, tx *sqlx.Tx, event string) (*entities.Archive, error) {
+	snapshot, err := takeSnapshot(ctx, tx, event, forUpdate)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errTableNotFound
+		return nil, err
+	}
+	archive := &entities.Archive{
+		Event:    event,
+		Tables:   int64(len(snapshot.Tables)),
+		Guests:   int64(len(snapshot.Guests)),
+		Snapshot: snapshot,
+	}
+	res, err := tx.ExecContext(ctx, "INSERT INTO `archives` (event, tables, guests, data) VALUES(?, ?, ?, ?)", archive.Event, archive.Tables, archive.Guests, archive.Snapshot)
+	if err != nil {
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		// Error archiving data
+		return nil, ErrDBErr
+	}
+	archive.ID, err = res.LastInsertId()
+	if err != nil {
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		// Error getting ID of newly created record
+		return nil, ErrDBErr
+	}
+	queries, args := emptyQueries, []interface{}{}
+	if event != "" {
+		queries, args = eventEmptyQueries, []interface{}{event}
+	}
+	for _, query := range queries {
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+			return nil, ErrDBErr
 		}
-		return nil, errDBErr
 	}
-	return tables, nil
+	return archive, nil
 }
 
-func (r *DBRepo) EmptyTables(ctx context.Context) error {
-	_, err := r.db.Exec("TRUNCATE TABLE `table`;")
+// Snapshot reads the tables of an event with their guests and party members, every table is read
+// when event is empty. Reads happen in a single read only transaction so that the snapshot is consistent.
+func (r *DBRepo) Snapshot(ctx context.Context, event string) (*entities.Snapshot, error) {
+	defer observeQuery("Snapshot")()
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		// Error starting transaction
+		return nil, ErrDBErr
+	}
+	snapshot, err := takeSnapshot(ctx, tx, event, "")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Commit()
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		return errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		// Error commiting transaction
+		return nil, ErrDBErr
+	}
+	return snapshot, nil
+}
+
+// takeSnapshot reads the tables of an event with their guests and party members within tx,
+// every table is read when event is empty. lock is appended to the reads to lock the rows read.
+func takeSnapshot(ctx context.Context, tx *sqlx.Tx, event string, lock string) (*entities.Snapshot, error) {
+	snapshot := &entities.Snapshot{
+		Version:      entities.SnapshotVersion,
+		Event:        event,
+		TakenAt:      time.Now().UTC(),
+		Tables:       []*entities.Table{},
+		Guests:       []*entities.Guest{},
+		PartyMembers: []*entities.PartyMember{},
+	}
+	queries, args := snapshotQueries, []interface{}{}
+	if event != "" {
+		queries, args = eventSnapshotQueries, []interface{}{event}
+	}
+	dests := []interface{}{&snapshot.Tables, &snapshot.Guests, &snapshot.PartyMembers}
+	for i, query := range queries {
+		err := tx.SelectContext(ctx, dests[i], query+lock, args...)
+		if err != nil {
+			logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+			return nil, ErrDBErr
+		}
 	}
-	_, err = r.db.Exec("TRUNCATE TABLE `guests`;")
+	return snapshot, nil
+}
+
+// ListArchives returns every archive, most recent first, without the archived data.
+func (r *DBRepo) ListArchives(ctx context.Context) ([]*entities.Archive, error) {
+	defer observeQuery("ListArchives")()
+	archives := []*entities.Archive{}
+	err := r.db.SelectContext(ctx, &archives, "SELECT id, event, tables, guests, created_at, restored FROM `archives` ORDER BY id DESC")
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		return errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
+	}
+	return archives, nil
+}
+
+// restoreSnapshot inserts every row of a snapshot with its original id within tx.
+func restoreSnapshot(ctx context.Context, tx *sqlx.Tx, snapshot *entities.Snapshot) error {
+	for _, t := range snapshot.Tables {
+		_, err := tx.ExecContext(ctx, "INSERT INTO `table` (id, capacity, pcapacity, acapacity, version, event, name, zone, shape, tags, pos_x, pos_y, rotation) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", t.TableID, t.Capacity, t.PlannedCapacity, t.AvailableCapacity, t.Version, t.Event, t.Name, t.Zone, t.Shape, t.Tags, t.X, t.Y, t.Rotation)
+		if err != nil {
+			return restoreErr(ctx, err)
+		}
+	}
+	for _, g := range snapshot.Guests {
+		_, err := tx.ExecContext(ctx, "INSERT INTO `guests` (id, name, total_rsvp_guests, total_arrived_guests, version, arrivaltime, tableid, dietary_tags, allergens, dietary_notes, meal_choice) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", g.ID, g.Name, g.TotalGuests, g.TotalArrivedGuests, g.Version, g.ArrivalTime, g.TableID, g.DietaryTags, g.Allergens, g.DietaryNotes, g.MealChoice)
+		if err != nil {
+			return restoreErr(ctx, err)
+		}
+	}
+	for _, m := range snapshot.PartyMembers {
+		_, err := tx.ExecContext(ctx, "INSERT INTO `party_members` (id, guestid, name, age_group, arrived, dietary_tags, allergens, dietary_notes, meal_choice) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)", m.ID, m.GuestID, m.Name, m.AgeGroup, m.Arrived, m.DietaryTags, m.Allergens, m.DietaryNotes, m.MealChoice)
+		if err != nil {
+			return restoreErr(ctx, err)
+		}
 	}
 	return nil
 }
 
+// restoreErr reports rows that already exist as ErrRestoreConflict. The MySQL error is not logged
+// then, its message quotes the duplicate value, e.g. the name of a guest.
+func restoreErr(ctx context.Context, err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDupEntry {
+		logger.FromContext(ctx).Warn(ErrRestoreConflict.Error())
+		return ErrRestoreConflict
+	}
+	return dbErr(ctx, err)
+}
+
 // GetEmptySeatsCount calculate current total unoccupied seats.
 func (r *DBRepo) GetEmptySeatsCount(ctx context.Context) (int, error) {
+	defer observeQuery("GetEmptySeatsCount")()
 	c := 0
 	// Execute query
-	err := r.db.Get(&c, "SELECT SUM(acapacity) FROM `table`")
+	err := r.db.GetContext(ctx, &c, "SELECT SUM(acapacity) FROM `table`")
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
 		// Error executing query
-		return c, errDBErr
+		return c, ErrDBErr
 	}
 	// All ok, return ok
 	return c, nil
 }
 
 func (r *DBRepo) GetGuestByName(ctx context.Context, g *entities.Guest) (*entities.Guest, error) {
+	defer observeQuery("GetGuestByName")()
+	return getGuestByName(ctx, r.db, g.Name, "")
+}
+
+// getGuestByName reads a guest, lock is appended to the statement to lock its row.
+func getGuestByName(ctx context.Context, q sqlx.QueryerContext, name string, lock string) (*entities.Guest, error) {
 	guest := entities.Guest{}
 	// Execute Statement
-	err := r.db.Get(&guest, "SELECT * FROM `guests` WHERE name = ?", g.Name)
+	err := sqlx.GetContext(ctx, q, &guest, "SELECT * FROM `guests` WHERE name = ?"+lock, name)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errGuestNotFound
+			return nil, ErrGuestNotFound
 		}
 		// Error paring statement result into struct
-		return nil, errDBErr
+		return nil, dbErr(ctx, err)
 	}
 	return &guest, nil
 }
 
 func (r *DBRepo) ListGuests(ctx context.Context, limit, offset int64) ([]*entities.Guest, error) {
+	defer observeQuery("ListGuests")()
 	guests := []*entities.Guest{}
 
-	err := r.db.Select(&guests, "SELECT * FROM `guests` LIMIT ? OFFSET ?", limit, offset)
+	err := r.db.SelectContext(ctx, &guests, "SELECT * FROM `guests` LIMIT ? OFFSET ?", limit, offset)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errGuestNotFound
+			return nil, ErrGuestNotFound
 		}
-		return nil, errDBErr
+		return nil, ErrDBErr
 	}
 	return guests, nil
 }
 
 func (r *DBRepo) ListArrivedGuests(ctx context.Context, limit, offset int64) ([]*entities.Guest, error) {
+	defer observeQuery("ListArrivedGuests")()
 	guests := []*entities.Guest{}
 
-	err := r.db.Select(&guests, "SELECT * FROM `guests` WHERE total_arrived_guests > 0 LIMIT ? OFFSET ?", limit, offset)
+	err := r.db.SelectContext(ctx, &guests, "SELECT * FROM `guests` WHERE total_arrived_guests > 0 LIMIT ? OFFSET ?", limit, offset)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errGuestNotFound
+			return nil, ErrGuestNotFound
 		}
-		return nil, errDBErr
+		return nil, ErrDBErr
 	}
 	return guests, nil
 }
 
-func (r *DBRepo) AddToGuestList(ctx context.Context, guest *entities.Guest) error {
-	rsvpGuest, err := r.GetGuestByName(ctx, guest)
-	if err != nil && err != errGuestNotFound {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting guest RSVP record, returning error
-		return errDBErr
+// ListGuestsWithMembers returns every guest seated at the tables of an event together with their party
+// members, the guests of every event when event is empty.
+func (r *DBRepo) ListGuestsWithMembers(ctx context.Context, event string) ([]*entities.Guest, error) {
+	defer observeQuery("ListGuestsWithMembers")()
+	guests := []*entities.Guest{}
+	queries, args := snapshotQueries, []interface{}{}
+	if event != "" {
+		queries, args = eventSnapshotQueries, []interface{}{event}
 	}
-	if rsvpGuest != nil {
-		return errGuestAlreadyRSVP
+	err := r.db.SelectContext(ctx, &guests, queries[1], args...)
+	if err != nil {
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
 	}
-	table, err := r.GetTable(ctx, guest.TableID)
+	members := []*entities.PartyMember{}
+	err = r.db.SelectContext(ctx, &members, queries[2], args...)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting guest RSVP record, returning error
-		return errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
 	}
-	// Table capacity less than number of guests
-	if table.PlannedCapacity < guest.TotalGuests {
-		return errTableIsFull
+	// Attach members to their guest
+	byID := map[int64]*entities.Guest{}
+	for _, g := range guests {
+		byID[g.ID] = g
 	}
-	tx, err := r.db.BeginTxx(ctx, nil)
-	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error starting transaction
-		return errDBErr
+	for _, m := range members {
+		if g, ok := byID[m.GuestID]; ok {
+			g.Members = append(g.Members, m)
+		}
 	}
-	insert, err := tx.ExecContext(ctx, "INSERT INTO `guests` (total_rsvp_guests, tableid, name) VALUES(?, ?, ?)", guest.TotalGuests, guest.TableID, guest.Name)
+	return guests, nil
+}
+
+// ListPartyMembers returns the named members of a guest's party.
+func (r *DBRepo) ListPartyMembers(ctx context.Context, guest *entities.Guest) ([]*entities.PartyMember, error) {
+	defer observeQuery("ListPartyMembers")()
+	rsvpGuest, err := r.GetGuestByName(ctx, guest)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error creating RSVP record for guest
-		tx.Rollback()
-		return errDBErr
+		return nil, err
 	}
-	_, err = insert.LastInsertId()
+	members := []*entities.PartyMember{}
+	err = r.db.SelectContext(ctx, &members, "SELECT * FROM `party_members` WHERE guestid = ?", rsvpGuest.ID)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting ID of newly created record
-		return errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
 	}
-	// Calculate new capacity
-	table.PlannedCapacity -= guest.TotalGuests
-	// Update table capacity information
-	res, err := tx.ExecContext(ctx, "UPDATE `table` SET pcapacity=?, version = version + 1 WHERE id = ? AND version = ?", table.PlannedCapacity, table.TableID, table.Version)
+	return members, nil
+}
+
+// GetLayoutTemplate retrieves a layout template by name.
+func (r *DBRepo) GetLayoutTemplate(ctx context.Context, name string) (*entities.LayoutTemplate, error) {
+	defer observeQuery("GetLayoutTemplate")()
+	return getLayoutTemplate(ctx, r.db, name, "")
+}
+
+// getLayoutTemplate reads a layout template, lock is appended to the statement to lock its row.
+func getLayoutTemplate(ctx context.Context, q sqlx.QueryerContext, name string, lock string) (*entities.LayoutTemplate, error) {
+	template := entities.LayoutTemplate{}
+	err := sqlx.GetContext(ctx, q, &template, "SELECT * FROM `layout_templates` WHERE name=?"+lock, name)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error updating table capacity information
-		tx.Rollback()
-		return errDBErr
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLayoutTemplateNotFound
+		}
+		return nil, dbErr(ctx, err)
 	}
-	c, err := res.RowsAffected()
+	return &template, nil
+}
+
+// ListLayoutTemplates returns every layout template ordered by name.
+func (r *DBRepo) ListLayoutTemplates(ctx context.Context) ([]*entities.LayoutTemplate, error) {
+	defer observeQuery("ListLayoutTemplates")()
+	templates := []*entities.LayoutTemplate{}
+	err := r.db.SelectContext(ctx, &templates, "SELECT * FROM `layout_templates` ORDER BY name")
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting optimistic lock data for table
-		tx.Rollback()
-		return errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
 	}
-	if c != 1 {
-		// Unable to secure optimistic lock for table
-		tx.Rollback()
-		return errFailedOptimisticLock
+	return templates, nil
+}
+
+// GetAPIKeyByHash retrieves an API key by the hash of the key.
+func (r *DBRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*entities.APIKey, error) {
+	defer observeQuery("GetAPIKeyByHash")()
+	key := entities.APIKey{}
+	err := r.db.GetContext(ctx, &key, "SELECT * FROM `api_keys` WHERE key_hash=?", hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
 	}
+	return &key, nil
+}
 
-	// All ok, commiting transaction
-	err = tx.Commit()
+// ListAPIKeys returns every API key ordered by id.
+func (r *DBRepo) ListAPIKeys(ctx context.Context) ([]*entities.APIKey, error) {
+	defer observeQuery("ListAPIKeys")()
+	keys := []*entities.APIKey{}
+	err := r.db.SelectContext(ctx, &keys, "SELECT * FROM `api_keys` ORDER BY id")
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error commiting transaction
-		return errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
 	}
+	return keys, nil
+}
 
-	return nil
+// ListAuditEntries returns the audit log entries matching filter, most recent first.
+func (r *DBRepo) ListAuditEntries(ctx context.Context, filter *entities.AuditFilter, limit, offset int64) ([]*entities.AuditEntry, error) {
+	defer observeQuery("ListAuditEntries")()
+	entries := []*entities.AuditEntry{}
+	where, args := auditFilterClause(filter)
+	args = append(args, limit, offset)
+	err := r.db.SelectContext(ctx, &entries, "SELECT * FROM `audit_log`"+where+" ORDER BY id DESC LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
+	}
+	return entries, nil
 }
 
-func (r *DBRepo) GuestArrived(ctx context.Context, guest *entities.Guest) error {
-	guestArrival, err := r.GetGuestByName(ctx, guest)
-	if err != nil {
-		if err == errGuestNotFound {
-			return errGuestNeverRSVP
-		}
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting guest arrival record, returning error
-		return errDBErr
+// auditFilterClause builds the WHERE clause matching an AuditFilter.
+func auditFilterClause(filter *entities.AuditFilter) (string, []interface{}) {
+	if filter == nil {
+		return "", nil
 	}
-	if guestArrival.TotalArrivedGuests != 0 {
-		return errGuestAlreadyArrived
+	conds := []string{}
+	args := []interface{}{}
+	if filter.Actor != "" {
+		conds = append(conds, "actor = ?")
+		args = append(args, filter.Actor)
 	}
-	table, err := r.GetTable(ctx, guestArrival.TableID)
-	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting guest RSVP record, returning error
-		return errDBErr
+	if filter.RequestID != "" {
+		conds = append(conds, "request_id = ?")
+		args = append(args, filter.RequestID)
 	}
-	if table.AvailableCapacity < guest.TotalArrivedGuests {
-		// Table capacity less than number of guests
-		return errTableIsFull
+	if filter.Operation != "" {
+		conds = append(conds, "operation = ?")
+		args = append(args, filter.Operation)
 	}
-	tx, err := r.db.BeginTxx(ctx, nil)
-	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error starting transaction
-		return errDBErr
+	if filter.Target != "" {
+		conds = append(conds, "target = ?")
+		args = append(args, filter.Target)
 	}
-	// Able to accomodate guests, checking-in guest
-	res, err := tx.ExecContext(ctx, "UPDATE `guests` SET total_arrived_guests=?, version = version + 1, arrivaltime=NOW() WHERE id = ? AND version = ?", guest.TotalArrivedGuests, guestArrival.ID, guestArrival.Version)
-	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		tx.Rollback()
-		return errDBErr
+	if !filter.Since.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, filter.Since)
 	}
-	c, err := res.RowsAffected()
-	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting optimistic lock data for table
-		tx.Rollback()
-		return errDBErr
+	if !filter.Until.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, filter.Until)
 	}
-	if c != 1 {
-		// Unable to secure optimistic lock for table
-		tx.Rollback()
-		return errFailedOptimisticLock
+	if len(conds) == 0 {
+		return "", nil
 	}
-	// Calculate new capacity
-	table.AvailableCapacity -= guest.TotalArrivedGuests
-	// Update table capacity information
-	res, err = tx.ExecContext(ctx, "UPDATE `table` SET acapacity=?, version = version + 1 WHERE id = ? AND version = ?", table.AvailableCapacity, table.TableID, table.Version)
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// ReserveIdempotencyKey claims an idempotency key for a request until ttl elapses, an expired claim of the key is dropped first.
+// ErrIdempotencyKeyExists is returned while the key is claimed.
+func (r *DBRepo) ReserveIdempotencyKey(ctx context.Context, record *entities.IdempotencyRecord, ttl time.Duration) error {
+	defer observeQuery("ReserveIdempotencyKey")()
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error updating table capacity information
-		tx.Rollback()
-		return errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return ErrDBErr
 	}
-	c, err = res.RowsAffected()
+	_, err = tx.ExecContext(ctx, "DELETE FROM `idempotency_keys` WHERE actor = ? AND idempotency_key = ? AND expires_at <= NOW()", record.Actor, record.Key)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting optimistic lock data for table
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
 		tx.Rollback()
-		return errDBErr
+		return ErrDBErr
 	}
-	if c != 1 {
-		// Unable to secure optimistic lock for table
+	_, err = tx.ExecContext(ctx, "INSERT INTO `idempotency_keys` (actor, idempotency_key, request_hash, expires_at) VALUES(?, ?, ?, NOW() + INTERVAL ? SECOND)", record.Actor, record.Key, record.RequestHash, int64(ttl/time.Second))
+	if err != nil {
 		tx.Rollback()
-		return errFailedOptimisticLock
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDupEntry {
+			return ErrIdempotencyKeyExists
+		}
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return ErrDBErr
 	}
-	// All ok, commiting transaction
 	err = tx.Commit()
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error commiting transaction
-		return errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return ErrDBErr
 	}
 	return nil
 }
 
-func (r *DBRepo) GuestDepart(ctx context.Context, guest *entities.Guest) error {
-	guestArrival, err := r.GetGuestByName(ctx, guest)
-	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting guest arrival record, returning error
-		return errDBErr
-	}
-	if guestArrival.TotalArrivedGuests == 0 {
-		return errGuestNotArrived
-	}
-	table, err := r.GetTable(ctx, guestArrival.TableID)
-	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting guest RSVP record, returning error
-		return errDBErr
-	}
-	tx, err := r.db.BeginTxx(ctx, nil)
-	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error starting transaction
-		return errDBErr
-	}
-	// Able to accomodate guests, checking-in guest
-	res, err := tx.ExecContext(ctx, "UPDATE `guests` SET total_arrived_guests=0, version = version + 1, arrivaltime=\"\" WHERE id = ? AND version = ?", guestArrival.ID, guestArrival.Version)
+// GetIdempotencyRecord retrieves the unexpired record of an idempotency key.
+func (r *DBRepo) GetIdempotencyRecord(ctx context.Context, actor, key string) (*entities.IdempotencyRecord, error) {
+	defer observeQuery("GetIdempotencyRecord")()
+	record := entities.IdempotencyRecord{}
+	err := r.db.GetContext(ctx, &record, "SELECT * FROM `idempotency_keys` WHERE actor = ? AND idempotency_key = ? AND expires_at > NOW()", actor, key)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error creating check-in record for guest
-		tx.Rollback()
-		return errDBErr
-	}
-	c, err := res.RowsAffected()
-	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting optimistic lock data for table
-		tx.Rollback()
-		return errDBErr
-	}
-	if c != 1 {
-		// Unable to secure optimistic lock for table
-		tx.Rollback()
-		return errFailedOptimisticLock
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIdempotencyKeyNotFound
+		}
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
 	}
-	table.AvailableCapacity += guestArrival.TotalArrivedGuests
-	res, err = tx.ExecContext(ctx, "UPDATE `table` SET acapacity=?, version = version + 1 WHERE id = ? AND version = ?", table.AvailableCapacity, table.TableID, table.Version)
+	return &record, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request that claimed an idempotency key.
+func (r *DBRepo) CompleteIdempotencyKey(ctx context.Context, record *entities.IdempotencyRecord) error {
+	defer observeQuery("CompleteIdempotencyKey")()
+	_, err := r.db.ExecContext(ctx, "UPDATE `idempotency_keys` SET status = ?, content_type = ?, body = ? WHERE actor = ? AND idempotency_key = ?", record.Status, record.ContentType, record.Body, record.Actor, record.Key)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error updating table capacity information
-		tx.Rollback()
-		return errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return ErrDBErr
 	}
-	c, err = res.RowsAffected()
+	return nil
+}
+
+// ReleaseIdempotencyKey drops the claim on an idempotency key whose request did not complete, so that it can be retried.
+func (r *DBRepo) ReleaseIdempotencyKey(ctx context.Context, actor, key string) error {
+	defer observeQuery("ReleaseIdempotencyKey")()
+	_, err := r.db.ExecContext(ctx, "DELETE FROM `idempotency_keys` WHERE actor = ? AND idempotency_key = ? AND status = 0", actor, key)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error getting optimistic lock data for table
-		tx.Rollback()
-		return errDBErr
-	}
-	if c != 1 {
-		// Unable to secure optimistic lock for table
-		tx.Rollback()
-		return errFailedOptimisticLock
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return ErrDBErr
 	}
-	// All ok, commiting transaction
-	err = tx.Commit()
+	return nil
+}
+
+// Ping verifies that the database is reachable.
+func (r *DBRepo) Ping(ctx context.Context) error {
+	defer observeQuery("Ping")()
+	err := r.db.PingContext(ctx)
 	if err != nil {
-		zap.L().Error(errDBErr.Error(), zap.Error(err))
-		// Error commiting transaction
-		return errDBErr
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return ErrDBErr
 	}
 	return nil
 }
+
+// MissingSchema returns the tables, columns and unique keys of the schema that are missing from
+// the database, e.g. when a script in sql/migrations has not been run. Missing columns are
+// reported as "table.column" and missing unique keys as "table unique key name".
+func (r *DBRepo) MissingSchema(ctx context.Context) ([]string, error) {
+	defer observeQuery("MissingSchema")()
+	type schemaRow struct {
+		Table  string `db:"tbl"`
+		Column string `db:"col"`
+	}
+	columns := []schemaRow{}
+	err := r.db.SelectContext(ctx, &columns, "SELECT table_name AS tbl, column_name AS col FROM information_schema.columns WHERE table_schema = DATABASE()")
+	if err != nil {
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
+	}
+	keys := []schemaRow{}
+	err = r.db.SelectContext(ctx, &keys, "SELECT DISTINCT table_name AS tbl, index_name AS col FROM information_schema.statistics WHERE table_schema = DATABASE() AND non_unique = 0")
+	if err != nil {
+		logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+		return nil, ErrDBErr
+	}
+	found := map[string]bool{}
+	for _, c := range columns {
+		found[c.Table] = true
+		found[c.Table+"."+c.Column] = true
+	}
+	for _, k := range keys {
+		found[k.Table+" unique key "+k.Column] = true
+	}
+	missing := []string{}
+	for _, t := range schema {
+		if !found[t.name] {
+			missing = append(missing, t.name)
+			continue
+		}
+		for _, c := range t.columns {
+			if !found[t.name+"."+c] {
+				missing = append(missing, t.name+"."+c)
+			}
+		}
+		for _, k := range t.uniqueKeys {
+			if !found[t.name+" unique key "+k] {
+				missing = append(missing, t.name+" unique key "+k)
+			}
+		}
+	}
+	return missing, nil
+}
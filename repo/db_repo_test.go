@@ -3,16 +3,22 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"log"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	sqlxmock "github.com/zhashkevych/go-sqlxmock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 
 	"ggv2/entities"
+	"ggv2/logger"
 )
 
 func NewMockDb() (*sqlx.DB, sqlxmock.Sqlmock) {
@@ -23,68 +29,6 @@ func NewMockDb() (*sqlx.DB, sqlxmock.Sqlmock) {
 	return db, mock
 }
 
-func TestCreateTable(t *testing.T) {
-	query := regexp.QuoteMeta("INSERT INTO `table` (capacity, pcapacity, acapacity) VALUES(?, ?, ?)")
-	type TestCase struct {
-		name          string
-		desc          string
-		input         *entities.Table
-		err           error
-		dbErr         bool
-		expRes        *entities.Table
-		expErr        error
-		lastInsertErr bool
-	}
-	testcases := []TestCase{
-		{
-			name: "Happy case",
-			desc: "Db return record",
-			input: &entities.Table{
-				Capacity: int64(7),
-			},
-			expRes: &entities.Table{
-				TableID:  99,
-				Capacity: int64(7),
-				Version:  0,
-			},
-		},
-		{
-			name: "Sad case",
-			desc: "Db return error",
-			input: &entities.Table{
-				Capacity: int64(7),
-			},
-			err:    fmt.Errorf("mock error"),
-			expErr: errDBErr,
-			dbErr:  true,
-		},
-		{
-			name: "Sad case",
-			desc: "LastInsertId return error",
-			input: &entities.Table{
-				Capacity: int64(7),
-			},
-			err:           fmt.Errorf("LastInsertId error"),
-			expErr:        errDBErr,
-			lastInsertErr: true,
-		},
-	}
-
-	for _, v := range testcases {
-		db, mock := NewMockDb()
-		repo := NewDbRepo(db)
-		if v.dbErr {
-			mock.ExpectExec(query).WillReturnError(v.err)
-		}
-		if v.lastInsertErr {
-			mock.ExpectExec(query).WillReturnResult(sqlxmock.NewErrorResult(v.err))
-		}
-		mock.ExpectExec(query).WillReturnResult(sqlxmock.NewResult(99, 1))
-		actRes, actErr := repo.CreateTable(context.Background(), v.input)
-		assert.Equal(t, v.expErr, actErr)
-		assert.Equal(t, v.expRes, actRes)
-	}
-}
 
 func TestGetEmptySeatsCount(t *testing.T) {
 	query := regexp.QuoteMeta("SELECT SUM(acapacity) FROM `table`")
@@ -107,7 +51,7 @@ func TestGetEmptySeatsCount(t *testing.T) {
 			desc:   "Db return error",
 			err:    fmt.Errorf("mock error"),
 			expRes: 0,
-			expErr: errDBErr,
+			expErr: ErrDBErr,
 		},
 	}
 	for _, v := range testcases {
@@ -161,14 +105,14 @@ func TestListTables(t *testing.T) {
 			desc:   "Db return error",
 			err:    fmt.Errorf("mock error"),
 			dbErr:  true,
-			expErr: errDBErr,
+			expErr: ErrDBErr,
 		},
 		{
 			name:   "Sad case",
 			desc:   "no table found",
 			dbErr:  true,
 			err:    sql.ErrNoRows,
-			expErr: errTableNotFound,
+			expErr: ErrTableNotFound,
 		},
 	}
 	for _, v := range testcases {
@@ -178,55 +122,52 @@ func TestListTables(t *testing.T) {
 			mock.ExpectQuery(query).WillReturnError(v.err)
 		}
 		mock.ExpectQuery(query).WillReturnRows(rows)
-		actRes, actErr := repo.ListTables(context.Background(), 10, 0)
+		actRes, actErr := repo.ListTables(context.Background(), nil, 10, 0)
 		assert.Equal(t, v.expErr, actErr)
 		assert.Equal(t, v.expRes, actRes)
 	}
 }
 
-func TestEmptyTables(t *testing.T) {
+
+func TestListArchives(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT id, event, tables, guests, created_at, restored FROM `archives` ORDER BY id DESC")
 	type TestCase struct {
-		name             string
-		desc             string
-		err              error
-		expErr           error
-		truncateTableErr bool
-		truncateGuestErr bool
+		name   string
+		desc   string
+		err    error
+		expRes []*entities.Archive
+		expErr error
 	}
 	testcases := []TestCase{
 		{
 			name: "Happy case",
-			desc: "tables truncated",
-		},
-		{
-			name:             "Sad case",
-			desc:             "Truncate `table` return error",
-			err:              fmt.Errorf("mock error"),
-			expErr:           errDBErr,
-			truncateTableErr: true,
+			desc: "Db return records",
+			expRes: []*entities.Archive{
+				{ID: 2, Event: "gala", Tables: 10, Guests: 40, CreatedAt: "2021-06-01 20:00:00"},
+				{ID: 1, Tables: 3, Guests: 5, CreatedAt: "2021-05-01 20:00:00", Restored: true},
+			},
 		},
 		{
-			name:             "Sad case",
-			desc:             "Truncate `guests` return error",
-			err:              fmt.Errorf("mock error"),
-			expErr:           errDBErr,
-			truncateGuestErr: true,
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
 		},
 	}
 	for _, v := range testcases {
 		db, mock := NewMockDb()
 		repo := NewDbRepo(db)
-		if v.truncateTableErr {
-			mock.ExpectExec(regexp.QuoteMeta("TRUNCATE TABLE `table`;")).WillReturnError(v.err)
-		}
-		mock.ExpectExec(regexp.QuoteMeta("TRUNCATE TABLE `table`;")).WillReturnResult(sqlxmock.NewResult(1, 1))
-		if v.truncateGuestErr {
-			mock.ExpectExec(regexp.QuoteMeta("TRUNCATE TABLE `guests`;")).WillReturnError(v.err)
+		if v.err != nil {
+			mock.ExpectQuery(query).WillReturnError(v.err)
+		} else {
+			rows := sqlxmock.NewRows([]string{"id", "event", "tables", "guests", "created_at", "restored"}).
+				AddRow(2, "gala", 10, 40, "2021-06-01 20:00:00", false).
+				AddRow(1, "", 3, 5, "2021-05-01 20:00:00", true)
+			mock.ExpectQuery(query).WillReturnRows(rows)
 		}
-		mock.ExpectExec(regexp.QuoteMeta("TRUNCATE TABLE `guests`;")).WillReturnResult(sqlxmock.NewResult(1, 1))
-
-		actErr := repo.EmptyTables(context.Background())
-		assert.Equal(t, v.expErr, actErr)
+		actRes, actErr := repo.ListArchives(context.Background())
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
 	}
 }
 
@@ -256,13 +197,13 @@ func TestGetGuestByName(t *testing.T) {
 			name:   "Sad case",
 			desc:   "Db return error",
 			err:    fmt.Errorf("mock error"),
-			expErr: errDBErr,
+			expErr: ErrDBErr,
 		},
 		{
 			name:   "Sad case",
 			desc:   "guest not found",
 			err:    sql.ErrNoRows,
-			expErr: errGuestNotFound,
+			expErr: ErrGuestNotFound,
 		},
 	}
 	for _, v := range testcases {
@@ -304,13 +245,13 @@ func TestGetTable(t *testing.T) {
 			name:   "Sad case",
 			desc:   "Db return error",
 			err:    fmt.Errorf("mock error"),
-			expErr: errDBErr,
+			expErr: ErrDBErr,
 		},
 		{
 			name:   "Sad case",
 			desc:   "table not found",
 			err:    sql.ErrNoRows,
-			expErr: errTableNotFound,
+			expErr: ErrTableNotFound,
 		},
 	}
 	for _, v := range testcases {
@@ -357,13 +298,13 @@ func TestListArrivedGuest(t *testing.T) {
 			name:   "Sad case",
 			desc:   "Db return error",
 			err:    fmt.Errorf("mock error"),
-			expErr: errDBErr,
+			expErr: ErrDBErr,
 		},
 		{
 			name:   "Sad case",
 			desc:   "no guest found",
 			err:    sql.ErrNoRows,
-			expErr: errGuestNotFound,
+			expErr: ErrGuestNotFound,
 		},
 	}
 	for _, v := range testcases {
@@ -410,13 +351,13 @@ func TestListGuests(t *testing.T) {
 			name:   "Sad case",
 			desc:   "Db return error",
 			err:    fmt.Errorf("mock error"),
-			expErr: errDBErr,
+			expErr: ErrDBErr,
 		},
 		{
 			name:   "Sad case",
 			desc:   "guest not found",
 			err:    sql.ErrNoRows,
-			expErr: errGuestNotFound,
+			expErr: ErrGuestNotFound,
 		},
 	}
 	for _, v := range testcases {
@@ -433,550 +374,930 @@ func TestListGuests(t *testing.T) {
 	}
 }
 
-func TestGuestArrived(t *testing.T) {
-	getTableQuery := regexp.QuoteMeta("SELECT * FROM `table` WHERE id=?")
-	updateGuestQuery := regexp.QuoteMeta("UPDATE `guests` SET total_arrived_guests=?, version = version + 1, arrivaltime=NOW() WHERE id = ? AND version = ?")
-	updateTableQuery := regexp.QuoteMeta("UPDATE `table` SET acapacity=?, version = version + 1 WHERE id = ? AND version = ?")
+func TestListPartyMembers(t *testing.T) {
 	getGuestByNameQuery := regexp.QuoteMeta("SELECT * FROM `guests` WHERE name = ?")
+	query := regexp.QuoteMeta("SELECT * FROM `party_members` WHERE guestid = ?")
 	type TestCase struct {
-		name                         string
-		desc                         string
-		err                          error
-		getTableRows                 *sqlxmock.Rows
-		getGuestByNameRows           *sqlxmock.Rows
-		getGuestByNameErr            bool
-		getTableErr                  bool
-		beginTxErr                   bool
-		updateGuestErr               bool
-		updateGuestRowsAffectedErr   bool
-		updateGuestOptimisticLockErr bool
-		updateTableErr               bool
-		updateTableRowsAffectedErr   bool
-		updateTableOptimisticLockErr bool
-		commitErr                    bool
-		expErr                       error
+		name              string
+		desc              string
+		err               error
+		getGuestByNameErr bool
+		expRes            []*entities.PartyMember
+		expErr            error
 	}
 	testcases := []TestCase{
 		{
-			name:               "Happy case",
-			desc:               "all ok, no error",
-			getGuestByNameRows: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRows:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 3, 2),
-		},
-		{
-			name:               "Sad case",
-			desc:               "commit error",
-			err:                fmt.Errorf("dummy error"),
-			commitErr:          true,
-			getGuestByNameRows: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRows:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 3, 2),
-			expErr:             errDBErr,
-		},
-		{
-			name:               "Sad case",
-			desc:               "update table error",
-			err:                fmt.Errorf("dummy error"),
-			updateTableErr:     true,
-			getGuestByNameRows: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRows:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 3, 2),
-			expErr:             errDBErr,
+			name: "Happy case",
+			desc: "Db return record",
+			expRes: []*entities.PartyMember{
+				{
+					ID:       1,
+					GuestID:  3,
+					Name:     "alice",
+					AgeGroup: entities.AgeGroupChild,
+					Arrived:  true,
+				},
+			},
 		},
 		{
-			name:                       "Sad case",
-			desc:                       "update table rows affected error",
-			err:                        fmt.Errorf("rows affected err"),
-			updateTableRowsAffectedErr: true,
-			getGuestByNameRows:         sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRows:               sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 3, 2),
-			expErr:                     errDBErr,
+			name:              "Sad case",
+			desc:              "guest not found",
+			err:               sql.ErrNoRows,
+			getGuestByNameErr: true,
+			expErr:            ErrGuestNotFound,
 		},
 		{
-			name:                         "Sad case",
-			desc:                         "update table optimistic lock error",
-			updateTableOptimisticLockErr: true,
-			getGuestByNameRows:           sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRows:                 sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 3, 2),
-			expErr:                       errFailedOptimisticLock,
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
 		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		if v.getGuestByNameErr {
+			mock.ExpectQuery(getGuestByNameQuery).WillReturnError(v.err)
+		} else {
+			mock.ExpectQuery(getGuestByNameQuery).WillReturnRows(sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "tableid"}).AddRow(3, "dummy", 2, 0, 5))
+		}
+		if v.err != nil {
+			mock.ExpectQuery(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectQuery(query).WithArgs(3).WillReturnRows(sqlxmock.NewRows([]string{"id", "guestid", "name", "age_group", "arrived"}).AddRow(1, 3, "alice", entities.AgeGroupChild, true))
+		}
+		actRes, actErr := repo.ListPartyMembers(context.Background(), &entities.Guest{Name: "dummy"})
+		assert.Equal(t, v.expErr, actErr)
+		assert.Equal(t, v.expRes, actRes)
+	}
+}
+
+func TestListGuestsWithMembers(t *testing.T) {
+	type TestCase struct {
+		name          string
+		desc          string
+		event         string
+		err           error
+		getGuestsErr  bool
+		getMembersErr bool
+		expRes        []*entities.Guest
+		expErr        error
+	}
+	testcases := []TestCase{
 		{
-			name:               "Sad case",
-			desc:               "update guest error",
-			err:                fmt.Errorf("dummy error"),
-			updateGuestErr:     true,
-			getGuestByNameRows: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRows:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 3, 2),
-			expErr:             errDBErr,
+			name: "Happy case",
+			desc: "members attached to guests",
+			expRes: []*entities.Guest{
+				{
+					ID:          1,
+					Name:        "dummy",
+					TableID:     5,
+					TotalGuests: 2,
+					DietaryTags: entities.Tags{entities.DietVegan, entities.DietGlutenFree},
+					MealChoice:  "tofu",
+					Members: []*entities.PartyMember{
+						{
+							ID:        3,
+							GuestID:   1,
+							Name:      "alice",
+							Allergens: entities.Tags{"peanut"},
+						},
+					},
+				},
+			},
 		},
 		{
-			name:                       "Sad case",
-			desc:                       "update guest rows affected error",
-			err:                        fmt.Errorf("rows affected err"),
-			updateGuestRowsAffectedErr: true,
-			getGuestByNameRows:         sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRows:               sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 3, 2),
-			expErr:                     errDBErr,
+			name:  "Happy case",
+			desc:  "guests of an event",
+			event: "gala",
+			expRes: []*entities.Guest{
+				{
+					ID:          1,
+					Name:        "dummy",
+					TableID:     5,
+					TotalGuests: 2,
+					DietaryTags: entities.Tags{entities.DietVegan, entities.DietGlutenFree},
+					MealChoice:  "tofu",
+					Members: []*entities.PartyMember{
+						{
+							ID:        3,
+							GuestID:   1,
+							Name:      "alice",
+							Allergens: entities.Tags{"peanut"},
+						},
+					},
+				},
+			},
 		},
 		{
-			name:                         "Sad case",
-			desc:                         "update guest optimistic lock error",
-			updateGuestOptimisticLockErr: true,
-			getGuestByNameRows:           sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRows:                 sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 3, 2),
-			expErr:                       errFailedOptimisticLock,
+			name:         "Sad case",
+			desc:         "get guests returns error",
+			err:          fmt.Errorf("mock error"),
+			getGuestsErr: true,
+			expErr:       ErrDBErr,
 		},
 		{
-			name:               "Sad case",
-			desc:               "begin tx error",
-			err:                fmt.Errorf("tx error"),
-			beginTxErr:         true,
-			getGuestByNameRows: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRows:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 3, 2),
-			expErr:             errDBErr,
+			name:          "Sad case",
+			desc:          "get members returns error",
+			err:           fmt.Errorf("mock error"),
+			getMembersErr: true,
+			expErr:        ErrDBErr,
 		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		guestsQuery, membersQuery, args := regexp.QuoteMeta(snapshotQueries[1]), regexp.QuoteMeta(snapshotQueries[2]), []driver.Value{}
+		if v.event != "" {
+			guestsQuery, membersQuery, args = regexp.QuoteMeta(eventSnapshotQueries[1]), regexp.QuoteMeta(eventSnapshotQueries[2]), []driver.Value{v.event}
+		}
+		if v.getGuestsErr {
+			mock.ExpectQuery(guestsQuery).WillReturnError(v.err)
+		}
+		mock.ExpectQuery(guestsQuery).WithArgs(args...).WillReturnRows(sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "tableid", "dietary_tags", "meal_choice"}).AddRow(1, "dummy", 2, 5, "vegan,gluten_free", "tofu"))
+		if v.getMembersErr {
+			mock.ExpectQuery(membersQuery).WillReturnError(v.err)
+		}
+		mock.ExpectQuery(membersQuery).WithArgs(args...).WillReturnRows(sqlxmock.NewRows([]string{"id", "guestid", "name", "allergens"}).AddRow(3, 1, "alice", "peanut"))
+		actRes, actErr := repo.ListGuestsWithMembers(context.Background(), v.event)
+		assert.Equal(t, v.expErr, actErr)
+		assert.Equal(t, v.expRes, actRes)
+	}
+}
+
+func TestListTablesWithFilter(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `table` WHERE event = ? AND zone = ? AND shape = ? AND FIND_IN_SET(?, tags) > 0 LIMIT ? OFFSET ?")
+	rows := sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version", "name", "zone", "shape", "tags"}).AddRow(1, 6, 6, 6, 0, "12", "Garden", "round", "vip,window")
+	db, mock := NewMockDb()
+	repo := NewDbRepo(db)
+	mock.ExpectQuery(query).WithArgs("gala", "Garden", "round", "vip", 10, 0).WillReturnRows(rows)
+	actRes, actErr := repo.ListTables(context.Background(), &entities.TableFilter{Event: "gala", Zone: "Garden", Shape: "round", Tag: "vip"}, 10, 0)
+	assert.Nil(t, actErr)
+	assert.Equal(t, []*entities.Table{
 		{
-			name:               "Sad case",
-			desc:               "get table returns db error",
-			err:                sql.ErrNoRows,
-			getGuestByNameRows: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRows:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 3, 2),
-			getTableErr:        true,
-			expErr:             errDBErr,
+			TableID:           1,
+			Capacity:          6,
+			AvailableCapacity: 6,
+			PlannedCapacity:   6,
+			Name:              "12",
+			Zone:              "Garden",
+			Shape:             "round",
+			Tags:              entities.Tags{"vip", "window"},
 		},
+	}, actRes)
+}
+
+func TestListAllTables(t *testing.T) {
+	type TestCase struct {
+		name   string
+		desc   string
+		event  string
+		err    error
+		expRes []*entities.Table
+		expErr error
+	}
+	testcases := []TestCase{
 		{
-			name:               "Sad case",
-			desc:               "table cannot accomodate guest",
-			getGuestByNameRows: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRows:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 0, 3, 2),
-			expErr:             errTableIsFull,
+			name: "Happy case",
+			desc: "Db return record",
+			expRes: []*entities.Table{
+				{
+					TableID:           1,
+					Capacity:          6,
+					AvailableCapacity: 4,
+					PlannedCapacity:   2,
+					X:                 100,
+					Y:                 200,
+					Rotation:          90,
+				},
+			},
 		},
 		{
-			name:               "Sad case",
-			desc:               "guest already arrived",
-			err:                nil,
-			getGuestByNameRows: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
-			getTableRows:       sqlxmock.NewRows([]string{}),
-			expErr:             errGuestAlreadyArrived,
+			name:  "Happy case",
+			desc:  "tables of an event",
+			event: "gala",
+			expRes: []*entities.Table{
+				{
+					TableID:           1,
+					Event:             "gala",
+					Capacity:          6,
+					AvailableCapacity: 4,
+					PlannedCapacity:   2,
+					X:                 100,
+					Y:                 200,
+					Rotation:          90,
+				},
+			},
 		},
 		{
-			name:               "Sad case",
-			desc:               "guest not found",
-			err:                sql.ErrNoRows,
-			getGuestByNameRows: sqlxmock.NewRows([]string{}),
-			getGuestByNameErr:  true,
-			getTableRows:       sqlxmock.NewRows([]string{}),
-			expErr:             errGuestNeverRSVP,
-		},
-		{
-			name:               "Sad case",
-			desc:               "get guest return db error",
-			err:                fmt.Errorf("dummy error"),
-			getGuestByNameRows: sqlxmock.NewRows([]string{}),
-			getGuestByNameErr:  true,
-			getTableRows:       sqlxmock.NewRows([]string{}),
-			expErr:             errDBErr,
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
 		},
 	}
 	for _, v := range testcases {
 		db, mock := NewMockDb()
 		repo := NewDbRepo(db)
-		if v.getGuestByNameErr {
-			mock.ExpectQuery(getGuestByNameQuery).WillReturnError(v.err)
-		}
-		mock.ExpectQuery(getGuestByNameQuery).WillReturnRows(v.getGuestByNameRows)
-		if v.getTableErr {
-			mock.ExpectQuery(getTableQuery).WillReturnError(v.err)
-		}
-		mock.ExpectQuery(getTableQuery).WillReturnRows(v.getTableRows)
-		if v.beginTxErr {
-			mock.ExpectBegin().WillReturnError(v.err)
-		}
-		mock.ExpectBegin()
-
-		if v.updateGuestErr {
-			mock.ExpectExec(updateGuestQuery).WillReturnError(v.err)
-			mock.ExpectRollback()
+		query, args := regexp.QuoteMeta(snapshotQueries[0]), []driver.Value{}
+		if v.event != "" {
+			query, args = regexp.QuoteMeta(eventSnapshotQueries[0]), []driver.Value{v.event}
 		}
-		if v.updateGuestRowsAffectedErr {
-			mock.ExpectExec(updateGuestQuery).WillReturnResult(sqlxmock.NewErrorResult(v.err))
-			mock.ExpectRollback()
-		}
-		if v.updateGuestOptimisticLockErr {
-			mock.ExpectExec(updateGuestQuery).WillReturnResult(sqlxmock.NewResult(0, 0))
-			mock.ExpectRollback()
-		}
-		mock.ExpectExec(updateGuestQuery).WillReturnResult(sqlxmock.NewResult(1, 1))
-		if v.updateTableErr {
-			mock.ExpectExec(updateTableQuery).WillReturnError(v.err)
-			mock.ExpectRollback()
-		}
-		if v.updateTableRowsAffectedErr {
-			mock.ExpectExec(updateTableQuery).WillReturnResult(sqlxmock.NewErrorResult(v.err))
-			mock.ExpectRollback()
-		}
-		if v.updateTableOptimisticLockErr {
-			mock.ExpectExec(updateTableQuery).WillReturnResult(sqlxmock.NewResult(0, 0))
-			mock.ExpectRollback()
-		}
-		mock.ExpectExec(updateTableQuery).WillReturnResult(sqlxmock.NewResult(1, 1))
-		if v.commitErr {
-			mock.ExpectCommit().WillReturnError(v.err)
+		if v.err != nil {
+			mock.ExpectQuery(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectQuery(query).WithArgs(args...).WillReturnRows(sqlxmock.NewRows([]string{"id", "event", "capacity", "acapacity", "pcapacity", "pos_x", "pos_y", "rotation"}).AddRow(1, v.event, 6, 4, 2, 100, 200, 90))
 		}
-		mock.ExpectCommit()
-		actErr := repo.GuestArrived(context.Background(), &entities.Guest{Name: "dummy", TotalArrivedGuests: 1})
+		actRes, actErr := repo.ListAllTables(context.Background(), v.event)
 		assert.Equal(t, v.expErr, actErr)
+		assert.Equal(t, v.expRes, actRes)
 	}
 }
 
-func TestAddToGuestList(t *testing.T) {
-	getGuestByNameQuery := regexp.QuoteMeta("SELECT * FROM `guests` WHERE name = ?")
-	getTableQuery := regexp.QuoteMeta("SELECT * FROM `table` WHERE id=?")
-	insertGuestQuery := regexp.QuoteMeta("INSERT INTO `guests` (total_rsvp_guests, tableid, name) VALUES(?, ?, ?)")
-	updateTableQuery := regexp.QuoteMeta("UPDATE `table` SET pcapacity=?, version = version + 1 WHERE id = ? AND version = ?")
+
+
+func TestGetLayoutTemplate(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `layout_templates` WHERE name=?")
 	type TestCase struct {
-		name                 string
-		desc                 string
-		err                  error
-		getTableRows         *sqlxmock.Rows
-		beginTxErr           bool
-		optimisticLockErr    bool
-		rowsAffectedErr      bool
-		commitErr            bool
-		expErr               error
-		getGuestByNameErr    bool
-		getTableErr          bool
-		insertGuestErr       bool
-		insertGuestLastIdErr bool
-		updateTableErr       bool
-		getGuestByNameRow    *sqlxmock.Rows
+		name   string
+		desc   string
+		err    error
+		expRes *entities.LayoutTemplate
+		expErr error
 	}
 	testcases := []TestCase{
 		{
-			name:              "Happy case",
-			desc:              "all ok, no error",
-			getGuestByNameRow: sqlxmock.NewRows([]string{}),
-			getTableRows:      sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
+			name: "Happy case",
+			desc: "Db return record",
+			expRes: &entities.LayoutTemplate{
+				ID:      1,
+				Name:    "wedding",
+				Groups:  entities.TableGroups{{Count: 10, Capacity: 8}, {Count: 4, Capacity: 12, Shape: "rectangle"}},
+				Version: 2,
+			},
 		},
 		{
-			name:              "Sad case",
-			desc:              "get guest returns error",
-			err:               fmt.Errorf("mock error"),
-			getGuestByNameRow: sqlxmock.NewRows([]string{}),
-			getGuestByNameErr: true,
-			expErr:            errDBErr,
-			getTableRows:      sqlxmock.NewRows([]string{}),
+			name:   "Sad case",
+			desc:   "template not found",
+			err:    sql.ErrNoRows,
+			expErr: ErrLayoutTemplateNotFound,
 		},
 		{
-			name:              "Sad case",
-			desc:              "get guest returns error",
-			err:               fmt.Errorf("mock error"),
-			getGuestByNameRow: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
-			expErr:            errGuestAlreadyRSVP,
-			getTableRows:      sqlxmock.NewRows([]string{}),
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
 		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		if v.err != nil {
+			mock.ExpectQuery(query).WillReturnError(v.err)
+		} else {
+			rows := sqlxmock.NewRows([]string{"id", "name", "layout", "version"}).AddRow(1, "wedding", `[{"count":10,"capacity":8},{"count":4,"capacity":12,"shape":"rectangle"}]`, 2)
+			mock.ExpectQuery(query).WithArgs("wedding").WillReturnRows(rows)
+		}
+		actRes, actErr := repo.GetLayoutTemplate(context.Background(), "wedding")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
+	}
+}
+
+func TestListLayoutTemplates(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `layout_templates` ORDER BY name")
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expRes []*entities.LayoutTemplate
+		expErr error
+	}
+	testcases := []TestCase{
 		{
-			name:              "Sad case",
-			desc:              "get table returns error",
-			getGuestByNameRow: sqlxmock.NewRows([]string{}),
-			getTableRows:      sqlxmock.NewRows([]string{}),
-			getTableErr:       true,
-			err:               fmt.Errorf("mock error"),
-			expErr:            errDBErr,
+			name: "Happy case",
+			desc: "Db return records",
+			expRes: []*entities.LayoutTemplate{
+				{ID: 1, Name: "gala", Groups: entities.TableGroups{{Count: 20, Capacity: 10}}},
+			},
 		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		if v.err != nil {
+			mock.ExpectQuery(query).WillReturnError(v.err)
+		} else {
+			rows := sqlxmock.NewRows([]string{"id", "name", "layout", "version"}).AddRow(1, "gala", `[{"count":20,"capacity":10}]`, 0)
+			mock.ExpectQuery(query).WillReturnRows(rows)
+		}
+		actRes, actErr := repo.ListLayoutTemplates(context.Background())
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
+	}
+}
+
 
+
+func TestGetAPIKeyByHash(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `api_keys` WHERE key_hash=?")
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expRes *entities.APIKey
+		expErr error
+	}
+	testcases := []TestCase{
 		{
-			name:              "Sad case",
-			desc:              "table cannot accomodate",
-			err:               fmt.Errorf("mock error"),
-			getGuestByNameRow: sqlxmock.NewRows([]string{}),
-			expErr:            errTableIsFull,
-			getTableRows:      sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 0, 2),
+			name:   "Happy case",
+			desc:   "Db return record",
+			expRes: &entities.APIKey{ID: 7, Name: "kiosk", Prefix: "ggv2_abcdefg", Role: "viewer", KeyHash: "hash", CreatedAt: "2021-06-04 04:06:44", Revoked: true},
 		},
 		{
-			name:              "Sad case",
-			desc:              "begin tx return error",
-			err:               fmt.Errorf("mock error"),
-			getGuestByNameRow: sqlxmock.NewRows([]string{}),
-			expErr:            errDBErr,
-			getTableRows:      sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			beginTxErr:        true,
+			name:   "Sad case",
+			desc:   "key not found",
+			err:    sql.ErrNoRows,
+			expErr: ErrAPIKeyNotFound,
 		},
 		{
-			name:              "Sad case",
-			desc:              "insert guest return error",
-			err:               fmt.Errorf("mock error"),
-			getGuestByNameRow: sqlxmock.NewRows([]string{}),
-			expErr:            errDBErr,
-			getTableRows:      sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			insertGuestErr:    true,
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
 		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		if v.err != nil {
+			mock.ExpectQuery(query).WillReturnError(v.err)
+		} else {
+			rows := sqlxmock.NewRows([]string{"id", "name", "prefix", "role", "key_hash", "created_at", "revoked"}).AddRow(7, "kiosk", "ggv2_abcdefg", "viewer", "hash", "2021-06-04 04:06:44", 1)
+			mock.ExpectQuery(query).WithArgs("hash").WillReturnRows(rows)
+		}
+		actRes, actErr := repo.GetAPIKeyByHash(context.Background(), "hash")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
+	}
+}
+
+func TestListAPIKeys(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `api_keys` ORDER BY id")
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expRes []*entities.APIKey
+		expErr error
+	}
+	testcases := []TestCase{
 		{
-			name:                 "Sad case",
-			desc:                 "last insert id return error",
-			err:                  fmt.Errorf("last insert id error"),
-			getGuestByNameRow:    sqlxmock.NewRows([]string{}),
-			expErr:               errDBErr,
-			getTableRows:         sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			insertGuestLastIdErr: true,
+			name:   "Happy case",
+			desc:   "Db return records",
+			expRes: []*entities.APIKey{{ID: 7, Name: "kiosk", Prefix: "ggv2_abcdefg", Role: "viewer", KeyHash: "hash", CreatedAt: "2021-06-04 04:06:44"}},
 		},
 		{
-			name:              "Sad case",
-			desc:              "update table return error",
-			err:               fmt.Errorf("mock error"),
-			getGuestByNameRow: sqlxmock.NewRows([]string{}),
-			expErr:            errDBErr,
-			getTableRows:      sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			updateTableErr:    true,
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
 		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		if v.err != nil {
+			mock.ExpectQuery(query).WillReturnError(v.err)
+		} else {
+			rows := sqlxmock.NewRows([]string{"id", "name", "prefix", "role", "key_hash", "created_at", "revoked"}).AddRow(7, "kiosk", "ggv2_abcdefg", "viewer", "hash", "2021-06-04 04:06:44", 0)
+			mock.ExpectQuery(query).WillReturnRows(rows)
+		}
+		actRes, actErr := repo.ListAPIKeys(context.Background())
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
+	}
+}
+
+
+func TestSnapshot(t *testing.T) {
+	type TestCase struct {
+		name      string
+		desc      string
+		event     string
+		err       error
+		beginErr  bool
+		selectErr bool
+		commitErr bool
+		expErr    error
+	}
+	testcases := []TestCase{
 		{
-			name:              "Sad case",
-			desc:              "rows affected return error",
-			err:               fmt.Errorf("mock error"),
-			getGuestByNameRow: sqlxmock.NewRows([]string{}),
-			expErr:            errDBErr,
-			getTableRows:      sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			rowsAffectedErr:   true,
+			name: "Happy case",
+			desc: "every event",
 		},
 		{
-			name:              "Sad case",
-			desc:              "optimistic lock error",
-			getGuestByNameRow: sqlxmock.NewRows([]string{}),
-			expErr:            errFailedOptimisticLock,
-			getTableRows:      sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			rowsAffectedErr:   true,
+			name:  "Happy case",
+			desc:  "single event",
+			event: "gala",
 		},
 		{
-			name:              "Sad case",
-			desc:              "commit error",
-			err:               fmt.Errorf("mock error"),
-			getGuestByNameRow: sqlxmock.NewRows([]string{}),
-			expErr:            errDBErr,
-			getTableRows:      sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			commitErr:         true,
+			name:     "Sad case",
+			desc:     "begin transaction return error",
+			err:      fmt.Errorf("mock error"),
+			beginErr: true,
+			expErr:   ErrDBErr,
+		},
+		{
+			name:      "Sad case",
+			desc:      "select return error",
+			err:       fmt.Errorf("mock error"),
+			selectErr: true,
+			expErr:    ErrDBErr,
+		},
+		{
+			name:      "Sad case",
+			desc:      "commit return error",
+			err:       fmt.Errorf("mock error"),
+			commitErr: true,
+			expErr:    ErrDBErr,
 		},
 	}
 	for _, v := range testcases {
 		db, mock := NewMockDb()
 		repo := NewDbRepo(db)
-		if v.getGuestByNameErr {
-			mock.ExpectQuery(getGuestByNameQuery).WillReturnError(v.err)
-		}
-		mock.ExpectQuery(getGuestByNameQuery).WillReturnRows(v.getGuestByNameRow)
-		if v.getTableErr {
-			mock.ExpectQuery(getTableQuery).WillReturnError(v.err)
-		}
-		mock.ExpectQuery(getTableQuery).WillReturnRows(v.getTableRows)
-		if v.beginTxErr {
-			mock.ExpectBegin().WillReturnError(v.err)
-		}
-		mock.ExpectBegin()
-		if v.insertGuestErr {
-			mock.ExpectExec(insertGuestQuery).WillReturnError(v.err)
-			mock.ExpectRollback()
-		}
-		if v.insertGuestLastIdErr {
-			mock.ExpectExec(insertGuestQuery).WillReturnResult(sqlxmock.NewErrorResult(v.err))
-			mock.ExpectRollback()
-		}
-		mock.ExpectExec(insertGuestQuery).WillReturnResult(sqlxmock.NewResult(1, 1))
-		if v.updateTableErr {
-			mock.ExpectExec(updateTableQuery).WillReturnError(v.err)
-			mock.ExpectRollback()
-		}
-		if v.rowsAffectedErr {
-			mock.ExpectExec(updateTableQuery).WillReturnResult(sqlxmock.NewErrorResult(v.err))
-			mock.ExpectRollback()
-		}
-		if v.optimisticLockErr {
-			mock.ExpectExec(updateTableQuery).WillReturnResult(sqlxmock.NewResult(0, 0))
-			mock.ExpectRollback()
+		selects, args := snapshotQueries, []driver.Value{}
+		if v.event != "" {
+			selects, args = eventSnapshotQueries, []driver.Value{v.event}
 		}
-		mock.ExpectExec(updateTableQuery).WillReturnResult(sqlxmock.NewResult(1, 1))
-		if v.commitErr {
-			mock.ExpectCommit().WillReturnError(v.err)
+		func() {
+			if v.beginErr {
+				mock.ExpectBegin().WillReturnError(v.err)
+				return
+			}
+			mock.ExpectBegin()
+			if v.selectErr {
+				mock.ExpectQuery(regexp.QuoteMeta(selects[0])).WillReturnError(v.err)
+				mock.ExpectRollback()
+				return
+			}
+			mock.ExpectQuery(regexp.QuoteMeta(selects[0])).WithArgs(args...).WillReturnRows(sqlxmock.NewRows([]string{"id", "capacity", "event"}).AddRow(1, 8, v.event))
+			mock.ExpectQuery(regexp.QuoteMeta(selects[1])).WithArgs(args...).WillReturnRows(sqlxmock.NewRows([]string{"id", "name", "tableid"}).AddRow(2, "bob", 1))
+			mock.ExpectQuery(regexp.QuoteMeta(selects[2])).WithArgs(args...).WillReturnRows(sqlxmock.NewRows([]string{"id", "guestid", "name"}).AddRow(3, 2, "alice"))
+			if v.commitErr {
+				mock.ExpectCommit().WillReturnError(v.err)
+				return
+			}
+			mock.ExpectCommit()
+		}()
+		actRes, actErr := repo.Snapshot(context.Background(), v.event)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expErr == nil {
+			assert.Equal(t, entities.SnapshotVersion, actRes.Version, v.desc)
+			assert.Equal(t, v.event, actRes.Event, v.desc)
+			assert.Equal(t, []*entities.Table{{TableID: 1, Capacity: 8, Event: v.event}}, actRes.Tables, v.desc)
+			assert.Equal(t, []*entities.Guest{{ID: 2, Name: "bob", TableID: 1}}, actRes.Guests, v.desc)
+			assert.Equal(t, []*entities.PartyMember{{ID: 3, GuestID: 2, Name: "alice"}}, actRes.PartyMembers, v.desc)
+		} else {
+			assert.Nil(t, actRes, v.desc)
 		}
-		mock.ExpectCommit()
-		actErr := repo.AddToGuestList(context.Background(), &entities.Guest{Name: "dummy", TableID: 1, TotalGuests: 2})
-		assert.Equal(t, v.expErr, actErr)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
 	}
 }
 
-func TestGuestDepart(t *testing.T) {
-	getGuestByNameQuery := regexp.QuoteMeta("SELECT * FROM `guests` WHERE name = ?")
-	getTableQuery := regexp.QuoteMeta("SELECT * FROM `table` WHERE id=?")
-	updateGuestQuery := regexp.QuoteMeta("UPDATE `guests` SET total_arrived_guests=0, version = version + 1, arrivaltime=\"\" WHERE id = ? AND version = ?")
-	updateTableQuery := regexp.QuoteMeta("UPDATE `table` SET acapacity=?, version = version + 1 WHERE id = ? AND version = ?")
+
+
+func TestListAuditEntries(t *testing.T) {
+	since := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC)
 	type TestCase struct {
-		name                         string
-		desc                         string
-		err                          error
-		getGuestByNameErr            bool
-		getGuestByNameRow            *sqlxmock.Rows
-		getTableErr                  bool
-		getTableRow                  *sqlxmock.Rows
-		updateGuestErr               bool
-		updateGuestRowAffectedErr    bool
-		updateGuestOptimisticLockErr bool
-		updateTableErr               bool
-		updateTableRowAffectedErr    bool
-		updateTableOptimisticLockErr bool
-		beginTxErr                   bool
-		commitErr                    bool
-		expErr                       error
+		name   string
+		desc   string
+		filter *entities.AuditFilter
+		query  string
+		args   []driver.Value
+		err    error
+		expErr error
 	}
 	testcases := []TestCase{
-
 		{
-			name:              "Happy case",
-			desc:              "all ok, no error",
-			getTableRow:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			getGuestByNameRow: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
+			name:  "Happy case",
+			desc:  "no filter",
+			query: "SELECT * FROM `audit_log` ORDER BY id DESC LIMIT ? OFFSET ?",
+			args:  []driver.Value{10, 0},
 		},
-
 		{
-			name:              "Sad case",
-			desc:              "get guest returns error",
-			err:               fmt.Errorf("mock error"),
-			getGuestByNameErr: true,
-			getGuestByNameRow: sqlxmock.NewRows([]string{}),
-			getTableRow:       sqlxmock.NewRows([]string{}),
-			expErr:            errDBErr,
+			name:   "Happy case",
+			desc:   "every filter",
+			filter: &entities.AuditFilter{Actor: "jwt:alice", RequestID: "req-1", Operation: "update_table", Target: "table:1", Since: since, Until: until},
+			query:  "SELECT * FROM `audit_log` WHERE actor = ? AND request_id = ? AND operation = ? AND target = ? AND created_at >= ? AND created_at < ? ORDER BY id DESC LIMIT ? OFFSET ?",
+			args:   []driver.Value{"jwt:alice", "req-1", "update_table", "table:1", since, until, 10, 0},
 		},
 		{
-			name:              "Sad case",
-			desc:              "guest not arrived",
-			getGuestByNameRow: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 0, 4, "", 5),
-			getTableRow:       sqlxmock.NewRows([]string{}),
-			expErr:            errGuestNotArrived,
+			name:   "Sad case",
+			desc:   "Db return error",
+			query:  "SELECT * FROM `audit_log` ORDER BY id DESC LIMIT ? OFFSET ?",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
 		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		var expRes []*entities.AuditEntry
+		if v.err != nil {
+			mock.ExpectQuery(regexp.QuoteMeta(v.query)).WillReturnError(v.err)
+		} else {
+			rows := sqlxmock.NewRows([]string{"id", "actor", "request_id", "operation", "target", "before_value", "after_value", "created_at"}).
+				AddRow(5, "jwt:alice", "req-1", "update_table", "table:1", `{"zone":""}`, `{"zone":"Garden"}`, "2021-06-01 20:00:00").
+				AddRow(4, "api_key:kiosk", "req-0", "create_table", "table:1", nil, `{"zone":""}`, "2021-06-01 19:00:00")
+			mock.ExpectQuery(regexp.QuoteMeta(v.query)).WithArgs(v.args...).WillReturnRows(rows)
+			expRes = []*entities.AuditEntry{
+				{ID: 5, Actor: "jwt:alice", RequestID: "req-1", Operation: "update_table", Target: "table:1", Before: entities.JSON(`{"zone":""}`), After: entities.JSON(`{"zone":"Garden"}`), CreatedAt: "2021-06-01 20:00:00"},
+				{ID: 4, Actor: "api_key:kiosk", RequestID: "req-0", Operation: "create_table", Target: "table:1", After: entities.JSON(`{"zone":""}`), CreatedAt: "2021-06-01 19:00:00"},
+			}
+		}
+		actRes, actErr := repo.ListAuditEntries(context.Background(), v.filter, 10, 0)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, expRes, actRes, v.desc)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	deleteQuery := regexp.QuoteMeta("DELETE FROM `idempotency_keys` WHERE actor = ? AND idempotency_key = ? AND expires_at <= NOW()")
+	insertQuery := regexp.QuoteMeta("INSERT INTO `idempotency_keys` (actor, idempotency_key, request_hash, expires_at) VALUES(?, ?, ?, NOW() + INTERVAL ? SECOND)")
+	type TestCase struct {
+		name      string
+		desc      string
+		beginErr  bool
+		deleteErr bool
+		insertErr error
+		commitErr bool
+		expErr    error
+	}
+	testcases := []TestCase{
 		{
-			name:              "Sad case",
-			desc:              "get table returns error",
-			err:               fmt.Errorf("mock error"),
-			getTableErr:       true,
-			getTableRow:       sqlxmock.NewRows([]string{}),
-			getGuestByNameRow: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
-			expErr:            errDBErr,
+			name: "Happy case",
+			desc: "key reserved",
 		},
 		{
-			name:              "Sad case",
-			desc:              "begin tx returns error",
-			err:               fmt.Errorf("mock error"),
-			beginTxErr:        true,
-			getTableRow:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			getGuestByNameRow: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
-			expErr:            errDBErr,
+			name:     "Sad case",
+			desc:     "begin transaction return error",
+			beginErr: true,
+			expErr:   ErrDBErr,
 		},
 		{
-			name:              "Sad case",
-			desc:              "update guest returns error",
-			err:               fmt.Errorf("mock error"),
-			updateGuestErr:    true,
-			getTableRow:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			getGuestByNameRow: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
-			expErr:            errDBErr,
+			name:      "Sad case",
+			desc:      "deleting expired key return error",
+			deleteErr: true,
+			expErr:    ErrDBErr,
 		},
 		{
-			name:                      "Sad case",
-			desc:                      "update guest rows affected returns error",
-			err:                       fmt.Errorf("mock error"),
-			updateGuestRowAffectedErr: true,
-			getTableRow:               sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			getGuestByNameRow:         sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
-			expErr:                    errDBErr,
+			name:      "Sad case",
+			desc:      "key already reserved",
+			insertErr: &mysql.MySQLError{Number: mysqlErrDupEntry},
+			expErr:    ErrIdempotencyKeyExists,
 		},
 		{
-			name:                         "Sad case",
-			desc:                         "update guest optimistic lock error",
-			updateGuestOptimisticLockErr: true,
-			getTableRow:                  sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			getGuestByNameRow:            sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
-			expErr:                       errFailedOptimisticLock,
+			name:      "Sad case",
+			desc:      "insert return error",
+			insertErr: fmt.Errorf("mock error"),
+			expErr:    ErrDBErr,
 		},
-
 		{
-			name:              "Sad case",
-			desc:              "update guest returns error",
-			err:               fmt.Errorf("mock error"),
-			updateTableErr:    true,
-			getTableRow:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			getGuestByNameRow: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
-			expErr:            errDBErr,
+			name:      "Sad case",
+			desc:      "commit return error",
+			commitErr: true,
+			expErr:    ErrDBErr,
 		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		func() {
+			if v.beginErr {
+				mock.ExpectBegin().WillReturnError(fmt.Errorf("mock error"))
+				return
+			}
+			mock.ExpectBegin()
+			if v.deleteErr {
+				mock.ExpectExec(deleteQuery).WillReturnError(fmt.Errorf("mock error"))
+				mock.ExpectRollback()
+				return
+			}
+			mock.ExpectExec(deleteQuery).WithArgs("api_key:kiosk", "key-1").WillReturnResult(sqlxmock.NewResult(0, 0))
+			if v.insertErr != nil {
+				mock.ExpectExec(insertQuery).WillReturnError(v.insertErr)
+				mock.ExpectRollback()
+				return
+			}
+			mock.ExpectExec(insertQuery).WithArgs("api_key:kiosk", "key-1", "hash", int64(3600)).WillReturnResult(sqlxmock.NewResult(0, 1))
+			if v.commitErr {
+				mock.ExpectCommit().WillReturnError(fmt.Errorf("mock error"))
+				return
+			}
+			mock.ExpectCommit()
+		}()
+		actErr := repo.ReserveIdempotencyKey(context.Background(), &entities.IdempotencyRecord{Actor: "api_key:kiosk", Key: "key-1", RequestHash: "hash"}, time.Hour)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestGetIdempotencyRecord(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `idempotency_keys` WHERE actor = ? AND idempotency_key = ? AND expires_at > NOW()")
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expRes *entities.IdempotencyRecord
+		expErr error
+	}
+	testcases := []TestCase{
 		{
-			name:                      "Sad case",
-			desc:                      "update guest rows affected returns error",
-			err:                       fmt.Errorf("mock error"),
-			updateTableRowAffectedErr: true,
-			getTableRow:               sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			getGuestByNameRow:         sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
-			expErr:                    errDBErr,
+			name:   "Happy case",
+			desc:   "Db return record",
+			expRes: &entities.IdempotencyRecord{Actor: "api_key:kiosk", Key: "key-1", RequestHash: "hash", Status: 201, ContentType: "application/json; charset=UTF-8", Body: []byte(`{"id":1}`), CreatedAt: "2021-06-04 04:06:44", ExpiresAt: "2021-06-05 04:06:44"},
 		},
 		{
-			name:                         "Sad case",
-			desc:                         "update guest optimistic lock error",
-			updateTableOptimisticLockErr: true,
-			getTableRow:                  sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			getGuestByNameRow:            sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
-			expErr:                       errFailedOptimisticLock,
+			name:   "Sad case",
+			desc:   "key not found or expired",
+			err:    sql.ErrNoRows,
+			expErr: ErrIdempotencyKeyNotFound,
 		},
 		{
-			name:              "Sad case",
-			desc:              "commit returns error",
-			err:               fmt.Errorf("mock error"),
-			commitErr:         true,
-			getTableRow:       sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 6, 6, 6, 2),
-			getGuestByNameRow: sqlxmock.NewRows([]string{"id", "name", "total_rsvp_guests", "total_arrived_guests", "version", "arrivaltime", "tableid"}).AddRow(1, "dummy", 2, 3, 4, "2021-06-04 04:06:44", 5),
-			expErr:            errDBErr,
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
 		},
 	}
 	for _, v := range testcases {
 		db, mock := NewMockDb()
 		repo := NewDbRepo(db)
-		if v.getGuestByNameErr {
-			mock.ExpectQuery(getGuestByNameQuery).WillReturnError(v.err)
-		}
-		mock.ExpectQuery(getGuestByNameQuery).WillReturnRows(v.getGuestByNameRow)
-		if v.getTableErr {
-			mock.ExpectQuery(getTableQuery).WillReturnError(v.err)
-		}
-		mock.ExpectQuery(getTableQuery).WillReturnRows(v.getTableRow)
-		if v.beginTxErr {
-			mock.ExpectBegin().WillReturnError(v.err)
-		}
-		mock.ExpectBegin()
-		if v.updateGuestErr {
-			mock.ExpectExec(updateGuestQuery).WillReturnError(v.err)
-			mock.ExpectRollback()
+		if v.err != nil {
+			mock.ExpectQuery(query).WillReturnError(v.err)
+		} else {
+			rows := sqlxmock.NewRows([]string{"actor", "idempotency_key", "request_hash", "status", "content_type", "body", "created_at", "expires_at"}).AddRow("api_key:kiosk", "key-1", "hash", 201, "application/json; charset=UTF-8", []byte(`{"id":1}`), "2021-06-04 04:06:44", "2021-06-05 04:06:44")
+			mock.ExpectQuery(query).WithArgs("api_key:kiosk", "key-1").WillReturnRows(rows)
 		}
-		if v.updateGuestRowAffectedErr {
-			mock.ExpectExec(updateGuestQuery).WillReturnResult(sqlxmock.NewErrorResult(v.err))
-			mock.ExpectRollback()
+		actRes, actErr := repo.GetIdempotencyRecord(context.Background(), "api_key:kiosk", "key-1")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
+	}
+}
+
+func TestCompleteIdempotencyKey(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE `idempotency_keys` SET status = ?, content_type = ?, body = ? WHERE actor = ? AND idempotency_key = ?")
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "response stored",
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		if v.err != nil {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectExec(query).WithArgs(201, "application/json", []byte(`{"id":1}`), "api_key:kiosk", "key-1").WillReturnResult(sqlxmock.NewResult(0, 1))
 		}
-		if v.updateGuestOptimisticLockErr {
-			mock.ExpectExec(updateGuestQuery).WillReturnResult(sqlxmock.NewResult(0, 0))
-			mock.ExpectRollback()
+		actErr := repo.CompleteIdempotencyKey(context.Background(), &entities.IdempotencyRecord{Actor: "api_key:kiosk", Key: "key-1", Status: 201, ContentType: "application/json", Body: []byte(`{"id":1}`)})
+		assert.Equal(t, v.expErr, actErr, v.desc)
+	}
+}
+
+func TestReleaseIdempotencyKey(t *testing.T) {
+	query := regexp.QuoteMeta("DELETE FROM `idempotency_keys` WHERE actor = ? AND idempotency_key = ? AND status = 0")
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "key released",
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		if v.err != nil {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectExec(query).WithArgs("api_key:kiosk", "key-1").WillReturnResult(sqlxmock.NewResult(0, 1))
 		}
-		mock.ExpectExec(updateGuestQuery).WillReturnResult(sqlxmock.NewResult(1, 1))
+		actErr := repo.ReleaseIdempotencyKey(context.Background(), "api_key:kiosk", "key-1")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+	}
+}
 
-		if v.updateTableErr {
-			mock.ExpectExec(updateTableQuery).WillReturnError(v.err)
-			mock.ExpectRollback()
+func TestPing(t *testing.T) {
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "database reachable",
+		},
+		{
+			name:   "Sad case",
+			desc:   "database unreachable",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		db, mock, err := sqlxmock.Newx(sqlxmock.MonitorPingsOption(true))
+		assert.Nil(t, err)
+		repo := NewDbRepo(db)
+		mock.ExpectPing().WillReturnError(v.err)
+		actErr := repo.Ping(context.Background())
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestMissingSchema(t *testing.T) {
+	columnsQuery := regexp.QuoteMeta("SELECT table_name AS tbl, column_name AS col FROM information_schema.columns WHERE table_schema = DATABASE()")
+	keysQuery := regexp.QuoteMeta("SELECT DISTINCT table_name AS tbl, index_name AS col FROM information_schema.statistics WHERE table_schema = DATABASE() AND non_unique = 0")
+	// created returns the columns and unique keys of every table of the schema, but those skipped
+	created := func(skip ...string) (columns, keys [][2]string) {
+		skipped := map[string]bool{}
+		for _, s := range skip {
+			skipped[s] = true
 		}
-		if v.updateTableRowAffectedErr {
-			mock.ExpectExec(updateTableQuery).WillReturnResult(sqlxmock.NewErrorResult(v.err))
-			mock.ExpectRollback()
+		for _, t := range schema {
+			if skipped[t.name] {
+				continue
+			}
+			for _, c := range t.columns {
+				if !skipped[t.name+"."+c] {
+					columns = append(columns, [2]string{t.name, c})
+				}
+			}
+			for _, k := range t.uniqueKeys {
+				if !skipped[t.name+" unique key "+k] {
+					keys = append(keys, [2]string{t.name, k})
+				}
+			}
 		}
-		if v.updateTableOptimisticLockErr {
-			mock.ExpectExec(updateTableQuery).WillReturnResult(sqlxmock.NewResult(0, 0))
-			mock.ExpectRollback()
+		return append(columns, [2]string{"other", "id"}), append(keys, [2]string{"table", "PRIMARY"})
+	}
+	type TestCase struct {
+		name    string
+		desc    string
+		skip    []string
+		colsErr error
+		keysErr error
+		expRes  []string
+		expErr  error
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "every table, column and unique key created",
+			expRes: []string{},
+		},
+		{
+			name:   "Happy case",
+			desc:   "tables missing",
+			skip:   []string{"audit_log", "idempotency_keys"},
+			expRes: []string{"audit_log", "idempotency_keys"},
+		},
+		{
+			name:   "Happy case",
+			desc:   "columns added by migrations missing",
+			skip:   []string{"table.event", "table.pos_x", "guests.dietary_tags"},
+			expRes: []string{"table.event", "table.pos_x", "guests.dietary_tags"},
+		},
+		{
+			name:   "Happy case",
+			desc:   "unique key on guest names missing",
+			skip:   []string{"guests unique key name"},
+			expRes: []string{"guests unique key name"},
+		},
+		{
+			name:   "Happy case",
+			desc:   "unique key on party member names missing",
+			skip:   []string{"party_members unique key guestid_name"},
+			expRes: []string{"party_members unique key guestid_name"},
+		},
+		{
+			name:    "Sad case",
+			desc:    "reading columns return error",
+			colsErr: fmt.Errorf("mock error"),
+			expErr:  ErrDBErr,
+		},
+		{
+			name:    "Sad case",
+			desc:    "reading unique keys return error",
+			keysErr: fmt.Errorf("mock error"),
+			expErr:  ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		columns, keys := created(v.skip...)
+		if v.colsErr != nil {
+			mock.ExpectQuery(columnsQuery).WillReturnError(v.colsErr)
+		} else {
+			rows := sqlxmock.NewRows([]string{"tbl", "col"})
+			for _, c := range columns {
+				rows.AddRow(c[0], c[1])
+			}
+			mock.ExpectQuery(columnsQuery).WillReturnRows(rows)
+			if v.keysErr != nil {
+				mock.ExpectQuery(keysQuery).WillReturnError(v.keysErr)
+			} else {
+				rows := sqlxmock.NewRows([]string{"tbl", "col"})
+				for _, k := range keys {
+					rows.AddRow(k[0], k[1])
+				}
+				mock.ExpectQuery(keysQuery).WillReturnRows(rows)
+			}
 		}
-		mock.ExpectExec(updateTableQuery).WillReturnResult(sqlxmock.NewResult(1, 1))
+		actRes, actErr := repo.MissingSchema(context.Background())
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
 
-		if v.commitErr {
-			mock.ExpectCommit().WillReturnError(v.err)
+func TestRestoreErr(t *testing.T) {
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expErr error
+		expLog string
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "duplicate rows conflict, the duplicate value is not logged",
+			err:    &mysql.MySQLError{Number: mysqlErrDupEntry, Message: "Duplicate entry 'dummy' for key 'name'"},
+			expErr: ErrRestoreConflict,
+			expLog: ErrRestoreConflict.Error(),
+		},
+		{
+			name:   "Sad case",
+			desc:   "deadlock is a lock conflict",
+			err:    &mysql.MySQLError{Number: mysqlErrDeadlock},
+			expErr: ErrLockConflict,
+			expLog: ErrDBErr.Error(),
+		},
+		{
+			name:   "Sad case",
+			desc:   "other errors are database errors",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+			expLog: ErrDBErr.Error(),
+		},
+	}
+	for _, v := range testcases {
+		core, logs := observer.New(zap.InfoLevel)
+		ctx := logger.NewContext(context.Background(), zap.New(core))
+		assert.Equal(t, v.expErr, restoreErr(ctx, v.err), v.desc)
+		if assert.Equal(t, 1, logs.Len(), v.desc) {
+			entry := logs.All()[0]
+			assert.Equal(t, v.expLog, entry.Message, v.desc)
+			if v.expErr == ErrRestoreConflict {
+				assert.Empty(t, entry.Context, v.desc)
+			}
 		}
-		mock.ExpectCommit()
-		actErr := repo.GuestDepart(context.Background(), &entities.Guest{Name: "dummy"})
-		assert.Equal(t, v.expErr, actErr)
 	}
 }
@@ -2,20 +2,70 @@ package repo
 
 import (
 	"context"
+	"time"
+
 	"ggv2/entities"
 )
 
 type DbRepo interface {
 	GetTable(context.Context, int64) (*entities.Table, error)
 
-	CreateTable(context.Context, *entities.Table) (*entities.Table, error)
-	ListTables(context.Context, int64, int64) ([]*entities.Table, error)
-	EmptyTables(context.Context) error
+	ListTables(context.Context, *entities.TableFilter, int64, int64) ([]*entities.Table, error)
+	ListAllTables(context.Context, string) ([]*entities.Table, error)
+	ListArchives(context.Context) ([]*entities.Archive, error)
+	Snapshot(context.Context, string) (*entities.Snapshot, error)
 	GetEmptySeatsCount(context.Context) (int, error)
 	GetGuestByName(context.Context, *entities.Guest) (*entities.Guest, error)
-	AddToGuestList(context.Context, *entities.Guest) error
 	ListGuests(context.Context, int64, int64) ([]*entities.Guest, error)
-	GuestArrived(context.Context, *entities.Guest) error
 	ListArrivedGuests(context.Context, int64, int64) ([]*entities.Guest, error)
-	GuestDepart(context.Context, *entities.Guest) error
+	ListPartyMembers(context.Context, *entities.Guest) ([]*entities.PartyMember, error)
+	ListGuestsWithMembers(context.Context, string) ([]*entities.Guest, error)
+	GetLayoutTemplate(context.Context, string) (*entities.LayoutTemplate, error)
+	ListLayoutTemplates(context.Context) ([]*entities.LayoutTemplate, error)
+	GetAPIKeyByHash(context.Context, string) (*entities.APIKey, error)
+	ListAPIKeys(context.Context) ([]*entities.APIKey, error)
+	ListAuditEntries(context.Context, *entities.AuditFilter, int64, int64) ([]*entities.AuditEntry, error)
+	ReserveIdempotencyKey(context.Context, *entities.IdempotencyRecord, time.Duration) error
+	GetIdempotencyRecord(context.Context, string, string) (*entities.IdempotencyRecord, error)
+	CompleteIdempotencyKey(context.Context, *entities.IdempotencyRecord) error
+	ReleaseIdempotencyKey(context.Context, string, string) error
+	Ping(context.Context) error
+	MissingSchema(context.Context) ([]string, error)
+	Atomic(context.Context, func(Tx) error) error
+}
+
+// UnitOfWork runs reads and writes atomically, the seating rules are applied within a unit of work
+// so that they hold whatever the storage.
+type UnitOfWork interface {
+	Atomic(context.Context, func(Tx) error) error
+}
+
+// Tx reads and writes within a unit of work. Writes of rows fail with an optimistic lock error
+// when the row changed since it was read. With pessimistic locking reads lock the rows read, units
+// of work lock tables before the guests seated at them and party members last. Mutations record
+// their audit entry within the unit of work, so that no mutation is kept without its entry.
+type Tx interface {
+	GetTable(context.Context, int64) (*entities.Table, error)
+	UpdateTable(context.Context, *entities.Table) error
+	DeleteTable(context.Context, *entities.Table) error
+	MoveGuests(context.Context, int64, int64) error
+	GetGuestByName(context.Context, string) (*entities.Guest, error)
+	PeekGuestByName(context.Context, string) (*entities.Guest, error)
+	CreateGuest(context.Context, *entities.Guest) error
+	UpdateGuest(context.Context, *entities.Guest) error
+	GetPartyMember(context.Context, int64, string) (*entities.PartyMember, error)
+	SetMemberArrived(context.Context, *entities.PartyMember, bool) error
+	ResetPartyArrival(context.Context, int64) error
+	HasTables(context.Context, string) (bool, error)
+	CreateTable(context.Context, *entities.Table) error
+	CreateTables(context.Context, []*entities.Table) error
+	EmptyTables(context.Context, string) (*entities.Archive, error)
+	RestoreArchive(context.Context, int64) (*entities.Archive, error)
+	RestoreSnapshot(context.Context, *entities.Snapshot, bool) (*entities.Archive, error)
+	GetLayoutTemplate(context.Context, string) (*entities.LayoutTemplate, error)
+	SaveLayoutTemplate(context.Context, *entities.LayoutTemplate) error
+	DeleteLayoutTemplate(context.Context, string) error
+	CreateAPIKey(context.Context, *entities.APIKey) error
+	RevokeAPIKey(context.Context, int64) error
+	CreateAuditEntry(context.Context, *entities.AuditEntry) error
 }
@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// MetricsSubsystem is the subsystem of every metric of the service served on /metrics
+const MetricsSubsystem = "GGv2"
+
+var queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Subsystem: MetricsSubsystem,
+	Name:      "db_query_duration_seconds",
+	Help:      "How long DBRepo methods took, partitioned by method.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"method"})
+
+// observeQuery starts timing a DBRepo method, call the returned func once it is done:
+//
+//	defer observeQuery("GetTable")()
+func observeQuery(method string) func() {
+	start := time.Now()
+	return func() {
+		queryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// DBStatsCollector exports the connection pool statistics of a database handle
+type DBStatsCollector struct {
+	db *sqlx.DB
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+func NewDBStatsCollector(db *sqlx.DB) *DBStatsCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("", MetricsSubsystem, name), help, nil, nil)
+	}
+	return &DBStatsCollector{
+		db:                db,
+		maxOpen:           desc("db_max_open_connections", "Maximum number of open connections to the database."),
+		open:              desc("db_open_connections", "Number of established connections, both in use and idle."),
+		inUse:             desc("db_in_use_connections", "Number of connections currently in use."),
+		idle:              desc("db_idle_connections", "Number of idle connections."),
+		waitCount:         desc("db_wait_count_total", "Total number of connections waited for."),
+		waitDuration:      desc("db_wait_duration_seconds_total", "Total time blocked waiting for a new connection."),
+		maxIdleClosed:     desc("db_max_idle_closed_total", "Total number of connections closed due to the maximum idle connections."),
+		maxIdleTimeClosed: desc("db_max_idle_time_closed_total", "Total number of connections closed due to the maximum idle time."),
+		maxLifetimeClosed: desc("db_max_lifetime_closed_total", "Total number of connections closed due to the maximum connection lifetime."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *DBStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+// Collect implements prometheus.Collector, the stats are read from the pool on every scrape
+func (c *DBStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(s.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, s.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(s.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxIdleTimeClosed, prometheus.CounterValue, float64(s.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(s.MaxLifetimeClosed))
+}
@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	sqlxmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestDBStatsCollector(t *testing.T) {
+	db, _ := NewMockDb()
+	db.SetMaxOpenConns(10)
+	c := NewDBStatsCollector(db)
+
+	expected := `
+# HELP GGv2_db_in_use_connections Number of connections currently in use.
+# TYPE GGv2_db_in_use_connections gauge
+GGv2_db_in_use_connections 0
+# HELP GGv2_db_max_open_connections Maximum number of open connections to the database.
+# TYPE GGv2_db_max_open_connections gauge
+GGv2_db_max_open_connections 10
+# HELP GGv2_db_wait_count_total Total number of connections waited for.
+# TYPE GGv2_db_wait_count_total counter
+GGv2_db_wait_count_total 0
+`
+	assert.Nil(t, testutil.CollectAndCompare(c, strings.NewReader(expected), "GGv2_db_in_use_connections", "GGv2_db_max_open_connections", "GGv2_db_wait_count_total"))
+	assert.Equal(t, 9, testutil.CollectAndCount(c))
+}
+
+func TestObserveQuery(t *testing.T) {
+	db, mock, err := sqlxmock.Newx(sqlxmock.MonitorPingsOption(true))
+	assert.Nil(t, err)
+	repo := NewDbRepo(db)
+	count := func() uint64 {
+		m := &dto.Metric{}
+		assert.Nil(t, queryDuration.WithLabelValues("Ping").(prometheus.Histogram).Write(m))
+		return m.GetHistogram().GetSampleCount()
+	}
+	before := count()
+	mock.ExpectPing()
+	assert.Nil(t, repo.Ping(context.Background()))
+	mock.ExpectPing()
+	assert.Nil(t, repo.Ping(context.Background()))
+	assert.Equal(t, before+2, count())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
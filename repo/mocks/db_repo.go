@@ -5,6 +5,8 @@ package mocks
 import (
 	context "context"
 	entities "ggv2/entities"
+	repo "ggv2/repo"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -14,12 +16,12 @@ type DbRepo struct {
 	mock.Mock
 }
 
-// AddToGuestList provides a mock function with given fields: _a0, _a1
-func (_m *DbRepo) AddToGuestList(_a0 context.Context, _a1 *entities.Guest) error {
+// Atomic provides a mock function with given fields: _a0, _a1
+func (_m *DbRepo) Atomic(_a0 context.Context, _a1 func(repo.Tx) error) error {
 	ret := _m.Called(_a0, _a1)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *entities.Guest) error); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, func(repo.Tx) error) error); ok {
 		r0 = rf(_a0, _a1)
 	} else {
 		r0 = ret.Error(0)
@@ -28,21 +30,35 @@ func (_m *DbRepo) AddToGuestList(_a0 context.Context, _a1 *entities.Guest) error
 	return r0
 }
 
-// CreateTable provides a mock function with given fields: _a0, _a1
-func (_m *DbRepo) CreateTable(_a0 context.Context, _a1 *entities.Table) (*entities.Table, error) {
+// CompleteIdempotencyKey provides a mock function with given fields: _a0, _a1
+func (_m *DbRepo) CompleteIdempotencyKey(_a0 context.Context, _a1 *entities.IdempotencyRecord) error {
 	ret := _m.Called(_a0, _a1)
 
-	var r0 *entities.Table
-	if rf, ok := ret.Get(0).(func(context.Context, *entities.Table) *entities.Table); ok {
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.IdempotencyRecord) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAPIKeyByHash provides a mock function with given fields: _a0, _a1
+func (_m *DbRepo) GetAPIKeyByHash(_a0 context.Context, _a1 string) (*entities.APIKey, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *entities.APIKey
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.APIKey); ok {
 		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*entities.Table)
+			r0 = ret.Get(0).(*entities.APIKey)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, *entities.Table) error); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
 		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
@@ -51,20 +67,6 @@ func (_m *DbRepo) CreateTable(_a0 context.Context, _a1 *entities.Table) (*entiti
 	return r0, r1
 }
 
-// EmptyTables provides a mock function with given fields: _a0
-func (_m *DbRepo) EmptyTables(_a0 context.Context) error {
-	ret := _m.Called(_a0)
-
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
-		r0 = rf(_a0)
-	} else {
-		r0 = ret.Error(0)
-	}
-
-	return r0
-}
-
 // GetEmptySeatsCount provides a mock function with given fields: _a0
 func (_m *DbRepo) GetEmptySeatsCount(_a0 context.Context) (int, error) {
 	ret := _m.Called(_a0)
@@ -109,6 +111,52 @@ func (_m *DbRepo) GetGuestByName(_a0 context.Context, _a1 *entities.Guest) (*ent
 	return r0, r1
 }
 
+// GetIdempotencyRecord provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbRepo) GetIdempotencyRecord(_a0 context.Context, _a1 string, _a2 string) (*entities.IdempotencyRecord, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *entities.IdempotencyRecord
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *entities.IdempotencyRecord); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.IdempotencyRecord)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLayoutTemplate provides a mock function with given fields: _a0, _a1
+func (_m *DbRepo) GetLayoutTemplate(_a0 context.Context, _a1 string) (*entities.LayoutTemplate, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *entities.LayoutTemplate
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.LayoutTemplate); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.LayoutTemplate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTable provides a mock function with given fields: _a0, _a1
 func (_m *DbRepo) GetTable(_a0 context.Context, _a1 int64) (*entities.Table, error) {
 	ret := _m.Called(_a0, _a1)
@@ -132,32 +180,73 @@ func (_m *DbRepo) GetTable(_a0 context.Context, _a1 int64) (*entities.Table, err
 	return r0, r1
 }
 
-// GuestArrived provides a mock function with given fields: _a0, _a1
-func (_m *DbRepo) GuestArrived(_a0 context.Context, _a1 *entities.Guest) error {
-	ret := _m.Called(_a0, _a1)
+// ListAPIKeys provides a mock function with given fields: _a0
+func (_m *DbRepo) ListAPIKeys(_a0 context.Context) ([]*entities.APIKey, error) {
+	ret := _m.Called(_a0)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *entities.Guest) error); ok {
-		r0 = rf(_a0, _a1)
+	var r0 []*entities.APIKey
+	if rf, ok := ret.Get(0).(func(context.Context) []*entities.APIKey); ok {
+		r0 = rf(_a0)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.APIKey)
+		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// GuestDepart provides a mock function with given fields: _a0, _a1
-func (_m *DbRepo) GuestDepart(_a0 context.Context, _a1 *entities.Guest) error {
+// ListAllTables provides a mock function with given fields: _a0, _a1
+func (_m *DbRepo) ListAllTables(_a0 context.Context, _a1 string) ([]*entities.Table, error) {
 	ret := _m.Called(_a0, _a1)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *entities.Guest) error); ok {
+	var r0 []*entities.Table
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*entities.Table); ok {
 		r0 = rf(_a0, _a1)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.Table)
+		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListArchives provides a mock function with given fields: _a0
+func (_m *DbRepo) ListArchives(_a0 context.Context) ([]*entities.Archive, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*entities.Archive
+	if rf, ok := ret.Get(0).(func(context.Context) []*entities.Archive); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.Archive)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // ListArrivedGuests provides a mock function with given fields: _a0, _a1, _a2
@@ -183,6 +272,29 @@ func (_m *DbRepo) ListArrivedGuests(_a0 context.Context, _a1 int64, _a2 int64) (
 	return r0, r1
 }
 
+// ListAuditEntries provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *DbRepo) ListAuditEntries(_a0 context.Context, _a1 *entities.AuditFilter, _a2 int64, _a3 int64) ([]*entities.AuditEntry, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 []*entities.AuditEntry
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.AuditFilter, int64, int64) []*entities.AuditEntry); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.AuditEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *entities.AuditFilter, int64, int64) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListGuests provides a mock function with given fields: _a0, _a1, _a2
 func (_m *DbRepo) ListGuests(_a0 context.Context, _a1 int64, _a2 int64) ([]*entities.Guest, error) {
 	ret := _m.Called(_a0, _a1, _a2)
@@ -206,13 +318,82 @@ func (_m *DbRepo) ListGuests(_a0 context.Context, _a1 int64, _a2 int64) ([]*enti
 	return r0, r1
 }
 
-// ListTables provides a mock function with given fields: _a0, _a1, _a2
-func (_m *DbRepo) ListTables(_a0 context.Context, _a1 int64, _a2 int64) ([]*entities.Table, error) {
-	ret := _m.Called(_a0, _a1, _a2)
+// ListGuestsWithMembers provides a mock function with given fields: _a0, _a1
+func (_m *DbRepo) ListGuestsWithMembers(_a0 context.Context, _a1 string) ([]*entities.Guest, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []*entities.Guest
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*entities.Guest); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.Guest)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListLayoutTemplates provides a mock function with given fields: _a0
+func (_m *DbRepo) ListLayoutTemplates(_a0 context.Context) ([]*entities.LayoutTemplate, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*entities.LayoutTemplate
+	if rf, ok := ret.Get(0).(func(context.Context) []*entities.LayoutTemplate); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.LayoutTemplate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPartyMembers provides a mock function with given fields: _a0, _a1
+func (_m *DbRepo) ListPartyMembers(_a0 context.Context, _a1 *entities.Guest) ([]*entities.PartyMember, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []*entities.PartyMember
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.Guest) []*entities.PartyMember); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.PartyMember)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *entities.Guest) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListTables provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *DbRepo) ListTables(_a0 context.Context, _a1 *entities.TableFilter, _a2 int64, _a3 int64) ([]*entities.Table, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
 
 	var r0 []*entities.Table
-	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) []*entities.Table); ok {
-		r0 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.TableFilter, int64, int64) []*entities.Table); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*entities.Table)
@@ -220,8 +401,96 @@ func (_m *DbRepo) ListTables(_a0 context.Context, _a1 int64, _a2 int64) ([]*enti
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
-		r1 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(1).(func(context.Context, *entities.TableFilter, int64, int64) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MissingSchema provides a mock function with given fields: _a0
+func (_m *DbRepo) MissingSchema(_a0 context.Context) ([]string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Ping provides a mock function with given fields: _a0
+func (_m *DbRepo) Ping(_a0 context.Context) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReleaseIdempotencyKey provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbRepo) ReleaseIdempotencyKey(_a0 context.Context, _a1 string, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReserveIdempotencyKey provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbRepo) ReserveIdempotencyKey(_a0 context.Context, _a1 *entities.IdempotencyRecord, _a2 time.Duration) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.IdempotencyRecord, time.Duration) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Snapshot provides a mock function with given fields: _a0, _a1
+func (_m *DbRepo) Snapshot(_a0 context.Context, _a1 string) (*entities.Snapshot, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *entities.Snapshot
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.Snapshot); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.Snapshot)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
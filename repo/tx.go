@@ -0,0 +1,420 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+
+	"go.uber.org/zap"
+
+	"ggv2/entities"
+	"ggv2/logger"
+)
+
+// Locking selects how units of work guard the rows they read against concurrent units of work
+type Locking string
+
+const (
+	// OptimisticLocking reads rows without locking them, writes fail when a row changed since
+	// it was read and the request is retried by the client
+	OptimisticLocking Locking = "optimistic"
+	// PessimisticLocking reads rows with SELECT ... FOR UPDATE, concurrent units of work wait
+	// for the rows until the transaction ends
+	PessimisticLocking Locking = "pessimistic"
+)
+
+// forUpdate is appended to the reads of a unit of work to lock the rows read
+const forUpdate = " FOR UPDATE"
+
+const (
+	// mysqlErrLockWaitTimeout and mysqlErrDeadlock are the MySQL error numbers of a transaction
+	// that gave up waiting for a lock and of a transaction rolled back to break a deadlock
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrDeadlock        = 1213
+
+	// atomicAttempts is how many times a unit of work runs when it conflicts on locks
+	atomicAttempts = 3
+	// atomicBackoff is how long the first retry of a unit of work waits, doubled after each retry
+	atomicBackoff = 10 * time.Millisecond
+)
+
+// dbTx is a unit of work running in a MySQL transaction
+type dbTx struct {
+	tx   *sqlx.Tx
+	lock string
+}
+
+// Atomic runs fn as a single unit of work. The writes of fn are committed when it returns nil
+// and rolled back otherwise, the error of fn is returned as is. With pessimistic locking the
+// rows read by fn stay locked until then. A unit of work that conflicts on locks with a
+// concurrent one, deadlocked or timed out waiting, is run again from the start.
+func (r *DBRepo) Atomic(ctx context.Context, fn func(Tx) error) error {
+	defer observeQuery("Atomic")()
+	backoff := atomicBackoff
+	for attempt := 1; ; attempt++ {
+		err := r.atomic(ctx, fn)
+		if err == nil || !errors.Is(err, ErrLockConflict) || attempt == atomicAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (r *DBRepo) atomic(ctx context.Context, fn func(Tx) error) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		// Error starting transaction
+		return dbErr(ctx, err)
+	}
+	unit := &dbTx{tx: tx}
+	if r.locking == PessimisticLocking {
+		unit.lock = forUpdate
+	}
+	if err = fn(unit); err != nil {
+		tx.Rollback()
+		return err
+	}
+	// All ok, commiting transaction
+	err = tx.Commit()
+	if err != nil {
+		// Error commiting transaction
+		return dbErr(ctx, err)
+	}
+	return nil
+}
+
+func (t *dbTx) GetTable(ctx context.Context, id int64) (*entities.Table, error) {
+	defer observeQuery("Tx.GetTable")()
+	return getTable(ctx, t.tx, id, t.lock)
+}
+
+// UpdateTable saves the capacity and metadata of a table read in the unit of work.
+func (t *dbTx) UpdateTable(ctx context.Context, table *entities.Table) error {
+	defer observeQuery("Tx.UpdateTable")()
+	res, err := t.tx.ExecContext(ctx, "UPDATE `table` SET capacity=?, pcapacity=?, acapacity=?, event=?, name=?, zone=?, shape=?, tags=?, pos_x=?, pos_y=?, rotation=?, version = version + 1 WHERE id = ? AND version = ?", table.Capacity, table.PlannedCapacity, table.AvailableCapacity, table.Event, table.Name, table.Zone, table.Shape, table.Tags, table.X, table.Y, table.Rotation, table.TableID, table.Version)
+	if err := versioned(ctx, res, err); err != nil {
+		return err
+	}
+	table.Version++
+	return nil
+}
+
+// DeleteTable removes a table, unless it changed since it was read.
+func (t *dbTx) DeleteTable(ctx context.Context, table *entities.Table) error {
+	defer observeQuery("Tx.DeleteTable")()
+	res, err := t.tx.ExecContext(ctx, "DELETE FROM `table` WHERE id = ? AND version = ?", table.TableID, table.Version)
+	return versioned(ctx, res, err)
+}
+
+// MoveGuests seats every guest of a table at another table.
+func (t *dbTx) MoveGuests(ctx context.Context, from, to int64) error {
+	defer observeQuery("Tx.MoveGuests")()
+	_, err := t.tx.ExecContext(ctx, "UPDATE `guests` SET tableid=?, version = version + 1 WHERE tableid = ?", to, from)
+	if err != nil {
+		return dbErr(ctx, err)
+	}
+	return nil
+}
+
+func (t *dbTx) GetGuestByName(ctx context.Context, name string) (*entities.Guest, error) {
+	defer observeQuery("Tx.GetGuestByName")()
+	return getGuestByName(ctx, t.tx, name, t.lock)
+}
+
+// PeekGuestByName reads a guest without locking it, even with pessimistic locking. A locking
+// read of a name that does not exist locks the gap the name would go in, concurrent guests
+// that RSVP into the same gap would then deadlock on insert.
+func (t *dbTx) PeekGuestByName(ctx context.Context, name string) (*entities.Guest, error) {
+	defer observeQuery("Tx.PeekGuestByName")()
+	return getGuestByName(ctx, t.tx, name, "")
+}
+
+// CreateGuest adds a guest and the named members of its party to the guest list. Guest names
+// are unique, a guest that RSVP concurrently under the same name fails the insert.
+func (t *dbTx) CreateGuest(ctx context.Context, guest *entities.Guest) error {
+	defer observeQuery("Tx.CreateGuest")()
+	insert, err := t.tx.ExecContext(ctx, "INSERT INTO `guests` (total_rsvp_guests, tableid, name, dietary_tags, allergens, dietary_notes, meal_choice) VALUES(?, ?, ?, ?, ?, ?, ?)", guest.TotalGuests, guest.TableID, guest.Name, guest.DietaryTags, guest.Allergens, guest.DietaryNotes, guest.MealChoice)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDupEntry {
+			return ErrGuestAlreadyRSVP
+		}
+		// Error creating RSVP record for guest
+		return dbErr(ctx, err)
+	}
+	guest.ID, err = insert.LastInsertId()
+	if err != nil {
+		// Error getting ID of newly created record
+		return dbErr(ctx, err)
+	}
+	for _, m := range guest.Members {
+		_, err = t.tx.ExecContext(ctx, "INSERT INTO `party_members` (guestid, name, age_group, dietary_tags, allergens, dietary_notes, meal_choice) VALUES(?, ?, ?, ?, ?, ?, ?)", guest.ID, m.Name, m.AgeGroup, m.DietaryTags, m.Allergens, m.DietaryNotes, m.MealChoice)
+		if err != nil {
+			// Error creating party member record
+			return dbErr(ctx, err)
+		}
+		m.GuestID = guest.ID
+	}
+	return nil
+}
+
+// UpdateGuest saves the table and arrival of a guest read in the unit of work.
+func (t *dbTx) UpdateGuest(ctx context.Context, guest *entities.Guest) error {
+	defer observeQuery("Tx.UpdateGuest")()
+	res, err := t.tx.ExecContext(ctx, "UPDATE `guests` SET tableid=?, total_arrived_guests=?, arrivaltime=?, version = version + 1 WHERE id = ? AND version = ?", guest.TableID, guest.TotalArrivedGuests, guest.ArrivalTime, guest.ID, guest.Version)
+	if err := versioned(ctx, res, err); err != nil {
+		return err
+	}
+	guest.Version++
+	return nil
+}
+
+func (t *dbTx) GetPartyMember(ctx context.Context, guestID int64, name string) (*entities.PartyMember, error) {
+	defer observeQuery("Tx.GetPartyMember")()
+	member := entities.PartyMember{}
+	err := t.tx.GetContext(ctx, &member, "SELECT * FROM `party_members` WHERE guestid = ? AND name = ?"+t.lock, guestID, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPartyMemberNotFound
+		}
+		return nil, dbErr(ctx, err)
+	}
+	return &member, nil
+}
+
+// SetMemberArrived checks-in or checks-out a party member, unless it was already.
+func (t *dbTx) SetMemberArrived(ctx context.Context, member *entities.PartyMember, arrived bool) error {
+	defer observeQuery("Tx.SetMemberArrived")()
+	res, err := t.tx.ExecContext(ctx, "UPDATE `party_members` SET arrived=? WHERE id = ? AND arrived=?", arrived, member.ID, !arrived)
+	if err := versioned(ctx, res, err); err != nil {
+		return err
+	}
+	member.Arrived = arrived
+	return nil
+}
+
+// ResetPartyArrival checks-out every member of a guest's party.
+func (t *dbTx) ResetPartyArrival(ctx context.Context, guestID int64) error {
+	defer observeQuery("Tx.ResetPartyArrival")()
+	_, err := t.tx.ExecContext(ctx, "UPDATE `party_members` SET arrived=0 WHERE guestid = ?", guestID)
+	if err != nil {
+		return dbErr(ctx, err)
+	}
+	return nil
+}
+
+// CreateTable adds a table with every seat planned and available.
+func (t *dbTx) CreateTable(ctx context.Context, table *entities.Table) error {
+	defer observeQuery("Tx.CreateTable")()
+	return createTable(ctx, t.tx, table)
+}
+
+// HasTables reports whether an event has tables, every table with the default event. The tables
+// read are locked along with the index gap new tables of the event go in, whatever the locking, so
+// that no table is added to the event until the unit of work ends.
+func (t *dbTx) HasTables(ctx context.Context, event string) (bool, error) {
+	defer observeQuery("Tx.HasTables")()
+	where, args := tableFilterClause(&entities.TableFilter{Event: event})
+	ids := []int64{}
+	err := t.tx.SelectContext(ctx, &ids, "SELECT id FROM `table`"+where+forUpdate, args...)
+	if err != nil {
+		return false, dbErr(ctx, err)
+	}
+	return len(ids) > 0, nil
+}
+
+// CreateTables adds every table, either every table is created or the unit of work fails.
+func (t *dbTx) CreateTables(ctx context.Context, tables []*entities.Table) error {
+	defer observeQuery("Tx.CreateTables")()
+	for _, table := range tables {
+		if err := createTable(ctx, t.tx, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createTable(ctx context.Context, tx *sqlx.Tx, table *entities.Table) error {
+	res, err := tx.ExecContext(ctx, "INSERT INTO `table` (capacity, pcapacity, acapacity, event, name, zone, shape, tags, pos_x, pos_y, rotation) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", table.Capacity, table.Capacity, table.Capacity, table.Event, table.Name, table.Zone, table.Shape, table.Tags, table.X, table.Y, table.Rotation)
+	if err != nil {
+		// Error inserting table
+		return dbErr(ctx, err)
+	}
+	table.TableID, err = res.LastInsertId()
+	if err != nil {
+		// Error getting ID of newly created record
+		return dbErr(ctx, err)
+	}
+	return nil
+}
+
+// EmptyTables removes the tables of an event together with their guests and party members, every
+// table is removed when event is empty. The removed data is archived first so that it can be restored.
+func (t *dbTx) EmptyTables(ctx context.Context, event string) (*entities.Archive, error) {
+	defer observeQuery("Tx.EmptyTables")()
+	return archiveAndEmpty(ctx, t.tx, event)
+}
+
+// RestoreSnapshot inserts every row of a snapshot with its original id. With replace, the tables of
+// the snapshot's event are archived and removed first and the archive is returned. The unit of work
+// fails when any row conflicts with existing data.
+func (t *dbTx) RestoreSnapshot(ctx context.Context, snapshot *entities.Snapshot, replace bool) (*entities.Archive, error) {
+	defer observeQuery("Tx.RestoreSnapshot")()
+	var archive *entities.Archive
+	if replace {
+		var err error
+		archive, err = archiveAndEmpty(ctx, t.tx, snapshot.Event)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := restoreSnapshot(ctx, t.tx, snapshot); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// RestoreArchive puts the tables, guests and party members of an archive back with their original ids.
+// An archive can only be restored once, the unit of work fails when any row conflicts with existing data.
+func (t *dbTx) RestoreArchive(ctx context.Context, id int64) (*entities.Archive, error) {
+	defer observeQuery("Tx.RestoreArchive")()
+	archive := entities.Archive{}
+	err := t.tx.GetContext(ctx, &archive, "SELECT * FROM `archives` WHERE id = ? FOR UPDATE", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrArchiveNotFound
+		}
+		return nil, dbErr(ctx, err)
+	}
+	if archive.Restored {
+		return nil, ErrArchiveAlreadyRestored
+	}
+	if err = restoreSnapshot(ctx, t.tx, archive.Snapshot); err != nil {
+		return nil, err
+	}
+	_, err = t.tx.ExecContext(ctx, "UPDATE `archives` SET restored=1 WHERE id = ?", id)
+	if err != nil {
+		return nil, dbErr(ctx, err)
+	}
+	archive.Restored = true
+	return &archive, nil
+}
+
+func (t *dbTx) GetLayoutTemplate(ctx context.Context, name string) (*entities.LayoutTemplate, error) {
+	defer observeQuery("Tx.GetLayoutTemplate")()
+	return getLayoutTemplate(ctx, t.tx, name, t.lock)
+}
+
+// SaveLayoutTemplate creates a layout template, or replaces the layout of an existing template with the same name.
+func (t *dbTx) SaveLayoutTemplate(ctx context.Context, template *entities.LayoutTemplate) error {
+	defer observeQuery("Tx.SaveLayoutTemplate")()
+	_, err := t.tx.ExecContext(ctx, "INSERT INTO `layout_templates` (name, layout) VALUES(?, ?) ON DUPLICATE KEY UPDATE layout = VALUES(layout), version = version + 1", template.Name, template.Groups)
+	if err != nil {
+		return dbErr(ctx, err)
+	}
+	return nil
+}
+
+// DeleteLayoutTemplate removes a layout template by name.
+func (t *dbTx) DeleteLayoutTemplate(ctx context.Context, name string) error {
+	defer observeQuery("Tx.DeleteLayoutTemplate")()
+	res, err := t.tx.ExecContext(ctx, "DELETE FROM `layout_templates` WHERE name = ?", name)
+	if err != nil {
+		return dbErr(ctx, err)
+	}
+	c, err := res.RowsAffected()
+	if err != nil {
+		return dbErr(ctx, err)
+	}
+	if c == 0 {
+		return ErrLayoutTemplateNotFound
+	}
+	return nil
+}
+
+// CreateAPIKey stores a new API key.
+func (t *dbTx) CreateAPIKey(ctx context.Context, key *entities.APIKey) error {
+	defer observeQuery("Tx.CreateAPIKey")()
+	res, err := t.tx.ExecContext(ctx, "INSERT INTO `api_keys` (name, prefix, role, key_hash) VALUES(?, ?, ?, ?)", key.Name, key.Prefix, key.Role, key.KeyHash)
+	if err != nil {
+		return dbErr(ctx, err)
+	}
+	key.ID, err = res.LastInsertId()
+	if err != nil {
+		// Error getting ID of newly created record
+		return dbErr(ctx, err)
+	}
+	return nil
+}
+
+// RevokeAPIKey marks an API key as revoked, revoked keys are kept for reference.
+// Keys that are already revoked are reported as not found.
+func (t *dbTx) RevokeAPIKey(ctx context.Context, id int64) error {
+	defer observeQuery("Tx.RevokeAPIKey")()
+	res, err := t.tx.ExecContext(ctx, "UPDATE `api_keys` SET revoked=1 WHERE id = ? AND revoked=0", id)
+	if err != nil {
+		return dbErr(ctx, err)
+	}
+	c, err := res.RowsAffected()
+	if err != nil {
+		return dbErr(ctx, err)
+	}
+	if c == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// CreateAuditEntry appends an entry to the audit log, the entry is only kept when the unit of
+// work that recorded it commits.
+func (t *dbTx) CreateAuditEntry(ctx context.Context, entry *entities.AuditEntry) error {
+	defer observeQuery("Tx.CreateAuditEntry")()
+	res, err := t.tx.ExecContext(ctx, "INSERT INTO `audit_log` (actor, request_id, operation, target, before_value, after_value) VALUES(?, ?, ?, ?, ?, ?)", entry.Actor, entry.RequestID, entry.Operation, entry.Target, entry.Before, entry.After)
+	if err != nil {
+		return dbErr(ctx, err)
+	}
+	entry.ID, err = res.LastInsertId()
+	if err != nil {
+		// Error getting ID of newly created record
+		return dbErr(ctx, err)
+	}
+	return nil
+}
+
+// dbErr logs an error of the database and maps it to the error returned by the repo. Lock
+// conflicts with a concurrent transaction are retryable, the unit of work is run again.
+func dbErr(ctx context.Context, err error) error {
+	logger.FromContext(ctx).Error(ErrDBErr.Error(), zap.Error(err))
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && (mysqlErr.Number == mysqlErrDeadlock || mysqlErr.Number == mysqlErrLockWaitTimeout) {
+		return ErrLockConflict
+	}
+	return ErrDBErr
+}
+
+// versioned checks the result of a write conditioned on the state the row was read in,
+// failing with an optimistic lock error when the row changed since.
+func versioned(ctx context.Context, res sql.Result, err error) error {
+	if err != nil {
+		return dbErr(ctx, err)
+	}
+	c, err := res.RowsAffected()
+	if err != nil {
+		// Error getting optimistic lock data
+		return dbErr(ctx, err)
+	}
+	if c != 1 {
+		// Unable to secure optimistic lock
+		return ErrFailedOptimisticLock
+	}
+	return nil
+}
@@ -0,0 +1,1364 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	sqlxmock "github.com/zhashkevych/go-sqlxmock"
+
+	"ggv2/entities"
+)
+
+// newMockTx begins a unit of work on a mock database.
+func newMockTx() (*dbTx, sqlxmock.Sqlmock) {
+	db, mock := NewMockDb()
+	mock.ExpectBegin()
+	return &dbTx{tx: db.MustBegin()}, mock
+}
+
+func TestAtomic(t *testing.T) {
+	query := regexp.QuoteMeta("DELETE FROM `table` WHERE id = ? AND version = ?")
+	type TestCase struct {
+		name      string
+		desc      string
+		beginErr  bool
+		fnErr     error
+		commitErr bool
+		expErr    error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "writes committed",
+		},
+		{
+			name:     "Sad case",
+			desc:     "begin return error",
+			beginErr: true,
+			expErr:   ErrDBErr,
+		},
+		{
+			name:   "Sad case",
+			desc:   "unit of work fails and is rolled back",
+			fnErr:  fmt.Errorf("mock error"),
+			expErr: fmt.Errorf("mock error"),
+		},
+		{
+			name:      "Sad case",
+			desc:      "commit return error",
+			commitErr: true,
+			expErr:    ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db)
+		if v.beginErr {
+			mock.ExpectBegin().WillReturnError(fmt.Errorf("mock error"))
+		} else {
+			mock.ExpectBegin()
+			mock.ExpectExec(query).WithArgs(1, 2).WillReturnResult(sqlxmock.NewResult(0, 1))
+		}
+		if v.fnErr != nil {
+			mock.ExpectRollback()
+		}
+		if v.commitErr {
+			mock.ExpectCommit().WillReturnError(fmt.Errorf("mock error"))
+		} else if !v.beginErr && v.fnErr == nil {
+			mock.ExpectCommit()
+		}
+		actErr := repo.Atomic(context.Background(), func(tx Tx) error {
+			if err := tx.DeleteTable(context.Background(), &entities.Table{TableID: 1, Version: 2}); err != nil {
+				return err
+			}
+			return v.fnErr
+		})
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestAtomicLocking(t *testing.T) {
+	type TestCase struct {
+		name    string
+		desc    string
+		locking Locking
+		lock    string
+	}
+	testcases := []TestCase{
+		{
+			name:    "Happy case",
+			desc:    "optimistic reads lock no rows",
+			locking: OptimisticLocking,
+		},
+		{
+			name:    "Happy case",
+			desc:    "pessimistic reads lock rows",
+			locking: PessimisticLocking,
+			lock:    " FOR UPDATE",
+		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db).WithLocking(v.locking)
+		mock.ExpectBegin()
+		mock.ExpectQuery("^" + regexp.QuoteMeta("SELECT * FROM `guests` WHERE name = ?"+v.lock) + "$").WithArgs("dummy").WillReturnRows(sqlxmock.NewRows([]string{"id", "name", "tableid"}).AddRow(2, "dummy", 1))
+		mock.ExpectQuery("^" + regexp.QuoteMeta("SELECT * FROM `table` WHERE id=?"+v.lock) + "$").WithArgs(1).WillReturnRows(sqlxmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("^"+regexp.QuoteMeta("SELECT * FROM `party_members` WHERE guestid = ? AND name = ?"+v.lock)+"$").WithArgs(2, "plusone").WillReturnRows(sqlxmock.NewRows([]string{"id", "guestid", "name"}).AddRow(4, 2, "plusone"))
+		mock.ExpectCommit()
+		actErr := repo.Atomic(context.Background(), func(tx Tx) error {
+			guest, err := tx.GetGuestByName(context.Background(), "dummy")
+			if err != nil {
+				return err
+			}
+			if _, err = tx.GetTable(context.Background(), guest.TableID); err != nil {
+				return err
+			}
+			_, err = tx.GetPartyMember(context.Background(), guest.ID, "plusone")
+			return err
+		})
+		assert.Nil(t, actErr, v.desc)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestAtomicRetry(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `table` WHERE id=? FOR UPDATE")
+	type TestCase struct {
+		name      string
+		desc      string
+		conflicts []error
+		expErr    error
+	}
+	testcases := []TestCase{
+		{
+			name:      "Happy case",
+			desc:      "unit of work run again after a deadlock",
+			conflicts: []error{&mysql.MySQLError{Number: mysqlErrDeadlock}},
+		},
+		{
+			name:      "Happy case",
+			desc:      "unit of work run again after a lock wait timeout",
+			conflicts: []error{&mysql.MySQLError{Number: mysqlErrLockWaitTimeout}},
+		},
+		{
+			name:      "Sad case",
+			desc:      "unit of work conflicts on every attempt",
+			conflicts: []error{&mysql.MySQLError{Number: mysqlErrDeadlock}, &mysql.MySQLError{Number: mysqlErrDeadlock}, &mysql.MySQLError{Number: mysqlErrLockWaitTimeout}},
+			expErr:    ErrLockConflict,
+		},
+	}
+	for _, v := range testcases {
+		db, mock := NewMockDb()
+		repo := NewDbRepo(db).WithLocking(PessimisticLocking)
+		for _, err := range v.conflicts {
+			mock.ExpectBegin()
+			mock.ExpectQuery(query).WithArgs(1).WillReturnError(err)
+			mock.ExpectRollback()
+		}
+		if v.expErr == nil {
+			mock.ExpectBegin()
+			mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlxmock.NewRows([]string{"id"}).AddRow(1))
+			mock.ExpectCommit()
+		}
+		attempts := 0
+		actErr := repo.Atomic(context.Background(), func(tx Tx) error {
+			attempts++
+			_, err := tx.GetTable(context.Background(), 1)
+			return err
+		})
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expErr == nil {
+			assert.Equal(t, len(v.conflicts)+1, attempts, v.desc)
+		} else {
+			assert.Equal(t, atomicAttempts, attempts, v.desc)
+		}
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestDbErr(t *testing.T) {
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "deadlock is a lock conflict",
+			err:    &mysql.MySQLError{Number: mysqlErrDeadlock},
+			expErr: ErrLockConflict,
+		},
+		{
+			name:   "Happy case",
+			desc:   "lock wait timeout is a lock conflict",
+			err:    &mysql.MySQLError{Number: mysqlErrLockWaitTimeout},
+			expErr: ErrLockConflict,
+		},
+		{
+			name:   "Sad case",
+			desc:   "other errors are database errors",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		assert.Equal(t, v.expErr, dbErr(context.Background(), v.err), v.desc)
+	}
+}
+
+func TestTxPeekGuestByName(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		mockErr  error
+		expGuest *entities.Guest
+		expErr   error
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "guest read without locking it",
+			expGuest: &entities.Guest{ID: 2, Name: "dummy", TableID: 1},
+		},
+		{
+			name:    "Sad case",
+			desc:    "guest not found",
+			mockErr: sql.ErrNoRows,
+			expErr:  ErrGuestNotFound,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		tx.lock = forUpdate
+		query := mock.ExpectQuery("^" + regexp.QuoteMeta("SELECT * FROM `guests` WHERE name = ?") + "$").WithArgs("dummy")
+		if v.mockErr != nil {
+			query.WillReturnError(v.mockErr)
+		} else {
+			query.WillReturnRows(sqlxmock.NewRows([]string{"id", "name", "tableid"}).AddRow(2, "dummy", 1))
+		}
+		actGuest, actErr := tx.PeekGuestByName(context.Background(), "dummy")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expGuest, actGuest, v.desc)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestTxGetTable(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `table` WHERE id=?")
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expRes *entities.Table
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "Db return record",
+			expRes: &entities.Table{TableID: 1, Capacity: 8, AvailableCapacity: 6, PlannedCapacity: 4, Version: 2},
+		},
+		{
+			name:   "Sad case",
+			desc:   "table not found",
+			err:    sql.ErrNoRows,
+			expErr: ErrTableNotFound,
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.err != nil {
+			mock.ExpectQuery(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlxmock.NewRows([]string{"id", "capacity", "acapacity", "pcapacity", "version"}).AddRow(1, 8, 6, 4, 2))
+		}
+		actRes, actErr := tx.GetTable(context.Background(), 1)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
+	}
+}
+
+func TestTxUpdateTable(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE `table` SET capacity=?, pcapacity=?, acapacity=?, event=?, name=?, zone=?, shape=?, tags=?, pos_x=?, pos_y=?, rotation=?, version = version + 1 WHERE id = ? AND version = ?")
+	type TestCase struct {
+		name              string
+		desc              string
+		err               error
+		dbErr             bool
+		rowsAffectedErr   bool
+		optimisticLockErr bool
+		expVersion        int64
+		expErr            error
+	}
+	testcases := []TestCase{
+		{
+			name:       "Happy case",
+			desc:       "table updated",
+			expVersion: 4,
+		},
+		{
+			name:       "Sad case",
+			desc:       "Db return error",
+			err:        fmt.Errorf("mock error"),
+			dbErr:      true,
+			expVersion: 3,
+			expErr:     ErrDBErr,
+		},
+		{
+			name:            "Sad case",
+			desc:            "rows affected return error",
+			err:             fmt.Errorf("mock error"),
+			rowsAffectedErr: true,
+			expVersion:      3,
+			expErr:          ErrDBErr,
+		},
+		{
+			name:              "Sad case",
+			desc:              "optimistic lock error",
+			optimisticLockErr: true,
+			expVersion:        3,
+			expErr:            ErrFailedOptimisticLock,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		exec := mock.ExpectExec(query).WithArgs(10, 6, 8, "gala", "12", "Garden", "round", "vip", 150, 300, 45, 1, 3)
+		switch {
+		case v.dbErr:
+			exec.WillReturnError(v.err)
+		case v.rowsAffectedErr:
+			exec.WillReturnResult(sqlxmock.NewErrorResult(v.err))
+		case v.optimisticLockErr:
+			exec.WillReturnResult(sqlxmock.NewResult(0, 0))
+		default:
+			exec.WillReturnResult(sqlxmock.NewResult(0, 1))
+		}
+		table := &entities.Table{TableID: 1, Version: 3, Capacity: 10, PlannedCapacity: 6, AvailableCapacity: 8, Event: "gala", Name: "12", Zone: "Garden", Shape: "round", Tags: entities.Tags{"vip"}, X: 150, Y: 300, Rotation: 45}
+		actErr := tx.UpdateTable(context.Background(), table)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expVersion, table.Version, v.desc)
+	}
+}
+
+func TestTxDeleteTable(t *testing.T) {
+	query := regexp.QuoteMeta("DELETE FROM `table` WHERE id = ? AND version = ?")
+	type TestCase struct {
+		name   string
+		desc   string
+		res    sql.Result
+		err    error
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "table deleted",
+			res:  sqlxmock.NewResult(0, 1),
+		},
+		{
+			name:   "Sad case",
+			desc:   "table changed since it was read",
+			res:    sqlxmock.NewResult(0, 0),
+			expErr: ErrFailedOptimisticLock,
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.err != nil {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectExec(query).WithArgs(1, 2).WillReturnResult(v.res)
+		}
+		actErr := tx.DeleteTable(context.Background(), &entities.Table{TableID: 1, Version: 2})
+		assert.Equal(t, v.expErr, actErr, v.desc)
+	}
+}
+
+func TestTxMoveGuests(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE `guests` SET tableid=?, version = version + 1 WHERE tableid = ?")
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "guests moved",
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.err != nil {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectExec(query).WithArgs(2, 1).WillReturnResult(sqlxmock.NewResult(0, 3))
+		}
+		actErr := tx.MoveGuests(context.Background(), 1, 2)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+	}
+}
+
+func TestTxCreateGuest(t *testing.T) {
+	guestQuery := regexp.QuoteMeta("INSERT INTO `guests` (total_rsvp_guests, tableid, name, dietary_tags, allergens, dietary_notes, meal_choice) VALUES(?, ?, ?, ?, ?, ?, ?)")
+	memberQuery := regexp.QuoteMeta("INSERT INTO `party_members` (guestid, name, age_group, dietary_tags, allergens, dietary_notes, meal_choice) VALUES(?, ?, ?, ?, ?, ?, ?)")
+	type TestCase struct {
+		name        string
+		desc        string
+		err         error
+		guestErr    bool
+		insertIDErr bool
+		memberErr   bool
+		expErr      error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "guest and party members created",
+		},
+		{
+			name:     "Sad case",
+			desc:     "insert guest return error",
+			err:      fmt.Errorf("mock error"),
+			guestErr: true,
+			expErr:   ErrDBErr,
+		},
+		{
+			name:     "Sad case",
+			desc:     "guest name already taken",
+			err:      &mysql.MySQLError{Number: mysqlErrDupEntry, Message: "Duplicate entry 'dummy' for key 'name'"},
+			guestErr: true,
+			expErr:   ErrGuestAlreadyRSVP,
+		},
+		{
+			name:        "Sad case",
+			desc:        "last insert id return error",
+			err:         fmt.Errorf("mock error"),
+			insertIDErr: true,
+			expErr:      ErrDBErr,
+		},
+		{
+			name:      "Sad case",
+			desc:      "insert party member return error",
+			err:       fmt.Errorf("mock error"),
+			memberErr: true,
+			expErr:    ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		exec := mock.ExpectExec(guestQuery).WithArgs(2, 3, "dummy", "vegan", "", "", "")
+		switch {
+		case v.guestErr:
+			exec.WillReturnError(v.err)
+		case v.insertIDErr:
+			exec.WillReturnResult(sqlxmock.NewErrorResult(v.err))
+		default:
+			exec.WillReturnResult(sqlxmock.NewResult(7, 1))
+		}
+		if v.memberErr {
+			mock.ExpectExec(memberQuery).WillReturnError(v.err)
+		} else {
+			mock.ExpectExec(memberQuery).WithArgs(7, "plusone", entities.AgeGroupChild, "", "", "", "").WillReturnResult(sqlxmock.NewResult(1, 1))
+		}
+		guest := &entities.Guest{Name: "dummy", TableID: 3, TotalGuests: 2, DietaryTags: entities.Tags{"vegan"}, Members: []*entities.PartyMember{{Name: "plusone", AgeGroup: entities.AgeGroupChild}}}
+		actErr := tx.CreateGuest(context.Background(), guest)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expErr == nil {
+			assert.Equal(t, int64(7), guest.ID, v.desc)
+			assert.Equal(t, int64(7), guest.Members[0].GuestID, v.desc)
+		}
+	}
+}
+
+func TestTxUpdateGuest(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE `guests` SET tableid=?, total_arrived_guests=?, arrivaltime=?, version = version + 1 WHERE id = ? AND version = ?")
+	type TestCase struct {
+		name       string
+		desc       string
+		res        sql.Result
+		err        error
+		expVersion int64
+		expErr     error
+	}
+	testcases := []TestCase{
+		{
+			name:       "Happy case",
+			desc:       "guest updated",
+			res:        sqlxmock.NewResult(0, 1),
+			expVersion: 2,
+		},
+		{
+			name:       "Sad case",
+			desc:       "optimistic lock error",
+			res:        sqlxmock.NewResult(0, 0),
+			expVersion: 1,
+			expErr:     ErrFailedOptimisticLock,
+		},
+		{
+			name:       "Sad case",
+			desc:       "Db return error",
+			err:        fmt.Errorf("mock error"),
+			expVersion: 1,
+			expErr:     ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.err != nil {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectExec(query).WithArgs(3, 2, "2021-06-01 19:30:00", 7, 1).WillReturnResult(v.res)
+		}
+		guest := &entities.Guest{ID: 7, TableID: 3, TotalArrivedGuests: 2, ArrivalTime: "2021-06-01 19:30:00", Version: 1}
+		actErr := tx.UpdateGuest(context.Background(), guest)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expVersion, guest.Version, v.desc)
+	}
+}
+
+func TestTxGetPartyMember(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT * FROM `party_members` WHERE guestid = ? AND name = ?")
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expRes *entities.PartyMember
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "Db return record",
+			expRes: &entities.PartyMember{ID: 1, GuestID: 7, Name: "plusone"},
+		},
+		{
+			name:   "Sad case",
+			desc:   "party member not found",
+			err:    sql.ErrNoRows,
+			expErr: ErrPartyMemberNotFound,
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.err != nil {
+			mock.ExpectQuery(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectQuery(query).WithArgs(7, "plusone").WillReturnRows(sqlxmock.NewRows([]string{"id", "guestid", "name", "arrived"}).AddRow(1, 7, "plusone", false))
+		}
+		actRes, actErr := tx.GetPartyMember(context.Background(), 7, "plusone")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
+	}
+}
+
+func TestTxSetMemberArrived(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE `party_members` SET arrived=? WHERE id = ? AND arrived=?")
+	type TestCase struct {
+		name       string
+		desc       string
+		res        sql.Result
+		err        error
+		expArrived bool
+		expErr     error
+	}
+	testcases := []TestCase{
+		{
+			name:       "Happy case",
+			desc:       "member checked-in",
+			res:        sqlxmock.NewResult(0, 1),
+			expArrived: true,
+		},
+		{
+			name:   "Sad case",
+			desc:   "member checked-in concurrently",
+			res:    sqlxmock.NewResult(0, 0),
+			expErr: ErrFailedOptimisticLock,
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.err != nil {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectExec(query).WithArgs(true, 1, false).WillReturnResult(v.res)
+		}
+		member := &entities.PartyMember{ID: 1}
+		actErr := tx.SetMemberArrived(context.Background(), member, true)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expArrived, member.Arrived, v.desc)
+	}
+}
+
+func TestTxResetPartyArrival(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE `party_members` SET arrived=0 WHERE guestid = ?")
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "party checked-out",
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.err != nil {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectExec(query).WithArgs(7).WillReturnResult(sqlxmock.NewResult(0, 2))
+		}
+		actErr := tx.ResetPartyArrival(context.Background(), 7)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+	}
+}
+
+func TestTxCreateTable(t *testing.T) {
+	query := regexp.QuoteMeta("INSERT INTO `table` (capacity, pcapacity, acapacity, event, name, zone, shape, tags, pos_x, pos_y, rotation) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	type TestCase struct {
+		name          string
+		desc          string
+		input         *entities.Table
+		err           error
+		dbErr         bool
+		expRes        *entities.Table
+		expErr        error
+		lastInsertErr bool
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "Db return record",
+			input: &entities.Table{
+				Capacity: int64(7),
+			},
+			expRes: &entities.Table{
+				TableID:  99,
+				Capacity: int64(7),
+				Version:  0,
+			},
+		},
+		{
+			name: "Sad case",
+			desc: "Db return error",
+			input: &entities.Table{
+				Capacity: int64(7),
+			},
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+			dbErr:  true,
+		},
+		{
+			name: "Sad case",
+			desc: "LastInsertId return error",
+			input: &entities.Table{
+				Capacity: int64(7),
+			},
+			err:           fmt.Errorf("LastInsertId error"),
+			expErr:        ErrDBErr,
+			lastInsertErr: true,
+		},
+	}
+
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.dbErr {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		}
+		if v.lastInsertErr {
+			mock.ExpectExec(query).WillReturnResult(sqlxmock.NewErrorResult(v.err))
+		}
+		mock.ExpectExec(query).WillReturnResult(sqlxmock.NewResult(99, 1))
+		actErr := tx.CreateTable(context.Background(), v.input)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expErr == nil {
+			assert.Equal(t, v.expRes, v.input, v.desc)
+		}
+	}
+}
+
+func TestTxEmptyTables(t *testing.T) {
+	archiveQuery := regexp.QuoteMeta("INSERT INTO `archives` (event, tables, guests, data) VALUES(?, ?, ?, ?)")
+	type TestCase struct {
+		name       string
+		desc       string
+		event      string
+		err        error
+		selectErr  bool
+		archiveErr bool
+		deleteErr  bool
+		expRes     *entities.Archive
+		expErr     error
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "every event archived and emptied",
+			expRes: &entities.Archive{ID: 3, Tables: 1, Guests: 1},
+		},
+		{
+			name:   "Happy case",
+			desc:   "single event archived and emptied",
+			event:  "gala",
+			expRes: &entities.Archive{ID: 3, Event: "gala", Tables: 1, Guests: 1},
+		},
+		{
+			name:      "Sad case",
+			desc:      "snapshot return error",
+			err:       fmt.Errorf("mock error"),
+			selectErr: true,
+			expErr:    ErrDBErr,
+		},
+		{
+			name:       "Sad case",
+			desc:       "archive return error",
+			err:        fmt.Errorf("mock error"),
+			archiveErr: true,
+			expErr:     ErrDBErr,
+		},
+		{
+			name:      "Sad case",
+			desc:      "delete return error",
+			event:     "gala",
+			err:       fmt.Errorf("mock error"),
+			deleteErr: true,
+			expErr:    ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		selects, deletes, args := snapshotQueries, emptyQueries, []driver.Value{}
+		if v.event != "" {
+			selects, deletes, args = eventSnapshotQueries, eventEmptyQueries, []driver.Value{v.event}
+		}
+		func() {
+			if v.selectErr {
+				mock.ExpectQuery(regexp.QuoteMeta(selects[0] + forUpdate)).WillReturnError(v.err)
+				return
+			}
+			mock.ExpectQuery(regexp.QuoteMeta(selects[0] + forUpdate)).WithArgs(args...).WillReturnRows(sqlxmock.NewRows([]string{"id", "capacity", "event"}).AddRow(1, 8, v.event))
+			mock.ExpectQuery(regexp.QuoteMeta(selects[1] + forUpdate)).WithArgs(args...).WillReturnRows(sqlxmock.NewRows([]string{"id", "name", "tableid"}).AddRow(2, "bob", 1))
+			mock.ExpectQuery(regexp.QuoteMeta(selects[2] + forUpdate)).WithArgs(args...).WillReturnRows(sqlxmock.NewRows([]string{"id", "guestid", "name"}))
+			if v.archiveErr {
+				mock.ExpectExec(archiveQuery).WillReturnError(v.err)
+				return
+			}
+			mock.ExpectExec(archiveQuery).WithArgs(v.event, 1, 1, sqlxmock.AnyArg()).WillReturnResult(sqlxmock.NewResult(3, 1))
+			if v.deleteErr {
+				mock.ExpectExec(regexp.QuoteMeta(deletes[0])).WillReturnError(v.err)
+				return
+			}
+			for _, query := range deletes {
+				mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(args...).WillReturnResult(sqlxmock.NewResult(0, 1))
+			}
+		}()
+		actRes, actErr := tx.EmptyTables(context.Background(), v.event)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expRes != nil {
+			assert.Equal(t, v.expRes.ID, actRes.ID, v.desc)
+			assert.Equal(t, v.expRes.Event, actRes.Event, v.desc)
+			assert.Equal(t, v.expRes.Tables, actRes.Tables, v.desc)
+			assert.Equal(t, v.expRes.Guests, actRes.Guests, v.desc)
+			assert.Equal(t, entities.SnapshotVersion, actRes.Snapshot.Version, v.desc)
+			assert.Equal(t, []*entities.Table{{TableID: 1, Capacity: 8, Event: v.event}}, actRes.Snapshot.Tables, v.desc)
+			assert.Equal(t, []*entities.Guest{{ID: 2, Name: "bob", TableID: 1}}, actRes.Snapshot.Guests, v.desc)
+			assert.Equal(t, []*entities.PartyMember{}, actRes.Snapshot.PartyMembers, v.desc)
+		} else {
+			assert.Nil(t, actRes, v.desc)
+		}
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestTxRestoreArchive(t *testing.T) {
+	getQuery := regexp.QuoteMeta("SELECT * FROM `archives` WHERE id = ? FOR UPDATE")
+	tableQuery := regexp.QuoteMeta("INSERT INTO `table` (id, capacity, pcapacity, acapacity, version, event, name, zone, shape, tags, pos_x, pos_y, rotation) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	guestQuery := regexp.QuoteMeta("INSERT INTO `guests` (id, name, total_rsvp_guests, total_arrived_guests, version, arrivaltime, tableid, dietary_tags, allergens, dietary_notes, meal_choice) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	memberQuery := regexp.QuoteMeta("INSERT INTO `party_members` (id, guestid, name, age_group, arrived, dietary_tags, allergens, dietary_notes, meal_choice) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	restoredQuery := regexp.QuoteMeta("UPDATE `archives` SET restored=1 WHERE id = ?")
+	data := `{"version":1,"event":"gala","tables":[{"id":1,"capacity":8,"acapacity":6,"pcapacity":4,"version":2,"event":"gala"}],"guests":[{"id":2,"name":"bob","tableid":1,"total_rsvp_guests":4,"total_arrived_guests":2}],"party_members":[{"id":3,"guestid":2,"name":"alice","arrived":true}]}`
+	columns := []string{"id", "event", "tables", "guests", "created_at", "restored", "data"}
+	type TestCase struct {
+		name        string
+		desc        string
+		err         error
+		restored    bool
+		notFound    bool
+		getErr      bool
+		conflictErr bool
+		insertErr   bool
+		updateErr   bool
+		expErr      error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "archive restored",
+		},
+		{
+			name:     "Sad case",
+			desc:     "archive not found",
+			err:      sql.ErrNoRows,
+			notFound: true,
+			expErr:   ErrArchiveNotFound,
+		},
+		{
+			name:   "Sad case",
+			desc:   "get archive return error",
+			err:    fmt.Errorf("mock error"),
+			getErr: true,
+			expErr: ErrDBErr,
+		},
+		{
+			name:     "Sad case",
+			desc:     "archive already restored",
+			restored: true,
+			expErr:   ErrArchiveAlreadyRestored,
+		},
+		{
+			name:        "Sad case",
+			desc:        "archived table recreated since",
+			err:         &mysql.MySQLError{Number: mysqlErrDupEntry, Message: "Duplicate entry '1' for key 'PRIMARY'"},
+			conflictErr: true,
+			expErr:      ErrRestoreConflict,
+		},
+		{
+			name:      "Sad case",
+			desc:      "insert return error",
+			err:       fmt.Errorf("mock error"),
+			insertErr: true,
+			expErr:    ErrDBErr,
+		},
+		{
+			name:      "Sad case",
+			desc:      "mark archive restored return error",
+			err:       fmt.Errorf("mock error"),
+			updateErr: true,
+			expErr:    ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		func() {
+			if v.notFound || v.getErr {
+				mock.ExpectQuery(getQuery).WillReturnError(v.err)
+				return
+			}
+			mock.ExpectQuery(getQuery).WithArgs(5).WillReturnRows(sqlxmock.NewRows(columns).AddRow(5, "gala", 1, 1, "2021-06-01 20:00:00", v.restored, data))
+			if v.restored {
+				return
+			}
+			if v.conflictErr {
+				mock.ExpectExec(tableQuery).WillReturnError(v.err)
+				return
+			}
+			mock.ExpectExec(tableQuery).WithArgs(1, 8, 4, 6, 2, "gala", "", "", "", "", 0, 0, 0).WillReturnResult(sqlxmock.NewResult(1, 1))
+			if v.insertErr {
+				mock.ExpectExec(guestQuery).WillReturnError(v.err)
+				return
+			}
+			mock.ExpectExec(guestQuery).WithArgs(2, "bob", 4, 2, 0, "", 1, "", "", "", "").WillReturnResult(sqlxmock.NewResult(2, 1))
+			mock.ExpectExec(memberQuery).WithArgs(3, 2, "alice", "", true, "", "", "", "").WillReturnResult(sqlxmock.NewResult(3, 1))
+			if v.updateErr {
+				mock.ExpectExec(restoredQuery).WillReturnError(v.err)
+				return
+			}
+			mock.ExpectExec(restoredQuery).WithArgs(5).WillReturnResult(sqlxmock.NewResult(0, 1))
+		}()
+		actRes, actErr := tx.RestoreArchive(context.Background(), 5)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expErr == nil {
+			assert.Equal(t, int64(5), actRes.ID, v.desc)
+			assert.True(t, actRes.Restored, v.desc)
+			assert.Equal(t, "gala", actRes.Snapshot.Event, v.desc)
+		} else {
+			assert.Nil(t, actRes, v.desc)
+		}
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestTxHasTables(t *testing.T) {
+	type TestCase struct {
+		name   string
+		desc   string
+		event  string
+		query  string
+		args   []driver.Value
+		rows   *sqlxmock.Rows
+		err    error
+		expRes bool
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "event with tables",
+			event:  "gala",
+			query:  "SELECT id FROM `table` WHERE event = ? FOR UPDATE",
+			args:   []driver.Value{"gala"},
+			rows:   sqlxmock.NewRows([]string{"id"}).AddRow(1),
+			expRes: true,
+		},
+		{
+			name:  "Happy case",
+			desc:  "default event without tables",
+			query: "SELECT id FROM `table` FOR UPDATE",
+			rows:  sqlxmock.NewRows([]string{"id"}),
+		},
+		{
+			name:   "Sad case",
+			desc:   "select return error",
+			event:  "gala",
+			query:  "SELECT id FROM `table` WHERE event = ? FOR UPDATE",
+			args:   []driver.Value{"gala"},
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		exp := mock.ExpectQuery(regexp.QuoteMeta(v.query)).WithArgs(v.args...)
+		if v.err != nil {
+			exp.WillReturnError(v.err)
+		} else {
+			exp.WillReturnRows(v.rows)
+		}
+		actRes, actErr := tx.HasTables(context.Background(), v.event)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestTxCreateTables(t *testing.T) {
+	query := regexp.QuoteMeta("INSERT INTO `table` (capacity, pcapacity, acapacity, event, name, zone, shape, tags, pos_x, pos_y, rotation) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	type TestCase struct {
+		name          string
+		desc          string
+		err           error
+		insertErr     bool
+		lastInsertErr bool
+		expErr        error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all tables created",
+		},
+		{
+			name:      "Sad case",
+			desc:      "second insert return error",
+			err:       fmt.Errorf("mock error"),
+			insertErr: true,
+			expErr:    ErrDBErr,
+		},
+		{
+			name:          "Sad case",
+			desc:          "LastInsertId return error",
+			err:           fmt.Errorf("mock error"),
+			lastInsertErr: true,
+			expErr:        ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		tables := []*entities.Table{
+			{Capacity: 8, Zone: "Garden"},
+			{Capacity: 12},
+		}
+		func() {
+			if v.lastInsertErr {
+				mock.ExpectExec(query).WillReturnResult(sqlxmock.NewErrorResult(v.err))
+				return
+			}
+			mock.ExpectExec(query).WithArgs(8, 8, 8, "", "", "Garden", "", "", 0, 0, 0).WillReturnResult(sqlxmock.NewResult(1, 1))
+			if v.insertErr {
+				mock.ExpectExec(query).WillReturnError(v.err)
+				return
+			}
+			mock.ExpectExec(query).WithArgs(12, 12, 12, "", "", "", "", "", 0, 0, 0).WillReturnResult(sqlxmock.NewResult(2, 1))
+		}()
+		actErr := tx.CreateTables(context.Background(), tables)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expErr == nil {
+			assert.Equal(t, int64(1), tables[0].TableID, v.desc)
+			assert.Equal(t, int64(2), tables[1].TableID, v.desc)
+		}
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestTxSaveLayoutTemplate(t *testing.T) {
+	query := regexp.QuoteMeta("INSERT INTO `layout_templates` (name, layout) VALUES(?, ?) ON DUPLICATE KEY UPDATE layout = VALUES(layout), version = version + 1")
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "template saved",
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.err != nil {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		} else {
+			mock.ExpectExec(query).WithArgs("wedding", `[{"count":10,"capacity":8,"zone":"Garden","tags":["vip"]}]`).WillReturnResult(sqlxmock.NewResult(1, 1))
+		}
+		actErr := tx.SaveLayoutTemplate(context.Background(), &entities.LayoutTemplate{
+			Name:   "wedding",
+			Groups: entities.TableGroups{{Count: 10, Capacity: 8, Zone: "Garden", Tags: entities.Tags{"vip"}}},
+		})
+		assert.Equal(t, v.expErr, actErr, v.desc)
+	}
+}
+
+func TestTxDeleteLayoutTemplate(t *testing.T) {
+	query := regexp.QuoteMeta("DELETE FROM `layout_templates` WHERE name = ?")
+	type TestCase struct {
+		name            string
+		desc            string
+		err             error
+		dbErr           bool
+		rowsAffectedErr bool
+		notFound        bool
+		expErr          error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "template deleted",
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			dbErr:  true,
+			expErr: ErrDBErr,
+		},
+		{
+			name:            "Sad case",
+			desc:            "rows affected return error",
+			err:             fmt.Errorf("mock error"),
+			rowsAffectedErr: true,
+			expErr:          ErrDBErr,
+		},
+		{
+			name:     "Sad case",
+			desc:     "template not found",
+			notFound: true,
+			expErr:   ErrLayoutTemplateNotFound,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.dbErr {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		}
+		if v.rowsAffectedErr {
+			mock.ExpectExec(query).WillReturnResult(sqlxmock.NewErrorResult(v.err))
+		}
+		if v.notFound {
+			mock.ExpectExec(query).WillReturnResult(sqlxmock.NewResult(0, 0))
+		}
+		mock.ExpectExec(query).WithArgs("wedding").WillReturnResult(sqlxmock.NewResult(0, 1))
+		actErr := tx.DeleteLayoutTemplate(context.Background(), "wedding")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+	}
+}
+
+func TestTxCreateAPIKey(t *testing.T) {
+	query := regexp.QuoteMeta("INSERT INTO `api_keys` (name, prefix, role, key_hash) VALUES(?, ?, ?, ?)")
+	type TestCase struct {
+		name          string
+		desc          string
+		err           error
+		dbErr         bool
+		lastInsertErr bool
+		expRes        *entities.APIKey
+		expErr        error
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "key created",
+			expRes: &entities.APIKey{ID: 7, Name: "kiosk", Prefix: "ggv2_abcdefg", Role: "door_staff", KeyHash: "hash"},
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			dbErr:  true,
+			expErr: ErrDBErr,
+		},
+		{
+			name:          "Sad case",
+			desc:          "LastInsertId return error",
+			err:           fmt.Errorf("mock error"),
+			lastInsertErr: true,
+			expErr:        ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.dbErr {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		}
+		if v.lastInsertErr {
+			mock.ExpectExec(query).WillReturnResult(sqlxmock.NewErrorResult(v.err))
+		}
+		mock.ExpectExec(query).WithArgs("kiosk", "ggv2_abcdefg", "door_staff", "hash").WillReturnResult(sqlxmock.NewResult(7, 1))
+		key := &entities.APIKey{Name: "kiosk", Prefix: "ggv2_abcdefg", Role: "door_staff", KeyHash: "hash"}
+		actErr := tx.CreateAPIKey(context.Background(), key)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expErr == nil {
+			assert.Equal(t, v.expRes, key, v.desc)
+		}
+	}
+}
+
+func TestTxRevokeAPIKey(t *testing.T) {
+	query := regexp.QuoteMeta("UPDATE `api_keys` SET revoked=1 WHERE id = ? AND revoked=0")
+	type TestCase struct {
+		name            string
+		desc            string
+		err             error
+		dbErr           bool
+		rowsAffectedErr bool
+		notFound        bool
+		expErr          error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "key revoked",
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			dbErr:  true,
+			expErr: ErrDBErr,
+		},
+		{
+			name:            "Sad case",
+			desc:            "rows affected return error",
+			err:             fmt.Errorf("mock error"),
+			rowsAffectedErr: true,
+			expErr:          ErrDBErr,
+		},
+		{
+			name:     "Sad case",
+			desc:     "key not found or already revoked",
+			notFound: true,
+			expErr:   ErrAPIKeyNotFound,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		if v.dbErr {
+			mock.ExpectExec(query).WillReturnError(v.err)
+		}
+		if v.rowsAffectedErr {
+			mock.ExpectExec(query).WillReturnResult(sqlxmock.NewErrorResult(v.err))
+		}
+		if v.notFound {
+			mock.ExpectExec(query).WillReturnResult(sqlxmock.NewResult(0, 0))
+		}
+		mock.ExpectExec(query).WithArgs(7).WillReturnResult(sqlxmock.NewResult(0, 1))
+		actErr := tx.RevokeAPIKey(context.Background(), 7)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+	}
+}
+
+func TestTxRestoreSnapshot(t *testing.T) {
+	archiveQuery := regexp.QuoteMeta("INSERT INTO `archives` (event, tables, guests, data) VALUES(?, ?, ?, ?)")
+	tableQuery := regexp.QuoteMeta("INSERT INTO `table` (id, capacity, pcapacity, acapacity, version, event, name, zone, shape, tags, pos_x, pos_y, rotation) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	snapshot := &entities.Snapshot{
+		Version:      entities.SnapshotVersion,
+		Event:        "gala",
+		Tables:       []*entities.Table{{TableID: 1, Capacity: 8, AvailableCapacity: 8, PlannedCapacity: 8, Event: "gala"}},
+		Guests:       []*entities.Guest{},
+		PartyMembers: []*entities.PartyMember{},
+	}
+	type TestCase struct {
+		name        string
+		desc        string
+		replace     bool
+		err         error
+		replaceErr  bool
+		conflictErr bool
+		expRes      *entities.Archive
+		expErr      error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "snapshot restored",
+		},
+		{
+			name:    "Happy case",
+			desc:    "snapshot replaced event",
+			replace: true,
+			expRes:  &entities.Archive{ID: 4, Event: "gala", Tables: 1},
+		},
+		{
+			name:       "Sad case",
+			desc:       "archiving replaced event return error",
+			replace:    true,
+			err:        fmt.Errorf("mock error"),
+			replaceErr: true,
+			expErr:     ErrDBErr,
+		},
+		{
+			name:        "Sad case",
+			desc:        "restored table conflicts",
+			err:         &mysql.MySQLError{Number: mysqlErrDupEntry, Message: "Duplicate entry '1' for key 'PRIMARY'"},
+			conflictErr: true,
+			expErr:      ErrRestoreConflict,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		func() {
+			if v.replace {
+				mock.ExpectQuery(regexp.QuoteMeta(eventSnapshotQueries[0] + forUpdate)).WithArgs("gala").WillReturnRows(sqlxmock.NewRows([]string{"id", "capacity", "event"}).AddRow(1, 8, "gala"))
+				mock.ExpectQuery(regexp.QuoteMeta(eventSnapshotQueries[1] + forUpdate)).WithArgs("gala").WillReturnRows(sqlxmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(regexp.QuoteMeta(eventSnapshotQueries[2] + forUpdate)).WithArgs("gala").WillReturnRows(sqlxmock.NewRows([]string{"id"}))
+				if v.replaceErr {
+					mock.ExpectExec(archiveQuery).WillReturnError(v.err)
+					return
+				}
+				mock.ExpectExec(archiveQuery).WithArgs("gala", 1, 0, sqlxmock.AnyArg()).WillReturnResult(sqlxmock.NewResult(4, 1))
+				for _, query := range eventEmptyQueries {
+					mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs("gala").WillReturnResult(sqlxmock.NewResult(0, 1))
+				}
+			}
+			if v.conflictErr {
+				mock.ExpectExec(tableQuery).WillReturnError(v.err)
+				return
+			}
+			mock.ExpectExec(tableQuery).WithArgs(1, 8, 8, 8, 0, "gala", "", "", "", "", 0, 0, 0).WillReturnResult(sqlxmock.NewResult(1, 1))
+		}()
+		actRes, actErr := tx.RestoreSnapshot(context.Background(), snapshot, v.replace)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expRes != nil {
+			assert.Equal(t, v.expRes.ID, actRes.ID, v.desc)
+			assert.Equal(t, v.expRes.Event, actRes.Event, v.desc)
+			assert.Equal(t, v.expRes.Tables, actRes.Tables, v.desc)
+		} else {
+			assert.Nil(t, actRes, v.desc)
+		}
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
+
+func TestTxCreateAuditEntry(t *testing.T) {
+	query := regexp.QuoteMeta("INSERT INTO `audit_log` (actor, request_id, operation, target, before_value, after_value) VALUES(?, ?, ?, ?, ?, ?)")
+	type TestCase struct {
+		name          string
+		desc          string
+		err           error
+		dbErr         bool
+		lastInsertErr bool
+		expErr        error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "entry recorded",
+		},
+		{
+			name:   "Sad case",
+			desc:   "Db return error",
+			err:    fmt.Errorf("mock error"),
+			dbErr:  true,
+			expErr: ErrDBErr,
+		},
+		{
+			name:          "Sad case",
+			desc:          "LastInsertId return error",
+			err:           fmt.Errorf("mock error"),
+			lastInsertErr: true,
+			expErr:        ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		tx, mock := newMockTx()
+		entry := &entities.AuditEntry{Actor: "jwt:alice", RequestID: "req-1", Operation: "update_table", Target: "table:1", Before: entities.JSON(`{"zone":""}`), After: entities.JSON(`{"zone":"Garden"}`)}
+		switch {
+		case v.dbErr:
+			mock.ExpectExec(query).WillReturnError(v.err)
+		case v.lastInsertErr:
+			mock.ExpectExec(query).WillReturnResult(sqlxmock.NewErrorResult(v.err))
+		default:
+			mock.ExpectExec(query).WithArgs("jwt:alice", "req-1", "update_table", "table:1", `{"zone":""}`, `{"zone":"Garden"}`).WillReturnResult(sqlxmock.NewResult(5, 1))
+		}
+		actErr := tx.CreateAuditEntry(context.Background(), entry)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expErr == nil {
+			assert.Equal(t, int64(5), entry.ID, v.desc)
+		}
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
@@ -1,66 +1,229 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"time"
 
-	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo-contrib/prometheus"
 	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+	prom "github.com/prometheus/client_golang/prometheus"
 
-	"ggv2/handler"
+	"ggv2/auth"
 	"ggv2/handler/middleware"
+	"ggv2/repo"
 )
 
 type router struct {
-	Port int
-	Conn *sqlx.DB
+	*container
 }
 
-func NewRouter(port int, conn *sqlx.DB) *router {
+func NewRouter(app *container) *router {
 	return &router{
-		Port: port,
-		Conn: conn,
+		container: app,
 	}
 }
 
-func (router *router) InitRouter() *echo.Echo {
-	th := handler.NewTableHandler(router.Conn)
-	gh := handler.NewGuestHandler(router.Conn)
+// InitRouter serves requests until ctx is cancelled, then stops accepting
+// connections and waits for in-flight requests to complete. An error is
+// returned when the server fails to start or to drain in time.
+func (router *router) InitRouter(ctx context.Context) error {
+	serverCfg := router.Config.Server
+	r := router.routes()
+	r.HideBanner = true
+	r.Server.ReadTimeout = serverCfg.ReadTimeout
+	r.Server.WriteTimeout = serverCfg.WriteTimeout
+	r.Server.IdleTimeout = serverCfg.IdleTimeout
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- r.Start(fmt.Sprintf(":%d", serverCfg.Port))
+	}()
+	select {
+	case err := <-errCh:
+		// Failed to start
+		return err
+	case <-ctx.Done():
+	}
+
+	// Fail readiness so that no new requests are routed here, then drain in-flight requests
+	router.Health.SetDraining()
+	time.Sleep(serverCfg.DrainDelay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverCfg.ShutdownTimeout)
+	defer cancel()
+	err := r.Shutdown(shutdownCtx)
+	if startErr := <-errCh; startErr != nil && !errors.Is(startErr, http.ErrServerClosed) {
+		return startErr
+	}
+	return err
+}
+
+// routes builds the echo instance with its middleware and routes, each route
+// requires the permission matching the action it performs.
+func (router *router) routes() *echo.Echo {
+	h := router.Handlers()
+	th, gh, rh, lh := h.Table, h.Guest, h.Report, h.Layout
+	ah, adh, auh, hh, ch := h.Auth, h.Admin, h.Audit, h.Health, h.Config
 	r := echo.New()
+	r.IPExtractor = ipExtractor(router.Config.Server.TrustedProxies)
+
+	// Health checks and metrics are public and not traced
+	public := func(c echo.Context) bool {
+		switch c.Path() {
+		case "/ping", "/healthz", "/readyz", "/metrics":
+			return true
+		}
+		return false
+	}
 
 	// Middleware
-	r.Use(middleware.Middleware())
-
-	p := prometheus.NewPrometheus("GGv2", nil)
+	logCfg := router.Config.Log
+	r.Use(middleware.Middleware(middleware.LogConfig{
+		Debug:        logCfg.Debug,
+		BodyRoutes:   logCfg.BodyRoutes,
+		MaxBodySize:  logCfg.MaxBodySize,
+		RedactFields: logCfg.RedactFields,
+		SampleReads:  logCfg.SampleReads,
+	}))
+	r.Use(middleware.Tracing(public))
+	r.Use(echomw.CORSWithConfig(echomw.CORSConfig{
+		AllowOrigins: router.Config.CORS.AllowOrigins,
+		AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete, http.MethodOptions},
+	}))
+
+	rateLimited := &prometheus.Metric{
+		ID:          "rateLimitRequests",
+		Name:        "rate_limit_requests_total",
+		Description: "How many requests were allowed or limited, partitioned by route group and result.",
+		Type:        "counter_vec",
+		Args:        []string{"group", "result"},
+	}
+	p := prometheus.NewPrometheus(repo.MetricsSubsystem, nil, []*prometheus.Metric{rateLimited})
 	p.Use(r)
 
+	// Rate limiting per IP, so that requests failing authentication are limited too
+	rateLimitCfg := middleware.RateLimitConfig{}
+	if router.Config.Features.RateLimit {
+		limits := router.Config.RateLimit
+		rateLimitCfg = middleware.RateLimitConfig{
+			middleware.GroupAuthenticate: {Rate: limits.Authenticate.RPS, Burst: limits.Authenticate.Burst},
+			auth.PermRead:                {Rate: limits.Read.RPS, Burst: limits.Read.Burst},
+			auth.PermCheckIn:             {Rate: limits.CheckIn.RPS, Burst: limits.CheckIn.Burst},
+			auth.PermPlan:                {Rate: limits.Plan.RPS, Burst: limits.Plan.Burst},
+			auth.PermAdmin:               {Rate: limits.Admin.RPS, Burst: limits.Admin.Burst},
+		}
+	}
+	limiter := middleware.NewRateLimiter(rateLimitCfg, rateLimited.MetricCollector.(*prom.CounterVec))
+	r.Use(limiter.LimitIP(public))
+
+	// Authentication
+	r.Use(middleware.Auth(middleware.AuthConfig{
+		Skipper: public,
+		Config:  router.Auth,
+		Keys:    router.Service,
+	}))
+
+	// Authorisation, then rate limiting per route group, then replay responses of retried POST
+	// and PUT requests carrying an Idempotency-Key, only requests that are allowed and within
+	// their limit are stored or replayed
+	idempotent := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	if router.Config.Features.Idempotency {
+		idempotent = middleware.Idempotency(middleware.IdempotencyConfig{
+			Store:       router.Service,
+			TTL:         router.Config.Idempotency.TTL,
+			MaxBodySize: router.Config.Idempotency.MaxBodySize,
+		})
+	}
+	read := middleware.Chain(middleware.RequirePermission(auth.PermRead), limiter.Limit(auth.PermRead))
+	checkIn := middleware.Chain(middleware.RequirePermission(auth.PermCheckIn), limiter.Limit(auth.PermCheckIn), idempotent)
+	plan := middleware.Chain(middleware.RequirePermission(auth.PermPlan), limiter.Limit(auth.PermPlan), idempotent)
+	admin := middleware.Chain(middleware.RequirePermission(auth.PermAdmin), limiter.Limit(auth.PermAdmin), idempotent)
+
 	// Healthcheck
 	r.GET("/ping", gh.Ping)
-
-	// // Empty tables
-	r.GET("/empty_tables", th.EmptyTables)
+	r.GET("/healthz", hh.Healthz)
+	r.GET("/readyz", hh.Readyz)
 
 	// // Tables
-	r.GET("/tables", th.GetTables)
-	r.GET("/table/:id", th.GetTable)
-	r.PUT("/table", th.CreateTable)
+	r.GET("/tables", th.GetTables, read)
+	r.GET("/table/:id", th.GetTable, read)
+	r.PUT("/table", th.CreateTable, plan)
+	r.PUT("/tables", th.CreateTables, plan)
+	r.PATCH("/table/:id", th.UpdateTable, plan)
+	r.DELETE("/table/:id", th.DeleteTable, plan)
+
+	// // Layout Templates
+	r.GET("/layout_templates", lh.ListLayoutTemplates, read)
+	r.GET("/layout_templates/:name", lh.GetLayoutTemplate, read)
+	r.PUT("/layout_templates/:name", lh.SaveLayoutTemplate, plan)
+	r.DELETE("/layout_templates/:name", lh.DeleteLayoutTemplate, plan)
+	r.POST("/layout_templates/:name/apply", lh.ApplyLayoutTemplate, plan)
+
+	// // Floor Plan
+	r.GET("/floor_plan", th.GetFloorPlan, read)
 
 	// // Guest List
-	r.POST("/guest_list/:name", gh.AddToGuestList)
-	r.GET("/guest_list", gh.GetGuestList)
+	r.POST("/guest_list/:name", gh.AddToGuestList, plan)
+	r.GET("/guest_list", gh.GetGuestList, read)
 
 	// // Guest Arrives
-	r.PUT("/guests/:name", gh.GuestArrived)
+	r.PUT("/guests/:name", gh.GuestArrived, checkIn)
 
 	// // Guest Leaves
-	r.DELETE("/guests/:name", gh.GuestDepart)
+	r.DELETE("/guests/:name", gh.GuestDepart, checkIn)
+
+	// // Party Members
+	r.GET("/guests/:name/members", gh.ListPartyMembers, read)
+	r.PUT("/guests/:name/members/:member", gh.MemberArrived, checkIn)
 
 	// // List Arrived Guest
-	r.GET("/guests", gh.ListArrivedGuest)
+	r.GET("/guests", gh.ListArrivedGuest, read)
 
 	// // Empty Seats
-	r.GET("/seats_empty", th.GetEmptySeatsCount)
+	r.GET("/seats_empty", th.GetEmptySeatsCount, read)
+
+	// // API Keys
+	r.GET("/admin/api_keys", ah.ListAPIKeys, admin)
+	r.POST("/admin/api_keys", ah.CreateAPIKey, admin)
+	r.DELETE("/admin/api_keys/:id", ah.RevokeAPIKey, admin)
+
+	// // Empty Tables, archived so that emptying can be undone
+	r.POST("/admin/empty_tables", adh.EmptyTables, admin)
+	r.GET("/admin/archives", adh.ListArchives, admin)
+	r.POST("/admin/archives/:id/restore", adh.RestoreArchive, admin)
+
+	// // Snapshots
+	r.GET("/admin/snapshot", adh.Snapshot, admin)
+	r.POST("/admin/restore", adh.RestoreSnapshot, admin)
+
+	// // Configuration, secrets redacted
+	r.GET("/admin/config", ch.GetConfig, admin)
+
+	// // Audit Log
+	r.GET("/audit", auh.ListAuditEntries, admin)
+
+	// // Reports
+	r.GET("/reports/catering", rh.CateringReport, read)
 
-	r.Start(fmt.Sprintf(":%d", router.Port))
 	return r
 }
+
+// ipExtractor takes the client IP from X-Forwarded-For past the trusted proxies, or from the
+// connection when no proxy is trusted so that clients cannot pick the IP they are limited by.
+func ipExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, p := range trustedProxies {
+		// Ranges are validated with the configuration
+		if _, ipRange, err := net.ParseCIDR(p); err == nil {
+			options = append(options, echo.TrustIPRange(ipRange))
+		}
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	sqlxmock "github.com/zhashkevych/go-sqlxmock"
+
+	"ggv2/auth"
+	"ggv2/config"
+)
+
+func TestRoutePermissions(t *testing.T) {
+	db, _, err := sqlxmock.Newx()
+	assert.Nil(t, err)
+	secret := []byte("secret")
+	cfg := config.Default()
+	cfg.Features.RateLimit = false
+	r := NewRouter(NewContainer(db, cfg, &auth.Config{JWTSecret: secret})).routes()
+
+	type Route struct {
+		method string
+		path   string
+		perm   auth.Permission
+	}
+	routes := []Route{
+		{http.MethodGet, "/tables", auth.PermRead},
+		{http.MethodGet, "/table/1", auth.PermRead},
+		{http.MethodPut, "/table", auth.PermPlan},
+		{http.MethodPut, "/tables", auth.PermPlan},
+		{http.MethodPatch, "/table/1", auth.PermPlan},
+		{http.MethodDelete, "/table/1", auth.PermPlan},
+		{http.MethodGet, "/layout_templates", auth.PermRead},
+		{http.MethodGet, "/layout_templates/wedding", auth.PermRead},
+		{http.MethodPut, "/layout_templates/wedding", auth.PermPlan},
+		{http.MethodDelete, "/layout_templates/wedding", auth.PermPlan},
+		{http.MethodPost, "/layout_templates/wedding/apply", auth.PermPlan},
+		{http.MethodGet, "/floor_plan", auth.PermRead},
+		{http.MethodPost, "/guest_list/dummy", auth.PermPlan},
+		{http.MethodGet, "/guest_list", auth.PermRead},
+		{http.MethodPut, "/guests/dummy", auth.PermCheckIn},
+		{http.MethodDelete, "/guests/dummy", auth.PermCheckIn},
+		{http.MethodGet, "/guests/dummy/members", auth.PermRead},
+		{http.MethodPut, "/guests/dummy/members/alice", auth.PermCheckIn},
+		{http.MethodGet, "/guests", auth.PermRead},
+		{http.MethodGet, "/seats_empty", auth.PermRead},
+		{http.MethodGet, "/admin/api_keys", auth.PermAdmin},
+		{http.MethodPost, "/admin/api_keys", auth.PermAdmin},
+		{http.MethodDelete, "/admin/api_keys/1", auth.PermAdmin},
+		{http.MethodPost, "/admin/empty_tables", auth.PermAdmin},
+		{http.MethodGet, "/admin/archives", auth.PermAdmin},
+		{http.MethodPost, "/admin/archives/1/restore", auth.PermAdmin},
+		{http.MethodGet, "/admin/snapshot", auth.PermAdmin},
+		{http.MethodPost, "/admin/restore", auth.PermAdmin},
+		{http.MethodGet, "/admin/config", auth.PermAdmin},
+		{http.MethodGet, "/audit", auth.PermAdmin},
+		{http.MethodGet, "/reports/catering", auth.PermRead},
+	}
+	// Every route except the health checks and metrics must be listed above
+	assert.Equal(t, len(routes)+4, len(r.Routes()))
+
+	type TestCase struct {
+		name    string
+		desc    string
+		role    string
+		allowed []auth.Permission
+	}
+	testcases := []TestCase{
+		{
+			name:    "Happy case",
+			desc:    "admin",
+			role:    auth.RoleAdmin,
+			allowed: []auth.Permission{auth.PermRead, auth.PermCheckIn, auth.PermPlan, auth.PermAdmin},
+		},
+		{
+			name:    "Happy case",
+			desc:    "planner",
+			role:    auth.RolePlanner,
+			allowed: []auth.Permission{auth.PermRead, auth.PermPlan},
+		},
+		{
+			name:    "Happy case",
+			desc:    "door staff",
+			role:    auth.RoleDoorStaff,
+			allowed: []auth.Permission{auth.PermRead, auth.PermCheckIn},
+		},
+		{
+			name:    "Happy case",
+			desc:    "viewer",
+			role:    auth.RoleViewer,
+			allowed: []auth.Permission{auth.PermRead},
+		},
+	}
+	for _, v := range testcases {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{Role: v.role, RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   v.role,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}}).SignedString(secret)
+		assert.Nil(t, err)
+		for _, route := range routes {
+			allowed := false
+			for _, a := range v.allowed {
+				allowed = allowed || a == route.perm
+			}
+			req := httptest.NewRequest(route.method, "http://localhost:1323"+route.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if allowed {
+				assert.NotContains(t, []int{http.StatusUnauthorized, http.StatusForbidden}, w.Code, "%s %s %s", v.desc, route.method, route.path)
+			} else {
+				assert.Equal(t, http.StatusForbidden, w.Code, "%s %s %s", v.desc, route.method, route.path)
+			}
+		}
+	}
+
+	// Requests without credentials are rejected before authorisation
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:1323/tables", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	// Health checks stay public
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:1323/ping", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:1323/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:1323/readyz", nil))
+	assert.NotContains(t, []int{http.StatusUnauthorized, http.StatusForbidden}, w.Code)
+}
+
+func TestIdempotencyAfterLimits(t *testing.T) {
+	// Any query fails, a request reaching the idempotency store gets a 500
+	db, _, err := sqlxmock.Newx()
+	assert.Nil(t, err)
+	secret := []byte("secret")
+	cfg := config.Default()
+	cfg.RateLimit.Plan = config.RateLimit{RPS: 0.001, Burst: 1}
+	r := NewRouter(NewContainer(db, cfg, &auth.Config{JWTSecret: secret})).routes()
+	token := func(role string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{Role: role, RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   role,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}}).SignedString(secret)
+		assert.Nil(t, err)
+		return token
+	}
+	type TestCase struct {
+		name     string
+		desc     string
+		role     string
+		key      string
+		httpCode int
+	}
+	testcases := []TestCase{
+		{
+			name:     "Sad case",
+			desc:     "forbidden before the key is claimed",
+			role:     auth.RoleViewer,
+			key:      "key-1",
+			httpCode: http.StatusForbidden,
+		},
+		{
+			name:     "Sad case",
+			desc:     "burst used up without a key",
+			role:     auth.RolePlanner,
+			httpCode: http.StatusInternalServerError,
+		},
+		{
+			name:     "Sad case",
+			desc:     "rate limited before the key is claimed",
+			role:     auth.RolePlanner,
+			key:      "key-1",
+			httpCode: http.StatusTooManyRequests,
+		},
+	}
+	for _, v := range testcases {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:1323/guest_list/dummy", strings.NewReader(`{"table":1,"accompanying_guests":0}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token(v.role))
+		if v.key != "" {
+			req.Header.Set("Idempotency-Key", v.key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, v.httpCode, w.Code, v.desc)
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"), v.desc)
+	}
+}
+
+func TestInitRouter(t *testing.T) {
+	db, _, err := sqlxmock.Newx()
+	assert.Nil(t, err)
+	l, err := net.Listen("tcp", ":0")
+	assert.Nil(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	cfg := config.Default()
+	cfg.Server.Port = port
+	cfg.Server.DrainDelay = 200 * time.Millisecond
+	type TestCase struct {
+		name   string
+		desc   string
+		inUse  bool
+		expErr bool
+	}
+	testcases := []TestCase{
+		{
+			name:   "Sad case",
+			desc:   "port already in use",
+			inUse:  true,
+			expErr: true,
+		},
+		{
+			name: "Happy case",
+			desc: "shuts down once cancelled",
+		},
+	}
+	for _, v := range testcases {
+		if !v.inUse {
+			l.Close()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- NewRouter(NewContainer(db, cfg, &auth.Config{})).InitRouter(ctx)
+		}()
+		if !v.expErr {
+			// Serving until cancelled
+			assert.Eventually(t, func() bool {
+				res, err := http.Get(fmt.Sprintf("http://localhost:%d/ping", port))
+				if err != nil {
+					return false
+				}
+				res.Body.Close()
+				return res.StatusCode == http.StatusOK
+			}, 5*time.Second, 10*time.Millisecond, v.desc)
+		}
+		cancel()
+		if !v.expErr {
+			// Readiness fails while draining
+			assert.Eventually(t, func() bool {
+				res, err := http.Get(fmt.Sprintf("http://localhost:%d/readyz", port))
+				if err != nil {
+					return false
+				}
+				defer res.Body.Close()
+				body, _ := ioutil.ReadAll(res.Body)
+				return res.StatusCode == http.StatusServiceUnavailable && strings.Contains(string(body), `"shutdown":{"status":"draining"}`)
+			}, 5*time.Second, 10*time.Millisecond, v.desc)
+		}
+		select {
+		case err := <-errCh:
+			assert.Equal(t, v.expErr, err != nil, v.desc)
+		case <-time.After(5 * time.Second):
+			t.Fatal(v.desc)
+		}
+	}
+}
+
+func TestLimitBeforeAuth(t *testing.T) {
+	type Request struct {
+		remoteAddr string
+		xff        string
+		httpCode   int
+	}
+	type TestCase struct {
+		name           string
+		desc           string
+		trustedProxies []string
+		requests       []Request
+	}
+	testcases := []TestCase{
+		{
+			name:           "Happy case",
+			desc:           "clients behind a trusted proxy limited separately",
+			trustedProxies: []string{"192.0.2.0/24"},
+			requests: []Request{
+				{remoteAddr: "192.0.2.1:1234", xff: "198.51.100.1", httpCode: http.StatusUnauthorized},
+				{remoteAddr: "192.0.2.1:1234", xff: "198.51.100.2", httpCode: http.StatusUnauthorized},
+			},
+		},
+		{
+			name: "Sad case",
+			desc: "failed logins limited",
+			requests: []Request{
+				{remoteAddr: "198.51.100.1:1234", httpCode: http.StatusUnauthorized},
+				{remoteAddr: "198.51.100.1:1234", httpCode: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "Sad case",
+			desc: "X-Forwarded-For ignored without trusted proxies",
+			requests: []Request{
+				{remoteAddr: "198.51.100.1:1234", xff: "203.0.113.1", httpCode: http.StatusUnauthorized},
+				{remoteAddr: "198.51.100.1:1234", xff: "203.0.113.2", httpCode: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:           "Sad case",
+			desc:           "X-Forwarded-For from an untrusted proxy ignored",
+			trustedProxies: []string{"192.0.2.0/24"},
+			requests: []Request{
+				{remoteAddr: "198.51.100.1:1234", xff: "203.0.113.1", httpCode: http.StatusUnauthorized},
+				{remoteAddr: "198.51.100.1:1234", xff: "203.0.113.2", httpCode: http.StatusTooManyRequests},
+			},
+		},
+	}
+	for _, v := range testcases {
+		db, _, err := sqlxmock.Newx()
+		assert.Nil(t, err)
+		cfg := config.Default()
+		cfg.Server.TrustedProxies = v.trustedProxies
+		cfg.RateLimit.Authenticate = config.RateLimit{RPS: 0.001, Burst: 1}
+		r := NewRouter(NewContainer(db, cfg, &auth.Config{JWTSecret: []byte("secret")})).routes()
+		for _, rq := range v.requests {
+			req := httptest.NewRequest(http.MethodGet, "/tables", nil)
+			req.RemoteAddr = rq.remoteAddr
+			if rq.xff != "" {
+				req.Header.Set(echo.HeaderXForwardedFor, rq.xff)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, rq.httpCode, w.Code, v.desc)
+		}
+	}
+}
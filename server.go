@@ -1,66 +1,129 @@
 package main
 
 import (
-	"flag"
+	"context"
 	"fmt"
 	"os"
-	"strconv"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
 
 	"go.uber.org/zap"
 
+	"ggv2/auth"
+	"ggv2/config"
 	"ggv2/logger"
+	"ggv2/tracing"
 )
 
 func main() {
-	logger := logger.NewLogger()
-	zap.ReplaceGlobals(logger)
+	// Log to stdout until the configured outputs are opened
+	zap.ReplaceGlobals(logger.NewLogger())
 
-	dsn := getDSN()
-	port := getPort()
-	conn := initDb(*dsn)
-	defer conn.Close()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		zap.L().Fatal(err.Error(), zap.Error(err))
+	}
+	rotation := cfg.Log.Rotation
+	err = logger.SetConfig(logger.Config{
+		Level:    cfg.Log.Level,
+		Outputs:  cfg.Log.Outputs,
+		Encoding: cfg.Log.Encoding,
+		Rotation: logger.Rotation{
+			MaxSizeMB:  rotation.MaxSizeMB,
+			Interval:   rotation.Interval,
+			MaxAge:     rotation.MaxAge,
+			MaxBackups: rotation.MaxBackups,
+			Compress:   rotation.Compress,
+		},
+	})
+	if err != nil {
+		zap.L().Fatal(err.Error(), zap.Error(err))
+	}
+	zap.ReplaceGlobals(logger.NewLogger())
+	authCfg, err := auth.NewConfig(cfg.Auth)
+	if err != nil {
+		zap.L().Fatal(err.Error(), zap.Error(err))
+	}
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		zap.L().Fatal(err.Error(), zap.Error(err))
+	}
 
-	router := NewRouter(*port, conn)
-	router.InitRouter()
-}
+	// Stop connecting or serving on SIGINT or SIGTERM
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		zap.L().Info("shutting down", zap.String("signal", s.String()))
+		cancel()
+	}()
 
-func getDSN() *string {
-	envdsn := os.Getenv("DSN")
+	conn, err := initDb(ctx, cfg.DB)
+	if err != nil {
+		zap.L().Fatal(err.Error(), zap.Error(err))
+	}
+	app := NewContainer(conn, cfg, authCfg)
+	prometheus.MustRegister(app.Collectors()...)
 
-	dsn := flag.String("dsn", "", "mysql datasource string")
+	router := NewRouter(app)
+	err = router.InitRouter(ctx)
 
-	flag.Parse()
-	if *dsn == "" {
-		dsn = &envdsn
-		fmt.Printf("-dsn flag not set, defaulting to %s \n", envdsn)
+	// In-flight requests are drained, release the DB pool and flush spans and logs
+	conn.Close()
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	if flushErr := shutdownTracing(flushCtx); flushErr != nil {
+		zap.L().Error(flushErr.Error(), zap.Error(flushErr))
 	}
-
-	return dsn
+	flushCancel()
+	if err != nil {
+		zap.L().Error(err.Error(), zap.Error(err))
+		zap.L().Sync()
+		os.Exit(1)
+	}
+	zap.L().Sync()
 }
 
-func getPort() *int {
-	envport := os.Getenv("PORT")
-	port := flag.Int("p", 0, "port on which the application should run on")
-	flag.Parse()
-	if *port == 0 {
-		p, err := strconv.Atoi(envport)
-		if err != nil {
-			zap.L().Fatal(err.Error(), zap.Error(err))
-		}
-		port = &p
-		fmt.Printf("-p flag not set, defaulting to port %s \n", envport)
+func initDb(ctx context.Context, cfg config.DBConfig) (*sqlx.DB, error) {
+	// Every statement run within a traced request gets its own span
+	sqlDB, err := otelsql.Open("mysql", cfg.DSN, otelsql.WithAttributes(semconv.DBSystemMySQL))
+	if err != nil {
+		return nil, err
 	}
-	return port
+	db := sqlx.NewDb(sqlDB, "mysql")
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	if err = pingDb(ctx, db, cfg.ConnectAttempts, cfg.ConnectBackoff); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
-func initDb(dsn string) *sqlx.DB {
-	db, err := sqlx.Open("mysql", dsn)
-	if err != nil {
-		zap.L().Fatal(err.Error(), zap.Error(err))
-		return nil
+// pingDb verifies the database is reachable, retrying with exponential backoff until attempts run out or ctx is done.
+func pingDb(ctx context.Context, db *sqlx.DB, attempts int, backoff time.Duration) (err error) {
+	for i := 1; ; i++ {
+		if err = db.PingContext(ctx); err == nil {
+			return nil
+		}
+		if i >= attempts {
+			return fmt.Errorf("database unreachable after %d attempts: %w", i, err)
+		}
+		zap.L().Warn("database unreachable, retrying", zap.Int("attempt", i), zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
 	}
-	return db
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sqlxmock "github.com/zhashkevych/go-sqlxmock"
+)
+
+func TestPingDb(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		failures int
+		attempts int
+		cancel   bool
+		expErr   bool
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "reachable on first attempt",
+			attempts: 3,
+		},
+		{
+			name:     "Happy case",
+			desc:     "reachable after retries",
+			failures: 2,
+			attempts: 3,
+		},
+		{
+			name:     "Sad case",
+			desc:     "attempts run out",
+			failures: 3,
+			attempts: 3,
+			expErr:   true,
+		},
+		{
+			name:     "Sad case",
+			desc:     "cancelled while waiting to retry",
+			failures: 1,
+			attempts: 3,
+			cancel:   true,
+			expErr:   true,
+		},
+	}
+	for _, v := range testcases {
+		db, mock, err := sqlxmock.Newx(sqlxmock.MonitorPingsOption(true))
+		assert.Nil(t, err)
+		for i := 0; i < v.failures; i++ {
+			mock.ExpectPing().WillReturnError(fmt.Errorf("mock error"))
+		}
+		if v.failures < v.attempts && !v.cancel {
+			mock.ExpectPing()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		backoff := time.Millisecond
+		if v.cancel {
+			// Cancelled long before the retry is due
+			backoff = time.Hour
+			time.AfterFunc(10*time.Millisecond, cancel)
+		}
+		actErr := pingDb(ctx, db, v.attempts, backoff)
+		cancel()
+		assert.Equal(t, v.expErr, actErr != nil, v.desc)
+		assert.Nil(t, mock.ExpectationsWereMet(), v.desc)
+	}
+}
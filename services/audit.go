@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"ggv2/audit"
+	"ggv2/entities"
+	"ggv2/logger"
+	"ggv2/repo"
+	"ggv2/tracing"
+)
+
+var (
+	errAuditFailed = errors.New("unable to record audit entry")
+)
+
+// auditGuest adds the party members of a guest to its audit values
+type auditGuest struct {
+	*entities.Guest
+	Members []*entities.PartyMember `json:"members,omitempty"`
+}
+
+// ListAuditEntries returns the audit log entries matching filter, most recent first.
+func (svc *DBService) ListAuditEntries(ctx context.Context, filter *entities.AuditFilter, limit, offset int64) ([]*entities.AuditEntry, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.ListAuditEntries")
+	defer span.End()
+	entries, err := svc.repo.ListAuditEntries(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// recordAudit records a mutation within the unit of work that applies it. Failing to record it
+// fails the unit of work, so that no mutation is kept without its audit entry.
+func recordAudit(ctx context.Context, tx repo.Tx, operation, target string, before, after interface{}) error {
+	entry, err := audit.NewEntry(ctx, operation, target, before, after)
+	if err == nil {
+		err = tx.CreateAuditEntry(ctx, entry)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error(errAuditFailed.Error(), zap.String("operation", operation), zap.String("target", target), zap.Error(err))
+		return errAuditFailed
+	}
+	return nil
+}
+
+func tableTarget(id int64) string {
+	return fmt.Sprintf("table:%d", id)
+}
+
+func eventTarget(event string) string {
+	return "event:" + event
+}
+
+func guestTarget(name string) string {
+	return "guest:" + name
+}
+
+func layoutTemplateTarget(name string) string {
+	return "layout_template:" + name
+}
+
+func archiveTarget(id int64) string {
+	return fmt.Sprintf("archive:%d", id)
+}
+
+func apiKeyTarget(id int64) string {
+	return fmt.Sprintf("api_key:%d", id)
+}
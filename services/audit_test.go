@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/audit"
+	"ggv2/auth"
+	"ggv2/entities"
+	"ggv2/repo/mocks"
+)
+
+// expectAudit lets the unit of work record audit entries.
+func expectAudit(tx *mocks.Tx) {
+	tx.On("CreateAuditEntry", mock.Anything, mock.Anything).Return(nil).Maybe()
+}
+
+func TestAuditUpdateTable(t *testing.T) {
+	repo := new(mocks.DbRepo)
+	tx := new(mocks.Tx)
+	expectAtomic(repo, tx)
+	dbService := NewDbService(repo)
+	ctx := audit.NewContext(auth.NewContext(context.Background(), &auth.Principal{Subject: "alice", Method: auth.MethodJWT, Role: auth.RolePlanner}), "req-1")
+	zone := "Garden"
+	tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 8, AvailableCapacity: 8}, nil)
+	tx.On("UpdateTable", mock.Anything, mock.Anything).Return(nil)
+	var entry *entities.AuditEntry
+	tx.On("CreateAuditEntry", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		entry = args.Get(1).(*entities.AuditEntry)
+	}).Return(nil)
+	_, err := dbService.UpdateTable(ctx, 1, &entities.TablePatch{Zone: &zone})
+	assert.Nil(t, err)
+	assert.Equal(t, "jwt:alice", entry.Actor)
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, audit.OpUpdateTable, entry.Operation)
+	assert.Equal(t, "table:1", entry.Target)
+	before, after := &entities.Table{}, &entities.Table{}
+	assert.Nil(t, json.Unmarshal(entry.Before, before))
+	assert.Nil(t, json.Unmarshal(entry.After, after))
+	assert.Equal(t, "", before.Zone)
+	assert.Equal(t, "Garden", after.Zone)
+}
+
+func TestAuditGuestArrival(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		err      error
+		recorded bool
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "arrival recorded with guest before and after",
+			recorded: true,
+		},
+		{
+			name: "Sad case",
+			desc: "failed arrival not recorded",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(repo, tx)
+		dbService := NewDbService(repo)
+		dbService.guests.now = fixedNow
+		tx.On("PeekGuestByName", mock.Anything, "bob").Return(&entities.Guest{ID: 2, Name: "bob", TotalGuests: 2}, nil)
+		tx.On("GetGuestByName", mock.Anything, "bob").Return(&entities.Guest{ID: 2, Name: "bob", TotalGuests: 2}, nil)
+		tx.On("GetTable", mock.Anything, int64(0)).Return(&entities.Table{Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 8}, nil)
+		tx.On("UpdateGuest", mock.Anything, mock.Anything).Return(v.err)
+		tx.On("UpdateTable", mock.Anything, mock.Anything).Return(nil)
+		var entry *entities.AuditEntry
+		tx.On("CreateAuditEntry", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			entry = args.Get(1).(*entities.AuditEntry)
+		}).Return(nil).Maybe()
+		actErr := dbService.GuestArrival(context.Background(), 1, "bob", nil)
+		assert.Equal(t, v.err, actErr, v.desc)
+		if !v.recorded {
+			assert.Nil(t, entry, v.desc)
+			continue
+		}
+		assert.Equal(t, audit.Anonymous, entry.Actor, v.desc)
+		assert.Equal(t, "guest:bob", entry.Target, v.desc)
+		assert.JSONEq(t, `{"id":2,"name":"bob","tableid":0,"total_rsvp_guests":2,"total_arrived_guests":0,"arrivaltime":"","version":0,"dietary_tags":null,"allergens":null,"dietary_notes":"","meal_choice":""}`, string(entry.Before), v.desc)
+		assert.JSONEq(t, `{"id":2,"name":"bob","tableid":0,"total_rsvp_guests":2,"total_arrived_guests":2,"arrivaltime":"2021-06-04 04:06:44","version":0,"dietary_tags":null,"allergens":null,"dietary_notes":"","meal_choice":""}`, string(entry.After), v.desc)
+	}
+}
+
+func TestAuditFailureFailsMutation(t *testing.T) {
+	type TestCase struct {
+		name   string
+		desc   string
+		mutate func(*DBService) error
+	}
+	testcases := []TestCase{
+		{
+			name: "Sad case",
+			desc: "api key revoked without audit entry",
+			mutate: func(svc *DBService) error {
+				return svc.RevokeAPIKey(context.Background(), 7)
+			},
+		},
+		{
+			name: "Sad case",
+			desc: "table resized without audit entry",
+			mutate: func(svc *DBService) error {
+				_, err := svc.UpdateTable(context.Background(), 1, &entities.TablePatch{})
+				return err
+			},
+		},
+		{
+			name: "Sad case",
+			desc: "guest departed without audit entry",
+			mutate: func(svc *DBService) error {
+				return svc.GuestDepart(context.Background(), "bob")
+			},
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(repo, tx)
+		dbService := NewDbService(repo)
+		tx.On("RevokeAPIKey", mock.Anything, int64(7)).Return(nil)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 4, AvailableCapacity: 6}, nil)
+		tx.On("UpdateTable", mock.Anything, mock.Anything).Return(nil)
+		tx.On("PeekGuestByName", mock.Anything, "bob").Return(&entities.Guest{ID: 2, Name: "bob", TableID: 1, TotalGuests: 2, TotalArrivedGuests: 2}, nil)
+		tx.On("GetGuestByName", mock.Anything, "bob").Return(&entities.Guest{ID: 2, Name: "bob", TableID: 1, TotalGuests: 2, TotalArrivedGuests: 2}, nil)
+		tx.On("UpdateGuest", mock.Anything, mock.Anything).Return(nil)
+		tx.On("ResetPartyArrival", mock.Anything, int64(2)).Return(nil)
+		tx.On("CreateAuditEntry", mock.Anything, mock.Anything).Return(fmt.Errorf("mock error"))
+		err := v.mutate(dbService)
+		// The unit of work fails so that the mutation is rolled back
+		assert.Equal(t, errAuditFailed, err, v.desc)
+		tx.AssertCalled(t, "CreateAuditEntry", mock.Anything, mock.Anything)
+	}
+}
+
+func TestListAuditEntries(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+		res  []*entities.AuditEntry
+	}
+	filter := &entities.AuditFilter{Operation: audit.OpEmptyTables}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+			res:  []*entities.AuditEntry{{ID: 1, Actor: "api_key:kiosk", Operation: audit.OpEmptyTables}},
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("ListAuditEntries", mock.Anything, filter, int64(10), int64(0)).Return(v.res, v.err)
+		actRes, actErr := dbService.ListAuditEntries(context.Background(), filter, 10, 0)
+		assert.Equal(t, v.err, actErr, v.desc)
+		assert.Equal(t, v.res, actRes, v.desc)
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"ggv2/audit"
+	"ggv2/auth"
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/tracing"
+)
+
+// AuthenticateAPIKey resolves a raw API key into the principal it was issued for.
+// Unknown and revoked keys are reported alike, so that callers cannot probe for revoked keys.
+func (svc *DBService) AuthenticateAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.AuthenticateAPIKey")
+	defer span.End()
+	apiKey, err := svc.repo.GetAPIKeyByHash(ctx, auth.HashAPIKey(key))
+	if err != nil {
+		if errors.Is(err, repo.ErrAPIKeyNotFound) {
+			return nil, auth.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if apiKey.Revoked {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	return &auth.Principal{Subject: apiKey.Name, Method: auth.MethodAPIKey, Role: apiKey.Role}, nil
+}
+
+// CreateAPIKey issues a new API key for a role. The raw key is returned once and never stored.
+func (svc *DBService) CreateAPIKey(ctx context.Context, name, role string) (*entities.APIKey, string, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.CreateAPIKey")
+	defer span.End()
+	raw, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key := &entities.APIKey{
+		Name:    name,
+		Prefix:  auth.KeyPrefix(raw),
+		Role:    role,
+		KeyHash: auth.HashAPIKey(raw),
+	}
+	err = svc.repo.Atomic(ctx, func(tx repo.Tx) error {
+		if err := tx.CreateAPIKey(ctx, key); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit.OpCreateAPIKey, apiKeyTarget(key.ID), nil, key)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return key, raw, nil
+}
+
+func (svc *DBService) ListAPIKeys(ctx context.Context) ([]*entities.APIKey, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.ListAPIKeys")
+	defer span.End()
+	keys, err := svc.repo.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (svc *DBService) RevokeAPIKey(ctx context.Context, id int64) error {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.RevokeAPIKey")
+	defer span.End()
+	return svc.repo.Atomic(ctx, func(tx repo.Tx) error {
+		if err := tx.RevokeAPIKey(ctx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit.OpRevokeAPIKey, apiKeyTarget(id), nil, map[string]bool{"revoked": true})
+	})
+}
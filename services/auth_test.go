@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/auth"
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/repo/mocks"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	type TestCase struct {
+		name   string
+		desc   string
+		key    *entities.APIKey
+		err    error
+		expRes *auth.Principal
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "active key",
+			key:    &entities.APIKey{ID: 1, Name: "kiosk", Role: auth.RoleDoorStaff},
+			expRes: &auth.Principal{Subject: "kiosk", Method: auth.MethodAPIKey, Role: auth.RoleDoorStaff},
+		},
+		{
+			name:   "Sad case",
+			desc:   "revoked key",
+			key:    &entities.APIKey{ID: 1, Name: "kiosk", Revoked: true},
+			expErr: auth.ErrInvalidAPIKey,
+		},
+		{
+			name:   "Sad case",
+			desc:   "unknown key",
+			err:    repo.ErrAPIKeyNotFound,
+			expErr: auth.ErrInvalidAPIKey,
+		},
+		{
+			name:   "Sad case",
+			desc:   "repo return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		dbRepo := new(mocks.DbRepo)
+		dbService := &DBService{repo: dbRepo}
+		dbRepo.On("GetAPIKeyByHash", mock.Anything, auth.HashAPIKey("ggv2_key")).Return(v.key, v.err)
+		actRes, actErr := dbService.AuthenticateAPIKey(context.Background(), "ggv2_key")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(repo, tx)
+		expectAudit(tx)
+		dbService := &DBService{repo: repo}
+		var stored *entities.APIKey
+		tx.On("CreateAPIKey", mock.Anything, mock.AnythingOfType("*entities.APIKey")).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*entities.APIKey)
+		}).Return(v.err)
+		actRes, actKey, actErr := dbService.CreateAPIKey(context.Background(), "kiosk", auth.RoleDoorStaff)
+		assert.Equal(t, v.err, actErr, v.desc)
+		if v.err != nil {
+			assert.Nil(t, actRes, v.desc)
+			assert.Empty(t, actKey, v.desc)
+			continue
+		}
+		// Only the hash and prefix of the key are stored
+		assert.Equal(t, "kiosk", stored.Name)
+		assert.Equal(t, auth.RoleDoorStaff, stored.Role)
+		assert.Equal(t, auth.HashAPIKey(actKey), stored.KeyHash)
+		assert.True(t, strings.HasPrefix(actKey, stored.Prefix))
+		assert.NotEqual(t, actKey, stored.Prefix)
+	}
+}
+
+func TestListAPIKeys(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+		res  []*entities.APIKey
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+			res:  []*entities.APIKey{{ID: 1, Name: "kiosk"}},
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("ListAPIKeys", mock.Anything).Return(v.res, v.err)
+		actRes, actErr := dbService.ListAPIKeys(context.Background())
+		assert.Equal(t, v.err, actErr, v.desc)
+		assert.Equal(t, v.res, actRes, v.desc)
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(repo, tx)
+		expectAudit(tx)
+		dbService := &DBService{repo: repo}
+		tx.On("RevokeAPIKey", mock.Anything, int64(1)).Return(v.err)
+		actErr := dbService.RevokeAPIKey(context.Background(), 1)
+		assert.Equal(t, v.err, actErr, v.desc)
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"ggv2/entities"
+	"ggv2/tracing"
+)
+
+// CateringReport aggregates dietary requirements and meal choices per table and for the whole event,
+// both for the RSVP headcount and the arrived headcount. Every event is aggregated when event is empty.
+func (svc *DBService) CateringReport(ctx context.Context, event string) (*entities.CateringReport, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.CateringReport")
+	defer span.End()
+	guests, err := svc.repo.ListGuestsWithMembers(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	report := &entities.CateringReport{
+		Tables:  []*entities.TableCatering{},
+		RSVP:    entities.NewCateringSummary(),
+		Arrived: entities.NewCateringSummary(),
+	}
+	tables := map[int64]*entities.TableCatering{}
+	for _, g := range guests {
+		t, ok := tables[g.TableID]
+		if !ok {
+			t = &entities.TableCatering{
+				TableID: g.TableID,
+				RSVP:    entities.NewCateringSummary(),
+				Arrived: entities.NewCateringSummary(),
+			}
+			tables[g.TableID] = t
+			report.Tables = append(report.Tables, t)
+		}
+		rsvp, arrived := partyDiners(g)
+		for _, d := range rsvp {
+			t.RSVP.Add(d)
+			report.RSVP.Add(d)
+		}
+		for _, d := range arrived {
+			t.Arrived.Add(d)
+			report.Arrived.Add(d)
+		}
+	}
+	sort.Slice(report.Tables, func(i, j int) bool {
+		return report.Tables[i].TableID < report.Tables[j].TableID
+	})
+	return report, nil
+}
+
+// partyDiners returns the diners of a guest's party that RSVP and that arrived.
+// Accompanying guests without a named party member are counted without requirements.
+func partyDiners(g *entities.Guest) ([]*entities.Diner, []*entities.Diner) {
+	rsvp := []*entities.Diner{g.Diner()}
+	arrived := []*entities.Diner{}
+	if g.TotalArrivedGuests > 0 {
+		arrived = append(arrived, g.Diner())
+	}
+	for _, m := range g.Members {
+		rsvp = append(rsvp, m.Diner())
+		if m.Arrived {
+			arrived = append(arrived, m.Diner())
+		}
+	}
+	for int64(len(rsvp)) < g.TotalGuests {
+		rsvp = append(rsvp, &entities.Diner{})
+	}
+	for int64(len(arrived)) < g.TotalArrivedGuests {
+		arrived = append(arrived, &entities.Diner{})
+	}
+	return rsvp, arrived
+}
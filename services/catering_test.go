@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/entities"
+	"ggv2/repo/mocks"
+)
+
+func TestCateringReport(t *testing.T) {
+	guests := []*entities.Guest{
+		{
+			ID:                 1,
+			Name:               "dummy",
+			TableID:            2,
+			TotalGuests:        3,
+			TotalArrivedGuests: 2,
+			DietaryTags:        entities.Tags{entities.DietVegan},
+			MealChoice:         "tofu",
+			Members: []*entities.PartyMember{
+				{
+					Name:       "alice",
+					Arrived:    true,
+					Allergens:  entities.Tags{"peanut"},
+					MealChoice: "fish",
+				},
+				{
+					Name:         "bob",
+					DietaryTags:  entities.Tags{entities.DietHalal},
+					DietaryNotes: "no alcohol in sauces",
+				},
+			},
+		},
+		{
+			ID:          2,
+			Name:        "other",
+			TableID:     1,
+			TotalGuests: 2,
+		},
+	}
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		guests []*entities.Guest
+		expRes *entities.CateringReport
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "requirements aggregated per table and event",
+			guests: guests,
+			expRes: &entities.CateringReport{
+				Tables: []*entities.TableCatering{
+					{
+						TableID: 1,
+						RSVP: &entities.CateringSummary{
+							Headcount: 2,
+							Diets:     map[string]int64{},
+							Allergens: map[string]int64{},
+							Meals:     map[string]int64{entities.MealUnspecified: 2},
+							Notes:     []string{},
+						},
+						Arrived: entities.NewCateringSummary(),
+					},
+					{
+						TableID: 2,
+						RSVP: &entities.CateringSummary{
+							Headcount: 3,
+							Diets:     map[string]int64{entities.DietVegan: 1, entities.DietHalal: 1},
+							Allergens: map[string]int64{"peanut": 1},
+							Meals:     map[string]int64{"tofu": 1, "fish": 1, entities.MealUnspecified: 1},
+							Notes:     []string{"no alcohol in sauces"},
+						},
+						Arrived: &entities.CateringSummary{
+							Headcount: 2,
+							Diets:     map[string]int64{entities.DietVegan: 1},
+							Allergens: map[string]int64{"peanut": 1},
+							Meals:     map[string]int64{"tofu": 1, "fish": 1},
+							Notes:     []string{},
+						},
+					},
+				},
+				RSVP: &entities.CateringSummary{
+					Headcount: 5,
+					Diets:     map[string]int64{entities.DietVegan: 1, entities.DietHalal: 1},
+					Allergens: map[string]int64{"peanut": 1},
+					Meals:     map[string]int64{"tofu": 1, "fish": 1, entities.MealUnspecified: 3},
+					Notes:     []string{"no alcohol in sauces"},
+				},
+				Arrived: &entities.CateringSummary{
+					Headcount: 2,
+					Diets:     map[string]int64{entities.DietVegan: 1},
+					Allergens: map[string]int64{"peanut": 1},
+					Meals:     map[string]int64{"tofu": 1, "fish": 1},
+					Notes:     []string{},
+				},
+			},
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("ListGuestsWithMembers", mock.Anything, "gala").Return(v.guests, v.err)
+		actRes, actErr := dbService.CateringReport(context.Background(), "gala")
+		assert.Equal(t, v.err, actErr)
+		assert.Equal(t, v.expRes, actRes)
+	}
+}
@@ -2,22 +2,31 @@ package services
 
 import (
 	"context"
+
+	"ggv2/audit"
 	"ggv2/entities"
 	"ggv2/repo"
+	"ggv2/tracing"
 )
 
 type DBService struct {
-	repo repo.DbRepo
+	repo   repo.DbRepo
+	tables *TableService
+	guests *GuestService
 }
 
-func NewDbService(r *repo.DBRepo) *DBService {
+func NewDbService(r repo.DbRepo) *DBService {
 	return &DBService{
-		repo: r,
+		repo:   r,
+		tables: NewTableService(r),
+		guests: NewGuestService(r),
 	}
 }
 
 // GetTable returns detail of a single table.
 func (svc *DBService) GetTable(ctx context.Context, id int64) (*entities.Table, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.GetTable")
+	defer span.End()
 	table, err := svc.repo.GetTable(ctx, id)
 	if err != nil {
 		// DB operation returns error
@@ -27,56 +36,136 @@ func (svc *DBService) GetTable(ctx context.Context, id int64) (*entities.Table,
 	return table, nil
 }
 
-func (svc *DBService) ListTables(ctx context.Context, limit, offset int64) ([]*entities.Table, error) {
-	tables, err := svc.repo.ListTables(ctx, limit, offset)
+func (svc *DBService) ListTables(ctx context.Context, filter *entities.TableFilter, limit, offset int64) ([]*entities.Table, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.ListTables")
+	defer span.End()
+	tables, err := svc.repo.ListTables(ctx, filter, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	return tables, nil
 }
 
-func (svc *DBService) CreateTable(ctx context.Context, capacity int64) (*entities.Table, error) {
+func (svc *DBService) CreateTable(ctx context.Context, capacity int64, patch *entities.TablePatch) (*entities.Table, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.CreateTable")
+	defer span.End()
 	table := &entities.Table{
 		Capacity:          capacity,
 		AvailableCapacity: capacity,
 		PlannedCapacity:   capacity,
 	}
-	table, err := svc.repo.CreateTable(ctx, table)
+	if patch != nil {
+		patch.Apply(table)
+	}
+	err := svc.repo.Atomic(ctx, func(tx repo.Tx) error {
+		if err := tx.CreateTable(ctx, table); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit.OpCreateTable, tableTarget(table.TableID), nil, table)
+	})
 	if err != nil {
 		return nil, err
 	}
 	return table, nil
 }
 
-func (svc *DBService) AddToGuestList(ctx context.Context, accompanyingGuests, tableID int64, name string) error {
+// UpdateTable applies a partial update to the capacity and metadata of a table.
+func (svc *DBService) UpdateTable(ctx context.Context, id int64, patch *entities.TablePatch) (*entities.Table, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.UpdateTable")
+	defer span.End()
+	_, table, err := svc.tables.UpdateTable(ctx, id, patch)
+	if err != nil {
+		recordRejection(audit.OpUpdateTable, err)
+		return nil, err
+	}
+	return table, nil
+}
+
+// DeleteTable removes a table, reassigning its guests to another table when reassignTo is set.
+func (svc *DBService) DeleteTable(ctx context.Context, id, reassignTo int64) error {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.DeleteTable")
+	defer span.End()
+	_, err := svc.tables.DeleteTable(ctx, id, reassignTo)
+	if err != nil {
+		recordRejection(audit.OpDeleteTable, err)
+		return err
+	}
+	return nil
+}
+
+func (svc *DBService) AddToGuestList(ctx context.Context, accompanyingGuests, tableID int64, name string, diner *entities.Diner, members []*entities.PartyMember) error {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.AddToGuestList")
+	defer span.End()
 	guest := &entities.Guest{
 		Name:        name,
 		TotalGuests: accompanyingGuests + 1,
 		TableID:     tableID,
+		Members:     members,
 	}
-	err := svc.repo.AddToGuestList(ctx, guest)
-
-	return err
+	if diner != nil {
+		guest.DietaryTags = diner.DietaryTags
+		guest.Allergens = diner.Allergens
+		guest.DietaryNotes = diner.DietaryNotes
+		guest.MealChoice = diner.MealChoice
+	}
+	err := svc.guests.AddToGuestList(ctx, guest)
+	if err != nil {
+		recordRejection(audit.OpAddToGuestList, err)
+		return err
+	}
+	rsvpGuests.Add(float64(guest.TotalGuests))
+	return nil
 }
 
 func (svc *DBService) GuestDepart(ctx context.Context, name string) error {
-	guest := &entities.Guest{
-		Name: name,
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.GuestDepart")
+	defer span.End()
+	before, _, err := svc.guests.GuestDepart(ctx, name)
+	if err != nil {
+		recordRejection(audit.OpGuestDepart, err)
+		return err
 	}
-	err := svc.repo.GuestDepart(ctx, guest)
-	return err
+	departedGuests.Add(float64(before.TotalArrivedGuests))
+	return nil
 }
 
-func (svc *DBService) GuestArrival(ctx context.Context, accompanyingGuests int64, name string) error {
-	guest := &entities.Guest{
-		Name:               name,
-		TotalArrivedGuests: accompanyingGuests + 1,
+func (svc *DBService) GuestArrival(ctx context.Context, accompanyingGuests int64, name string, members []string) error {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.GuestArrival")
+	defer span.End()
+	_, after, err := svc.guests.GuestArrival(ctx, name, accompanyingGuests+1, members)
+	if err != nil {
+		recordRejection(audit.OpGuestArrival, err)
+		return err
+	}
+	arrivedGuests.Add(float64(after.TotalArrivedGuests))
+	return nil
+}
+
+func (svc *DBService) ListPartyMembers(ctx context.Context, name string) ([]*entities.PartyMember, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.ListPartyMembers")
+	defer span.End()
+	members, err := svc.repo.ListPartyMembers(ctx, &entities.Guest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (svc *DBService) MemberArrival(ctx context.Context, name, member string) error {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.MemberArrival")
+	defer span.End()
+	_, _, err := svc.guests.MemberArrival(ctx, name, member)
+	if err != nil {
+		recordRejection(audit.OpMemberArrival, err)
+		return err
 	}
-	err := svc.repo.GuestArrived(ctx, guest)
-	return err
+	arrivedGuests.Inc()
+	return nil
 }
 
 func (svc *DBService) ListArrivedGuests(ctx context.Context, limit, offset int64) ([]*entities.Guest, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.ListArrivedGuests")
+	defer span.End()
 	guests, err := svc.repo.ListArrivedGuests(ctx, limit, offset)
 	if err != nil {
 		return nil, err
@@ -85,6 +174,8 @@ func (svc *DBService) ListArrivedGuests(ctx context.Context, limit, offset int64
 }
 
 func (svc *DBService) ListRSVPGuests(ctx context.Context, limit, offset int64) ([]*entities.Guest, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.ListRSVPGuests")
+	defer span.End()
 	guests, err := svc.repo.ListGuests(ctx, limit, offset)
 	if err != nil {
 		return nil, err
@@ -92,12 +183,93 @@ func (svc *DBService) ListRSVPGuests(ctx context.Context, limit, offset int64) (
 	return guests, nil
 }
 
-func (svc *DBService) EmptyTables(ctx context.Context) error {
-	err := svc.repo.EmptyTables(ctx)
-	return err
+// EmptyTables archives and removes the tables, guests and party members of an event, every event when event is empty.
+func (svc *DBService) EmptyTables(ctx context.Context, event string) (*entities.Archive, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.EmptyTables")
+	defer span.End()
+	var archive *entities.Archive
+	err := svc.repo.Atomic(ctx, func(tx repo.Tx) error {
+		var err error
+		archive, err = tx.EmptyTables(ctx, event)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit.OpEmptyTables, eventTarget(event), archive, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// ListArchives returns every archive taken when tables were emptied.
+func (svc *DBService) ListArchives(ctx context.Context) ([]*entities.Archive, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.ListArchives")
+	defer span.End()
+	archives, err := svc.repo.ListArchives(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return archives, nil
+}
+
+// RestoreArchive undoes emptying tables by restoring an archive.
+func (svc *DBService) RestoreArchive(ctx context.Context, id int64) (*entities.Archive, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.RestoreArchive")
+	defer span.End()
+	var archive *entities.Archive
+	err := svc.repo.Atomic(ctx, func(tx repo.Tx) error {
+		var err error
+		archive, err = tx.RestoreArchive(ctx, id)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit.OpRestoreArchive, archiveTarget(id), nil, archive)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// Snapshot returns a consistent copy of the tables, guests and party members of an event, every event when event is empty.
+func (svc *DBService) Snapshot(ctx context.Context, event string) (*entities.Snapshot, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.Snapshot")
+	defer span.End()
+	snapshot, err := svc.repo.Snapshot(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// RestoreSnapshot loads a snapshot back, replacing the current data of its event when replace is set.
+// The archive of the replaced data is returned.
+func (svc *DBService) RestoreSnapshot(ctx context.Context, snapshot *entities.Snapshot, replace bool) (*entities.Archive, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.RestoreSnapshot")
+	defer span.End()
+	var archive *entities.Archive
+	err := svc.repo.Atomic(ctx, func(tx repo.Tx) error {
+		var err error
+		archive, err = tx.RestoreSnapshot(ctx, snapshot, replace)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit.OpRestoreSnapshot, eventTarget(snapshot.Event), archive, map[string]int{
+			"tables":        len(snapshot.Tables),
+			"guests":        len(snapshot.Guests),
+			"party_members": len(snapshot.PartyMembers),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
 }
 
 func (svc *DBService) GetEmptySeatsCount(ctx context.Context) (int, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.GetEmptySeatsCount")
+	defer span.End()
 	count, err := svc.repo.GetEmptySeatsCount(ctx)
 	if err != nil {
 		return 0, err
@@ -6,8 +6,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"ggv2/entities"
+	"ggv2/repo"
 	"ggv2/repo/mocks"
 )
 
@@ -39,9 +41,14 @@ func TestCreateTable(t *testing.T) {
 
 	for _, v := range testcases {
 		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(repo, tx)
+		expectAudit(tx)
 		dbService := &DBService{repo: repo}
-		repo.On("CreateTable", context.Background(), &entities.Table{Capacity: 7, AvailableCapacity: 7, PlannedCapacity: 7}).Return(v.res, v.err)
-		actT, actErr := dbService.CreateTable(context.Background(), 7)
+		tx.On("CreateTable", mock.Anything, &entities.Table{Capacity: 7, AvailableCapacity: 7, PlannedCapacity: 7}).Run(func(args mock.Arguments) {
+			args.Get(1).(*entities.Table).TableID = 1
+		}).Return(v.err)
+		actT, actErr := dbService.CreateTable(context.Background(), 7, nil)
 		assert.Equal(t, v.res, actT)
 		assert.Equal(t, v.err, actErr)
 	}
@@ -77,8 +84,8 @@ func TestListTables(t *testing.T) {
 	for _, v := range testcases {
 		repo := new(mocks.DbRepo)
 		dbService := &DBService{repo: repo}
-		repo.On("ListTables", context.Background(), int64(10), int64(0)).Return(v.res, v.err)
-		actRes, actErr := dbService.ListTables(context.Background(), 10, 0)
+		repo.On("ListTables", mock.Anything, (*entities.TableFilter)(nil), int64(10), int64(0)).Return(v.res, v.err)
+		actRes, actErr := dbService.ListTables(context.Background(), nil, 10, 0)
 		assert.Equal(t, v.res, actRes)
 		assert.Equal(t, v.err, actErr)
 	}
@@ -107,7 +114,7 @@ func TestGetEmptySeatsCount(t *testing.T) {
 	for _, v := range testcases {
 		repo := new(mocks.DbRepo)
 		dbService := &DBService{repo: repo}
-		repo.On("GetEmptySeatsCount", context.Background()).Return(v.res, v.err)
+		repo.On("GetEmptySeatsCount", mock.Anything).Return(v.res, v.err)
 		actRes, actErr := dbService.GetEmptySeatsCount(context.Background())
 		assert.Equal(t, v.res, actRes)
 		assert.Equal(t, v.err, actErr)
@@ -132,10 +139,16 @@ func TestAddToGuestList(t *testing.T) {
 		},
 	}
 	for _, v := range testcases {
-		repo := new(mocks.DbRepo)
-		dbService := &DBService{repo: repo}
-		repo.On("AddToGuestList", context.Background(), &entities.Guest{Name: "dummy", TableID: 1, TotalGuests: 3}).Return(v.err)
-		actErr := dbService.AddToGuestList(context.Background(), 2, 1, "dummy")
+		dbRepo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAudit(tx)
+		expectAtomic(dbRepo, tx)
+		dbService := NewDbService(dbRepo)
+		tx.On("PeekGuestByName", mock.Anything, "dummy").Return(nil, repo.ErrGuestNotFound)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 8, AvailableCapacity: 8}, nil)
+		tx.On("CreateGuest", mock.Anything, &entities.Guest{Name: "dummy", TableID: 1, TotalGuests: 3}).Return(v.err)
+		tx.On("UpdateTable", mock.Anything, &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 8}).Return(nil)
+		actErr := dbService.AddToGuestList(context.Background(), 2, 1, "dummy", nil, nil)
 		assert.Equal(t, v.err, actErr)
 	}
 }
@@ -171,7 +184,7 @@ func TestListRSVPGuests(t *testing.T) {
 	for _, v := range testcases {
 		repo := new(mocks.DbRepo)
 		dbService := &DBService{repo: repo}
-		repo.On("ListGuests", context.Background(), int64(10), int64(0)).Return(v.res, v.err)
+		repo.On("ListGuests", mock.Anything, int64(10), int64(0)).Return(v.res, v.err)
 		actRes, actErr := dbService.ListRSVPGuests(context.Background(), 10, 0)
 		assert.Equal(t, v.err, actErr)
 		assert.Equal(t, v.res, actRes)
@@ -197,8 +210,16 @@ func TestGuestDepart(t *testing.T) {
 	}
 	for _, v := range testcases {
 		repo := new(mocks.DbRepo)
-		dbService := &DBService{repo: repo}
-		repo.On("GuestDepart", context.Background(), &entities.Guest{Name: "dummy"}).Return(v.err)
+		tx := new(mocks.Tx)
+		expectAudit(tx)
+		expectAtomic(repo, tx)
+		dbService := NewDbService(repo)
+		tx.On("PeekGuestByName", mock.Anything, "dummy").Return(&entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 3, TotalArrivedGuests: 3}, nil)
+		tx.On("GetGuestByName", mock.Anything, "dummy").Return(&entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 3, TotalArrivedGuests: 3}, nil)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 5}, nil)
+		tx.On("UpdateGuest", mock.Anything, &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 3}).Return(v.err)
+		tx.On("ResetPartyArrival", mock.Anything, int64(2)).Return(nil)
+		tx.On("UpdateTable", mock.Anything, &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 8}).Return(nil)
 		actErr := dbService.GuestDepart(context.Background(), "dummy")
 		assert.Equal(t, v.err, actErr)
 	}
@@ -223,9 +244,17 @@ func TestGuestArrived(t *testing.T) {
 	}
 	for _, v := range testcases {
 		repo := new(mocks.DbRepo)
-		dbService := &DBService{repo: repo}
-		repo.On("GuestArrived", context.Background(), &entities.Guest{Name: "dummy", TotalArrivedGuests: 2}).Return(v.err)
-		actErr := dbService.GuestArrival(context.Background(), 1, "dummy")
+		tx := new(mocks.Tx)
+		expectAudit(tx)
+		expectAtomic(repo, tx)
+		dbService := NewDbService(repo)
+		dbService.guests.now = fixedNow
+		tx.On("PeekGuestByName", mock.Anything, "dummy").Return(&entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2}, nil)
+		tx.On("GetGuestByName", mock.Anything, "dummy").Return(&entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2}, nil)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 8}, nil)
+		tx.On("UpdateGuest", mock.Anything, &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2, TotalArrivedGuests: 2, ArrivalTime: "2021-06-04 04:06:44"}).Return(v.err)
+		tx.On("UpdateTable", mock.Anything, &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 6}).Return(nil)
+		actErr := dbService.GuestArrival(context.Background(), 1, "dummy", nil)
 		assert.Equal(t, v.err, actErr)
 	}
 }
@@ -235,11 +264,74 @@ func TestEmptyTables(t *testing.T) {
 		name string
 		desc string
 		err  error
+		res  *entities.Archive
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+			res:  &entities.Archive{ID: 1, Event: "gala", Tables: 10, Guests: 40},
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(repo, tx)
+		expectAudit(tx)
+		dbService := &DBService{repo: repo}
+		tx.On("EmptyTables", mock.Anything, "gala").Return(v.res, v.err)
+		actRes, actErr := dbService.EmptyTables(context.Background(), "gala")
+		assert.Equal(t, v.err, actErr)
+		assert.Equal(t, v.res, actRes)
+	}
+}
+
+func TestListArchives(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+		res  []*entities.Archive
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+			res:  []*entities.Archive{{ID: 1, Event: "gala", Tables: 10, Guests: 40}},
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("ListArchives", mock.Anything).Return(v.res, v.err)
+		actRes, actErr := dbService.ListArchives(context.Background())
+		assert.Equal(t, v.err, actErr)
+		assert.Equal(t, v.res, actRes)
+	}
+}
+
+func TestRestoreArchive(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+		res  *entities.Archive
 	}
 	testcases := []TestCase{
 		{
 			name: "Happy case",
 			desc: "all ok",
+			res:  &entities.Archive{ID: 1, Event: "gala", Tables: 10, Guests: 40, Restored: true},
 		},
 		{
 			name: "Sad case",
@@ -249,9 +341,87 @@ func TestEmptyTables(t *testing.T) {
 	}
 	for _, v := range testcases {
 		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(repo, tx)
+		expectAudit(tx)
 		dbService := &DBService{repo: repo}
-		repo.On("EmptyTables", context.Background()).Return(v.err)
-		actErr := dbService.EmptyTables(context.Background())
+		tx.On("RestoreArchive", mock.Anything, int64(1)).Return(v.res, v.err)
+		actRes, actErr := dbService.RestoreArchive(context.Background(), 1)
+		assert.Equal(t, v.err, actErr)
+		assert.Equal(t, v.res, actRes)
+	}
+}
+func TestResizeTable(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		capacity int64
+		expTable *entities.Table
+		expErr   error
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "table grown",
+			capacity: 10,
+			expTable: &entities.Table{TableID: 1, Capacity: 10, PlannedCapacity: 5, AvailableCapacity: 7, Version: 2},
+		},
+		{
+			name:     "Happy case",
+			desc:     "table shrunk down to planned guests",
+			capacity: 5,
+			expTable: &entities.Table{TableID: 1, Capacity: 5, PlannedCapacity: 0, AvailableCapacity: 2, Version: 2},
+		},
+		{
+			name:     "Sad case",
+			desc:     "table shrunk below planned guests",
+			capacity: 4,
+			expErr:   ErrCapacityBelowGuests,
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAudit(tx)
+		expectAtomic(repo, tx)
+		dbService := NewDbService(repo)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 3, AvailableCapacity: 5, Version: 2}, nil)
+		tx.On("UpdateTable", mock.Anything, mock.AnythingOfType("*entities.Table")).Return(nil)
+		actRes, actErr := dbService.UpdateTable(context.Background(), 1, &entities.TablePatch{Capacity: &v.capacity})
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expTable, actRes, v.desc)
+		if v.expErr != nil {
+			tx.AssertNotCalled(t, "UpdateTable", mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestDeleteTable(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAudit(tx)
+		expectAtomic(repo, tx)
+		dbService := NewDbService(repo)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 8, AvailableCapacity: 8}, nil)
+		tx.On("DeleteTable", mock.Anything, &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 8, AvailableCapacity: 8}).Return(v.err)
+		actErr := dbService.DeleteTable(context.Background(), 1, 2)
 		assert.Equal(t, v.err, actErr)
 	}
 }
@@ -287,7 +457,7 @@ func TestListArrivedGuest(t *testing.T) {
 	for _, v := range testcases {
 		repo := new(mocks.DbRepo)
 		dbService := &DBService{repo: repo}
-		repo.On("ListArrivedGuests", context.Background(), int64(10), int64(0)).Return(v.res, v.err)
+		repo.On("ListArrivedGuests", mock.Anything, int64(10), int64(0)).Return(v.res, v.err)
 		actRes, actErr := dbService.ListArrivedGuests(context.Background(), 10, 0)
 		assert.Equal(t, v.err, actErr)
 		assert.Equal(t, v.res, actRes)
@@ -322,9 +492,213 @@ func TestGetTable(t *testing.T) {
 	for _, v := range testcases {
 		repo := new(mocks.DbRepo)
 		dbService := &DBService{repo: repo}
-		repo.On("GetTable", context.Background(), int64(1)).Return(v.res, v.err)
+		repo.On("GetTable", mock.Anything, int64(1)).Return(v.res, v.err)
 		actRes, actErr := dbService.GetTable(context.Background(), 1)
 		assert.Equal(t, v.err, actErr)
 		assert.Equal(t, v.res, actRes)
 	}
 }
+
+func TestGuestArrivedWithMembers(t *testing.T) {
+	repo := new(mocks.DbRepo)
+	tx := new(mocks.Tx)
+	expectAudit(tx)
+	expectAtomic(repo, tx)
+	dbService := NewDbService(repo)
+	tx.On("PeekGuestByName", mock.Anything, "dummy").Return(&entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2}, nil)
+	tx.On("GetGuestByName", mock.Anything, "dummy").Return(&entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2}, nil)
+	tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 8}, nil)
+	tx.On("UpdateGuest", mock.Anything, mock.AnythingOfType("*entities.Guest")).Return(nil)
+	tx.On("GetPartyMember", mock.Anything, int64(2), "plusone").Return(&entities.PartyMember{ID: 4, GuestID: 2, Name: "plusone"}, nil)
+	tx.On("SetMemberArrived", mock.Anything, &entities.PartyMember{ID: 4, GuestID: 2, Name: "plusone"}, true).Return(nil)
+	tx.On("UpdateTable", mock.Anything, &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 6}).Return(nil)
+	actErr := dbService.GuestArrival(context.Background(), 1, "dummy", []string{"plusone"})
+	assert.Nil(t, actErr)
+	tx.AssertExpectations(t)
+}
+
+func TestListPartyMembers(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+		res  []*entities.PartyMember
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+			res: []*entities.PartyMember{
+				{
+					ID:       1,
+					GuestID:  2,
+					Name:     "plusone",
+					AgeGroup: entities.AgeGroupChild,
+				},
+			},
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("ListPartyMembers", mock.Anything, &entities.Guest{Name: "dummy"}).Return(v.res, v.err)
+		actRes, actErr := dbService.ListPartyMembers(context.Background(), "dummy")
+		assert.Equal(t, v.err, actErr)
+		assert.Equal(t, v.res, actRes)
+	}
+}
+
+func TestMemberArrival(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAudit(tx)
+		expectAtomic(repo, tx)
+		dbService := NewDbService(repo)
+		tx.On("PeekGuestByName", mock.Anything, "dummy").Return(&entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2, TotalArrivedGuests: 1}, nil)
+		tx.On("GetGuestByName", mock.Anything, "dummy").Return(&entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2, TotalArrivedGuests: 1}, nil)
+		tx.On("GetPartyMember", mock.Anything, int64(2), "plusone").Return(&entities.PartyMember{ID: 4, GuestID: 2, Name: "plusone"}, nil)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 7}, nil)
+		tx.On("SetMemberArrived", mock.Anything, &entities.PartyMember{ID: 4, GuestID: 2, Name: "plusone"}, true).Return(v.err)
+		tx.On("UpdateGuest", mock.Anything, &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2, TotalArrivedGuests: 2}).Return(nil)
+		tx.On("UpdateTable", mock.Anything, &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 6}).Return(nil)
+		actErr := dbService.MemberArrival(context.Background(), "dummy", "plusone")
+		assert.Equal(t, v.err, actErr)
+	}
+}
+
+func TestUpdateTable(t *testing.T) {
+	zone := "Garden"
+	type TestCase struct {
+		name      string
+		desc      string
+		getErr    error
+		updateErr error
+		res       *entities.Table
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+			res: &entities.Table{
+				TableID:  1,
+				Capacity: 7,
+				Name:     "12",
+				Zone:     "Garden",
+			},
+		},
+		{
+			name:   "Sad case",
+			desc:   "get table return error",
+			getErr: fmt.Errorf("mock error"),
+		},
+		{
+			name:      "Sad case",
+			desc:      "update table return error",
+			updateErr: fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAudit(tx)
+		expectAtomic(repo, tx)
+		dbService := NewDbService(repo)
+		var table *entities.Table
+		if v.getErr == nil {
+			table = &entities.Table{TableID: 1, Capacity: 7, Name: "12"}
+		}
+		tx.On("GetTable", mock.Anything, int64(1)).Return(table, v.getErr)
+		tx.On("UpdateTable", mock.Anything, &entities.Table{TableID: 1, Capacity: 7, Name: "12", Zone: "Garden"}).Return(v.updateErr)
+		actRes, actErr := dbService.UpdateTable(context.Background(), 1, &entities.TablePatch{Zone: &zone})
+		if v.getErr != nil {
+			assert.Equal(t, v.getErr, actErr)
+		} else {
+			assert.Equal(t, v.updateErr, actErr)
+		}
+		assert.Equal(t, v.res, actRes)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+		res  *entities.Snapshot
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+			res:  &entities.Snapshot{Version: entities.SnapshotVersion, Event: "gala"},
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("Snapshot", mock.Anything, "gala").Return(v.res, v.err)
+		actRes, actErr := dbService.Snapshot(context.Background(), "gala")
+		assert.Equal(t, v.err, actErr)
+		assert.Equal(t, v.res, actRes)
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+		res  *entities.Archive
+	}
+	snapshot := &entities.Snapshot{Version: entities.SnapshotVersion, Event: "gala"}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+			res:  &entities.Archive{ID: 4, Event: "gala"},
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(repo, tx)
+		expectAudit(tx)
+		dbService := &DBService{repo: repo}
+		tx.On("RestoreSnapshot", mock.Anything, snapshot, true).Return(v.res, v.err)
+		actRes, actErr := dbService.RestoreSnapshot(context.Background(), snapshot, true)
+		assert.Equal(t, v.err, actErr)
+		assert.Equal(t, v.res, actRes)
+	}
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ggv2/entities"
+	"ggv2/tracing"
+)
+
+// FloorPlan returns every table of an event with the names of the guests seated at it, the tables of
+// every event when event is empty.
+func (svc *DBService) FloorPlan(ctx context.Context, event string) ([]*entities.FloorPlanTable, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.FloorPlan")
+	defer span.End()
+	tables, err := svc.repo.ListAllTables(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	guests, err := svc.repo.ListGuestsWithMembers(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	plan := []*entities.FloorPlanTable{}
+	byID := map[int64]*entities.FloorPlanTable{}
+	for _, t := range tables {
+		pt := &entities.FloorPlanTable{Table: t, Guests: []string{}}
+		byID[t.TableID] = pt
+		plan = append(plan, pt)
+	}
+	for _, g := range guests {
+		pt, ok := byID[g.TableID]
+		if !ok || g.TotalArrivedGuests == 0 {
+			continue
+		}
+		pt.Guests = append(pt.Guests, seatedParty(g))
+	}
+	return plan, nil
+}
+
+// seatedParty describes the arrived members of a guest's party, e.g. "Alice, Bob +1".
+func seatedParty(g *entities.Guest) string {
+	names := []string{g.Name}
+	for _, m := range g.Members {
+		if m.Arrived {
+			names = append(names, m.Name)
+		}
+	}
+	party := strings.Join(names, ", ")
+	if unnamed := g.TotalArrivedGuests - int64(len(names)); unnamed > 0 {
+		party = fmt.Sprintf("%s +%d", party, unnamed)
+	}
+	return party
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/entities"
+	"ggv2/repo/mocks"
+)
+
+func TestFloorPlan(t *testing.T) {
+	tables := []*entities.Table{
+		{TableID: 1, Capacity: 6, AvailableCapacity: 2},
+		{TableID: 2, Capacity: 4, AvailableCapacity: 4},
+	}
+	guests := []*entities.Guest{
+		{
+			Name:               "alice",
+			TableID:            1,
+			TotalGuests:        4,
+			TotalArrivedGuests: 4,
+			Members: []*entities.PartyMember{
+				{Name: "bob", Arrived: true},
+				{Name: "carol"},
+			},
+		},
+		{
+			Name:        "dave",
+			TableID:     2,
+			TotalGuests: 1,
+		},
+	}
+	type TestCase struct {
+		name      string
+		desc      string
+		tablesErr error
+		guestsErr error
+		expRes    []*entities.FloorPlanTable
+		expErr    error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "seated guests attached to tables",
+			expRes: []*entities.FloorPlanTable{
+				{Table: tables[0], Guests: []string{"alice, bob +2"}},
+				{Table: tables[1], Guests: []string{}},
+			},
+		},
+		{
+			name:      "Sad case",
+			desc:      "list tables return error",
+			tablesErr: fmt.Errorf("mock error"),
+			expErr:    fmt.Errorf("mock error"),
+		},
+		{
+			name:      "Sad case",
+			desc:      "list guests return error",
+			guestsErr: fmt.Errorf("mock error"),
+			expErr:    fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("ListAllTables", mock.Anything, "gala").Return(tables, v.tablesErr)
+		repo.On("ListGuestsWithMembers", mock.Anything, "gala").Return(guests, v.guestsErr)
+		actRes, actErr := dbService.FloorPlan(context.Background(), "gala")
+		assert.Equal(t, v.expErr, actErr)
+		assert.Equal(t, v.expRes, actRes)
+	}
+}
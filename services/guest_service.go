@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ggv2/audit"
+	"ggv2/entities"
+	"ggv2/repo"
+)
+
+// arrivalTimeLayout formats the arrival time of guests
+const arrivalTimeLayout = "2006-01-02 15:04:05"
+
+var (
+	ErrGuestNeverRSVP            = errors.New("guest never rsvp")
+	ErrGuestAlreadyArrived       = errors.New("guest already arrived")
+	ErrGuestNotArrived           = errors.New("guest not arrived")
+	ErrPartyAlreadyArrived       = errors.New("whole party already arrived")
+	ErrPartyMemberAlreadyArrived = errors.New("party member already arrived")
+	errPartySizeBelowOne         = errors.New("party size cannot be less than 1")
+	errTooManyPartyMembers       = errors.New("party members cannot outnumber accompanying guests")
+)
+
+// GuestService applies the rules of the guest list and of check-in. Guests RSVP for seats at a
+// table, which are planned until they arrive and take seats that are available. Every rule is
+// checked and applied within a single unit of work, the change is audited in that same unit.
+type GuestService struct {
+	uow repo.UnitOfWork
+	now func() time.Time
+}
+
+func NewGuestService(uow repo.UnitOfWork) *GuestService {
+	return &GuestService{
+		uow: uow,
+		now: time.Now,
+	}
+}
+
+// AddToGuestList adds a guest and the named members of its party to the guest list, planning
+// seats for the whole party at its table. The name is checked without locking it, a guest that
+// RSVP concurrently under the same name is rejected by the repo when the guest is created.
+func (s *GuestService) AddToGuestList(ctx context.Context, guest *entities.Guest) error {
+	if guest.TotalGuests < 1 {
+		return errPartySizeBelowOne
+	}
+	if int64(len(guest.Members)) > guest.TotalGuests-1 {
+		return errTooManyPartyMembers
+	}
+	return s.uow.Atomic(ctx, func(tx repo.Tx) error {
+		_, err := tx.PeekGuestByName(ctx, guest.Name)
+		if err == nil {
+			return repo.ErrGuestAlreadyRSVP
+		}
+		if !errors.Is(err, repo.ErrGuestNotFound) {
+			return err
+		}
+		table, err := tx.GetTable(ctx, guest.TableID)
+		if err != nil {
+			return err
+		}
+		if table.PlannedCapacity < guest.TotalGuests {
+			// Table capacity less than number of guests
+			return ErrTableIsFull
+		}
+		if err = tx.CreateGuest(ctx, guest); err != nil {
+			return err
+		}
+		table.PlannedCapacity -= guest.TotalGuests
+		if err = tx.UpdateTable(ctx, table); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit.OpAddToGuestList, guestTarget(guest.Name), nil, &auditGuest{Guest: guest, Members: guest.Members})
+	})
+}
+
+// GuestArrival checks-in a guest that RSVP together with the named members of its party. The
+// arriving party may differ from the party that RSVP, as long as the table has seats available
+// for it. The guest is returned as it was before and after checking-in.
+func (s *GuestService) GuestArrival(ctx context.Context, name string, arriving int64, members []string) (before, after *entities.Guest, err error) {
+	if arriving < 1 {
+		return nil, nil, errPartySizeBelowOne
+	}
+	if int64(len(members)) > arriving-1 {
+		return nil, nil, errTooManyPartyMembers
+	}
+	err = s.uow.Atomic(ctx, func(tx repo.Tx) error {
+		guest, table, err := s.rsvpGuest(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if guest.TotalArrivedGuests != 0 {
+			return ErrGuestAlreadyArrived
+		}
+		if table.AvailableCapacity < arriving {
+			// Table capacity less than number of guests
+			return ErrTableIsFull
+		}
+		prev := *guest
+		guest.TotalArrivedGuests = arriving
+		guest.ArrivalTime = s.now().Format(arrivalTimeLayout)
+		if err = tx.UpdateGuest(ctx, guest); err != nil {
+			return err
+		}
+		// Mark named party members as arrived
+		for _, m := range members {
+			member, err := tx.GetPartyMember(ctx, guest.ID, m)
+			if err != nil {
+				return err
+			}
+			if member.Arrived {
+				return ErrPartyMemberAlreadyArrived
+			}
+			if err = tx.SetMemberArrived(ctx, member, true); err != nil {
+				return err
+			}
+		}
+		table.AvailableCapacity -= arriving
+		if err = tx.UpdateTable(ctx, table); err != nil {
+			return err
+		}
+		if err = recordAudit(ctx, tx, audit.OpGuestArrival, guestTarget(name), &prev, guest); err != nil {
+			return err
+		}
+		before, after = &prev, guest
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// MemberArrival checks-in a single member of the party of a guest that already arrived, taking
+// one more available seat at its table. The guest is returned as it was before and after.
+func (s *GuestService) MemberArrival(ctx context.Context, name, memberName string) (before, after *entities.Guest, err error) {
+	err = s.uow.Atomic(ctx, func(tx repo.Tx) error {
+		guest, table, err := s.rsvpGuest(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if guest.TotalArrivedGuests == 0 {
+			return ErrGuestNotArrived
+		}
+		if guest.TotalArrivedGuests >= guest.TotalGuests {
+			return ErrPartyAlreadyArrived
+		}
+		member, err := tx.GetPartyMember(ctx, guest.ID, memberName)
+		if err != nil {
+			return err
+		}
+		if member.Arrived {
+			return ErrPartyMemberAlreadyArrived
+		}
+		if table.AvailableCapacity < 1 {
+			// Table cannot fit one more guest
+			return ErrTableIsFull
+		}
+		if err = tx.SetMemberArrived(ctx, member, true); err != nil {
+			return err
+		}
+		prev := *guest
+		guest.TotalArrivedGuests++
+		if err = tx.UpdateGuest(ctx, guest); err != nil {
+			return err
+		}
+		table.AvailableCapacity--
+		if err = tx.UpdateTable(ctx, table); err != nil {
+			return err
+		}
+		if err = recordAudit(ctx, tx, audit.OpMemberArrival, guestTarget(name)+"/"+memberName, &prev, guest); err != nil {
+			return err
+		}
+		before, after = &prev, guest
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// GuestDepart checks-out a guest, the whole party leaves together and frees its seats. The
+// guest is returned as it was before and after departing.
+func (s *GuestService) GuestDepart(ctx context.Context, name string) (before, after *entities.Guest, err error) {
+	err = s.uow.Atomic(ctx, func(tx repo.Tx) error {
+		guest, table, err := seatedGuest(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if guest.TotalArrivedGuests == 0 {
+			return ErrGuestNotArrived
+		}
+		prev := *guest
+		table.AvailableCapacity += guest.TotalArrivedGuests
+		guest.TotalArrivedGuests = 0
+		guest.ArrivalTime = ""
+		if err = tx.UpdateGuest(ctx, guest); err != nil {
+			return err
+		}
+		if err = tx.ResetPartyArrival(ctx, guest.ID); err != nil {
+			return err
+		}
+		if err = tx.UpdateTable(ctx, table); err != nil {
+			return err
+		}
+		if err = recordAudit(ctx, tx, audit.OpGuestDepart, guestTarget(name), &prev, guest); err != nil {
+			return err
+		}
+		before, after = &prev, guest
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// rsvpGuest reads a guest that is expected to have RSVP together with its table.
+func (s *GuestService) rsvpGuest(ctx context.Context, tx repo.Tx, name string) (*entities.Guest, *entities.Table, error) {
+	guest, table, err := seatedGuest(ctx, tx, name)
+	if err != nil {
+		if errors.Is(err, repo.ErrGuestNotFound) {
+			return nil, nil, ErrGuestNeverRSVP
+		}
+		return nil, nil, err
+	}
+	return guest, table, nil
+}
+
+// seatedGuest reads a guest together with its table. The table is read before the guest so
+// that every unit of work locks tables before guests, a guest is first read without locking
+// it to find its table.
+func seatedGuest(ctx context.Context, tx repo.Tx, name string) (*entities.Guest, *entities.Table, error) {
+	peek, err := tx.PeekGuestByName(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	table, err := tx.GetTable(ctx, peek.TableID)
+	if err != nil {
+		return nil, nil, err
+	}
+	guest, err := tx.GetGuestByName(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if guest.TableID != table.TableID {
+		// Guest reassigned to another table since it was first read
+		return nil, nil, repo.ErrLockConflict
+	}
+	return guest, table, nil
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/repo/mocks"
+)
+
+// expectAtomic runs the units of work of r against tx.
+func expectAtomic(r *mocks.DbRepo, tx *mocks.Tx) {
+	r.On("Atomic", mock.Anything, mock.Anything).Return(func(_ context.Context, fn func(repo.Tx) error) error {
+		return fn(tx)
+	})
+}
+
+func fixedNow() time.Time {
+	return time.Date(2021, 6, 4, 4, 6, 44, 0, time.UTC)
+}
+
+func TestGuestServiceAddToGuestList(t *testing.T) {
+	type TestCase struct {
+		name      string
+		desc      string
+		guest     *entities.Guest
+		guestErr  error
+		table     *entities.Table
+		tableErr  error
+		expCreate bool
+		expTable  *entities.Table
+		expErr    error
+	}
+	testcases := []TestCase{
+		{
+			name:      "Happy case",
+			desc:      "seats planned for the whole party",
+			guest:     &entities.Guest{Name: "dummy", TableID: 1, TotalGuests: 3, Members: []*entities.PartyMember{{Name: "plusone"}}},
+			guestErr:  repo.ErrGuestNotFound,
+			table:     &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 3, AvailableCapacity: 8},
+			expCreate: true,
+			expTable:  &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 0, AvailableCapacity: 8},
+		},
+		{
+			name:   "Sad case",
+			desc:   "party size below one",
+			guest:  &entities.Guest{Name: "dummy", TableID: 1},
+			expErr: errPartySizeBelowOne,
+		},
+		{
+			name:   "Sad case",
+			desc:   "more party members than accompanying guests",
+			guest:  &entities.Guest{Name: "dummy", TableID: 1, TotalGuests: 1, Members: []*entities.PartyMember{{Name: "plusone"}}},
+			expErr: errTooManyPartyMembers,
+		},
+		{
+			name:   "Sad case",
+			desc:   "guest already RSVP",
+			guest:  &entities.Guest{Name: "dummy", TableID: 1, TotalGuests: 1},
+			expErr: repo.ErrGuestAlreadyRSVP,
+		},
+		{
+			name:     "Sad case",
+			desc:     "reading guest fails",
+			guest:    &entities.Guest{Name: "dummy", TableID: 1, TotalGuests: 1},
+			guestErr: fmt.Errorf("mock error"),
+			expErr:   fmt.Errorf("mock error"),
+		},
+		{
+			name:     "Sad case",
+			desc:     "table not found",
+			guest:    &entities.Guest{Name: "dummy", TableID: 1, TotalGuests: 1},
+			guestErr: repo.ErrGuestNotFound,
+			tableErr: repo.ErrTableNotFound,
+			expErr:   repo.ErrTableNotFound,
+		},
+		{
+			name:     "Sad case",
+			desc:     "not enough planned seats",
+			guest:    &entities.Guest{Name: "dummy", TableID: 1, TotalGuests: 4},
+			guestErr: repo.ErrGuestNotFound,
+			table:    &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 3, AvailableCapacity: 8},
+			expErr:   ErrTableIsFull,
+		},
+	}
+	for _, v := range testcases {
+		r := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(r, tx)
+		expectAudit(tx)
+		svc := NewGuestService(r)
+		var existing *entities.Guest
+		if v.guestErr == nil {
+			existing = &entities.Guest{ID: 2, Name: "dummy"}
+		}
+		tx.On("PeekGuestByName", mock.Anything, "dummy").Return(existing, v.guestErr)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(v.table, v.tableErr)
+		tx.On("CreateGuest", mock.Anything, v.guest).Return(nil)
+		tx.On("UpdateTable", mock.Anything, mock.Anything).Return(nil)
+		actErr := svc.AddToGuestList(context.Background(), v.guest)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expCreate {
+			tx.AssertCalled(t, "CreateGuest", mock.Anything, v.guest)
+			tx.AssertCalled(t, "UpdateTable", mock.Anything, v.expTable)
+		} else {
+			tx.AssertNotCalled(t, "CreateGuest", mock.Anything, mock.Anything)
+			tx.AssertNotCalled(t, "UpdateTable", mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestGuestServiceGuestArrival(t *testing.T) {
+	type TestCase struct {
+		name      string
+		desc      string
+		arriving  int64
+		members   []string
+		guest     *entities.Guest
+		guestErr  error
+		member    *entities.PartyMember
+		memberErr error
+		table     *entities.Table
+		expGuest  *entities.Guest
+		expTable  *entities.Table
+		expErr    error
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "party checked-in",
+			arriving: 2,
+			members:  []string{"plusone"},
+			guest:    &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2},
+			member:   &entities.PartyMember{ID: 4, GuestID: 2, Name: "plusone"},
+			table:    &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 8},
+			expGuest: &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2, TotalArrivedGuests: 2, ArrivalTime: "2021-06-04 04:06:44"},
+			expTable: &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 6},
+		},
+		{
+			name:     "Happy case",
+			desc:     "more guests arrive than RSVP while seats are available",
+			arriving: 4,
+			guest:    &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2},
+			table:    &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 8},
+			expGuest: &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2, TotalArrivedGuests: 4, ArrivalTime: "2021-06-04 04:06:44"},
+			expTable: &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 4},
+		},
+		{
+			name:     "Sad case",
+			desc:     "more party members than arriving guests",
+			arriving: 1,
+			members:  []string{"plusone"},
+			expErr:   errTooManyPartyMembers,
+		},
+		{
+			name:     "Sad case",
+			desc:     "guest never RSVP",
+			arriving: 1,
+			guestErr: repo.ErrGuestNotFound,
+			expErr:   ErrGuestNeverRSVP,
+		},
+		{
+			name:     "Sad case",
+			desc:     "guest already arrived",
+			arriving: 1,
+			guest:    &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2, TotalArrivedGuests: 2},
+			table:    &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 6},
+			expErr:   ErrGuestAlreadyArrived,
+		},
+		{
+			name:     "Sad case",
+			desc:     "not enough available seats",
+			arriving: 3,
+			guest:    &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2},
+			table:    &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 2},
+			expErr:   ErrTableIsFull,
+		},
+		{
+			name:      "Sad case",
+			desc:      "party member not found",
+			arriving:  2,
+			members:   []string{"plusone"},
+			guest:     &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2},
+			memberErr: repo.ErrPartyMemberNotFound,
+			table:     &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 8},
+			expErr:    repo.ErrPartyMemberNotFound,
+		},
+	}
+	for _, v := range testcases {
+		r := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(r, tx)
+		expectAudit(tx)
+		svc := NewGuestService(r)
+		svc.now = fixedNow
+		tx.On("PeekGuestByName", mock.Anything, "dummy").Return(v.guest, v.guestErr)
+		tx.On("GetGuestByName", mock.Anything, "dummy").Return(v.guest, v.guestErr)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(v.table, nil)
+		tx.On("UpdateGuest", mock.Anything, mock.Anything).Return(nil)
+		tx.On("GetPartyMember", mock.Anything, int64(2), "plusone").Return(v.member, v.memberErr)
+		tx.On("SetMemberArrived", mock.Anything, mock.Anything, true).Return(nil)
+		tx.On("UpdateTable", mock.Anything, mock.Anything).Return(nil)
+		before, after, actErr := svc.GuestArrival(context.Background(), "dummy", v.arriving, v.members)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expGuest, after, v.desc)
+		if v.expErr != nil {
+			assert.Nil(t, before, v.desc)
+			tx.AssertNotCalled(t, "UpdateTable", mock.Anything, mock.Anything)
+			continue
+		}
+		assert.Equal(t, int64(0), before.TotalArrivedGuests, v.desc)
+		tx.AssertCalled(t, "UpdateTable", mock.Anything, v.expTable)
+		if v.member != nil {
+			tx.AssertCalled(t, "SetMemberArrived", mock.Anything, v.member, true)
+		}
+	}
+}
+
+func TestGuestServiceMemberArrival(t *testing.T) {
+	type TestCase struct {
+		name      string
+		desc      string
+		guest     *entities.Guest
+		guestErr  error
+		member    *entities.PartyMember
+		memberErr error
+		table     *entities.Table
+		expGuest  *entities.Guest
+		expErr    error
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "member checked-in",
+			guest:    &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 3, TotalArrivedGuests: 2},
+			member:   &entities.PartyMember{ID: 4, GuestID: 2, Name: "plusone"},
+			table:    &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 6},
+			expGuest: &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 3, TotalArrivedGuests: 3},
+		},
+		{
+			name:     "Sad case",
+			desc:     "guest never RSVP",
+			guestErr: repo.ErrGuestNotFound,
+			expErr:   ErrGuestNeverRSVP,
+		},
+		{
+			name:   "Sad case",
+			desc:   "guest not arrived",
+			guest:  &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 3},
+			table:  &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 6},
+			expErr: ErrGuestNotArrived,
+		},
+		{
+			name:   "Sad case",
+			desc:   "whole party already arrived",
+			guest:  &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 3, TotalArrivedGuests: 3},
+			table:  &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 6},
+			expErr: ErrPartyAlreadyArrived,
+		},
+		{
+			name:      "Sad case",
+			desc:      "party member not found",
+			guest:     &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 3, TotalArrivedGuests: 2},
+			memberErr: repo.ErrPartyMemberNotFound,
+			table:     &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 6},
+			expErr:    repo.ErrPartyMemberNotFound,
+		},
+		{
+			name:   "Sad case",
+			desc:   "party member already arrived",
+			guest:  &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 3, TotalArrivedGuests: 2},
+			member: &entities.PartyMember{ID: 4, GuestID: 2, Name: "plusone", Arrived: true},
+			table:  &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 6},
+			expErr: ErrPartyMemberAlreadyArrived,
+		},
+		{
+			name:   "Sad case",
+			desc:   "no available seat",
+			guest:  &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 3, TotalArrivedGuests: 2},
+			member: &entities.PartyMember{ID: 4, GuestID: 2, Name: "plusone"},
+			table:  &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 0},
+			expErr: ErrTableIsFull,
+		},
+	}
+	for _, v := range testcases {
+		r := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(r, tx)
+		expectAudit(tx)
+		svc := NewGuestService(r)
+		tx.On("PeekGuestByName", mock.Anything, "dummy").Return(v.guest, v.guestErr)
+		tx.On("GetGuestByName", mock.Anything, "dummy").Return(v.guest, v.guestErr)
+		tx.On("GetPartyMember", mock.Anything, int64(2), "plusone").Return(v.member, v.memberErr)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(v.table, nil)
+		tx.On("SetMemberArrived", mock.Anything, mock.Anything, true).Return(nil)
+		tx.On("UpdateGuest", mock.Anything, mock.Anything).Return(nil)
+		tx.On("UpdateTable", mock.Anything, mock.Anything).Return(nil)
+		_, after, actErr := svc.MemberArrival(context.Background(), "dummy", "plusone")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expGuest, after, v.desc)
+		if v.expErr != nil {
+			tx.AssertNotCalled(t, "SetMemberArrived", mock.Anything, mock.Anything, mock.Anything)
+			continue
+		}
+		tx.AssertCalled(t, "UpdateTable", mock.Anything, &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 5})
+	}
+}
+
+func TestGuestServiceGuestDepart(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		guest    *entities.Guest
+		guestErr error
+		locked   *entities.Guest
+		expGuest *entities.Guest
+		expErr   error
+	}
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "whole party leaves and frees its seats",
+			guest:    &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2, TotalArrivedGuests: 3, ArrivalTime: "2021-06-04 04:06:44"},
+			expGuest: &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2},
+		},
+		{
+			name:     "Sad case",
+			desc:     "guest not found",
+			guestErr: repo.ErrGuestNotFound,
+			expErr:   repo.ErrGuestNotFound,
+		},
+		{
+			name:   "Sad case",
+			desc:   "guest not arrived",
+			guest:  &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2},
+			expErr: ErrGuestNotArrived,
+		},
+		{
+			name:   "Sad case",
+			desc:   "guest reassigned to another table before it was locked",
+			guest:  &entities.Guest{ID: 2, Name: "dummy", TableID: 1, TotalGuests: 2, TotalArrivedGuests: 2},
+			locked: &entities.Guest{ID: 2, Name: "dummy", TableID: 3, TotalGuests: 2, TotalArrivedGuests: 2},
+			expErr: repo.ErrLockConflict,
+		},
+	}
+	for _, v := range testcases {
+		r := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(r, tx)
+		expectAudit(tx)
+		svc := NewGuestService(r)
+		locked := v.guest
+		if v.locked != nil {
+			locked = v.locked
+		}
+		tx.On("PeekGuestByName", mock.Anything, "dummy").Return(v.guest, v.guestErr)
+		tx.On("GetGuestByName", mock.Anything, "dummy").Return(locked, v.guestErr)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 5}, nil)
+		tx.On("UpdateGuest", mock.Anything, mock.Anything).Return(nil)
+		tx.On("ResetPartyArrival", mock.Anything, int64(2)).Return(nil)
+		tx.On("UpdateTable", mock.Anything, mock.Anything).Return(nil)
+		before, after, actErr := svc.GuestDepart(context.Background(), "dummy")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expGuest, after, v.desc)
+		if v.expErr != nil {
+			tx.AssertNotCalled(t, "UpdateGuest", mock.Anything, mock.Anything)
+			continue
+		}
+		// The table is locked before the guest seated at it
+		assert.Equal(t, "GetTable", tx.Calls[1].Method, v.desc)
+		assert.Equal(t, "GetGuestByName", tx.Calls[2].Method, v.desc)
+		assert.Equal(t, int64(3), before.TotalArrivedGuests, v.desc)
+		tx.AssertCalled(t, "ResetPartyArrival", mock.Anything, int64(2))
+		tx.AssertCalled(t, "UpdateTable", mock.Anything, &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 6, AvailableCapacity: 8})
+	}
+}
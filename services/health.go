@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ggv2/tracing"
+)
+
+// CheckDatabase reports whether the database is reachable.
+func (svc *DBService) CheckDatabase(ctx context.Context) error {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.CheckDatabase")
+	defer span.End()
+	err := svc.repo.Ping(ctx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CheckMigrations reports whether every table, column and unique key of the schema has been
+// created, including those added to existing databases by the scripts in sql/migrations.
+func (svc *DBService) CheckMigrations(ctx context.Context) error {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.CheckMigrations")
+	defer span.End()
+	missing, err := svc.repo.MissingSchema(ctx)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing schema: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
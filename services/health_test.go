@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/repo/mocks"
+)
+
+func TestCheckDatabase(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("Ping", mock.Anything).Return(v.err)
+		actErr := dbService.CheckDatabase(context.Background())
+		assert.Equal(t, v.err, actErr, v.desc)
+	}
+}
+
+func TestCheckMigrations(t *testing.T) {
+	type TestCase struct {
+		name    string
+		desc    string
+		missing []string
+		err     error
+		expErr  error
+	}
+	testcases := []TestCase{
+		{
+			name:    "Happy case",
+			desc:    "every table, column and unique key created",
+			missing: []string{},
+		},
+		{
+			name:    "Sad case",
+			desc:    "schema missing",
+			missing: []string{"audit_log", "guests.dietary_tags", "guests unique key name"},
+			expErr:  fmt.Errorf("missing schema: audit_log, guests.dietary_tags, guests unique key name"),
+		},
+		{
+			name:   "Sad case",
+			desc:   "repo return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("MissingSchema", mock.Anything).Return(v.missing, v.err)
+		actErr := dbService.CheckMigrations(context.Background())
+		assert.Equal(t, v.expErr, actErr, v.desc)
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/tracing"
+)
+
+// ReserveIdempotencyKey claims an idempotency key for a request until ttl elapses.
+// When the key is already claimed its record is returned instead, nil means the key was claimed.
+func (svc *DBService) ReserveIdempotencyKey(ctx context.Context, record *entities.IdempotencyRecord, ttl time.Duration) (*entities.IdempotencyRecord, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.ReserveIdempotencyKey")
+	defer span.End()
+	err := svc.repo.ReserveIdempotencyKey(ctx, record, ttl)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, repo.ErrIdempotencyKeyExists) {
+		return nil, err
+	}
+	existing, err := svc.repo.GetIdempotencyRecord(ctx, record.Actor, record.Key)
+	if err != nil {
+		if errors.Is(err, repo.ErrIdempotencyKeyNotFound) {
+			// Claim expired or released in between, the request is still reported as a duplicate
+			return nil, repo.ErrIdempotencyKeyExists
+		}
+		return nil, err
+	}
+	return existing, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request that claimed an idempotency key.
+func (svc *DBService) CompleteIdempotencyKey(ctx context.Context, record *entities.IdempotencyRecord) error {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.CompleteIdempotencyKey")
+	defer span.End()
+	err := svc.repo.CompleteIdempotencyKey(ctx, record)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey drops the claim on an idempotency key whose request did not complete.
+func (svc *DBService) ReleaseIdempotencyKey(ctx context.Context, actor, key string) error {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.ReleaseIdempotencyKey")
+	defer span.End()
+	err := svc.repo.ReleaseIdempotencyKey(ctx, actor, key)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/repo/mocks"
+)
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	existing := &entities.IdempotencyRecord{Actor: "api_key:kiosk", Key: "key-1", RequestHash: "hash", Status: 201}
+	type TestCase struct {
+		name       string
+		desc       string
+		reserveErr error
+		getRes     *entities.IdempotencyRecord
+		getErr     error
+		expRes     *entities.IdempotencyRecord
+		expErr     error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "key reserved",
+		},
+		{
+			name:       "Happy case",
+			desc:       "key already used",
+			reserveErr: repo.ErrIdempotencyKeyExists,
+			getRes:     existing,
+			expRes:     existing,
+		},
+		{
+			name:       "Sad case",
+			desc:       "key expired after reserving failed",
+			reserveErr: repo.ErrIdempotencyKeyExists,
+			getErr:     repo.ErrIdempotencyKeyNotFound,
+			expErr:     repo.ErrIdempotencyKeyExists,
+		},
+		{
+			name:       "Sad case",
+			desc:       "reserve return error",
+			reserveErr: fmt.Errorf("mock error"),
+			expErr:     fmt.Errorf("mock error"),
+		},
+		{
+			name:       "Sad case",
+			desc:       "get return error",
+			reserveErr: repo.ErrIdempotencyKeyExists,
+			getErr:     fmt.Errorf("mock error"),
+			expErr:     fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		dbRepo := new(mocks.DbRepo)
+		dbService := &DBService{repo: dbRepo}
+		record := &entities.IdempotencyRecord{Actor: "api_key:kiosk", Key: "key-1", RequestHash: "hash"}
+		dbRepo.On("ReserveIdempotencyKey", mock.Anything, record, time.Hour).Return(v.reserveErr)
+		dbRepo.On("GetIdempotencyRecord", mock.Anything, "api_key:kiosk", "key-1").Return(v.getRes, v.getErr)
+		actRes, actErr := dbService.ReserveIdempotencyKey(context.Background(), record, time.Hour)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expRes, actRes, v.desc)
+	}
+}
+
+func TestCompleteIdempotencyKey(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		record := &entities.IdempotencyRecord{Actor: "api_key:kiosk", Key: "key-1", Status: 201}
+		repo.On("CompleteIdempotencyKey", mock.Anything, record).Return(v.err)
+		actErr := dbService.CompleteIdempotencyKey(context.Background(), record)
+		assert.Equal(t, v.err, actErr, v.desc)
+	}
+}
+
+func TestReleaseIdempotencyKey(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("ReleaseIdempotencyKey", mock.Anything, "api_key:kiosk", "key-1").Return(v.err)
+		actErr := dbService.ReleaseIdempotencyKey(context.Background(), "api_key:kiosk", "key-1")
+		assert.Equal(t, v.err, actErr, v.desc)
+	}
+}
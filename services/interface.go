@@ -2,18 +2,47 @@ package services
 
 import (
 	"context"
+	"time"
+
+	"ggv2/auth"
 	"ggv2/entities"
 )
 
 type DbService interface {
 	GetTable(context.Context, int64) (*entities.Table, error)
-	ListTables(context.Context, int64, int64) ([]*entities.Table, error)
-	CreateTable(context.Context, int64) (*entities.Table, error)
+	ListTables(context.Context, *entities.TableFilter, int64, int64) ([]*entities.Table, error)
+	CreateTable(context.Context, int64, *entities.TablePatch) (*entities.Table, error)
+	UpdateTable(context.Context, int64, *entities.TablePatch) (*entities.Table, error)
+	DeleteTable(context.Context, int64, int64) error
 	GetEmptySeatsCount(context.Context) (int, error)
-	AddToGuestList(context.Context, int64, int64, string) error
+	AddToGuestList(context.Context, int64, int64, string, *entities.Diner, []*entities.PartyMember) error
 	ListRSVPGuests(context.Context, int64, int64) ([]*entities.Guest, error)
 	GuestDepart(context.Context, string) error
-	GuestArrival(context.Context, int64, string) error
+	GuestArrival(context.Context, int64, string, []string) error
 	ListArrivedGuests(context.Context, int64, int64) ([]*entities.Guest, error)
-	EmptyTables(context.Context) error
+	EmptyTables(context.Context, string) (*entities.Archive, error)
+	ListArchives(context.Context) ([]*entities.Archive, error)
+	RestoreArchive(context.Context, int64) (*entities.Archive, error)
+	Snapshot(context.Context, string) (*entities.Snapshot, error)
+	RestoreSnapshot(context.Context, *entities.Snapshot, bool) (*entities.Archive, error)
+	ListPartyMembers(context.Context, string) ([]*entities.PartyMember, error)
+	MemberArrival(context.Context, string, string) error
+	CateringReport(context.Context, string) (*entities.CateringReport, error)
+	FloorPlan(context.Context, string) ([]*entities.FloorPlanTable, error)
+	CreateTables(context.Context, string, entities.TableGroups) ([]*entities.Table, error)
+	SaveLayoutTemplate(context.Context, string, entities.TableGroups) (*entities.LayoutTemplate, error)
+	GetLayoutTemplate(context.Context, string) (*entities.LayoutTemplate, error)
+	ListLayoutTemplates(context.Context) ([]*entities.LayoutTemplate, error)
+	DeleteLayoutTemplate(context.Context, string) error
+	ApplyLayoutTemplate(context.Context, string, string) ([]*entities.Table, error)
+	AuthenticateAPIKey(context.Context, string) (*auth.Principal, error)
+	CreateAPIKey(context.Context, string, string) (*entities.APIKey, string, error)
+	ListAPIKeys(context.Context) ([]*entities.APIKey, error)
+	RevokeAPIKey(context.Context, int64) error
+	ListAuditEntries(context.Context, *entities.AuditFilter, int64, int64) ([]*entities.AuditEntry, error)
+	ReserveIdempotencyKey(context.Context, *entities.IdempotencyRecord, time.Duration) (*entities.IdempotencyRecord, error)
+	CompleteIdempotencyKey(context.Context, *entities.IdempotencyRecord) error
+	ReleaseIdempotencyKey(context.Context, string, string) error
+	CheckDatabase(context.Context) error
+	CheckMigrations(context.Context) error
 }
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"ggv2/audit"
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/tracing"
+)
+
+var (
+	ErrTablesAlreadyExist = errors.New("event already has tables, empty tables before applying a layout template")
+)
+
+// CreateTables creates every table described by the groups for an event in a single transaction.
+func (svc *DBService) CreateTables(ctx context.Context, event string, groups entities.TableGroups) ([]*entities.Table, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.CreateTables")
+	defer span.End()
+	var tables []*entities.Table
+	err := svc.repo.Atomic(ctx, func(tx repo.Tx) error {
+		var err error
+		tables, err = createTables(ctx, tx, event, groups)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit.OpCreateTables, eventTarget(event), nil, tables)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+func createTables(ctx context.Context, tx repo.Tx, event string, groups entities.TableGroups) ([]*entities.Table, error) {
+	tables := groups.Tables()
+	for _, t := range tables {
+		t.Event = event
+	}
+	if err := tx.CreateTables(ctx, tables); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// SaveLayoutTemplate creates or replaces the layout template with the given name.
+func (svc *DBService) SaveLayoutTemplate(ctx context.Context, name string, groups entities.TableGroups) (*entities.LayoutTemplate, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.SaveLayoutTemplate")
+	defer span.End()
+	template := &entities.LayoutTemplate{
+		Name:   name,
+		Groups: groups,
+	}
+	err := svc.repo.Atomic(ctx, func(tx repo.Tx) error {
+		// A template saved for the first time has no previous state to audit
+		before, err := tx.GetLayoutTemplate(ctx, name)
+		if err != nil && !errors.Is(err, repo.ErrLayoutTemplateNotFound) {
+			return err
+		}
+		if err = tx.SaveLayoutTemplate(ctx, template); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit.OpSaveLayoutTemplate, layoutTemplateTarget(name), before, template)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (svc *DBService) GetLayoutTemplate(ctx context.Context, name string) (*entities.LayoutTemplate, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.GetLayoutTemplate")
+	defer span.End()
+	template, err := svc.repo.GetLayoutTemplate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (svc *DBService) ListLayoutTemplates(ctx context.Context) ([]*entities.LayoutTemplate, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.ListLayoutTemplates")
+	defer span.End()
+	templates, err := svc.repo.ListLayoutTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (svc *DBService) DeleteLayoutTemplate(ctx context.Context, name string) error {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.DeleteLayoutTemplate")
+	defer span.End()
+	return svc.repo.Atomic(ctx, func(tx repo.Tx) error {
+		before, err := tx.GetLayoutTemplate(ctx, name)
+		if err != nil {
+			return err
+		}
+		if err = tx.DeleteLayoutTemplate(ctx, name); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit.OpDeleteLayoutTemplate, layoutTemplateTarget(name), before, nil)
+	})
+}
+
+// ApplyLayoutTemplate creates the tables of a layout template for an event. Templates are only
+// applied to a fresh event or after its tables were emptied, so that table layouts are never mixed.
+// The default event has no name and spans every table.
+func (svc *DBService) ApplyLayoutTemplate(ctx context.Context, name, event string) ([]*entities.Table, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DBService.ApplyLayoutTemplate")
+	defer span.End()
+	var tables []*entities.Table
+	err := svc.repo.Atomic(ctx, func(tx repo.Tx) error {
+		template, err := tx.GetLayoutTemplate(ctx, name)
+		if err != nil {
+			return err
+		}
+		// The event stays without tables until the template is applied, concurrent
+		// applications wait and find the tables of the first
+		exists, err := tx.HasTables(ctx, event)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrTablesAlreadyExist
+		}
+		tables, err = createTables(ctx, tx, event, template.Groups)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit.OpApplyLayoutTemplate, layoutTemplateTarget(name), nil, tables)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
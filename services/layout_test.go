@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/repo/mocks"
+)
+
+func TestCreateTables(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+		res  []*entities.Table
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+			res: []*entities.Table{
+				{TableID: 1, Capacity: 8, AvailableCapacity: 8, PlannedCapacity: 8, Event: "gala", Zone: "Garden"},
+				{TableID: 2, Capacity: 8, AvailableCapacity: 8, PlannedCapacity: 8, Event: "gala", Zone: "Garden"},
+				{TableID: 3, Capacity: 12, AvailableCapacity: 12, PlannedCapacity: 12, Event: "gala"},
+			},
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(repo, tx)
+		expectAudit(tx)
+		dbService := &DBService{repo: repo}
+		tx.On("CreateTables", mock.Anything, []*entities.Table{
+			{Capacity: 8, AvailableCapacity: 8, PlannedCapacity: 8, Event: "gala", Zone: "Garden"},
+			{Capacity: 8, AvailableCapacity: 8, PlannedCapacity: 8, Event: "gala", Zone: "Garden"},
+			{Capacity: 12, AvailableCapacity: 12, PlannedCapacity: 12, Event: "gala"},
+		}).Run(func(args mock.Arguments) {
+			for i, t := range args.Get(1).([]*entities.Table) {
+				t.TableID = int64(i + 1)
+			}
+		}).Return(v.err)
+		actRes, actErr := dbService.CreateTables(context.Background(), "gala", entities.TableGroups{
+			{Count: 2, Capacity: 8, Zone: "Garden"},
+			{Count: 1, Capacity: 12},
+		})
+		assert.Equal(t, v.err, actErr, v.desc)
+		assert.Equal(t, v.res, actRes, v.desc)
+	}
+}
+
+func TestSaveLayoutTemplate(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		previous *entities.LayoutTemplate
+		getErr   error
+		err      error
+		res      *entities.LayoutTemplate
+		expErr   error
+	}
+	groups := entities.TableGroups{{Count: 10, Capacity: 8}}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "new template saved",
+			getErr: repo.ErrLayoutTemplateNotFound,
+			res:    &entities.LayoutTemplate{Name: "wedding", Groups: groups},
+		},
+		{
+			name:     "Happy case",
+			desc:     "existing template replaced",
+			previous: &entities.LayoutTemplate{ID: 1, Name: "wedding"},
+			res:      &entities.LayoutTemplate{Name: "wedding", Groups: groups},
+		},
+		{
+			name:   "Sad case",
+			desc:   "get template return error",
+			getErr: fmt.Errorf("mock error"),
+			expErr: fmt.Errorf("mock error"),
+		},
+		{
+			name:   "Sad case",
+			desc:   "repo return error",
+			getErr: repo.ErrLayoutTemplateNotFound,
+			err:    fmt.Errorf("mock error"),
+			expErr: fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		dbRepo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(dbRepo, tx)
+		dbService := &DBService{repo: dbRepo}
+		tx.On("GetLayoutTemplate", mock.Anything, "wedding").Return(v.previous, v.getErr)
+		tx.On("SaveLayoutTemplate", mock.Anything, &entities.LayoutTemplate{Name: "wedding", Groups: groups}).Return(v.err)
+		var entry *entities.AuditEntry
+		tx.On("CreateAuditEntry", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			entry = args.Get(1).(*entities.AuditEntry)
+		}).Return(nil).Maybe()
+		actRes, actErr := dbService.SaveLayoutTemplate(context.Background(), "wedding", groups)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.res, actRes, v.desc)
+		if v.expErr != nil {
+			assert.Nil(t, entry, v.desc)
+			continue
+		}
+		// The replaced template is recorded as the state before saving
+		assert.Equal(t, v.previous != nil, entry.Before != nil, v.desc)
+	}
+}
+
+func TestGetLayoutTemplate(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+		res  *entities.LayoutTemplate
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+			res:  &entities.LayoutTemplate{ID: 1, Name: "wedding"},
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("GetLayoutTemplate", mock.Anything, "wedding").Return(v.res, v.err)
+		actRes, actErr := dbService.GetLayoutTemplate(context.Background(), "wedding")
+		assert.Equal(t, v.err, actErr, v.desc)
+		assert.Equal(t, v.res, actRes, v.desc)
+	}
+}
+
+func TestListLayoutTemplates(t *testing.T) {
+	type TestCase struct {
+		name string
+		desc string
+		err  error
+		res  []*entities.LayoutTemplate
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+			res:  []*entities.LayoutTemplate{{ID: 1, Name: "wedding"}},
+		},
+		{
+			name: "Sad case",
+			desc: "repo return error",
+			err:  fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		dbService := &DBService{repo: repo}
+		repo.On("ListLayoutTemplates", mock.Anything).Return(v.res, v.err)
+		actRes, actErr := dbService.ListLayoutTemplates(context.Background())
+		assert.Equal(t, v.err, actErr, v.desc)
+		assert.Equal(t, v.res, actRes, v.desc)
+	}
+}
+
+func TestDeleteLayoutTemplate(t *testing.T) {
+	type TestCase struct {
+		name   string
+		desc   string
+		getErr error
+		err    error
+		expErr error
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "all ok",
+		},
+		{
+			name:   "Sad case",
+			desc:   "template not found",
+			getErr: repo.ErrLayoutTemplateNotFound,
+			expErr: repo.ErrLayoutTemplateNotFound,
+		},
+		{
+			name:   "Sad case",
+			desc:   "repo return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		dbRepo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(dbRepo, tx)
+		expectAudit(tx)
+		dbService := &DBService{repo: dbRepo}
+		var template *entities.LayoutTemplate
+		if v.getErr == nil {
+			template = &entities.LayoutTemplate{ID: 1, Name: "wedding"}
+		}
+		tx.On("GetLayoutTemplate", mock.Anything, "wedding").Return(template, v.getErr)
+		tx.On("DeleteLayoutTemplate", mock.Anything, "wedding").Return(v.err)
+		actErr := dbService.DeleteLayoutTemplate(context.Background(), "wedding")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.getErr != nil {
+			tx.AssertNotCalled(t, "DeleteLayoutTemplate", mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestApplyLayoutTemplate(t *testing.T) {
+	type TestCase struct {
+		name      string
+		desc      string
+		getErr    error
+		hasErr    error
+		exists    bool
+		createErr error
+		res       []*entities.Table
+		expErr    error
+	}
+	created := []*entities.Table{{TableID: 1, Capacity: 8, AvailableCapacity: 8, PlannedCapacity: 8, Event: "gala"}}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "template applied to an empty event",
+			res:  created,
+		},
+		{
+			name:   "Sad case",
+			desc:   "get template return error",
+			getErr: fmt.Errorf("mock error"),
+			expErr: fmt.Errorf("mock error"),
+		},
+		{
+			name:   "Sad case",
+			desc:   "has tables return error",
+			hasErr: fmt.Errorf("mock error"),
+			expErr: fmt.Errorf("mock error"),
+		},
+		{
+			name:   "Sad case",
+			desc:   "event already has tables",
+			exists: true,
+			expErr: ErrTablesAlreadyExist,
+		},
+		{
+			name:      "Sad case",
+			desc:      "create tables return error",
+			createErr: fmt.Errorf("mock error"),
+			expErr:    fmt.Errorf("mock error"),
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(repo, tx)
+		expectAudit(tx)
+		dbService := &DBService{repo: repo}
+		var template *entities.LayoutTemplate
+		if v.getErr == nil {
+			template = &entities.LayoutTemplate{Name: "wedding", Groups: entities.TableGroups{{Count: 1, Capacity: 8}}}
+		}
+		tx.On("GetLayoutTemplate", mock.Anything, "wedding").Return(template, v.getErr)
+		tx.On("HasTables", mock.Anything, "gala").Return(v.exists, v.hasErr)
+		tx.On("CreateTables", mock.Anything, []*entities.Table{{Capacity: 8, AvailableCapacity: 8, PlannedCapacity: 8, Event: "gala"}}).Run(func(args mock.Arguments) {
+			args.Get(1).([]*entities.Table)[0].TableID = 1
+		}).Return(v.createErr)
+		actRes, actErr := dbService.ApplyLayoutTemplate(context.Background(), "wedding", "gala")
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.res, actRes, v.desc)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"ggv2/repo"
+)
+
+// occupancyTimeout bounds reading the tables on a metrics scrape
+const occupancyTimeout = 5 * time.Second
+
+var (
+	// rejectionReasons labels the errors of requests refused by the seating rules
+	rejectionReasons = []struct {
+		err    error
+		reason string
+	}{
+		{repo.ErrGuestAlreadyRSVP, "already_rsvp"},
+		{ErrGuestAlreadyArrived, "already_arrived"},
+		{ErrGuestNotArrived, "not_arrived"},
+		{ErrPartyAlreadyArrived, "party_already_arrived"},
+		{ErrPartyMemberAlreadyArrived, "member_already_arrived"},
+		{ErrTableIsFull, "table_full"},
+		{ErrTableNotEmpty, "table_not_empty"},
+		{ErrCapacityBelowGuests, "capacity_below_guests"},
+	}
+)
+var (
+	rsvpGuests = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: repo.MetricsSubsystem,
+		Name:      "rsvp_guests_total",
+		Help:      "How many guests RSVP, accompanying guests included.",
+	})
+	arrivedGuests = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: repo.MetricsSubsystem,
+		Name:      "arrived_guests_total",
+		Help:      "How many guests checked in, accompanying guests included.",
+	})
+	departedGuests = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: repo.MetricsSubsystem,
+		Name:      "departed_guests_total",
+		Help:      "How many checked in guests departed, accompanying guests included.",
+	})
+	rejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: repo.MetricsSubsystem,
+		Name:      "rejections_total",
+		Help:      "How many seating and check-in requests were refused, partitioned by operation and reason.",
+	}, []string{"operation", "reason"})
+	lockConflicts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: repo.MetricsSubsystem,
+		Name:      "optimistic_lock_conflicts_total",
+		Help:      "How many updates lost a lock to a concurrent update, partitioned by operation.",
+	}, []string{"operation"})
+)
+
+// recordRejection counts a failed operation as refused or conflicting, other errors are not counted.
+func recordRejection(operation string, err error) {
+	if errors.Is(err, repo.ErrFailedOptimisticLock) || errors.Is(err, repo.ErrLockConflict) {
+		lockConflicts.WithLabelValues(operation).Inc()
+		return
+	}
+	for _, r := range rejectionReasons {
+		if errors.Is(err, r.err) {
+			rejections.WithLabelValues(operation, r.reason).Inc()
+			return
+		}
+	}
+}
+
+// OccupancyCollector exports the seats of every table and the arrived headcount of every event,
+// read from the database on every scrape
+type OccupancyCollector struct {
+	repo repo.DbRepo
+
+	capacity  *prometheus.Desc
+	planned   *prometheus.Desc
+	available *prometheus.Desc
+	headcount *prometheus.Desc
+}
+
+func NewOccupancyCollector(r repo.DbRepo) *OccupancyCollector {
+	return &OccupancyCollector{
+		repo:      r,
+		capacity:  prometheus.NewDesc(prometheus.BuildFQName("", repo.MetricsSubsystem, "table_capacity_seats"), "Seats of a table.", []string{"table", "event"}, nil),
+		planned:   prometheus.NewDesc(prometheus.BuildFQName("", repo.MetricsSubsystem, "table_planned_seats"), "Seats of a table taken by guests that RSVP.", []string{"table", "event"}, nil),
+		available: prometheus.NewDesc(prometheus.BuildFQName("", repo.MetricsSubsystem, "table_available_seats"), "Seats of a table not taken by arrived guests.", []string{"table", "event"}, nil),
+		headcount: prometheus.NewDesc(prometheus.BuildFQName("", repo.MetricsSubsystem, "arrived_headcount"), "Guests currently seated at an event.", []string{"event"}, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *OccupancyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.capacity
+	ch <- c.planned
+	ch <- c.available
+	ch <- c.headcount
+}
+
+// Collect implements prometheus.Collector, failing to read the tables fails the scrape
+func (c *OccupancyCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), occupancyTimeout)
+	defer cancel()
+	tables, err := c.repo.ListAllTables(ctx, "")
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.capacity, err)
+		return
+	}
+	headcount := map[string]int64{}
+	for _, t := range tables {
+		id := strconv.FormatInt(t.TableID, 10)
+		ch <- prometheus.MustNewConstMetric(c.capacity, prometheus.GaugeValue, float64(t.Capacity), id, t.Event)
+		ch <- prometheus.MustNewConstMetric(c.planned, prometheus.GaugeValue, float64(t.Capacity-t.PlannedCapacity), id, t.Event)
+		ch <- prometheus.MustNewConstMetric(c.available, prometheus.GaugeValue, float64(t.AvailableCapacity), id, t.Event)
+		headcount[t.Event] += t.Capacity - t.AvailableCapacity
+	}
+	for event, n := range headcount {
+		ch <- prometheus.MustNewConstMetric(c.headcount, prometheus.GaugeValue, float64(n), event)
+	}
+}
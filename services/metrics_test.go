@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/audit"
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/repo/mocks"
+)
+
+func TestOccupancyCollector(t *testing.T) {
+	tables := []*entities.Table{
+		{TableID: 1, Event: "wedding", Capacity: 6, PlannedCapacity: 1, AvailableCapacity: 2},
+		{TableID: 2, Event: "wedding", Capacity: 4, PlannedCapacity: 4, AvailableCapacity: 3},
+	}
+	type TestCase struct {
+		name   string
+		desc   string
+		err    error
+		expRes string
+		expErr bool
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "seats and headcount of every table",
+			expRes: `
+# HELP GGv2_arrived_headcount Guests currently seated at an event.
+# TYPE GGv2_arrived_headcount gauge
+GGv2_arrived_headcount{event="wedding"} 5
+# HELP GGv2_table_available_seats Seats of a table not taken by arrived guests.
+# TYPE GGv2_table_available_seats gauge
+GGv2_table_available_seats{event="wedding",table="1"} 2
+GGv2_table_available_seats{event="wedding",table="2"} 3
+# HELP GGv2_table_capacity_seats Seats of a table.
+# TYPE GGv2_table_capacity_seats gauge
+GGv2_table_capacity_seats{event="wedding",table="1"} 6
+GGv2_table_capacity_seats{event="wedding",table="2"} 4
+# HELP GGv2_table_planned_seats Seats of a table taken by guests that RSVP.
+# TYPE GGv2_table_planned_seats gauge
+GGv2_table_planned_seats{event="wedding",table="1"} 5
+GGv2_table_planned_seats{event="wedding",table="2"} 0
+`,
+		},
+		{
+			name:   "Sad case",
+			desc:   "list tables return error",
+			err:    fmt.Errorf("mock error"),
+			expErr: true,
+		},
+	}
+	for _, v := range testcases {
+		repo := new(mocks.DbRepo)
+		repo.On("ListAllTables", mock.Anything, "").Return(tables, v.err)
+		c := NewOccupancyCollector(repo)
+		actErr := testutil.CollectAndCompare(c, strings.NewReader(v.expRes))
+		assert.Equal(t, v.expErr, actErr != nil, v.desc)
+	}
+}
+
+func TestCheckInMetrics(t *testing.T) {
+	type TestCase struct {
+		name         string
+		desc         string
+		err          error
+		expArrived   float64
+		expRejection float64
+		expConflict  float64
+	}
+	testcases := []TestCase{
+		{
+			name:       "Happy case",
+			desc:       "arrived headcount counted",
+			expArrived: 3,
+		},
+		{
+			name:         "Sad case",
+			desc:         "rejected as table is full",
+			err:          ErrTableIsFull,
+			expRejection: 1,
+		},
+		{
+			name:        "Sad case",
+			desc:        "lost optimistic lock",
+			err:         repo.ErrFailedOptimisticLock,
+			expConflict: 1,
+		},
+		{
+			name: "Sad case",
+			desc: "database error not counted",
+			err:  repo.ErrDBErr,
+		},
+	}
+	for _, v := range testcases {
+		dbRepo := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAudit(tx)
+		expectAtomic(dbRepo, tx)
+		dbService := NewDbService(dbRepo)
+		tx.On("PeekGuestByName", mock.Anything, "alice").Return(&entities.Guest{ID: 1, Name: "alice", TableID: 1, TotalGuests: 3}, nil)
+		tx.On("GetGuestByName", mock.Anything, "alice").Return(&entities.Guest{ID: 1, Name: "alice", TableID: 1, TotalGuests: 3}, nil)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 8}, nil)
+		tx.On("UpdateGuest", mock.Anything, mock.Anything).Return(v.err)
+		tx.On("UpdateTable", mock.Anything, mock.Anything).Return(nil)
+		arrived := testutil.ToFloat64(arrivedGuests)
+		rejected := testutil.ToFloat64(rejections.WithLabelValues(audit.OpGuestArrival, "table_full"))
+		conflicts := testutil.ToFloat64(lockConflicts.WithLabelValues(audit.OpGuestArrival))
+		dbService.GuestArrival(context.Background(), 2, "alice", nil)
+		assert.Equal(t, v.expArrived, testutil.ToFloat64(arrivedGuests)-arrived, v.desc)
+		assert.Equal(t, v.expRejection, testutil.ToFloat64(rejections.WithLabelValues(audit.OpGuestArrival, "table_full"))-rejected, v.desc)
+		assert.Equal(t, v.expConflict, testutil.ToFloat64(lockConflicts.WithLabelValues(audit.OpGuestArrival))-conflicts, v.desc)
+	}
+}
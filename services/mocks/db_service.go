@@ -4,7 +4,9 @@ package mocks
 
 import (
 	context "context"
+	auth "ggv2/auth"
 	entities "ggv2/entities"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -14,13 +16,13 @@ type DbService struct {
 	mock.Mock
 }
 
-// AddToGuestList provides a mock function with given fields: _a0, _a1, _a2, _a3
-func (_m *DbService) AddToGuestList(_a0 context.Context, _a1 int64, _a2 int64, _a3 string) error {
-	ret := _m.Called(_a0, _a1, _a2, _a3)
+// AddToGuestList provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4, _a5
+func (_m *DbService) AddToGuestList(_a0 context.Context, _a1 int64, _a2 int64, _a3 string, _a4 *entities.Diner, _a5 []*entities.PartyMember) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4, _a5)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string) error); ok {
-		r0 = rf(_a0, _a1, _a2, _a3)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, *entities.Diner, []*entities.PartyMember) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4, _a5)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -28,21 +30,67 @@ func (_m *DbService) AddToGuestList(_a0 context.Context, _a1 int64, _a2 int64, _
 	return r0
 }
 
-// CreateTable provides a mock function with given fields: _a0, _a1
-func (_m *DbService) CreateTable(_a0 context.Context, _a1 int64) (*entities.Table, error) {
+// ApplyLayoutTemplate provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbService) ApplyLayoutTemplate(_a0 context.Context, _a1 string, _a2 string) ([]*entities.Table, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []*entities.Table
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*entities.Table); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.Table)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthenticateAPIKey provides a mock function with given fields: _a0, _a1
+func (_m *DbService) AuthenticateAPIKey(_a0 context.Context, _a1 string) (*auth.Principal, error) {
 	ret := _m.Called(_a0, _a1)
 
-	var r0 *entities.Table
-	if rf, ok := ret.Get(0).(func(context.Context, int64) *entities.Table); ok {
+	var r0 *auth.Principal
+	if rf, ok := ret.Get(0).(func(context.Context, string) *auth.Principal); ok {
 		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*entities.Table)
+			r0 = ret.Get(0).(*auth.Principal)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CateringReport provides a mock function with given fields: _a0, _a1
+func (_m *DbService) CateringReport(_a0 context.Context, _a1 string) (*entities.CateringReport, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *entities.CateringReport
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.CateringReport); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.CateringReport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
 		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
@@ -51,8 +99,22 @@ func (_m *DbService) CreateTable(_a0 context.Context, _a1 int64) (*entities.Tabl
 	return r0, r1
 }
 
-// EmptyTables provides a mock function with given fields: _a0
-func (_m *DbService) EmptyTables(_a0 context.Context) error {
+// CheckDatabase provides a mock function with given fields: _a0
+func (_m *DbService) CheckDatabase(_a0 context.Context) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CheckMigrations provides a mock function with given fields: _a0
+func (_m *DbService) CheckMigrations(_a0 context.Context) error {
 	ret := _m.Called(_a0)
 
 	var r0 error
@@ -65,6 +127,170 @@ func (_m *DbService) EmptyTables(_a0 context.Context) error {
 	return r0
 }
 
+// CompleteIdempotencyKey provides a mock function with given fields: _a0, _a1
+func (_m *DbService) CompleteIdempotencyKey(_a0 context.Context, _a1 *entities.IdempotencyRecord) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.IdempotencyRecord) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateAPIKey provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbService) CreateAPIKey(_a0 context.Context, _a1 string, _a2 string) (*entities.APIKey, string, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *entities.APIKey
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *entities.APIKey); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.APIKey)
+		}
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) string); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, string) error); ok {
+		r2 = rf(_a0, _a1, _a2)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// CreateTable provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbService) CreateTable(_a0 context.Context, _a1 int64, _a2 *entities.TablePatch) (*entities.Table, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *entities.Table
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *entities.TablePatch) *entities.Table); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.Table)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, *entities.TablePatch) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateTables provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbService) CreateTables(_a0 context.Context, _a1 string, _a2 entities.TableGroups) ([]*entities.Table, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []*entities.Table
+	if rf, ok := ret.Get(0).(func(context.Context, string, entities.TableGroups) []*entities.Table); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.Table)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, entities.TableGroups) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteLayoutTemplate provides a mock function with given fields: _a0, _a1
+func (_m *DbService) DeleteLayoutTemplate(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteTable provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbService) DeleteTable(_a0 context.Context, _a1 int64, _a2 int64) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EmptyTables provides a mock function with given fields: _a0, _a1
+func (_m *DbService) EmptyTables(_a0 context.Context, _a1 string) (*entities.Archive, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *entities.Archive
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.Archive); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.Archive)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FloorPlan provides a mock function with given fields: _a0, _a1
+func (_m *DbService) FloorPlan(_a0 context.Context, _a1 string) ([]*entities.FloorPlanTable, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []*entities.FloorPlanTable
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*entities.FloorPlanTable); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.FloorPlanTable)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetEmptySeatsCount provides a mock function with given fields: _a0
 func (_m *DbService) GetEmptySeatsCount(_a0 context.Context) (int, error) {
 	ret := _m.Called(_a0)
@@ -86,6 +312,29 @@ func (_m *DbService) GetEmptySeatsCount(_a0 context.Context) (int, error) {
 	return r0, r1
 }
 
+// GetLayoutTemplate provides a mock function with given fields: _a0, _a1
+func (_m *DbService) GetLayoutTemplate(_a0 context.Context, _a1 string) (*entities.LayoutTemplate, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *entities.LayoutTemplate
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.LayoutTemplate); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.LayoutTemplate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTable provides a mock function with given fields: _a0, _a1
 func (_m *DbService) GetTable(_a0 context.Context, _a1 int64) (*entities.Table, error) {
 	ret := _m.Called(_a0, _a1)
@@ -109,13 +358,13 @@ func (_m *DbService) GetTable(_a0 context.Context, _a1 int64) (*entities.Table,
 	return r0, r1
 }
 
-// GuestArrival provides a mock function with given fields: _a0, _a1, _a2
-func (_m *DbService) GuestArrival(_a0 context.Context, _a1 int64, _a2 string) error {
-	ret := _m.Called(_a0, _a1, _a2)
+// GuestArrival provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *DbService) GuestArrival(_a0 context.Context, _a1 int64, _a2 string, _a3 []string) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
-		r0 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, []string) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -137,6 +386,52 @@ func (_m *DbService) GuestDepart(_a0 context.Context, _a1 string) error {
 	return r0
 }
 
+// ListAPIKeys provides a mock function with given fields: _a0
+func (_m *DbService) ListAPIKeys(_a0 context.Context) ([]*entities.APIKey, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*entities.APIKey
+	if rf, ok := ret.Get(0).(func(context.Context) []*entities.APIKey); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListArchives provides a mock function with given fields: _a0
+func (_m *DbService) ListArchives(_a0 context.Context) ([]*entities.Archive, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*entities.Archive
+	if rf, ok := ret.Get(0).(func(context.Context) []*entities.Archive); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.Archive)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListArrivedGuests provides a mock function with given fields: _a0, _a1, _a2
 func (_m *DbService) ListArrivedGuests(_a0 context.Context, _a1 int64, _a2 int64) ([]*entities.Guest, error) {
 	ret := _m.Called(_a0, _a1, _a2)
@@ -160,6 +455,75 @@ func (_m *DbService) ListArrivedGuests(_a0 context.Context, _a1 int64, _a2 int64
 	return r0, r1
 }
 
+// ListAuditEntries provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *DbService) ListAuditEntries(_a0 context.Context, _a1 *entities.AuditFilter, _a2 int64, _a3 int64) ([]*entities.AuditEntry, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 []*entities.AuditEntry
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.AuditFilter, int64, int64) []*entities.AuditEntry); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.AuditEntry)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *entities.AuditFilter, int64, int64) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListLayoutTemplates provides a mock function with given fields: _a0
+func (_m *DbService) ListLayoutTemplates(_a0 context.Context) ([]*entities.LayoutTemplate, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*entities.LayoutTemplate
+	if rf, ok := ret.Get(0).(func(context.Context) []*entities.LayoutTemplate); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.LayoutTemplate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPartyMembers provides a mock function with given fields: _a0, _a1
+func (_m *DbService) ListPartyMembers(_a0 context.Context, _a1 string) ([]*entities.PartyMember, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []*entities.PartyMember
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*entities.PartyMember); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entities.PartyMember)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListRSVPGuests provides a mock function with given fields: _a0, _a1, _a2
 func (_m *DbService) ListRSVPGuests(_a0 context.Context, _a1 int64, _a2 int64) ([]*entities.Guest, error) {
 	ret := _m.Called(_a0, _a1, _a2)
@@ -183,13 +547,13 @@ func (_m *DbService) ListRSVPGuests(_a0 context.Context, _a1 int64, _a2 int64) (
 	return r0, r1
 }
 
-// ListTables provides a mock function with given fields: _a0, _a1, _a2
-func (_m *DbService) ListTables(_a0 context.Context, _a1 int64, _a2 int64) ([]*entities.Table, error) {
-	ret := _m.Called(_a0, _a1, _a2)
+// ListTables provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *DbService) ListTables(_a0 context.Context, _a1 *entities.TableFilter, _a2 int64, _a3 int64) ([]*entities.Table, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
 
 	var r0 []*entities.Table
-	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) []*entities.Table); ok {
-		r0 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.TableFilter, int64, int64) []*entities.Table); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*entities.Table)
@@ -197,7 +561,187 @@ func (_m *DbService) ListTables(_a0 context.Context, _a1 int64, _a2 int64) ([]*e
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, *entities.TableFilter, int64, int64) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MemberArrival provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbService) MemberArrival(_a0 context.Context, _a1 string, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReleaseIdempotencyKey provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbService) ReleaseIdempotencyKey(_a0 context.Context, _a1 string, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReserveIdempotencyKey provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbService) ReserveIdempotencyKey(_a0 context.Context, _a1 *entities.IdempotencyRecord, _a2 time.Duration) (*entities.IdempotencyRecord, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *entities.IdempotencyRecord
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.IdempotencyRecord, time.Duration) *entities.IdempotencyRecord); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.IdempotencyRecord)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *entities.IdempotencyRecord, time.Duration) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreArchive provides a mock function with given fields: _a0, _a1
+func (_m *DbService) RestoreArchive(_a0 context.Context, _a1 int64) (*entities.Archive, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *entities.Archive
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *entities.Archive); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.Archive)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreSnapshot provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbService) RestoreSnapshot(_a0 context.Context, _a1 *entities.Snapshot, _a2 bool) (*entities.Archive, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *entities.Archive
+	if rf, ok := ret.Get(0).(func(context.Context, *entities.Snapshot, bool) *entities.Archive); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.Archive)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *entities.Snapshot, bool) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeAPIKey provides a mock function with given fields: _a0, _a1
+func (_m *DbService) RevokeAPIKey(_a0 context.Context, _a1 int64) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveLayoutTemplate provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbService) SaveLayoutTemplate(_a0 context.Context, _a1 string, _a2 entities.TableGroups) (*entities.LayoutTemplate, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *entities.LayoutTemplate
+	if rf, ok := ret.Get(0).(func(context.Context, string, entities.TableGroups) *entities.LayoutTemplate); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.LayoutTemplate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, entities.TableGroups) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Snapshot provides a mock function with given fields: _a0, _a1
+func (_m *DbService) Snapshot(_a0 context.Context, _a1 string) (*entities.Snapshot, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *entities.Snapshot
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entities.Snapshot); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.Snapshot)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateTable provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DbService) UpdateTable(_a0 context.Context, _a1 int64, _a2 *entities.TablePatch) (*entities.Table, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *entities.Table
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *entities.TablePatch) *entities.Table); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entities.Table)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, *entities.TablePatch) error); ok {
 		r1 = rf(_a0, _a1, _a2)
 	} else {
 		r1 = ret.Error(1)
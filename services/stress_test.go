@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"ggv2/entities"
+	"ggv2/repo"
+)
+
+const (
+	// stressParties is how many parties compete for the seats of a single table
+	stressParties = 40
+	// stressCapacity is the seats of the table, fewer than the parties want
+	stressCapacity = 20
+)
+
+// stressOutcomes are the errors calls of the stress test are expected to fail with
+var stressOutcomes = []error{
+	repo.ErrGuestAlreadyRSVP,
+	repo.ErrFailedOptimisticLock,
+	repo.ErrLockConflict,
+	repo.ErrDBErr,
+	repo.ErrGuestNotFound,
+	ErrTableIsFull,
+	ErrGuestAlreadyArrived,
+	ErrGuestNotArrived,
+	ErrPartyAlreadyArrived,
+	ErrPartyMemberAlreadyArrived,
+	repo.ErrPartyMemberNotFound,
+}
+
+// stress runs an evening at a single table against uow, every step running concurrently:
+// each party RSVP twice, then half of the parties check-in while the others check-in a party
+// member or leave, then every party leaves twice. The seats of the table are checked after
+// each step, the outcome of every call is counted by the sentinel error it failed with, nil
+// when it succeeded.
+func stress(t *testing.T, uow repo.UnitOfWork, tableID int64, prefix string, seats func() (*entities.Table, []*entities.Guest)) map[error]int {
+	ctx := context.Background()
+	svc := NewGuestService(uow)
+	outcomes := map[error]int{}
+	var mu sync.Mutex
+	parallel := func(n int, fn func(i int) error) {
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				var outcome error
+				if err := fn(i); err != nil {
+					outcome = err
+					for _, sentinel := range stressOutcomes {
+						if errors.Is(err, sentinel) {
+							outcome = sentinel
+							break
+						}
+					}
+				}
+				mu.Lock()
+				outcomes[outcome]++
+				mu.Unlock()
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+	}
+	name := func(i int) string {
+		return fmt.Sprintf("%s%d", prefix, i%stressParties)
+	}
+
+	parallel(2*stressParties, func(i int) error {
+		guest := &entities.Guest{Name: name(i), TableID: tableID, TotalGuests: int64(1 + i%3)}
+		if i%3 == 2 {
+			guest.Members = []*entities.PartyMember{{Name: "plusone"}}
+		}
+		return svc.AddToGuestList(ctx, guest)
+	})
+	assertSeats(t, "after RSVP", seats)
+
+	parallel(2*stressParties, func(i int) error {
+		switch {
+		case i < stressParties:
+			// Parties arrive larger than they RSVP
+			arriving := int64(1 + i%4)
+			var members []string
+			if i%3 == 2 && arriving > 1 {
+				members = []string{"plusone"}
+			}
+			_, _, err := svc.GuestArrival(ctx, name(i), arriving, members)
+			return err
+		case i%2 == 0:
+			_, _, err := svc.GuestDepart(ctx, name(i))
+			return err
+		default:
+			_, _, err := svc.MemberArrival(ctx, name(i), "plusone")
+			return err
+		}
+	})
+	assertSeats(t, "after check-in", seats)
+
+	parallel(2*stressParties, func(i int) error {
+		_, _, err := svc.GuestDepart(ctx, name(i))
+		return err
+	})
+	assertSeats(t, "after departure", seats)
+	return outcomes
+}
+
+// assertSeats checks that the seats of the table are never oversold and that they add up to
+// the parties seated at it.
+func assertSeats(t *testing.T, desc string, seats func() (*entities.Table, []*entities.Guest)) {
+	table, guests := seats()
+	planned, arrived := int64(0), int64(0)
+	names := map[string]bool{}
+	for _, g := range guests {
+		assert.False(t, names[g.Name], "%s: %s RSVP twice", desc, g.Name)
+		names[g.Name] = true
+		planned += g.TotalGuests
+		arrived += g.TotalArrivedGuests
+	}
+	assert.GreaterOrEqual(t, table.PlannedCapacity, int64(0), desc)
+	assert.GreaterOrEqual(t, table.AvailableCapacity, int64(0), desc)
+	assert.Equal(t, table.Capacity-planned, table.PlannedCapacity, desc)
+	assert.Equal(t, table.Capacity-arrived, table.AvailableCapacity, desc)
+}
+
+// TestStress runs the stress test with both kinds of locking against the database of STRESS_DSN,
+// created by the scripts in sql/. CI runs it against a MySQL container, it is skipped when
+// STRESS_DSN is not set. The rows it creates are removed afterwards.
+func TestStress(t *testing.T) {
+	dsn := os.Getenv("STRESS_DSN")
+	if dsn == "" {
+		t.Skip("STRESS_DSN is not set")
+	}
+	ctx := context.Background()
+	db, err := sqlx.Connect("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	type TestCase struct {
+		name    string
+		desc    string
+		locking repo.Locking
+	}
+	testcases := []TestCase{
+		{
+			name:    "optimistic",
+			desc:    "versioned writes retried on conflicts",
+			locking: repo.OptimisticLocking,
+		},
+		{
+			name:    "pessimistic",
+			desc:    "units of work wait for the rows they lock",
+			locking: repo.PessimisticLocking,
+		},
+	}
+	for _, v := range testcases {
+		v := v
+		t.Run(v.name, func(t *testing.T) {
+			r := repo.NewDbRepo(db).WithLocking(v.locking)
+			table := &entities.Table{Capacity: stressCapacity, Name: "stress"}
+			err := r.Atomic(ctx, func(tx repo.Tx) error {
+				return tx.CreateTable(ctx, table)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Remove the rows even when an assertion stops the test
+			t.Cleanup(func() {
+				db.MustExec("DELETE FROM `party_members` WHERE guestid IN (SELECT id FROM `guests` WHERE tableid = ?)", table.TableID)
+				db.MustExec("DELETE FROM `guests` WHERE tableid = ?", table.TableID)
+				db.MustExec("DELETE FROM `table` WHERE id = ?", table.TableID)
+			})
+			prefix := fmt.Sprintf("stress-%s-%d-", v.locking, time.Now().UnixNano())
+			outcomes := stress(t, r, table.TableID, prefix, func() (*entities.Table, []*entities.Guest) {
+				current, err := r.GetTable(ctx, table.TableID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				guests := []*entities.Guest{}
+				if err = db.Select(&guests, "SELECT * FROM `guests` WHERE tableid = ?", table.TableID); err != nil {
+					t.Fatal(err)
+				}
+				return current, guests
+			})
+			t.Logf("%s: %v", v.desc, outcomes)
+			assert.NotZero(t, outcomes[nil], v.desc)
+			// Deadlocks and lock wait timeouts are retried rather than failing as database errors
+			assert.Zero(t, outcomes[repo.ErrDBErr], v.desc)
+			// Every call succeeds or fails with one of the sentinels of the seating rules
+			for outcome := range outcomes {
+				if outcome != nil {
+					assert.Contains(t, stressOutcomes, outcome, v.desc)
+				}
+			}
+		})
+	}
+}
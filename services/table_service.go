@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"ggv2/audit"
+	"ggv2/entities"
+	"ggv2/repo"
+)
+
+var (
+	ErrCapacityBelowGuests   = errors.New("capacity cannot be less than number of guests RSVP or arrived")
+	ErrTableIsFull           = errors.New("table is full")
+	ErrTableNotEmpty         = errors.New("table still has guests, provide a table to reassign them to")
+	ErrReassignTableNotFound = errors.New("table to reassign guests to not found")
+)
+
+// TableService applies the seating rules of resizing and removing tables. Every rule is checked
+// and applied within a single unit of work, which also records the change in the audit log.
+type TableService struct {
+	uow repo.UnitOfWork
+}
+
+func NewTableService(uow repo.UnitOfWork) *TableService {
+	return &TableService{
+		uow: uow,
+	}
+}
+
+// UpdateTable applies a partial update to the capacity and metadata of a table. A table cannot
+// be resized below the seats taken by guests that RSVP or arrived. The table is returned as it
+// was before and after the update.
+func (s *TableService) UpdateTable(ctx context.Context, id int64, patch *entities.TablePatch) (before, after *entities.Table, err error) {
+	err = s.uow.Atomic(ctx, func(tx repo.Tx) error {
+		table, err := tx.GetTable(ctx, id)
+		if err != nil {
+			return err
+		}
+		prev := *table
+		patch.Apply(table)
+		if table.PlannedCapacity < 0 || table.AvailableCapacity < 0 {
+			// New capacity cannot fit guests that RSVP or arrived
+			return ErrCapacityBelowGuests
+		}
+		if err = tx.UpdateTable(ctx, table); err != nil {
+			return err
+		}
+		if err = recordAudit(ctx, tx, audit.OpUpdateTable, tableTarget(id), &prev, table); err != nil {
+			return err
+		}
+		before, after = &prev, table
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// DeleteTable removes a table. A table with guests can only be removed when its guests are
+// reassigned to another table with enough planned and available seats for them. The removed
+// table is returned.
+func (s *TableService) DeleteTable(ctx context.Context, id, reassignTo int64) (*entities.Table, error) {
+	var deleted *entities.Table
+	err := s.uow.Atomic(ctx, func(tx repo.Tx) error {
+		// Tables are locked in ascending id order, the table guests are reassigned to may be
+		// read before the removed table
+		var target *entities.Table
+		var targetErr error
+		targetFirst := reassignTo != 0 && reassignTo < id
+		if targetFirst {
+			target, targetErr = tx.GetTable(ctx, reassignTo)
+			if targetErr != nil && !errors.Is(targetErr, repo.ErrTableNotFound) {
+				return targetErr
+			}
+		}
+		table, err := tx.GetTable(ctx, id)
+		if err != nil {
+			return err
+		}
+		planned, arrived := plannedSeats(table), arrivedSeats(table)
+		if planned > 0 || arrived > 0 {
+			if reassignTo == 0 || reassignTo == id {
+				return ErrTableNotEmpty
+			}
+			if !targetFirst {
+				target, targetErr = tx.GetTable(ctx, reassignTo)
+			}
+			if targetErr != nil {
+				if errors.Is(targetErr, repo.ErrTableNotFound) {
+					return ErrReassignTableNotFound
+				}
+				return targetErr
+			}
+			if target.PlannedCapacity < planned || target.AvailableCapacity < arrived {
+				// Target table cannot accomodate guests
+				return ErrTableIsFull
+			}
+			if err = tx.MoveGuests(ctx, table.TableID, target.TableID); err != nil {
+				return err
+			}
+			target.PlannedCapacity -= planned
+			target.AvailableCapacity -= arrived
+			if err = tx.UpdateTable(ctx, target); err != nil {
+				return err
+			}
+		}
+		if err = tx.DeleteTable(ctx, table); err != nil {
+			// Table changed since it was read, guests may have been added
+			return err
+		}
+		// The guests of a removed table are recorded as moved to the other table
+		var reassigned interface{}
+		if reassignTo != 0 {
+			reassigned = map[string]int64{"guests_reassigned_to": reassignTo}
+		}
+		if err = recordAudit(ctx, tx, audit.OpDeleteTable, tableTarget(id), table, reassigned); err != nil {
+			return err
+		}
+		deleted = table
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// plannedSeats returns the seats of a table taken by guests that RSVP.
+func plannedSeats(t *entities.Table) int64 {
+	return t.Capacity - t.PlannedCapacity
+}
+
+// arrivedSeats returns the seats of a table taken by guests that arrived.
+func arrivedSeats(t *entities.Table) int64 {
+	return t.Capacity - t.AvailableCapacity
+}
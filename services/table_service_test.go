@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"ggv2/entities"
+	"ggv2/repo"
+	"ggv2/repo/mocks"
+)
+
+func TestTableServiceUpdateTable(t *testing.T) {
+	type TestCase struct {
+		name     string
+		desc     string
+		patch    *entities.TablePatch
+		expTable *entities.Table
+		expErr   error
+	}
+	capacity := func(c int64) *int64 { return &c }
+	testcases := []TestCase{
+		{
+			name:     "Happy case",
+			desc:     "table resized above seats taken",
+			patch:    &entities.TablePatch{Capacity: capacity(6)},
+			expTable: &entities.Table{TableID: 1, Capacity: 6, PlannedCapacity: 2, AvailableCapacity: 5},
+		},
+		{
+			name:   "Sad case",
+			desc:   "table resized below guests that RSVP",
+			patch:  &entities.TablePatch{Capacity: capacity(3)},
+			expErr: ErrCapacityBelowGuests,
+		},
+	}
+	for _, v := range testcases {
+		r := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(r, tx)
+		expectAudit(tx)
+		svc := NewTableService(r)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(&entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 4, AvailableCapacity: 7}, nil)
+		tx.On("UpdateTable", mock.Anything, mock.Anything).Return(nil)
+		before, after, actErr := svc.UpdateTable(context.Background(), 1, v.patch)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		assert.Equal(t, v.expTable, after, v.desc)
+		if v.expErr != nil {
+			tx.AssertNotCalled(t, "UpdateTable", mock.Anything, mock.Anything)
+			continue
+		}
+		assert.Equal(t, int64(8), before.Capacity, v.desc)
+	}
+}
+
+func TestTableServiceDeleteTable(t *testing.T) {
+	type TestCase struct {
+		name       string
+		desc       string
+		table      *entities.Table
+		reassignTo int64
+		target     *entities.Table
+		targetErr  error
+		expTarget  *entities.Table
+		expErr     error
+	}
+	testcases := []TestCase{
+		{
+			name:  "Happy case",
+			desc:  "empty table removed",
+			table: &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 8, AvailableCapacity: 8},
+		},
+		{
+			name:       "Happy case",
+			desc:       "guests reassigned before table removed",
+			table:      &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 6},
+			reassignTo: 2,
+			target:     &entities.Table{TableID: 2, Capacity: 10, PlannedCapacity: 4, AvailableCapacity: 10},
+			expTarget:  &entities.Table{TableID: 2, Capacity: 10, PlannedCapacity: 1, AvailableCapacity: 8},
+		},
+		{
+			name:   "Sad case",
+			desc:   "table with guests and no table to reassign them to",
+			table:  &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 8},
+			expErr: ErrTableNotEmpty,
+		},
+		{
+			name:       "Sad case",
+			desc:       "guests reassigned to the table being removed",
+			table:      &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 8},
+			reassignTo: 1,
+			expErr:     ErrTableNotEmpty,
+		},
+		{
+			name:       "Sad case",
+			desc:       "table to reassign guests to not found",
+			table:      &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 8},
+			reassignTo: 2,
+			targetErr:  repo.ErrTableNotFound,
+			expErr:     ErrReassignTableNotFound,
+		},
+		{
+			name:       "Sad case",
+			desc:       "table to reassign guests to cannot seat them",
+			table:      &entities.Table{TableID: 1, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 6},
+			reassignTo: 2,
+			target:     &entities.Table{TableID: 2, Capacity: 10, PlannedCapacity: 4, AvailableCapacity: 1},
+			expErr:     ErrTableIsFull,
+		},
+	}
+	for _, v := range testcases {
+		r := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(r, tx)
+		expectAudit(tx)
+		svc := NewTableService(r)
+		tx.On("GetTable", mock.Anything, int64(1)).Return(v.table, nil)
+		tx.On("GetTable", mock.Anything, int64(2)).Return(v.target, v.targetErr)
+		tx.On("MoveGuests", mock.Anything, int64(1), int64(2)).Return(nil)
+		tx.On("UpdateTable", mock.Anything, mock.Anything).Return(nil)
+		tx.On("DeleteTable", mock.Anything, v.table).Return(nil)
+		deleted, actErr := svc.DeleteTable(context.Background(), 1, v.reassignTo)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		if v.expErr != nil {
+			assert.Nil(t, deleted, v.desc)
+			tx.AssertNotCalled(t, "MoveGuests", mock.Anything, mock.Anything, mock.Anything)
+			tx.AssertNotCalled(t, "DeleteTable", mock.Anything, mock.Anything)
+			continue
+		}
+		assert.Equal(t, v.table, deleted, v.desc)
+		if v.expTarget != nil {
+			tx.AssertCalled(t, "MoveGuests", mock.Anything, int64(1), int64(2))
+			tx.AssertCalled(t, "UpdateTable", mock.Anything, v.expTarget)
+		} else {
+			tx.AssertNotCalled(t, "MoveGuests", mock.Anything, mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestTableServiceDeleteTableLockOrder(t *testing.T) {
+	type TestCase struct {
+		name      string
+		desc      string
+		table     *entities.Table
+		target    *entities.Table
+		targetErr error
+		expErr    error
+	}
+	testcases := []TestCase{
+		{
+			name:   "Happy case",
+			desc:   "guests reassigned to a table with a lower id",
+			table:  &entities.Table{TableID: 3, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 8},
+			target: &entities.Table{TableID: 2, Capacity: 10, PlannedCapacity: 10, AvailableCapacity: 10},
+		},
+		{
+			name:      "Happy case",
+			desc:      "empty table removed while the table to reassign guests to is missing",
+			table:     &entities.Table{TableID: 3, Capacity: 8, PlannedCapacity: 8, AvailableCapacity: 8},
+			targetErr: repo.ErrTableNotFound,
+		},
+		{
+			name:      "Sad case",
+			desc:      "table with guests while the table to reassign guests to is missing",
+			table:     &entities.Table{TableID: 3, Capacity: 8, PlannedCapacity: 5, AvailableCapacity: 8},
+			targetErr: repo.ErrTableNotFound,
+			expErr:    ErrReassignTableNotFound,
+		},
+	}
+	for _, v := range testcases {
+		r := new(mocks.DbRepo)
+		tx := new(mocks.Tx)
+		expectAtomic(r, tx)
+		expectAudit(tx)
+		svc := NewTableService(r)
+		tx.On("GetTable", mock.Anything, int64(2)).Return(v.target, v.targetErr)
+		tx.On("GetTable", mock.Anything, int64(3)).Return(v.table, nil)
+		tx.On("MoveGuests", mock.Anything, int64(3), int64(2)).Return(nil)
+		tx.On("UpdateTable", mock.Anything, mock.Anything).Return(nil)
+		tx.On("DeleteTable", mock.Anything, v.table).Return(nil)
+		_, actErr := svc.DeleteTable(context.Background(), 3, 2)
+		assert.Equal(t, v.expErr, actErr, v.desc)
+		// Tables are locked in ascending id order
+		assert.Equal(t, int64(2), tx.Calls[0].Arguments.Get(1), v.desc)
+		assert.Equal(t, int64(3), tx.Calls[1].Arguments.Get(1), v.desc)
+	}
+}
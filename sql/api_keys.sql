@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `api_keys` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(45) NOT NULL,
+  `prefix` varchar(12) NOT NULL,
+  `role` varchar(20) NOT NULL DEFAULT 'viewer',
+  `key_hash` char(64) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `revoked` tinyint(1) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `key_hash` (`key_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS `archives` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `event` varchar(45) NOT NULL DEFAULT '',
+  `tables` int(11) NOT NULL DEFAULT '0',
+  `guests` int(11) NOT NULL DEFAULT '0',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `restored` tinyint(1) NOT NULL DEFAULT '0',
+  `data` longtext NOT NULL,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
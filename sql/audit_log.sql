@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS `audit_log` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `actor` varchar(100) NOT NULL DEFAULT '',
+  `request_id` varchar(64) NOT NULL DEFAULT '',
+  `operation` varchar(45) NOT NULL,
+  `target` varchar(100) NOT NULL DEFAULT '',
+  `before_value` longtext,
+  `after_value` longtext,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `actor` (`actor`),
+  KEY `request_id` (`request_id`),
+  KEY `operation` (`operation`),
+  KEY `target` (`target`),
+  KEY `created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
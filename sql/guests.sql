@@ -6,5 +6,10 @@ CREATE TABLE IF NOT EXISTS `guests` (
   `version` int(11) NOT NULL,
   `arrivaltime` varchar(45) DEFAULT NULL,
   `tableid` int(11) NOT NULL,
-  PRIMARY KEY (`id`)
+  `dietary_tags` varchar(255) NOT NULL DEFAULT '',
+  `allergens` varchar(255) NOT NULL DEFAULT '',
+  `dietary_notes` varchar(255) NOT NULL DEFAULT '',
+  `meal_choice` varchar(45) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `name` (`name`)
 ) ENGINE=InnoDB DEFAULT CHARSET=latin1;
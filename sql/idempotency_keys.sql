@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `idempotency_keys` (
+  `actor` varchar(100) NOT NULL,
+  `idempotency_key` varchar(255) NOT NULL,
+  `request_hash` char(64) NOT NULL,
+  `status` int(11) NOT NULL DEFAULT '0',
+  `content_type` varchar(255) NOT NULL DEFAULT '',
+  `body` mediumblob,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expires_at` timestamp NOT NULL,
+  PRIMARY KEY (`actor`,`idempotency_key`),
+  KEY `expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
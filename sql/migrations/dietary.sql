@@ -0,0 +1,57 @@
+-- Adds the dietary requirements and meal choices to guests and party_members tables created
+-- before them. Each column is added only when it is missing, the script can be run again.
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'guests' AND column_name = 'dietary_tags'),
+  'DO 0',
+  'ALTER TABLE `guests` ADD COLUMN `dietary_tags` varchar(255) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'guests' AND column_name = 'allergens'),
+  'DO 0',
+  'ALTER TABLE `guests` ADD COLUMN `allergens` varchar(255) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'guests' AND column_name = 'dietary_notes'),
+  'DO 0',
+  'ALTER TABLE `guests` ADD COLUMN `dietary_notes` varchar(255) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'guests' AND column_name = 'meal_choice'),
+  'DO 0',
+  'ALTER TABLE `guests` ADD COLUMN `meal_choice` varchar(45) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'party_members' AND column_name = 'dietary_tags'),
+  'DO 0',
+  'ALTER TABLE `party_members` ADD COLUMN `dietary_tags` varchar(255) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'party_members' AND column_name = 'allergens'),
+  'DO 0',
+  'ALTER TABLE `party_members` ADD COLUMN `allergens` varchar(255) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'party_members' AND column_name = 'dietary_notes'),
+  'DO 0',
+  'ALTER TABLE `party_members` ADD COLUMN `dietary_notes` varchar(255) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'party_members' AND column_name = 'meal_choice'),
+  'DO 0',
+  'ALTER TABLE `party_members` ADD COLUMN `meal_choice` varchar(45) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
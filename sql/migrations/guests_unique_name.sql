@@ -0,0 +1,24 @@
+-- Adds the unique key on guest names to a guests table created before it existed. Guests that
+-- RSVP under a name already taken keep their seats and are renamed "<name>#<id>", the guest
+-- that RSVP first keeps the name. Rows are grouped by the collation of the column, like the
+-- unique key compares them. The key is added only when it is missing, the script can be run again.
+START TRANSACTION;
+
+UPDATE `guests` g
+JOIN (
+  SELECT `name`, MIN(`id`) AS `keep`
+  FROM `guests`
+  GROUP BY `name`
+  HAVING COUNT(*) > 1
+) d ON g.`name` = d.`name` AND g.`id` <> d.`keep`
+SET g.`name` = CONCAT(LEFT(g.`name`, 44 - LENGTH(g.`id`)), '#', g.`id`),
+    g.`version` = g.`version` + 1;
+
+COMMIT;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'guests' AND index_name = 'name'),
+  'DO 0',
+  'ALTER TABLE `guests` ADD UNIQUE KEY `name` (`name`)');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
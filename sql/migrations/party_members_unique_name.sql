@@ -0,0 +1,24 @@
+-- Adds the unique key on the names of the members of a party to a party_members table created
+-- before it existed. Members that share a name with an earlier member of the same party are
+-- renamed "<name>#<id>", the member added first keeps the name. Rows are grouped by the collation
+-- of the column, like the unique key compares them. The key is added only when it is missing, the
+-- script can be run again.
+START TRANSACTION;
+
+UPDATE `party_members` m
+JOIN (
+  SELECT `guestid`, `name`, MIN(`id`) AS `keep`
+  FROM `party_members`
+  GROUP BY `guestid`, `name`
+  HAVING COUNT(*) > 1
+) d ON m.`guestid` = d.`guestid` AND m.`name` = d.`name` AND m.`id` <> d.`keep`
+SET m.`name` = CONCAT(LEFT(m.`name`, 44 - LENGTH(m.`id`)), '#', m.`id`);
+
+COMMIT;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'party_members' AND index_name = 'guestid_name'),
+  'DO 0',
+  'ALTER TABLE `party_members` ADD UNIQUE KEY `guestid_name` (`guestid`, `name`)');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
@@ -0,0 +1,16 @@
+-- Adds the event tables belong to, and the key tables are scoped by event with, to a table
+-- created before them. Existing tables belong to the default event, the empty string. The
+-- column and the key are added only when they are missing, the script can be run again.
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'table' AND column_name = 'event'),
+  'DO 0',
+  'ALTER TABLE `table` ADD COLUMN `event` varchar(45) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'table' AND index_name = 'event'),
+  'DO 0',
+  'ALTER TABLE `table` ADD KEY `event` (`event`)');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
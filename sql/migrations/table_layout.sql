@@ -0,0 +1,37 @@
+-- Adds the names, zones, shapes and tags of tables, and the key tables are filtered by zone
+-- with, to a table created before them. Each column and the key are added only when they are
+-- missing, the script can be run again.
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'table' AND column_name = 'name'),
+  'DO 0',
+  'ALTER TABLE `table` ADD COLUMN `name` varchar(45) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'table' AND column_name = 'zone'),
+  'DO 0',
+  'ALTER TABLE `table` ADD COLUMN `zone` varchar(45) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'table' AND column_name = 'shape'),
+  'DO 0',
+  'ALTER TABLE `table` ADD COLUMN `shape` varchar(10) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'table' AND column_name = 'tags'),
+  'DO 0',
+  'ALTER TABLE `table` ADD COLUMN `tags` varchar(255) NOT NULL DEFAULT ''''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'table' AND index_name = 'zone'),
+  'DO 0',
+  'ALTER TABLE `table` ADD KEY `zone` (`zone`)');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
@@ -0,0 +1,22 @@
+-- Adds the floor plan positions and rotations to a table created before them. Each column is
+-- added only when it is missing, the script can be run again.
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'table' AND column_name = 'pos_x'),
+  'DO 0',
+  'ALTER TABLE `table` ADD COLUMN `pos_x` int(11) NOT NULL DEFAULT ''0''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'table' AND column_name = 'pos_y'),
+  'DO 0',
+  'ALTER TABLE `table` ADD COLUMN `pos_y` int(11) NOT NULL DEFAULT ''0''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
+
+SET @ddl = IF(EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'table' AND column_name = 'rotation'),
+  'DO 0',
+  'ALTER TABLE `table` ADD COLUMN `rotation` int(11) NOT NULL DEFAULT ''0''');
+PREPARE ddl FROM @ddl;
+EXECUTE ddl;
+DEALLOCATE PREPARE ddl;
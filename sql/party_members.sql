@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `party_members` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `guestid` int(11) NOT NULL,
+  `name` varchar(45) NOT NULL,
+  `age_group` varchar(10) NOT NULL DEFAULT '',
+  `arrived` tinyint(1) NOT NULL DEFAULT '0',
+  `dietary_tags` varchar(255) NOT NULL DEFAULT '',
+  `allergens` varchar(255) NOT NULL DEFAULT '',
+  `dietary_notes` varchar(255) NOT NULL DEFAULT '',
+  `meal_choice` varchar(45) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `guestid_name` (`guestid`, `name`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
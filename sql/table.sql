@@ -4,5 +4,15 @@ CREATE TABLE IF NOT EXISTS `table` (
   `pcapacity` int(11) NOT NULL DEFAULT '0',
   `acapacity` int(11) NOT NULL DEFAULT '0',
   `version` int(11) NOT NULL DEFAULT '0',
-  PRIMARY KEY (`id`)
+  `event` varchar(45) NOT NULL DEFAULT '',
+  `name` varchar(45) NOT NULL DEFAULT '',
+  `zone` varchar(45) NOT NULL DEFAULT '',
+  `shape` varchar(10) NOT NULL DEFAULT '',
+  `tags` varchar(255) NOT NULL DEFAULT '',
+  `pos_x` int(11) NOT NULL DEFAULT '0',
+  `pos_y` int(11) NOT NULL DEFAULT '0',
+  `rotation` int(11) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`id`),
+  KEY `event` (`event`),
+  KEY `zone` (`zone`)
 ) ENGINE=InnoDB DEFAULT CHARSET=latin1;
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+
+	"ggv2/config"
+)
+
+func TestInit(t *testing.T) {
+	type TestCase struct {
+		name   string
+		desc   string
+		cfg    config.TracingConfig
+		expErr bool
+	}
+	testcases := []TestCase{
+		{
+			name: "Happy case",
+			desc: "no exporter",
+			cfg:  config.TracingConfig{},
+		},
+		{
+			name: "Happy case",
+			desc: "stdout exporter",
+			cfg:  config.TracingConfig{Exporter: ExporterStdout, SampleRatio: 1, ServiceName: "ggv2"},
+		},
+		{
+			name: "Happy case",
+			desc: "otlp exporter",
+			cfg:  config.TracingConfig{Exporter: ExporterOTLP, OTLPEndpoint: "localhost:4318", OTLPInsecure: true, SampleRatio: 1, ServiceName: "ggv2"},
+		},
+		{
+			name:   "Sad case",
+			desc:   "unknown exporter",
+			cfg:    config.TracingConfig{Exporter: "jaeger"},
+			expErr: true,
+		},
+	}
+	for _, v := range testcases {
+		shutdown, err := Init(context.Background(), v.cfg)
+		assert.Equal(t, v.expErr, err != nil, v.desc)
+		if err == nil {
+			// W3C trace context is propagated with or without an exporter
+			assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent", v.desc)
+			assert.Nil(t, shutdown(context.Background()), v.desc)
+		}
+	}
+}